@@ -1,7 +1,6 @@
 package boredmodule
 
 import (
-	"fmt"
 	"math"
 	"strings"
 	"time"
@@ -17,6 +16,12 @@ type BoredModule struct {
 	count        map[bot.DiscordChannel]int   // Count of consecutive bored messages per channel
 }
 
+func init() {
+	bot.RegisterModuleFactory("Bored", func(guild *bot.GuildInfo, deps map[bot.ModuleID]bot.Module) bot.Module {
+		return New()
+	})
+}
+
 // New instance of BoredModule
 func New() *BoredModule {
 	return &BoredModule{0, make(map[bot.DiscordChannel]int64), make(map[bot.DiscordChannel]int)}
@@ -47,7 +52,7 @@ func (w *BoredModule) idle(info *bot.GuildInfo, id bot.DiscordChannel, t time.Ti
 			},
 			Timestamp: t,
 		}
-		fmt.Println("Sending bored command ", m.Content, " on ", id)
+		info.ModuleLogger(w).Debug("Sending bored command "+m.Content, bot.LogFields{"channel": id})
 
 		info.Bot.ProcessCommand(m, info, t.Unix(), info.IsDebug(bot.DiscordChannel(m.ChannelID)), false)
 	}
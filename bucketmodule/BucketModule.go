@@ -15,6 +15,12 @@ import (
 type BucketModule struct {
 }
 
+func init() {
+	bot.RegisterModuleFactory("Bucket", func(guild *bot.GuildInfo, deps map[bot.ModuleID]bot.Module) bot.Module {
+		return New()
+	})
+}
+
 // New instance of BucketModule
 func New() *BucketModule {
 	return &BucketModule{}
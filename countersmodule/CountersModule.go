@@ -13,6 +13,12 @@ import (
 type CountersModule struct {
 }
 
+func init() {
+	bot.RegisterModuleFactory("Counters", func(guild *bot.GuildInfo, deps map[bot.ModuleID]bot.Module) bot.Module {
+		return New()
+	})
+}
+
 // New instance of CountersModule
 func New() *CountersModule {
 	return &CountersModule{}
@@ -26,11 +32,11 @@ func (w *CountersModule) Name() string {
 // Commands in the module
 func (w *CountersModule) Commands() []bot.Command {
 	return []bot.Command{
-		&addCounterCommand{},
-		&removeCounterCommand{},
-		&counterCommand{},
-		&incrementCommand{},
-		&decrementCommand{},
+		bot.NewCommandGroup("Counter", "Manages counters.", &counterCommand{}).
+			Add("Add", &addCounterCommand{}).
+			Add("Remove", &removeCounterCommand{}).
+			Add("Inc", &incrementCommand{}).
+			Add("Dec", &decrementCommand{}),
 	}
 }
 
@@ -81,7 +87,7 @@ func (c *addCounterCommand) Process(args []string, msg *discordgo.Message, indic
 }
 func (c *addCounterCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
 	return &bot.CommandUsage{
-		Desc: "Creates a new counter with an initial value and description that can be incremented with the `" + info.Config.Basic.CommandPrefix + "increment` command, or decremented with the `" + info.Config.Basic.CommandPrefix + "decrement` command.",
+		Desc: "Creates a new counter with an initial value and description that can be incremented with the `" + info.Config.Basic.CommandPrefix + "counter inc` command, or decremented with the `" + info.Config.Basic.CommandPrefix + "counter dec` command.",
 		Params: []bot.CommandUsageParam{
 			{Name: "name", Desc: "A short name for the counter. Quotes are required if it has spaces.", Optional: false},
 			{Name: "initial value", Desc: "A number to start the counter at. If omitted, defaults to 0.", Optional: true},
@@ -0,0 +1,368 @@
+package custommodule
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	bot "github.com/erikmcclure/sweetiebot/sweetiebot"
+)
+
+const (
+	maxResponseLength = 1900
+	maxTagLookups     = 3 // Each tag lookup hits the database, so we only allow a few per response
+)
+
+var nameregex = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+var templateregex = regexp.MustCompile(`\{([a-z0-9.]+)(?::([^{}]*))?\}`)
+var userregex = regexp.MustCompile(`^<@!?([0-9]+)>$`)
+
+// ItemPicker picks a random item using a tag search, which is implemented by the tag module
+type ItemPicker interface {
+	PickItem(tags string, info *bot.GuildInfo) (string, error)
+}
+
+// CustomModule lets moderators create simple commands that respond with a message
+type CustomModule struct {
+	lock     sync.RWMutex
+	commands map[string]*customCommand
+	picker   ItemPicker
+	loaded   bool // False until the custom commands have been loaded from the database
+}
+
+func init() {
+	bot.RegisterModuleFactory("Custom", func(guild *bot.GuildInfo, deps map[bot.ModuleID]bot.Module) bot.Module {
+		picker, _ := deps["tag"].(ItemPicker)
+		return New(guild, picker)
+	}, "Tag?") // Tag lookups are skipped if the tag module isn't loaded
+}
+
+// New instance of CustomModule, which loads the custom commands for this guild from the database. If the database is
+// unavailable, the commands are loaded on a later tick instead.
+func New(guild *bot.GuildInfo, picker ItemPicker) *CustomModule {
+	w := &CustomModule{commands: make(map[string]*customCommand), picker: picker}
+	if w.load(guild) == nil {
+		guild.Bot.Logger.Warn("Custom commands couldn't be loaded because the database is unavailable, they will be loaded once it's back", bot.LogFields{"guild": guild.ID, "module": "custom"})
+	}
+	return w
+}
+
+// load gets the custom commands for this guild from the database and returns the ones that weren't already known,
+// or nil if the database is unavailable
+func (w *CustomModule) load(guild *bot.GuildInfo) []bot.Command {
+	if !guild.Bot.DB.CheckStatus() {
+		return nil
+	}
+	stored := guild.Bot.DB.GetCustomCommands(bot.SBatoi(guild.ID))
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	added := []bot.Command{}
+	for _, v := range stored {
+		if _, ok := w.commands[v.Name]; !ok {
+			w.commands[v.Name] = &customCommand{v.Name, v.Response, w}
+			added = append(added, w.commands[v.Name])
+		}
+	}
+	w.loaded = true
+	return added
+}
+
+// OnTick retries loading the custom commands if the database was unavailable when the module was created
+func (w *CustomModule) OnTick(info *bot.GuildInfo, t time.Time) {
+	w.lock.RLock()
+	loaded := w.loaded
+	w.lock.RUnlock()
+	if loaded {
+		return
+	}
+	added := w.load(info)
+	for _, c := range added {
+		info.AddCommand(c, w)
+	}
+	if len(added) > 0 {
+		info.Log("Loaded ", len(added), " custom commands now that the database is available.")
+		info.UpdateApplicationCommands()
+	}
+}
+
+// Name of the module
+func (w *CustomModule) Name() string {
+	return "Custom"
+}
+
+// Commands in the module
+func (w *CustomModule) Commands() []bot.Command {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	cmds := make([]bot.Command, 0, 3+len(w.commands))
+	cmds = append(cmds, &addCommand{w}, &removeCommand{w}, &listCommand{w})
+	names := make([]string, 0, len(w.commands))
+	for k := range w.commands {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		cmds = append(cmds, w.commands[k])
+	}
+	return cmds
+}
+
+// Description of the module
+func (w *CustomModule) Description(info *bot.GuildInfo) string {
+	return "Allows moderators to create custom commands that respond with a message. Use `" + info.Config.Basic.CommandPrefix + "help addcommand` to see what variables a response can contain."
+}
+
+// Render fills in the template variables in a custom command response
+func (w *CustomModule) Render(response string, args []string, msg *discordgo.Message, info *bot.GuildInfo) string {
+	author := bot.DiscordUser(msg.Author.ID)
+	user := author
+	for _, arg := range args { // The mentioned user is the first argument that pings someone, or the author if there isn't one
+		if m := userregex.FindStringSubmatch(arg); m != nil {
+			user = bot.DiscordUser(m[1])
+			break
+		}
+	}
+	lookups := 0
+
+	return templateregex.ReplaceAllStringFunc(response, func(s string) string {
+		m := templateregex.FindStringSubmatch(s)
+		name, param := m[1], strings.TrimSpace(m[2])
+		if n, err := strconv.Atoi(name); err == nil {
+			if n > 0 && n <= len(args) {
+				return info.Sanitize(args[n-1], bot.CleanMentions)
+			}
+			return ""
+		}
+		switch name {
+		case "author":
+			return info.GetUserName(author)
+		case "author.mention":
+			return author.Display()
+		case "user":
+			return info.GetUserName(user)
+		case "user.mention":
+			return user.Display()
+		case "args":
+			return info.Sanitize(strings.Join(args, " "), bot.CleanMentions)
+		case "channel":
+			return bot.DiscordChannel(msg.ChannelID).Display()
+		case "server":
+			return info.Name
+		case "randommember":
+			return randomMember(info)
+		case "tag":
+			if w.picker == nil || lookups >= maxTagLookups || !info.Bot.DB.CheckStatus() {
+				return ""
+			}
+			lookups++
+			item, err := w.picker.PickItem(param, info)
+			if err != nil {
+				return ""
+			}
+			return info.Sanitize(item, bot.CleanMentions|bot.CleanPings)
+		case "counter":
+			if v, ok := info.Config.Counters.Map[param]; ok {
+				return strconv.FormatInt(v, 10)
+			}
+			return "0"
+		}
+		return s // Leave anything we don't recognize alone so responses can still contain braces
+	})
+}
+
+func randomMember(info *bot.GuildInfo) string {
+	g, err := info.GetGuild()
+	if err != nil {
+		return ""
+	}
+	info.Bot.DG.State.RLock()
+	defer info.Bot.DG.State.RUnlock()
+	if len(g.Members) == 0 {
+		return ""
+	}
+	return info.GetMemberName(g.Members[rand.Intn(len(g.Members))])
+}
+
+type customCommand struct {
+	name     string
+	response string
+	w        *CustomModule
+}
+
+func (c *customCommand) Info() *bot.CommandInfo {
+	return &bot.CommandInfo{
+		Name:  c.name,
+		Usage: "Custom command.",
+	}
+}
+func (c *customCommand) Process(args []string, msg *discordgo.Message, indices []int, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	c.w.lock.RLock()
+	response := c.response
+	c.w.lock.RUnlock()
+	return c.w.Render(response, args, msg, info), false, nil
+}
+func (c *customCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
+	c.w.lock.RLock()
+	defer c.w.lock.RUnlock()
+	return &bot.CommandUsage{
+		Desc: "A custom command that responds with: " + c.response,
+		Params: []bot.CommandUsageParam{
+			{Name: "arguments", Desc: "Any arguments used by the response.", Optional: true, Variadic: true},
+		},
+	}
+}
+
+type addCommand struct {
+	w *CustomModule
+}
+
+func (c *addCommand) Info() *bot.CommandInfo {
+	return &bot.CommandInfo{
+		Name:      "AddCommand",
+		Usage:     "Creates or changes a custom command.",
+		Sensitive: true,
+		RawText:   true,
+	}
+}
+func (c *addCommand) Process(args []string, msg *discordgo.Message, indices []int, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	if !info.Bot.DB.CheckStatus() {
+		return "```\nA temporary database outage is preventing this command from being executed.```", false, nil
+	}
+	if len(args) < 2 {
+		return "```\nYou must provide a command name and a response.```", false, nil
+	}
+	name := strings.ToLower(args[0])
+	if !nameregex.MatchString(name) {
+		return "```\nCommand names can only contain letters, numbers, - and _, and can't be longer than 32 characters.```", false, nil
+	}
+	if existing, ok := info.GetCommand(bot.CommandID(name)); ok {
+		if _, custom := existing.(*customCommand); !custom {
+			return "```\nThere is already a built-in command called " + name + ".```", false, nil
+		}
+	}
+	if _, ok := info.Config.Basic.Aliases[name]; ok {
+		return "```\nThere is already an alias called " + name + ". Remove it with " + info.Config.Basic.CommandPrefix + "setconfig basic.aliases " + name + " first.```", false, nil
+	}
+	for _, m := range info.GetModules() {
+		if strings.ToLower(m.Name()) == name {
+			return "```\nA custom command can't have the same name as the " + m.Name() + " module.```", false, nil
+		}
+	}
+
+	response := strings.TrimSpace(msg.Content[indices[1]:])
+	if len(args) == 2 && len(response) > 1 && response[0] == '"' { // Strip the quotes if the response was quoted
+		response = args[1]
+	}
+	if len(response) == 0 {
+		return "```\nThe response can't be empty.```", false, nil
+	}
+	if len(response) > maxResponseLength {
+		return fmt.Sprintf("```\nThe response can't be longer than %v characters.```", maxResponseLength), false, nil
+	}
+
+	if err := info.Bot.DB.SetCustomCommand(bot.SBatoi(info.ID), name, response, bot.SBatoi(msg.Author.ID)); err != nil {
+		return bot.ReturnError(err)
+	}
+	c.w.lock.Lock()
+	cmd, exists := c.w.commands[name]
+	if exists {
+		cmd.response = response
+	} else {
+		cmd = &customCommand{name, response, c.w}
+		c.w.commands[name] = cmd
+	}
+	c.w.lock.Unlock()
+	if exists {
+		return "```\nChanged the response of " + info.Config.Basic.CommandPrefix + name + ".```", false, nil
+	}
+	info.AddCommand(cmd, c.w)
+	info.UpdateApplicationCommands()
+	return "```\nAdded the " + info.Config.Basic.CommandPrefix + name + " command.```", false, nil
+}
+func (c *addCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
+	return &bot.CommandUsage{
+		Desc: "Creates a custom command that responds with the given message, or changes the response of an existing custom command. Custom commands obey `modules.commandroles`, `modules.commandchannels` and `modules.commandlimits` like any other command. The response can contain these variables:\n`{author}` - The name of the user who ran the command. `{author.mention}` pings them instead.\n`{user}` - The name of the first user pinged in the arguments, or the author if no one was pinged. `{user.mention}` pings them instead.\n`{args}` - All of the arguments. `{1}`, `{2}`, etc. are the individual arguments.\n`{channel}` - The channel the command was used in.\n`{server}` - The name of the server.\n`{randommember}` - The name of a random member of the server.\n`{tag:search}` - A random item from a tag search, like the `pick` command.\n`{counter:name}` - The current value of a counter.",
+		Params: []bot.CommandUsageParam{
+			{Name: "name", Desc: "The name of the command. Can only contain letters, numbers, - and _.", Optional: false},
+			{Name: "response", Desc: "The message the command responds with.", Optional: false},
+		},
+	}
+}
+
+type removeCommand struct {
+	w *CustomModule
+}
+
+func (c *removeCommand) Info() *bot.CommandInfo {
+	return &bot.CommandInfo{
+		Name:      "RemoveCommand",
+		Usage:     "Removes a custom command.",
+		Sensitive: true,
+	}
+}
+func (c *removeCommand) Process(args []string, msg *discordgo.Message, indices []int, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	if !info.Bot.DB.CheckStatus() {
+		return "```\nA temporary database outage is preventing this command from being executed.```", false, nil
+	}
+	if len(args) < 1 {
+		return "```\nYou must provide the name of a custom command to remove.```", false, nil
+	}
+	name := strings.ToLower(args[0])
+	c.w.lock.RLock()
+	cmd, ok := c.w.commands[name]
+	c.w.lock.RUnlock()
+	if !ok {
+		return "```\nThere is no custom command called " + name + ".```", false, nil
+	}
+	if err := info.Bot.DB.RemoveCustomCommand(bot.SBatoi(info.ID), name); err != nil && err != sql.ErrNoRows {
+		return bot.ReturnError(err)
+	}
+	c.w.lock.Lock()
+	delete(c.w.commands, name)
+	c.w.lock.Unlock()
+	info.RemoveCommand(cmd)
+	info.UpdateApplicationCommands()
+	return "```\nRemoved the " + info.Config.Basic.CommandPrefix + name + " command.```", false, nil
+}
+func (c *removeCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
+	return &bot.CommandUsage{
+		Desc: "Removes a custom command created with `addcommand`.",
+		Params: []bot.CommandUsageParam{
+			{Name: "name", Desc: "The name of the custom command.", Optional: false},
+		},
+	}
+}
+
+type listCommand struct {
+	w *CustomModule
+}
+
+func (c *listCommand) Info() *bot.CommandInfo {
+	return &bot.CommandInfo{
+		Name:  "CustomCommands",
+		Usage: "Lists all custom commands.",
+	}
+}
+func (c *listCommand) Process(args []string, msg *discordgo.Message, indices []int, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	c.w.lock.RLock()
+	names := make([]string, 0, len(c.w.commands))
+	for k := range c.w.commands {
+		names = append(names, k)
+	}
+	c.w.lock.RUnlock()
+	if len(names) == 0 {
+		return "```\nThis server has no custom commands.```", false, nil
+	}
+	sort.Strings(names)
+	return "```\n" + strings.Join(names, ", ") + "```", false, nil
+}
+func (c *listCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
+	return &bot.CommandUsage{Desc: "Lists the names of all the custom commands on this server."}
+}
@@ -0,0 +1,43 @@
+package custommodule
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	bot "github.com/erikmcclure/sweetiebot/sweetiebot"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	state := discordgo.NewState()
+	g := &discordgo.Guild{ID: "1", Name: "Test Server"}
+	state.GuildAdd(g)
+	state.MemberAdd(&discordgo.Member{GuildID: "1", User: &discordgo.User{ID: "3", Username: "Author"}})
+	state.MemberAdd(&discordgo.Member{GuildID: "1", User: &discordgo.User{ID: "4", Username: "Target"}, Nick: "Nick"})
+	sb := &bot.SweetieBot{DB: &bot.BotDB{}, DG: &bot.DiscordGoSession{Session: &discordgo.Session{State: state}}}
+	info := bot.NewGuildInfo(sb, g)
+	info.Config.Counters.Map = map[string]int64{"boops": 7}
+
+	w := &CustomModule{commands: make(map[string]*customCommand)}
+	msg := &discordgo.Message{ChannelID: "2", Author: &discordgo.User{ID: "3"}}
+	for _, v := range []struct {
+		response string
+		args     []string
+		expected string
+	}{
+		{"{author} hugs {user}", []string{"<@!4>"}, "Author hugs Nick"},
+		{"{author.mention} boops {user.mention}", nil, "<@3> boops <@3>"},
+		{"{2} and {1}{3}", []string{"a", "b"}, "b and a"},
+		{"{args} in {channel} on {server}", []string{"x", "y"}, "x y in <#2> on Test Server"},
+		{"{counter:boops} boops, {counter:missing} hugs", nil, "7 boops, 0 hugs"},
+		{"{tag:cute} {unknown} {}", nil, " {unknown} {}"},
+	} {
+		if s := w.Render(v.response, v.args, msg, info); s != v.expected {
+			t.Errorf("%s: expected %q but got %q", v.response, v.expected, s)
+		}
+	}
+	if s := w.Render("{randommember}", nil, msg, info); s != "Author" && s != "Nick" {
+		t.Errorf("unexpected random member %q", s)
+	}
+}
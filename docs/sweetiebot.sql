@@ -320,6 +320,71 @@ CREATE TABLE IF NOT EXISTS `transcripts` (
   PRIMARY KEY (`Season`,`Episode`,`Line`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4//
 
+-- Dumping structure for table sweetiebot.guild_config
+CREATE TABLE IF NOT EXISTS `guild_config` (
+  `Guild` bigint(20) unsigned NOT NULL,
+  `Version` bigint(20) unsigned NOT NULL,
+  `Expires` bigint(20) NOT NULL DEFAULT 0,
+  `Updated` datetime NOT NULL,
+  `Config` mediumtext NOT NULL,
+  PRIMARY KEY (`Guild`),
+  KEY `INDEX_EXPIRES` (`Expires`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4//
+
+-- Dumping structure for table sweetiebot.config_history
+CREATE TABLE IF NOT EXISTS `config_history` (
+  `ID` bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+  `Guild` bigint(20) unsigned NOT NULL,
+  `User` bigint(20) unsigned NOT NULL,
+  `Timestamp` datetime NOT NULL,
+  `Path` varchar(128) NOT NULL,
+  `OldValue` mediumtext NOT NULL,
+  `NewValue` mediumtext NOT NULL,
+  PRIMARY KEY (`ID`),
+  KEY `INDEX_GUILD_PATH` (`Guild`,`Path`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4//
+
+-- Dumping structure for table sweetiebot.custom_commands
+CREATE TABLE IF NOT EXISTS `custom_commands` (
+  `Guild` bigint(20) unsigned NOT NULL,
+  `Name` varchar(32) NOT NULL,
+  `Response` text NOT NULL,
+  `Author` bigint(20) unsigned NOT NULL,
+  `Updated` datetime NOT NULL,
+  PRIMARY KEY (`Guild`,`Name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4//
+
+-- Dumping structure for table sweetiebot.api_tokens
+CREATE TABLE IF NOT EXISTS `api_tokens` (
+  `Guild` bigint(20) unsigned NOT NULL,
+  `Name` varchar(32) NOT NULL,
+  `Hash` char(64) NOT NULL,
+  `Author` bigint(20) unsigned NOT NULL,
+  `Created` datetime NOT NULL,
+  PRIMARY KEY (`Guild`,`Name`),
+  UNIQUE KEY `INDEX_HASH` (`Hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4//
+
+-- Dumping structure for table sweetiebot.module_state
+CREATE TABLE IF NOT EXISTS `module_state` (
+  `Guild` bigint(20) unsigned NOT NULL,
+  `Module` varchar(32) NOT NULL,
+  `State` mediumblob NOT NULL,
+  `Updated` datetime NOT NULL,
+  PRIMARY KEY (`Guild`,`Module`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4//
+
+-- Dumping structure for table sweetiebot.module_data
+CREATE TABLE IF NOT EXISTS `module_data` (
+  `Guild` bigint(20) unsigned NOT NULL,
+  `Module` varchar(32) NOT NULL,
+  `Name` varbinary(255) NOT NULL,
+  `Value` mediumblob NOT NULL,
+  `Expires` bigint(20) NOT NULL DEFAULT 0,
+  PRIMARY KEY (`Guild`,`Module`,`Name`),
+  KEY `INDEX_EXPIRES` (`Expires`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4//
+
 -- Dumping structure for trigger sweetiebot.itemtags_after_delete
 SET @OLDTMP_SQL_MODE=@@SQL_MODE, SQL_MODE='STRICT_TRANS_TABLES,ERROR_FOR_DIVISION_BY_ZERO,NO_AUTO_CREATE_USER,NO_ENGINE_SUBSTITUTION'//
 CREATE TRIGGER `itemtags_after_delete` AFTER DELETE ON `itemtags` FOR EACH ROW BEGIN
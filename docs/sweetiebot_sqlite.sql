@@ -0,0 +1,200 @@
+-- SQLite schema for sweetiebot. Statements use the same delimiter as sweetiebot.sql so that ExecuteSQLFile can run triggers containing semicolons.
+-- Stored procedures from sweetiebot.sql (AddChat, AddUser, AddMember, AddItem, RemoveSchedule, RemoveGuild) are implemented in db_sqlite.go instead.
+
+CREATE TABLE IF NOT EXISTS `timezones` (
+  `Location` varchar(40) NOT NULL,
+  `Offset` int(11) NOT NULL,
+  `DST` int(11) NOT NULL,
+  PRIMARY KEY (`Location`)
+)//
+
+CREATE TABLE IF NOT EXISTS `users` (
+  `ID` bigint(20) NOT NULL,
+  `Username` varchar(128) NOT NULL DEFAULT '',
+  `Discriminator` int(10) NOT NULL DEFAULT 0,
+  `LastSeen` datetime NOT NULL,
+  `LastNameChange` datetime NOT NULL,
+  `Location` varchar(40) DEFAULT NULL,
+  `DefaultServer` bigint(20) DEFAULT NULL,
+  PRIMARY KEY (`ID`),
+  CONSTRAINT `FK_Location_timezone` FOREIGN KEY (`Location`) REFERENCES `timezones` (`Location`)
+)//
+
+CREATE INDEX IF NOT EXISTS `INDEX_USERNAME` ON `users` (`Username`)//
+
+CREATE TABLE IF NOT EXISTS `aliases` (
+  `User` bigint(20) NOT NULL,
+  `Alias` varchar(128) NOT NULL,
+  `Timestamp` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `Duration` bigint(20) NOT NULL,
+  PRIMARY KEY (`User`,`Alias`),
+  CONSTRAINT `ALIASES_USERS` FOREIGN KEY (`User`) REFERENCES `users` (`ID`)
+)//
+
+CREATE INDEX IF NOT EXISTS `ALIASES_ALIAS` ON `aliases` (`Alias`)//
+
+CREATE TABLE IF NOT EXISTS `chatlog` (
+  `ID` bigint(20) NOT NULL,
+  `Author` bigint(20) NOT NULL,
+  `Message` varchar(2000) NOT NULL,
+  `Timestamp` datetime NOT NULL,
+  `Channel` bigint(20) NOT NULL,
+  `Guild` bigint(20) NOT NULL,
+  PRIMARY KEY (`ID`),
+  CONSTRAINT `CHATLOG_USERS` FOREIGN KEY (`Author`) REFERENCES `users` (`ID`)
+)//
+
+CREATE INDEX IF NOT EXISTS `CHATLOG_TIMESTAMP` ON `chatlog` (`Timestamp`)//
+CREATE INDEX IF NOT EXISTS `CHATLOG_CHANNEL` ON `chatlog` (`Channel`)//
+
+CREATE TABLE IF NOT EXISTS `debuglog` (
+  `ID` INTEGER PRIMARY KEY AUTOINCREMENT,
+  `Type` tinyint(3) NOT NULL,
+  `User` bigint(20) DEFAULT NULL,
+  `Message` varchar(4096) NOT NULL,
+  `Timestamp` datetime NOT NULL,
+  `Guild` bigint(20) NOT NULL,
+  CONSTRAINT `debuglog_Users` FOREIGN KEY (`User`) REFERENCES `users` (`ID`)
+)//
+
+CREATE INDEX IF NOT EXISTS `DEBUGLOG_TIMESTAMP` ON `debuglog` (`Timestamp`)//
+
+CREATE TABLE IF NOT EXISTS `items` (
+  `ID` INTEGER PRIMARY KEY AUTOINCREMENT,
+  `Content` varchar(500) NOT NULL UNIQUE
+)//
+
+CREATE TABLE IF NOT EXISTS `tags` (
+  `ID` INTEGER PRIMARY KEY AUTOINCREMENT,
+  `Name` varchar(50) NOT NULL,
+  `Guild` bigint(20) NOT NULL,
+  UNIQUE (`Name`,`Guild`)
+)//
+
+CREATE TABLE IF NOT EXISTS `itemtags` (
+  `Item` bigint(20) NOT NULL,
+  `Tag` bigint(20) NOT NULL,
+  PRIMARY KEY (`Item`,`Tag`),
+  CONSTRAINT `FK_itemtags_items` FOREIGN KEY (`Item`) REFERENCES `items` (`ID`),
+  CONSTRAINT `FK_itemtags_tags` FOREIGN KEY (`Tag`) REFERENCES `tags` (`ID`)
+)//
+
+CREATE INDEX IF NOT EXISTS `FK_itemtags_tags` ON `itemtags` (`Tag`)//
+
+CREATE TABLE IF NOT EXISTS `members` (
+  `ID` bigint(20) NOT NULL,
+  `Guild` bigint(20) NOT NULL,
+  `FirstSeen` datetime NOT NULL,
+  `Nickname` varchar(128) NOT NULL DEFAULT '',
+  `FirstMessage` datetime DEFAULT NULL,
+  PRIMARY KEY (`ID`,`Guild`),
+  CONSTRAINT `FK_members_users` FOREIGN KEY (`ID`) REFERENCES `users` (`ID`)
+)//
+
+CREATE INDEX IF NOT EXISTS `INDEX_NICKNAME` ON `members` (`Nickname`)//
+CREATE INDEX IF NOT EXISTS `INDEX_GUILD_FIRSTSEEN` ON `members` (`Guild`,`FirstSeen`)//
+
+CREATE TABLE IF NOT EXISTS `schedule` (
+  `ID` INTEGER PRIMARY KEY AUTOINCREMENT,
+  `Guild` bigint(20) NOT NULL,
+  `Date` datetime NOT NULL,
+  `RepeatInterval` tinyint(3) DEFAULT NULL,
+  `Repeat` int(11) DEFAULT NULL,
+  `Type` tinyint(3) NOT NULL,
+  `Data` text NOT NULL
+)//
+
+CREATE INDEX IF NOT EXISTS `INDEX_GUILD_DATE_TYPE` ON `schedule` (`Date`,`Guild`,`Type`)//
+CREATE INDEX IF NOT EXISTS `INDEX_GUILD` ON `schedule` (`Guild`)//
+
+CREATE TABLE IF NOT EXISTS `transcripts` (
+  `Season` int(10) NOT NULL,
+  `Episode` int(10) NOT NULL,
+  `Line` int(10) NOT NULL,
+  `Speaker` varchar(128) NOT NULL,
+  `Text` varchar(2000) NOT NULL,
+  PRIMARY KEY (`Season`,`Episode`,`Line`)
+)//
+
+CREATE TABLE IF NOT EXISTS `guild_config` (
+  `Guild` bigint(20) NOT NULL,
+  `Version` bigint(20) NOT NULL,
+  `Expires` bigint(20) NOT NULL DEFAULT 0,
+  `Updated` datetime NOT NULL,
+  `Config` mediumtext NOT NULL,
+  PRIMARY KEY (`Guild`)
+)//
+
+CREATE INDEX IF NOT EXISTS `INDEX_EXPIRES` ON `guild_config` (`Expires`)//
+
+CREATE TABLE IF NOT EXISTS `config_history` (
+  `ID` INTEGER PRIMARY KEY AUTOINCREMENT,
+  `Guild` bigint(20) NOT NULL,
+  `User` bigint(20) NOT NULL,
+  `Timestamp` datetime NOT NULL,
+  `Path` varchar(128) NOT NULL,
+  `OldValue` mediumtext NOT NULL,
+  `NewValue` mediumtext NOT NULL
+)//
+
+CREATE INDEX IF NOT EXISTS `INDEX_GUILD_PATH` ON `config_history` (`Guild`,`Path`)//
+
+CREATE TABLE IF NOT EXISTS `custom_commands` (
+  `Guild` bigint(20) NOT NULL,
+  `Name` varchar(32) NOT NULL,
+  `Response` text NOT NULL,
+  `Author` bigint(20) NOT NULL,
+  `Updated` datetime NOT NULL,
+  PRIMARY KEY (`Guild`,`Name`)
+)//
+
+CREATE TABLE IF NOT EXISTS `api_tokens` (
+  `Guild` bigint(20) NOT NULL,
+  `Name` varchar(32) NOT NULL,
+  `Hash` char(64) NOT NULL UNIQUE,
+  `Author` bigint(20) NOT NULL,
+  `Created` datetime NOT NULL,
+  PRIMARY KEY (`Guild`,`Name`)
+)//
+
+CREATE TABLE IF NOT EXISTS `module_state` (
+  `Guild` bigint(20) NOT NULL,
+  `Module` varchar(32) NOT NULL,
+  `State` blob NOT NULL,
+  `Updated` datetime NOT NULL,
+  PRIMARY KEY (`Guild`,`Module`)
+)//
+
+CREATE TABLE IF NOT EXISTS `module_data` (
+  `Guild` bigint(20) NOT NULL,
+  `Module` varchar(32) NOT NULL,
+  `Name` varchar(255) NOT NULL,
+  `Value` blob NOT NULL,
+  `Expires` bigint(20) NOT NULL DEFAULT 0,
+  PRIMARY KEY (`Guild`,`Module`,`Name`)
+)//
+
+CREATE INDEX IF NOT EXISTS `MODULE_DATA_EXPIRES` ON `module_data` (`Expires`)//
+
+CREATE TRIGGER IF NOT EXISTS `itemtags_after_delete` AFTER DELETE ON `itemtags` FOR EACH ROW
+WHEN (SELECT COUNT(*) FROM itemtags WHERE Item = OLD.Item) = 0
+BEGIN
+DELETE FROM items WHERE ID = OLD.Item;
+END//
+
+CREATE TRIGGER IF NOT EXISTS `tags_before_delete` BEFORE DELETE ON `tags` FOR EACH ROW
+BEGIN
+DELETE FROM itemtags WHERE Tag = OLD.ID;
+END//
+
+CREATE TRIGGER IF NOT EXISTS `users_before_delete` BEFORE DELETE ON `users` FOR EACH ROW
+BEGIN
+DELETE FROM aliases WHERE `User` = OLD.ID;
+DELETE FROM debuglog WHERE `User` = OLD.ID;
+END//
+
+CREATE TRIGGER IF NOT EXISTS `members_before_delete` BEFORE DELETE ON `members` FOR EACH ROW
+BEGIN
+DELETE FROM `schedule` WHERE CAST(OLD.ID AS TEXT) = `Data` AND (`Type` = 1 OR `Type` = 4 OR `Type` = 8);
+DELETE FROM `schedule` WHERE `Data` LIKE CAST(OLD.ID AS TEXT) || '|%' AND (`Type` = 9 OR `Type` = 6);
+END//
@@ -8,23 +8,26 @@ import (
 	"time"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/erikmcclure/sweetiebot/spammodule"
 	bot "github.com/erikmcclure/sweetiebot/sweetiebot"
 )
 
 // FilterModule implements word filters that allow you to look for spoilers or profanity uses regex matching.
 type FilterModule struct {
-	spam    *spammodule.SpamModule
 	filters map[string]*regexp.Regexp
 	lastmsg int64 // Universal saturation limit on all filter responses
 }
 
+func init() {
+	bot.RegisterModuleFactory("Filter", func(guild *bot.GuildInfo, deps map[bot.ModuleID]bot.Module) bot.Module {
+		return New(guild)
+	})
+}
+
 // New instance of FilterModule
-func New(info *bot.GuildInfo, s *spammodule.SpamModule) *FilterModule {
+func New(info *bot.GuildInfo) *FilterModule {
 	w := &FilterModule{
 		filters: make(map[string]*regexp.Regexp),
 		lastmsg: 0,
-		spam:    s,
 	}
 	for k := range info.Config.Filter.Filters {
 		w.UpdateRegex(k, info)
@@ -40,17 +43,18 @@ func (w *FilterModule) Name() string {
 // Commands in the module
 func (w *FilterModule) Commands() []bot.Command {
 	return []bot.Command{
-		&setFilterCommand{},
-		&addFilterCommand{w},
-		&removeFilterCommand{w},
-		&deleteFilterCommand{w},
-		&searchFilterCommand{},
+		bot.NewCommandGroup("Filter", "Manages the filters on this server.", nil).
+			Add("Set", &setFilterCommand{}).
+			Add("Add", &addFilterCommand{w}).
+			Add("Remove", &removeFilterCommand{w}).
+			Add("Delete", &deleteFilterCommand{w}).
+			Add("Search", &searchFilterCommand{}),
 	}
 }
 
 // Description of the module
 func (w *FilterModule) Description(info *bot.GuildInfo) string {
-	return "Implements customizable filters that search for forbiddan words or phrases and removes them with a customizable response and excludable channels. Optionally also adds pressure to the user for triggering a filter, and if the response is set to !, doesn't remove the message at all, only adding pressure.\n\nIf you just want a basic case-insensitive word filter that respects spaces, use `!setconfig filter.templates` with your filter name and this template: `(?i)(^| )%%($| )`. \n\nExample usage: \n```!setfilter badwords \"This is a christian server, no swearing allowed.\"\n!setconfig filter.templates badwords (?i)(^| )%%($| )\n!addfilter badwords hell\n!addfilter badwords \"jesus christ\"```"
+	return "Implements customizable filters that search for forbiddan words or phrases and removes them with a customizable response and excludable channels. Optionally also adds pressure to the user for triggering a filter, and if the response is set to !, doesn't remove the message at all, only adding pressure.\n\nIf you just want a basic case-insensitive word filter that respects spaces, use `!setconfig filter.templates` with your filter name and this template: `(?i)(^| )%%($| )`. \n\nExample usage: \n```!filter set badwords \"This is a christian server, no swearing allowed.\"\n!setconfig filter.templates badwords (?i)(^| )%%($| )\n!filter add badwords hell\n!filter add badwords \"jesus christ\"```"
 }
 
 func (w *FilterModule) matchFilter(info *bot.GuildInfo, m *discordgo.Message) bool {
@@ -72,10 +76,8 @@ func (w *FilterModule) matchFilter(info *bot.GuildInfo, m *discordgo.Message) bo
 		if v.MatchString(m.Content) {
 			ch, _ := info.Bot.DG.State.Channel(m.ChannelID)
 
-			if len(info.Config.Filter.Pressure) > 0 && w.spam != nil {
-				if p, ok := info.Config.Filter.Pressure[k]; ok && p > 0.0 {
-					w.spam.AddPressure(info, m, w.spam.TrackUser(author, bot.GetTimestamp(m)), p, "triggering the "+k+" filter")
-				}
+			if p, ok := info.Config.Filter.Pressure[k]; ok && p > 0.0 {
+				info.Bus.Publish(&bot.PressureEvent{Message: m, Pressure: p, Reason: "triggering the " + k + " filter"})
 			}
 
 			if s, _ := info.Config.Filter.Responses[k]; len(s) != 1 || s[0] != '!' {
@@ -309,7 +311,7 @@ func (c *deleteFilterCommand) Process(args []string, msg *discordgo.Message, ind
 		return "```\nNo filter given. All filters: " + strings.Join(getAllFilters(info), ", ") + "```", false, nil
 	}
 	if len(args) > 1 {
-		return "```\nYou specified more than one argument. This command completely removes an entire filter, use " + info.Config.Basic.CommandPrefix + "removefilter to remove a single item.```", false, nil
+		return "```\nYou specified more than one argument. This command completely removes an entire filter, use " + info.Config.Basic.CommandPrefix + "filter remove to remove a single item.```", false, nil
 	}
 
 	filter := args[0]
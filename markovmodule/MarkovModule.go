@@ -16,6 +16,12 @@ import (
 type MarkovModule struct {
 }
 
+func init() {
+	bot.RegisterModuleFactory("Markov", func(guild *bot.GuildInfo, deps map[bot.ModuleID]bot.Module) bot.Module {
+		return New()
+	})
+}
+
 // New MarkovModule
 func New() *MarkovModule {
 	return &MarkovModule{}
@@ -18,6 +18,12 @@ func (w *MiscModule) Name() string {
 	return "Miscellaneous"
 }
 
+func init() {
+	bot.RegisterModuleFactory("Miscellaneous", func(guild *bot.GuildInfo, deps map[bot.ModuleID]bot.Module) bot.Module {
+		return New()
+	})
+}
+
 // New instance of MiscModule
 func New() *MiscModule {
 	return &MiscModule{}
@@ -70,7 +70,7 @@ func (c *pollCommand) Process(args []string, msg *discordgo.Message, indices []i
 	}
 
 	str := make([]string, 0, len(target.Reactions))
-	desc, _ := target.ContentWithMoreMentionsReplaced(&info.Bot.DG.Session)
+	desc, _ := target.ContentWithMoreMentionsReplaced(info.Bot.DG.Session)
 	if len(desc) > 0 {
 		str = append(str, desc)
 	}
@@ -84,7 +84,7 @@ func (c *pollCommand) Process(args []string, msg *discordgo.Message, indices []i
 					votes[v.ID] = n + 1
 				}
 			} else {
-				fmt.Println(err.Error())
+				info.Bot.Logger.Error("Error retrieving poll reactions: "+err.Error(), bot.LogFields{"guild": info.ID, "module": "misc"})
 			}
 		}
 
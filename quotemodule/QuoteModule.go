@@ -14,6 +14,12 @@ import (
 type QuoteModule struct {
 }
 
+func init() {
+	bot.RegisterModuleFactory("Quotes", func(guild *bot.GuildInfo, deps map[bot.ModuleID]bot.Module) bot.Module {
+		return New()
+	})
+}
+
 // New QuoteModule
 func New() *QuoteModule {
 	return &QuoteModule{}
@@ -214,12 +220,10 @@ func (c *searchQuoteCommand) Process(args []string, msg *discordgo.Message, indi
 		}
 		return "```\nThe following users have at least one quote:\n" + strings.Join(info.IDsToUsernames(s, true), "\n") + "```", len(s) > bot.MaxPublicLines, nil
 	}
-
-	user, err := bot.ParseUser(msg.Content[indices[0]:], info)
-	if err != nil {
-		return bot.ReturnError(err)
-	}
-
+	return bot.ProcessTypedCommand(c, args, msg, indices, info)
+}
+func (c *searchQuoteCommand) ProcessArgs(args *bot.CommandArgs, msg *discordgo.Message, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	user := args.User("user")
 	l := len(info.Config.Quote.Quotes[user])
 	if l == 0 {
 		return "```\nThat user has no quotes.```", false, nil
@@ -234,7 +238,7 @@ func (c *searchQuoteCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
 	return &bot.CommandUsage{
 		Desc: "Lists all quotes for the given user.",
 		Params: []bot.CommandUsageParam{
-			{Name: "user", Desc: "A @user ping or simply the name of the user to quote. If the username has spaces, it must be in quotes.", Optional: false},
+			{Name: "user", Desc: "A @user ping or simply the name of the user to quote. If the username has spaces, it must be in quotes.", Optional: false, Type: bot.ParamUser},
 		},
 	}
 }
@@ -17,6 +17,12 @@ var pingRegex = regexp.MustCompile("<[@#](!|&)?[0-9]+>")
 type RolesModule struct {
 }
 
+func init() {
+	bot.RegisterModuleFactory("Roles", func(guild *bot.GuildInfo, deps map[bot.ModuleID]bot.Module) bot.Module {
+		return New()
+	})
+}
+
 // New RolesModule
 func New() *RolesModule {
 	return &RolesModule{}
@@ -64,33 +70,23 @@ func GetUserAssignableRole(role string, info *bot.GuildInfo) (*discordgo.Role, e
 	return r, nil
 }
 
-// GetRoleByNameOrPing gets a role by its name or by pinging it
-func GetRoleByNameOrPing(role string, info *bot.GuildInfo) (*discordgo.Role, error) {
-	if bot.RoleRegex.MatchString(role) {
-		r, err := bot.ParseRole(role, nil)
-		if err != nil {
-			return nil, err
-		}
-		if r == bot.RoleEmpty || r == bot.RoleExclusion {
-			return nil, errNotUserAssignable
-		}
-		_, ok := info.Config.Users.Roles[r]
-		if !ok || r == info.Config.Basic.ModRole {
-			return nil, errNotUserAssignable
-		}
+// getAssignableRole gets a role parsed by a command, but only if it's user-assignable
+func getAssignableRole(r bot.DiscordRole, info *bot.GuildInfo) (*discordgo.Role, error) {
+	_, ok := info.Config.Users.Roles[r]
+	if !ok || r == info.Config.Basic.ModRole {
+		return nil, errNotUserAssignable
+	}
 
-		roles, err := info.Bot.DG.GuildRoles(info.ID)
-		if err != nil {
-			return nil, err
-		}
-		for _, v := range roles {
-			if r.Equals(v.ID) {
-				return v, nil
-			}
+	roles, err := info.Bot.DG.GuildRoles(info.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range roles {
+		if r.Equals(v.ID) {
+			return v, nil
 		}
-		return nil, bot.ErrRoleNoMatch
 	}
-	return GetUserAssignableRole(role, info)
+	return nil, bot.ErrRoleNoMatch
 }
 
 type createRoleCommand struct {
@@ -374,8 +370,10 @@ func (c *removeRoleCommand) Process(args []string, msg *discordgo.Message, indic
 	if len(args) < 1 {
 		return "```\nYou must provide either a role name, or a role ping.```", false, nil
 	}
-
-	r, err := GetRoleByNameOrPing(msg.Content[indices[0]:], info)
+	return bot.ProcessTypedCommand(c, args, msg, indices, info)
+}
+func (c *removeRoleCommand) ProcessArgs(args *bot.CommandArgs, msg *discordgo.Message, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	r, err := getAssignableRole(args.Role("name"), info)
 	if err != nil {
 		return bot.ReturnError(info.ResolveRoleAddError(err))
 	}
@@ -387,7 +385,7 @@ func (c *removeRoleCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
 	return &bot.CommandUsage{
 		Desc: "Removes a role from the list of user-assignable roles, but DOES NOT DELETE IT. If you want to also delete the role, use " + info.Config.Basic.CommandPrefix + "deleterole.",
 		Params: []bot.CommandUsageParam{
-			{Name: "name", Desc: "Name or ping of the role you no longer want user-assignable.", Optional: false},
+			{Name: "name", Desc: "Name or ping of the role you no longer want user-assignable.", Optional: false, Type: bot.ParamRole},
 		},
 	}
 }
@@ -410,7 +408,10 @@ func (c *deleteRoleCommand) Process(args []string, msg *discordgo.Message, indic
 	if len(args) < 1 {
 		return "```\nYou must provide either a role name, or a role ping.```", false, nil
 	}
-	r, err := GetRoleByNameOrPing(msg.Content[indices[0]:], info)
+	return bot.ProcessTypedCommand(c, args, msg, indices, info)
+}
+func (c *deleteRoleCommand) ProcessArgs(args *bot.CommandArgs, msg *discordgo.Message, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	r, err := getAssignableRole(args.Role("name"), info)
 	if err != nil {
 		return bot.ReturnError(info.ResolveRoleAddError(err))
 	}
@@ -424,7 +425,7 @@ func (c *deleteRoleCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
 	return &bot.CommandUsage{
 		Desc: "Completely deletes a user-assignable role. Cannot be used to delete roles that aren't user-assignable to prevent accidents.",
 		Params: []bot.CommandUsageParam{
-			{Name: "name", Desc: "Name or ping of the role you want to delete.", Optional: false},
+			{Name: "name", Desc: "Name or ping of the role you want to delete.", Optional: false, Type: bot.ParamRole},
 		},
 	}
 }
@@ -29,6 +29,12 @@ const ( // We don't use iota here because this must match the database values ex
 	typeEventRemoveRole = 9
 )
 
+func init() {
+	bot.RegisterModuleFactory("Scheduler", func(guild *bot.GuildInfo, deps map[bot.ModuleID]bot.Module) bot.Module {
+		return New()
+	})
+}
+
 // New SchedulerModule
 func New() *SchedulerModule {
 	return &SchedulerModule{}
@@ -151,6 +157,7 @@ func (w *SchedulerModule) OnTick(info *bot.GuildInfo, t time.Time) {
 		}
 
 		info.Bot.DB.RemoveSchedule(v.ID)
+		info.Bus.Publish(&bot.ScheduleFiredEvent{Event: v})
 	}
 }
 
@@ -1,6 +1,8 @@
 package spammodule
 
 import (
+	"container/heap"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -46,15 +48,43 @@ type SpamModule struct {
 	tracker      sync.Map                      //map[bot.DiscordUser]*userPressure
 	lockdown     discordgo.VerificationLevel   // if -1 no lockdown was initiated, otherwise remembers the previous lockdown setting
 	silenced     map[bot.DiscordUser]time.Time // Tracking users we know we've silenced so we only send the message once
+	timeouts     *userTimeoutHeap              // When silenced users will be unsilenced, protected by silenceLock
 	silenceLock  sync.Mutex
 	lastlockdown time.Time
 }
 
+type pressureState struct {
+	Pressure    float32 `json:"pressure"`
+	LastMessage int64   `json:"lastmessage"`
+}
+
+type timeoutState struct {
+	User bot.DiscordUser `json:"user"`
+	Time time.Time       `json:"time"`
+}
+
+// spamState is the part of the spam module's state that is saved to the database so it survives a restart
+type spamState struct {
+	Pressure     map[bot.DiscordUser]pressureState `json:"pressure"`
+	Silenced     map[bot.DiscordUser]time.Time     `json:"silenced"`
+	Timeouts     []timeoutState                    `json:"timeouts"`
+	Lockdown     discordgo.VerificationLevel       `json:"lockdown"`
+	LastLockdown time.Time                         `json:"lastlockdown"`
+	LastRaid     int64                             `json:"lastraid"`
+}
+
+func init() {
+	bot.RegisterModuleFactory("Spam", func(guild *bot.GuildInfo, deps map[bot.ModuleID]bot.Module) bot.Module {
+		return New()
+	})
+}
+
 // New spam module
 func New() *SpamModule {
 	w := &SpamModule{
 		lockdown: -1,
 		silenced: make(map[bot.DiscordUser]time.Time),
+		timeouts: &userTimeoutHeap{},
 	}
 	return w
 }
@@ -77,7 +107,7 @@ func (w *SpamModule) Commands() []bot.Command {
 
 // Description of the module
 func (w *SpamModule) Description(info *bot.GuildInfo) string {
-	return fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_DESCRIPTION], info.Config.Basic.CommandPrefix, info.Config.Basic.CommandPrefix, info.Config.Basic.CommandPrefix, info.Config.Basic.CommandPrefix)
+	return fmt.Sprintf(info.GetString(bot.STRING_SPAM_DESCRIPTION), info.Config.Basic.CommandPrefix, info.Config.Basic.CommandPrefix, info.Config.Basic.CommandPrefix, info.Config.Basic.CommandPrefix)
 }
 
 // OnTick discord hook
@@ -85,25 +115,119 @@ func (w *SpamModule) OnTick(info *bot.GuildInfo, t time.Time) {
 	if w.lockdown != -1 && t.Sub(w.lastlockdown) > (time.Duration(info.Config.Spam.LockdownDuration)*time.Second) {
 		w.DisableLockdown(info)
 	}
+	w.checkTimeouts(info, t)
+}
+
+// Subscribe to pressure that other modules add to users
+func (w *SpamModule) Subscribe(info *bot.GuildInfo, subscribe func(handler interface{}) func()) {
+	subscribe(func(info *bot.GuildInfo, e *bot.PressureEvent) {
+		if e.Message.Author != nil {
+			w.AddPressure(info, e.Message, w.TrackUser(bot.DiscordUser(e.Message.Author.ID), bot.GetTimestamp(e.Message)), e.Pressure, e.Reason)
+		}
+	})
 }
 
-func (w *SpamModule) timeoutMember(user *discordgo.User, info *bot.GuildInfo) (bool, string) {
+// SaveState snapshots tracked pressure, silenced users, pending unsilences and any lockdown or raid in progress
+func (w *SpamModule) SaveState(info *bot.GuildInfo) ([]byte, error) {
+	state := spamState{
+		Pressure:     make(map[bot.DiscordUser]pressureState),
+		Lockdown:     w.lockdown,
+		LastLockdown: w.lastlockdown,
+		LastRaid:     info.LastRaid,
+	}
+	w.tracker.Range(func(k, v interface{}) bool {
+		if p := v.(*userPressure); p.pressure > 0 { // Users with no pressure are the same as users we aren't tracking
+			state.Pressure[k.(bot.DiscordUser)] = pressureState{p.pressure, p.lastmessage}
+		}
+		return true
+	})
+	w.silenceLock.Lock()
+	state.Silenced = make(map[bot.DiscordUser]time.Time, len(w.silenced))
+	for k, v := range w.silenced {
+		state.Silenced[k] = v
+	}
+	state.Timeouts = make([]timeoutState, 0, w.timeouts.Len())
+	for _, v := range *w.timeouts {
+		state.Timeouts = append(state.Timeouts, timeoutState{v.user, v.time})
+	}
+	w.silenceLock.Unlock()
+	return json.Marshal(&state)
+}
+
+// LoadState restores a snapshot made by SaveState, then immediately unsilences anyone whose silence expired while the bot was offline
+func (w *SpamModule) LoadState(info *bot.GuildInfo, data []byte) error {
+	var state spamState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	for k, v := range state.Pressure {
+		w.tracker.Store(k, &userPressure{v.Pressure, v.LastMessage, ""})
+	}
+	w.silenceLock.Lock()
+	for k, v := range state.Silenced {
+		w.silenced[k] = v
+	}
+	for _, v := range state.Timeouts {
+		heap.Push(w.timeouts, userTimeout{v.User, v.Time})
+	}
+	w.silenceLock.Unlock()
+	w.lockdown = state.Lockdown
+	w.lastlockdown = state.LastLockdown
+	if state.LastRaid > info.LastRaid {
+		info.LastRaid = state.LastRaid
+	}
+	w.checkTimeouts(info, time.Now().UTC())
+	return nil
+}
+
+// checkTimeouts forgets about any silenced users whose silence has expired and tells the moderators they were unsilenced
+func (w *SpamModule) checkTimeouts(info *bot.GuildInfo, t time.Time) {
+	unsilenced := []bot.DiscordUser{}
+	w.silenceLock.Lock()
+	for w.timeouts.Len() > 0 && !w.timeouts.Peek().time.After(t) {
+		v := heap.Pop(w.timeouts).(userTimeout)
+		if silenced, ok := w.silenced[v.user]; ok && !silenced.After(v.time) { // If they were silenced again after this, a later timeout will handle it
+			delete(w.silenced, v.user)
+			unsilenced = append(unsilenced, v.user)
+		}
+	}
+	w.silenceLock.Unlock()
+	for _, u := range unsilenced {
+		info.Log(fmt.Sprintf(info.GetString(bot.STRING_SPAM_UNSILENCING), info.GetUserName(u)))
+	}
+}
+
+func (w *SpamModule) timeoutMember(user *discordgo.User, info *bot.GuildInfo, reason string) (bool, string) {
 	timeout, _ := info.TimeoutMember(user.ID)
 
 	addmsg := "."
+	expires := timeout != time.Duration(0) // Discord only lifts the timeout by itself if it has a duration
 
-	if timeout != time.Duration(0) {
-		addmsg = fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_WILL_BE_UNSILENCED], bot.TimeDiff(timeout))
+	if expires {
+		addmsg = fmt.Sprintf(info.GetString(bot.STRING_SPAM_WILL_BE_UNSILENCED), bot.TimeDiff(timeout))
 	} else {
 		timeout = time.Duration(50) * time.Second // If there is no duration we just want enough time to let discord resolve any errors it has.
 	}
 
+	until := time.Now().UTC().Add(timeout)
 	w.silenceLock.Lock()
 	silenced, ok := w.silenced[bot.DiscordUser(user.ID)]
-	w.silenced[bot.DiscordUser(user.ID)] = time.Now().UTC().Add(timeout)
+	w.silenced[bot.DiscordUser(user.ID)] = until
+	if expires {
+		heap.Push(w.timeouts, userTimeout{bot.DiscordUser(user.ID), until})
+	}
 	w.silenceLock.Unlock()
 
-	return (ok && time.Now().UTC().Before(silenced)), addmsg
+	already := ok && time.Now().UTC().Before(silenced)
+	if !already {
+		bot.RecordSilence(info.ID)
+		e := &bot.SilenceEvent{User: bot.DiscordUser(user.ID), Reason: reason}
+		if expires {
+			e.Until = until
+		}
+		info.Bus.Publish(e)
+	}
+	return already, addmsg
 }
 
 func (w *SpamModule) killSpammer(u *discordgo.User, info *bot.GuildInfo, msg *discordgo.Message, reason string, oldpressure float32, newpressure float32) {
@@ -116,7 +240,7 @@ func (w *SpamModule) killSpammer(u *discordgo.User, info *bot.GuildInfo, msg *di
 	timestamp := bot.GetTimestamp(msg)
 	msgembeds := ""
 	if len(msg.Embeds) > 0 {
-		msgembeds = bot.StringMap[bot.STRING_SPAM_EMBEDDED_URLS]
+		msgembeds = info.GetString(bot.STRING_SPAM_EMBEDDED_URLS)
 		for _, v := range msg.Embeds {
 			msgembeds += "\n<" + v.URL + ">"
 		}
@@ -134,12 +258,12 @@ func (w *SpamModule) killSpammer(u *discordgo.User, info *bot.GuildInfo, msg *di
 		if len(lastmsg) > 300 {
 			lastmsg = lastmsg[:300]
 		}
-		lastmsg += bot.StringMap[bot.STRING_SPAM_TRUNCATED]
+		lastmsg += info.GetString(bot.STRING_SPAM_TRUNCATED)
 	} else if len(lastmsg) > 300 {
-		lastmsg = lastmsg[:300] + bot.StringMap[bot.STRING_SPAM_TRUNCATED]
+		lastmsg = lastmsg[:300] + info.GetString(bot.STRING_SPAM_TRUNCATED)
 	}
-	logmsg := fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_KILLING_SPAMMER_DETAIL], u.Username, oldpressure, newpressure, chname, info.Name, lastmsg, msgembeds)
-	silenced, addmsg := w.timeoutMember(u, info)
+	logmsg := fmt.Sprintf(info.GetString(bot.STRING_SPAM_KILLING_SPAMMER_DETAIL), u.Username, oldpressure, newpressure, chname, info.Name, lastmsg, msgembeds)
+	silenced, addmsg := w.timeoutMember(u, info, reason)
 
 	if info.Config.Spam.MaxRemoveLookback > 0 {
 		IDs := []string{msg.ID}
@@ -149,7 +273,7 @@ func (w *SpamModule) killSpammer(u *discordgo.User, info *bot.GuildInfo, msg *di
 	EndLoop: // Even though this label is defined above the for loop, breaking to this label will actually skip the for loop entirely. Don't ask.
 		for {
 			messages, err := info.Bot.DG.ChannelMessages(msg.ChannelID, 99, lastid, "", "")
-			info.LogError(bot.StringMap[bot.STRING_SPAM_ERROR_RETRIEVE_MESSAGES], err)
+			info.LogError(info.GetString(bot.STRING_SPAM_ERROR_RETRIEVE_MESSAGES), err)
 			if len(messages) == 0 || err != nil {
 				break
 			}
@@ -169,10 +293,10 @@ func (w *SpamModule) killSpammer(u *discordgo.User, info *bot.GuildInfo, msg *di
 	} // otherwise we don't delete anything
 
 	if !silenced { // Only send the alert if they weren't silenced already
-		info.SendMessage(info.Config.Basic.ModChannel, fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_SILENCE_ALERT], u.ID, reason, addmsg)) // Alert admins
+		info.SendMessage(info.Config.Basic.ModChannel, fmt.Sprintf(info.GetString(bot.STRING_SPAM_SILENCE_ALERT), u.ID, reason, addmsg)) // Alert admins
 		info.Log(logmsg)
 	} else {
-		info.Log(fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_KILLING_SPAMMER], u.Username))
+		info.Log(fmt.Sprintf(info.GetString(bot.STRING_SPAM_KILLING_SPAMMER), u.Username))
 	}
 }
 
@@ -236,26 +360,26 @@ func (w *SpamModule) checkSpam(info *bot.GuildInfo, m *discordgo.Message) bool {
 			track.pressure = 0
 		}
 
-		if w.AddPressure(info, m, track, info.Config.Spam.BasePressure, bot.StringMap[bot.STRING_SPAM_REASON_MESSAGES]) {
+		if w.AddPressure(info, m, track, info.Config.Spam.BasePressure, info.GetString(bot.STRING_SPAM_REASON_MESSAGES)) {
 			return true
 		}
-		if w.AddPressure(info, m, track, info.Config.Spam.ImagePressure*float32(len(m.Attachments)), bot.StringMap[bot.STRING_SPAM_REASON_FILES]) {
+		if w.AddPressure(info, m, track, info.Config.Spam.ImagePressure*float32(len(m.Attachments)), info.GetString(bot.STRING_SPAM_REASON_FILES)) {
 			return true
 		}
-		if w.AddPressure(info, m, track, info.Config.Spam.ImagePressure*float32(len(m.Embeds)), bot.StringMap[bot.STRING_SPAM_REASON_IMAGES]) {
+		if w.AddPressure(info, m, track, info.Config.Spam.ImagePressure*float32(len(m.Embeds)), info.GetString(bot.STRING_SPAM_REASON_IMAGES)) {
 			return true
 		}
-		if w.AddPressure(info, m, track, info.Config.Spam.PingPressure*float32(len(m.Mentions)), bot.StringMap[bot.STRING_SPAM_REASON_PINGS]) {
+		if w.AddPressure(info, m, track, info.Config.Spam.PingPressure*float32(len(m.Mentions)), info.GetString(bot.STRING_SPAM_REASON_PINGS)) {
 			return true
 		}
-		if w.AddPressure(info, m, track, info.Config.Spam.LengthPressure*float32(len(m.Content)), bot.StringMap[bot.STRING_SPAM_REASON_LENGTH]) {
+		if w.AddPressure(info, m, track, info.Config.Spam.LengthPressure*float32(len(m.Content)), info.GetString(bot.STRING_SPAM_REASON_LENGTH)) {
 			return true
 		}
-		if w.AddPressure(info, m, track, info.Config.Spam.LinePressure*float32(strings.Count(m.Content, "\n")), bot.StringMap[bot.STRING_SPAM_REASON_NEWLINES]) {
+		if w.AddPressure(info, m, track, info.Config.Spam.LinePressure*float32(strings.Count(m.Content, "\n")), info.GetString(bot.STRING_SPAM_REASON_NEWLINES)) {
 			return true
 		}
 		if len(m.Content) > 0 && strings.ToLower(m.Content) == track.lastcache {
-			if w.AddPressure(info, m, track, info.Config.Spam.RepeatPressure, bot.StringMap[bot.STRING_SPAM_REASON_COPY]) {
+			if w.AddPressure(info, m, track, info.Config.Spam.RepeatPressure, info.GetString(bot.STRING_SPAM_REASON_COPY)) {
 				return true
 			}
 		}
@@ -283,9 +407,9 @@ func (w *SpamModule) DisableLockdown(info *bot.GuildInfo) {
 		}
 		guild, err := info.GetGuild()
 		if err != nil {
-			info.SendMessage(modchan, bot.StringMap[bot.STRING_SPAM_GUILD_NOT_FOUND])
+			info.SendMessage(modchan, info.GetString(bot.STRING_SPAM_GUILD_NOT_FOUND))
 		} else if guild.VerificationLevel != discordgo.VerificationLevelHigh {
-			info.SendMessage(modchan, fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_VERIFICATION_LEVEL_ERROR], guild.VerificationLevel, discordgo.VerificationLevelHigh, info.GetBotName()))
+			info.SendMessage(modchan, fmt.Sprintf(info.GetString(bot.STRING_SPAM_VERIFICATION_LEVEL_ERROR), guild.VerificationLevel, discordgo.VerificationLevelHigh, info.GetBotName()))
 		} else {
 			g := discordgo.GuildParams{
 				Name:                        "",
@@ -302,9 +426,9 @@ func (w *SpamModule) DisableLockdown(info *bot.GuildInfo) {
 			_, err = info.Bot.DG.GuildEdit(info.ID, &g)
 		}
 		if err != nil {
-			info.SendMessage(modchan, fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_LOCKDOWN_DISENGAGE_FAILURE], info.Bot.AppName))
+			info.SendMessage(modchan, fmt.Sprintf(info.GetString(bot.STRING_SPAM_LOCKDOWN_DISENGAGE_FAILURE), info.Bot.AppName))
 		} else {
-			info.SendMessage(modchan, bot.StringMap[bot.STRING_SPAM_LOCKDOWN_DISENGAGE])
+			info.SendMessage(modchan, info.GetString(bot.STRING_SPAM_LOCKDOWN_DISENGAGE))
 		}
 		w.lockdown = -1
 	}
@@ -316,24 +440,28 @@ func (w *SpamModule) checkRaid(info *bot.GuildInfo, m *discordgo.Member, t time.
 	}
 	raidsize := info.Bot.DB.CountNewUsers(info.Config.Spam.RaidTime, bot.SBatoi(info.ID))
 	if info.Config.Spam.RaidSize > 0 && raidsize >= info.Config.Spam.RaidSize && bot.RateLimit(&info.LastRaid, info.Config.Spam.RaidTime*2, t.Unix()) {
+		bot.RecordRaid(info.ID)
 		r := info.Bot.DB.GetNewestUsers(raidsize, bot.SBatoi(info.ID))
 		s := make([]string, 0, len(r))
+		users := make([]*discordgo.User, 0, len(r))
 
 		for _, v := range r {
-			s = append(s, fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_USER_JOINED], v.User.Username, info.ApplyTimezone(v.FirstSeen, bot.UserEmpty).Format(time.ANSIC)))
+			s = append(s, fmt.Sprintf(info.GetString(bot.STRING_SPAM_USER_JOINED), v.User.Username, info.ApplyTimezone(v.FirstSeen, bot.UserEmpty).Format(time.ANSIC)))
+			users = append(users, v.User)
 			if info.Config.Spam.RaidSilence >= 1 {
-				w.timeoutMember(v.User, info)
+				w.timeoutMember(v.User, info, "joining during a raid")
 			}
 		}
+		info.Bus.Publish(&bot.RaidEvent{Users: users})
 		ch := info.Config.Basic.ModChannel
 		if info.Bot.Debug {
 			ch, _ = info.Bot.DebugChannels[bot.DiscordGuild(info.ID)]
 		}
-		message := fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_RAIDSILENCE_ALL_POSTFIX], info.Config.Basic.CommandPrefix)
+		message := fmt.Sprintf(info.GetString(bot.STRING_SPAM_RAIDSILENCE_ALL_POSTFIX), info.Config.Basic.CommandPrefix)
 		if info.Config.Spam.RaidSilence > 0 {
-			message = bot.StringMap[bot.STRING_SPAM_RAIDSILENCE_ENGAGED]
+			message = info.GetString(bot.STRING_SPAM_RAIDSILENCE_ENGAGED)
 		}
-		go info.SendMessage(ch, info.Config.Basic.ModRole.Display()+bot.StringMap[bot.STRING_SPAM_RAID_DETECTED]+message+"\n```"+strings.Join(s, "\n")+"```")
+		go info.SendMessage(ch, info.Config.Basic.ModRole.Display()+info.GetString(bot.STRING_SPAM_RAID_DETECTED)+message+"\n```"+strings.Join(s, "\n")+"```")
 		if info.Config.Spam.LockdownDuration > 0 {
 			if w.lockdown == -1 { // Only engage lockdown if it wasn't already engaged
 				guild, err := info.GetGuild()
@@ -346,9 +474,9 @@ func (w *SpamModule) checkRaid(info *bot.GuildInfo, m *discordgo.Member, t time.
 				g := discordgo.GuildParams{VerificationLevel: &level}
 				_, err = info.Bot.DG.GuildEdit(info.ID, &g)
 				if err != nil {
-					info.SendMessage(ch, fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_LOCKDOWN_ENGAGE_FAILURE], info.GetBotName(), info.Config.Basic.CommandPrefix))
+					info.SendMessage(ch, fmt.Sprintf(info.GetString(bot.STRING_SPAM_LOCKDOWN_ENGAGE_FAILURE), info.GetBotName(), info.Config.Basic.CommandPrefix))
 				} else {
-					info.SendMessage(ch, fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_LOCKDOWN_ENGAGE], info.Config.Spam.LockdownDuration, info.Config.Basic.CommandPrefix))
+					info.SendMessage(ch, fmt.Sprintf(info.GetString(bot.STRING_SPAM_LOCKDOWN_ENGAGE), info.Config.Spam.LockdownDuration, info.Config.Basic.CommandPrefix))
 				}
 			}
 			// Otherwise just reset the timer
@@ -390,13 +518,13 @@ type raidSilenceCommand struct {
 func (c *raidSilenceCommand) Info() *bot.CommandInfo {
 	return &bot.CommandInfo{
 		Name:      "RaidSilence",
-		Usage:     bot.StringMap[bot.STRING_SPAM_RAIDSILENCE_USAGE],
+		Usage:     bot.DefaultString(bot.STRING_SPAM_RAIDSILENCE_USAGE),
 		Sensitive: true,
 	}
 }
 func (c *raidSilenceCommand) Process(args []string, msg *discordgo.Message, indices []int, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
 	if len(args) < 1 {
-		return bot.StringMap[bot.STRING_SPAM_RAIDSILENCE_ARGS_ERROR], false, nil
+		return info.GetString(bot.STRING_SPAM_RAIDSILENCE_ARGS_ERROR), false, nil
 	}
 	timestamp := bot.GetTimestamp(msg)
 
@@ -415,7 +543,7 @@ func (c *raidSilenceCommand) Process(args []string, msg *discordgo.Message, indi
 	info.LastRaid = timestamp.Unix() - subtract
 	fmt.Println(time.Unix(info.LastRaid, 0))*/
 	default:
-		return bot.StringMap[bot.STRING_SPAM_RAIDSILENCE_ARGS], false, nil
+		return info.GetString(bot.STRING_SPAM_RAIDSILENCE_ARGS), false, nil
 	}
 
 	info.SaveConfig()
@@ -425,26 +553,26 @@ func (c *raidSilenceCommand) Process(args []string, msg *discordgo.Message, indi
 	} else if c.s.isRecentRaid(info, timestamp) { // If there has recently been a raid, silence everyone who joined or theoretically could have joined since the beginning of the raid.
 		c.s.lastlockdown = timestamp // Reset lockdown timer just in case
 		if !info.Bot.DB.CheckStatus() {
-			return bot.StringMap[bot.STRING_SPAM_RAIDSILENCE_DATABASE_ERROR], false, nil
+			return info.GetString(bot.STRING_SPAM_RAIDSILENCE_DATABASE_ERROR), false, nil
 		}
 		// BEFORE we make any calls to discord, which could take some time, immediately respond with a silence set message so the admins know the command is functioning
-		go info.SendMessage(bot.DiscordChannel(msg.ChannelID), fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_RAIDSILENCE_SET_RAID], strings.ToLower(args[0])))
+		go info.SendMessage(bot.DiscordChannel(msg.ChannelID), fmt.Sprintf(info.GetString(bot.STRING_SPAM_RAIDSILENCE_SET_RAID), strings.ToLower(args[0])))
 		r := c.s.getRaidUsers(info)
 		s := make([]string, 0, len(r))
-		s = append(s, bot.StringMap[bot.STRING_SPAM_RAIDSILENCE_DETECTION])
+		s = append(s, info.GetString(bot.STRING_SPAM_RAIDSILENCE_DETECTION))
 		for _, v := range r {
 			s = append(s, v.Username)
 			info.TimeoutMember(v.ID)
 		}
 		return strings.Join(s, "\n") + "```", false, nil
 	}
-	return fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_RAIDSILENCE_SET], strings.ToLower(args[0])), false, nil
+	return fmt.Sprintf(info.GetString(bot.STRING_SPAM_RAIDSILENCE_SET), strings.ToLower(args[0])), false, nil
 }
 func (c *raidSilenceCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
 	return &bot.CommandUsage{
-		Desc: bot.StringMap[bot.STRING_SPAM_RAIDSILENCE_DESCRIPTION],
+		Desc: info.GetString(bot.STRING_SPAM_RAIDSILENCE_DESCRIPTION),
 		Params: []bot.CommandUsageParam{
-			{Name: "all/raid/off", Desc: bot.StringMap[bot.STRING_SPAM_RAIDSILENCE_DESCRIPTION_NAME], Optional: false},
+			{Name: "all/raid/off", Desc: info.GetString(bot.STRING_SPAM_RAIDSILENCE_DESCRIPTION_NAME), Optional: false},
 		},
 	}
 }
@@ -455,7 +583,7 @@ type wipeCommand struct {
 func (c *wipeCommand) Info() *bot.CommandInfo {
 	return &bot.CommandInfo{
 		Name:      "Wipe",
-		Usage:     bot.StringMap[bot.STRING_SPAM_WIPE_USAGE],
+		Usage:     bot.DefaultString(bot.STRING_SPAM_WIPE_USAGE),
 		Sensitive: true,
 	}
 }
@@ -497,7 +625,7 @@ func (c *wipeCommand) WipeMessages(ch *discordgo.Channel, num int, seconds int,
 }
 func (c *wipeCommand) Process(args []string, msg *discordgo.Message, indices []int, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
 	if len(args) < 1 {
-		return bot.StringMap[bot.STRING_SPAM_WIPE_ARG_ERROR], false, nil
+		return info.GetString(bot.STRING_SPAM_WIPE_ARG_ERROR), false, nil
 	}
 
 	var err error
@@ -527,14 +655,14 @@ func (c *wipeCommand) Process(args []string, msg *discordgo.Message, indices []i
 	}
 	channel, private := info.Bot.ChannelIsPrivate(ch)
 	if private {
-		return bot.StringMap[bot.STRING_SPAM_WIPE_PM_ERROR], false, nil
+		return info.GetString(bot.STRING_SPAM_WIPE_PM_ERROR), false, nil
 	}
 	if channel == nil || channel.GuildID != info.ID {
-		return bot.StringMap[bot.STRING_SPAM_WIPE_CHANNEL_ERROR], false, nil
+		return info.GetString(bot.STRING_SPAM_WIPE_CHANNEL_ERROR), false, nil
 	}
 	timestamp := bot.GetTimestamp(msg)
 	if num <= 0 {
-		return bot.StringMap[bot.STRING_SPAM_WIPE_NO_MESSAGES], false, nil
+		return info.GetString(bot.STRING_SPAM_WIPE_NO_MESSAGES), false, nil
 	}
 	if messages {
 		num, err = c.WipeMessages(channel, num, 0, timestamp, info)
@@ -542,16 +670,16 @@ func (c *wipeCommand) Process(args []string, msg *discordgo.Message, indices []i
 		num, err = c.WipeMessages(channel, 9999, num, timestamp, info)
 	}
 	if err != nil {
-		return fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_WIPE_RETRIEVAL_ERROR], info.GetBotName(), err.Error()), false, nil
+		return fmt.Sprintf(info.GetString(bot.STRING_SPAM_WIPE_RETRIEVAL_ERROR), info.GetBotName(), err.Error()), false, nil
 	}
-	return fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_WIPE_DELETED], num, ch), false, nil
+	return fmt.Sprintf(info.GetString(bot.STRING_SPAM_WIPE_DELETED), num, ch), false, nil
 }
 func (c *wipeCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
 	return &bot.CommandUsage{
-		Desc: fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_WIPE_DESCRIPTION], info.Config.Basic.CommandPrefix, info.Config.Basic.CommandPrefix),
+		Desc: fmt.Sprintf(info.GetString(bot.STRING_SPAM_WIPE_DESCRIPTION), info.Config.Basic.CommandPrefix, info.Config.Basic.CommandPrefix),
 		Params: []bot.CommandUsageParam{
-			{Name: "channel", Desc: bot.StringMap[bot.STRING_SPAM_WIPE_CHANNEL], Optional: true},
-			{Name: "seconds/messages", Desc: bot.StringMap[bot.STRING_SPAM_WIPE_MESSAGES], Optional: false},
+			{Name: "channel", Desc: info.GetString(bot.STRING_SPAM_WIPE_CHANNEL), Optional: true},
+			{Name: "seconds/messages", Desc: info.GetString(bot.STRING_SPAM_WIPE_MESSAGES), Optional: false},
 		},
 	}
 }
@@ -563,22 +691,19 @@ type getPressureCommand struct {
 func (c *getPressureCommand) Info() *bot.CommandInfo {
 	return &bot.CommandInfo{
 		Name:      "GetPressure",
-		Usage:     bot.StringMap[bot.STRING_SPAM_PRESSURE_USAGE],
+		Usage:     bot.DefaultString(bot.STRING_SPAM_PRESSURE_USAGE),
 		Sensitive: true,
 	}
 }
 
 func (c *getPressureCommand) Process(args []string, msg *discordgo.Message, indices []int, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
 	if len(args) < 1 {
-		return bot.StringMap[bot.STRING_SPAM_PRESSURE_ARG_ERROR], false, nil
+		return info.GetString(bot.STRING_SPAM_PRESSURE_ARG_ERROR), false, nil
 	}
-
-	user, err := bot.ParseUser(msg.Content[indices[0]:], info)
-	if err != nil {
-		return bot.ReturnError(err)
-	}
-
-	u, ok := c.s.tracker.Load(user)
+	return bot.ProcessTypedCommand(c, args, msg, indices, info)
+}
+func (c *getPressureCommand) ProcessArgs(args *bot.CommandArgs, msg *discordgo.Message, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	u, ok := c.s.tracker.Load(args.User("user"))
 	if !ok {
 		return "0", false, nil
 	}
@@ -586,9 +711,9 @@ func (c *getPressureCommand) Process(args []string, msg *discordgo.Message, indi
 }
 func (c *getPressureCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
 	return &bot.CommandUsage{
-		Desc: bot.StringMap[bot.STRING_SPAM_PRESSURE_DESCRIPTION],
+		Desc: info.GetString(bot.STRING_SPAM_PRESSURE_DESCRIPTION),
 		Params: []bot.CommandUsageParam{
-			{Name: "user", Desc: bot.StringMap[bot.STRING_SPAM_PRESSURE_USER], Optional: false},
+			{Name: "user", Desc: info.GetString(bot.STRING_SPAM_PRESSURE_USER), Optional: false, Type: bot.ParamUser},
 		},
 	}
 }
@@ -600,23 +725,23 @@ type getRaidCommand struct {
 func (c *getRaidCommand) Info() *bot.CommandInfo {
 	return &bot.CommandInfo{
 		Name:      "GetRaid",
-		Usage:     bot.StringMap[bot.STRING_SPAM_RAID_USAGE],
+		Usage:     bot.DefaultString(bot.STRING_SPAM_RAID_USAGE),
 		Sensitive: true,
 	}
 }
 
 func (c *getRaidCommand) Process(args []string, msg *discordgo.Message, indices []int, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
 	if !c.s.isRecentRaid(info, bot.GetTimestamp(msg)) {
-		return fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_RAID_NONE], bot.TimeDiff(time.Duration(info.Config.Spam.RaidTime*2)*time.Second)), false, nil
+		return fmt.Sprintf(info.GetString(bot.STRING_SPAM_RAID_NONE), bot.TimeDiff(time.Duration(info.Config.Spam.RaidTime*2)*time.Second)), false, nil
 	}
-	s := []string{bot.StringMap[bot.STRING_SPAM_RAID_USERS]}
+	s := []string{info.GetString(bot.STRING_SPAM_RAID_USERS)}
 	for _, v := range c.s.getRaidUsers(info) {
 		s = append(s, v.Username+"#"+v.Discriminator)
 	}
 	return "```\n" + strings.Join(s, "\n") + "```", false, nil
 }
 func (c *getRaidCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
-	return &bot.CommandUsage{Desc: bot.StringMap[bot.STRING_SPAM_RAID_DESCRIPTION]}
+	return &bot.CommandUsage{Desc: info.GetString(bot.STRING_SPAM_RAID_DESCRIPTION)}
 }
 
 type banRaidCommand struct {
@@ -626,21 +751,21 @@ type banRaidCommand struct {
 func (c *banRaidCommand) Info() *bot.CommandInfo {
 	return &bot.CommandInfo{
 		Name:      "BanRaid",
-		Usage:     bot.StringMap[bot.STRING_SPAM_BANRAID_USAGE],
+		Usage:     bot.DefaultString(bot.STRING_SPAM_BANRAID_USAGE),
 		Sensitive: true,
 	}
 }
 func (c *banRaidCommand) Process(args []string, msg *discordgo.Message, indices []int, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
 	if !c.s.isRecentRaid(info, bot.GetTimestamp(msg)) {
-		return fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_RAID_NONE], bot.TimeDiff(time.Duration(info.Config.Spam.RaidTime*2)*time.Second)), false, nil
+		return fmt.Sprintf(info.GetString(bot.STRING_SPAM_RAID_NONE), bot.TimeDiff(time.Duration(info.Config.Spam.RaidTime*2)*time.Second)), false, nil
 	}
-	reason := fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_BANRAID_REASON], msg.Author.Username, msg.Author.Discriminator, info.Config.Basic.CommandPrefix)
+	reason := fmt.Sprintf(info.GetString(bot.STRING_SPAM_BANRAID_REASON), msg.Author.Username, msg.Author.Discriminator, info.Config.Basic.CommandPrefix)
 	users := c.s.getRaidUsers(info)
 	for _, v := range users {
 		info.Bot.DG.GuildBanCreateWithReason(info.ID, v.ID, reason, 1)
 	}
-	return fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_BANRAID_RESULT], len(users)), false, nil
+	return fmt.Sprintf(info.GetString(bot.STRING_SPAM_BANRAID_RESULT), len(users)), false, nil
 }
 func (c *banRaidCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
-	return &bot.CommandUsage{Desc: fmt.Sprintf(bot.StringMap[bot.STRING_SPAM_BANRAID_DESCRIPTION], info.Config.Basic.CommandPrefix)}
+	return &bot.CommandUsage{Desc: fmt.Sprintf(info.GetString(bot.STRING_SPAM_BANRAID_DESCRIPTION), info.Config.Basic.CommandPrefix)}
 }
@@ -5,29 +5,101 @@ import (
 	"testing"
 	"time"
 
+	"github.com/bwmarrin/discordgo"
 	bot "github.com/erikmcclure/sweetiebot/sweetiebot"
 )
 
 func TestHeap(t *testing.T) {
 	t.Parallel()
 
-	spam := New()
+	timeouts := &userTimeoutHeap{}
 
 	insert := []int64{1000, 1300, 1400, 1100, 1200}
 	for _, v := range insert {
-		heap.Push(spam.timeouts, userTimeout{bot.DiscordUser(""), time.Unix(v, 0)})
+		heap.Push(timeouts, userTimeout{bot.DiscordUser(""), time.Unix(v, 0)})
 	}
 
 	out := []int64{1000, 1100, 1200, 1300, 1400}
 	for _, v := range out {
-		if heap.Pop(spam.timeouts).(userTimeout).time != time.Unix(v, 0) {
+		if heap.Pop(timeouts).(userTimeout).time != time.Unix(v, 0) {
 			t.Error(v)
 		}
 	}
 
 	tmp := userTimeout{bot.DiscordUser(""), time.Unix(900, 0)}
-	heap.Push(spam.timeouts, tmp)
-	if heap.Pop(spam.timeouts).(userTimeout).time != tmp.time {
+	heap.Push(timeouts, tmp)
+	if heap.Pop(timeouts).(userTimeout).time != tmp.time {
 		t.Error("900 did not match")
 	}
 }
+
+func TestState(t *testing.T) {
+	t.Parallel()
+
+	sb := &bot.SweetieBot{DG: &bot.DiscordGoSession{Session: &discordgo.Session{State: discordgo.NewState()}}}
+	info := bot.NewGuildInfo(sb, &discordgo.Guild{ID: "10"})
+	info.LastRaid = 1234
+	now := time.Now().UTC()
+
+	spam := New()
+	spam.TrackUser("1", now).pressure = 5
+	spam.TrackUser("2", now)
+	spam.silenced["3"] = now.Add(-time.Minute) // Came due while the bot was offline
+	heap.Push(spam.timeouts, userTimeout{"3", now.Add(-time.Minute)})
+	spam.silenced["4"] = now.Add(time.Hour)
+	heap.Push(spam.timeouts, userTimeout{"4", now.Add(time.Hour)})
+	spam.lockdown = discordgo.VerificationLevelLow
+	spam.lastlockdown = now
+
+	data, err := spam.SaveState(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New()
+	info = bot.NewGuildInfo(sb, &discordgo.Guild{ID: "10"})
+	if err := restored.LoadState(info, data); err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := restored.tracker.Load(bot.DiscordUser("1")); !ok || p.(*userPressure).pressure != 5 {
+		t.Error("pressure for 1 was not restored")
+	}
+	if _, ok := restored.tracker.Load(bot.DiscordUser("2")); ok {
+		t.Error("2 had no pressure and should not have been saved")
+	}
+	if _, ok := restored.silenced["3"]; ok {
+		t.Error("3 should have been unsilenced right after loading")
+	}
+	if v, ok := restored.silenced["4"]; !ok || !v.Equal(now.Add(time.Hour)) {
+		t.Error("4 should still be silenced")
+	}
+	if restored.timeouts.Len() != 1 || restored.timeouts.Peek().user != "4" {
+		t.Error("only 4's timeout should be pending")
+	}
+	if restored.lockdown != discordgo.VerificationLevelLow || !restored.lastlockdown.Equal(now) {
+		t.Error("lockdown was not restored")
+	}
+	if info.LastRaid != 1234 {
+		t.Error("last raid was not restored")
+	}
+
+	restored.checkTimeouts(info, now.Add(2*time.Hour))
+	if _, ok := restored.silenced["4"]; ok || restored.timeouts.Len() != 0 {
+		t.Error("4 should have been unsilenced")
+	}
+}
+
+func TestPressureEvent(t *testing.T) {
+	t.Parallel()
+
+	sb := &bot.SweetieBot{DG: &bot.DiscordGoSession{Session: &discordgo.Session{State: discordgo.NewState()}}}
+	info := bot.NewGuildInfo(sb, &discordgo.Guild{ID: "10"})
+	spam := New()
+	info.RegisterModule(spam)
+
+	m := &discordgo.Message{ChannelID: "11", Author: &discordgo.User{ID: "1"}, Timestamp: time.Now()}
+	info.Bus.Publish(&bot.PressureEvent{Message: m, Pressure: 5, Reason: "testing"})
+	if p, ok := spam.tracker.Load(bot.DiscordUser("1")); !ok || p.(*userPressure).pressure != 5 {
+		t.Error("pressure from the event was not added")
+	}
+}
@@ -12,6 +12,12 @@ type StatusModule struct {
 	lastchange time.Time
 }
 
+func init() {
+	bot.RegisterModuleFactory("Status", func(guild *bot.GuildInfo, deps map[bot.ModuleID]bot.Module) bot.Module {
+		return New()
+	})
+}
+
 // New StatusModule
 func New() *StatusModule {
 	return &StatusModule{}
@@ -40,7 +46,7 @@ func (w *StatusModule) OnTick(info *bot.GuildInfo, t time.Time) {
 		if w.lastchange.Add(time.Duration(info.Config.Status.Cooldown) * time.Second).Before(t) {
 			w.lastchange = t
 			if len(info.Config.Status.Lines) > 0 {
-				info.Bot.DG.UpdateGameStatus(0, bot.MapGetRandomItem(info.Config.Status.Lines))
+				info.Bot.UpdateGameStatus(0, bot.MapGetRandomItem(info.Config.Status.Lines))
 			}
 		}
 	}
@@ -62,11 +68,11 @@ func (c *setStatusCommand) Process(args []string, msg *discordgo.Message, indice
 		return "```\nYou can only do this from the main server!```", false, nil
 	}
 	if len(args) < 1 {
-		info.Bot.DG.UpdateGameStatus(0, "")
+		info.Bot.UpdateGameStatus(0, "")
 		return "```\nRemoved status```", false, nil
 	}
 	arg := msg.Content[indices[0]:]
-	info.Bot.DG.UpdateGameStatus(0, arg)
+	info.Bot.UpdateGameStatus(0, arg)
 	return "```\nStatus was set to " + arg + "```", false, nil
 }
 func (c *setStatusCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
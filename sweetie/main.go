@@ -3,48 +3,27 @@ package main
 import (
 	"os"
 
-	"github.com/erikmcclure/sweetiebot/boredmodule"
-	"github.com/erikmcclure/sweetiebot/bucketmodule"
-	"github.com/erikmcclure/sweetiebot/countersmodule"
-	"github.com/erikmcclure/sweetiebot/filtermodule"
-	"github.com/erikmcclure/sweetiebot/markovmodule"
-	"github.com/erikmcclure/sweetiebot/miscmodule"
-	"github.com/erikmcclure/sweetiebot/quotemodule"
-	"github.com/erikmcclure/sweetiebot/rolesmodule"
-	"github.com/erikmcclure/sweetiebot/schedulermodule"
-	"github.com/erikmcclure/sweetiebot/spammodule"
-	"github.com/erikmcclure/sweetiebot/statusmodule"
+	_ "github.com/erikmcclure/sweetiebot/boredmodule"
+	_ "github.com/erikmcclure/sweetiebot/bucketmodule"
+	_ "github.com/erikmcclure/sweetiebot/countersmodule"
+	_ "github.com/erikmcclure/sweetiebot/custommodule"
+	_ "github.com/erikmcclure/sweetiebot/filtermodule"
+	_ "github.com/erikmcclure/sweetiebot/markovmodule"
+	_ "github.com/erikmcclure/sweetiebot/miscmodule"
+	_ "github.com/erikmcclure/sweetiebot/quotemodule"
+	_ "github.com/erikmcclure/sweetiebot/rolesmodule"
+	_ "github.com/erikmcclure/sweetiebot/schedulermodule"
+	_ "github.com/erikmcclure/sweetiebot/spammodule"
+	_ "github.com/erikmcclure/sweetiebot/statusmodule"
 	"github.com/erikmcclure/sweetiebot/sweetiebot"
-	"github.com/erikmcclure/sweetiebot/tagmodule"
-	"github.com/erikmcclure/sweetiebot/usersmodule"
-	"github.com/erikmcclure/sweetiebot/wittymodule"
+	_ "github.com/erikmcclure/sweetiebot/tagmodule"
+	_ "github.com/erikmcclure/sweetiebot/usersmodule"
+	_ "github.com/erikmcclure/sweetiebot/wittymodule"
 )
 
-func loader(guild *sweetiebot.GuildInfo) []sweetiebot.Module {
-	modules := make([]sweetiebot.Module, 0, 18)
-	modules = append(modules, &sweetiebot.InfoModule{})
-	modules = append(modules, &sweetiebot.ConfigModule{})
-	modules = append(modules, &sweetiebot.DebugModule{})
-	modules = append(modules, statusmodule.New())
-	modules = append(modules, usersmodule.New())
-	modules = append(modules, tagmodule.New())
-	modules = append(modules, schedulermodule.New())
-	modules = append(modules, rolesmodule.New())
-	modules = append(modules, markovmodule.New())
-	modules = append(modules, quotemodule.New())
-	modules = append(modules, bucketmodule.New())
-	modules = append(modules, boredmodule.New())
-	modules = append(modules, miscmodule.New())
-	modules = append(modules, countersmodule.New())
-	modules = append(modules, wittymodule.New(guild))
-	spam := spammodule.New()
-	modules = append(modules, spam)
-	modules = append(modules, filtermodule.New(guild, spam))
-
-	return modules
-}
+// Modules register themselves when their package is imported, so adding a module only requires importing it above
 func mainCode() int {
-	bot := sweetiebot.New("", loader)
+	bot := sweetiebot.New("", nil)
 	if bot != nil {
 		return bot.Connect()
 	}
@@ -1,6 +1,7 @@
 package sweetiebot
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -26,75 +27,81 @@ type BotConfig struct {
 	Basic       struct {
 		IgnoreInvalidCommands bool                    `json:"ignoreinvalidcommands"`
 		Importable            bool                    `json:"importable"`
-		ModRole               DiscordRole             `json:"modrole"`
-		ModChannel            DiscordChannel          `json:"modchannel"`
-		FreeChannels          map[DiscordChannel]bool `json:"freechannels"`
-		BotChannel            DiscordChannel          `json:"botchannel"`
+		ModRole               DiscordRole             `json:"modrole" validate:"role"`
+		ModChannel            DiscordChannel          `json:"modchannel" validate:"channel"`
+		FreeChannels          map[DiscordChannel]bool `json:"freechannels" validate:"channel"`
+		BotChannel            DiscordChannel          `json:"botchannel" validate:"channel"`
 		Aliases               map[string]string       `json:"aliases"`
 		ListenToBots          bool                    `json:"listentobots"`
-		CommandPrefix         string                  `json:"commandprefix"`
+		CommandPrefix         string                  `json:"commandprefix" validate:"nonempty"`
+		ExtraPrefixes         map[string]bool         `json:"extraprefixes"`
+		Language              string                  `json:"language" validate:"language"`
+		Strings               map[string]string       `json:"strings" validate:"strings"`
 	} `json:"basic"`
 	Modules struct {
-		Channels           map[ModuleID]map[DiscordChannel]bool  `json:"modulechannels"`
+		Channels           map[ModuleID]map[DiscordChannel]bool  `json:"modulechannels" validate:"channel"`
 		Disabled           map[ModuleID]bool                     `json:"moduledisabled"`
-		CommandRoles       map[CommandID]map[DiscordRole]bool    `json:"commandroles"`
-		CommandChannels    map[CommandID]map[DiscordChannel]bool `json:"commandchannels"`
-		CommandLimits      map[CommandID]int64                   `json:"Commandlimits"`
+		CommandRoles       map[CommandID]map[DiscordRole]bool    `json:"commandroles" validate:"role"`
+		CommandChannels    map[CommandID]map[DiscordChannel]bool `json:"commandchannels" validate:"channel"`
+		CommandLimits      map[CommandID]int64                   `json:"Commandlimits" validate:"min=0"`
 		CommandDisabled    map[CommandID]bool                    `json:"commanddisabled"`
-		CommandPerDuration int                                   `json:"commandperduration"`
-		CommandMaxDuration int64                                 `json:"commandmaxduration"`
+		CommandPerDuration int                                   `json:"commandperduration" validate:"min=1"`
+		CommandMaxDuration int64                                 `json:"commandmaxduration" validate:"min=1"`
+		UserCooldowns      map[CommandID]map[DiscordRole]int64   `json:"usercooldowns" validate:"role,min=0"`
+		UserQuotas         map[CommandID]map[DiscordRole]int64   `json:"userquotas" validate:"role,min=0"`
+		UserQuotaWindow    int64                                 `json:"userquotawindow" validate:"min=1"`
 	} `json:"modules"`
 	Spam struct {
-		ImagePressure      float32                    `json:"imagepressure"`
-		PingPressure       float32                    `json:"pingpressure"`
-		LengthPressure     float32                    `json:"lengthpressure"`
-		RepeatPressure     float32                    `json:"repeatpressure"`
-		LinePressure       float32                    `json:"linepressure"`
-		BasePressure       float32                    `json:"basepressure"`
-		PressureDecay      float32                    `json:"pressuredecay"`
-		MaxPressure        float32                    `json:"maxpressure"`
-		MaxChannelPressure map[DiscordChannel]float32 `json:"maxchannelpressure"`
+		ImagePressure      float32                    `json:"imagepressure" validate:"min=0"`
+		PingPressure       float32                    `json:"pingpressure" validate:"min=0"`
+		LengthPressure     float32                    `json:"lengthpressure" validate:"min=0"`
+		RepeatPressure     float32                    `json:"repeatpressure" validate:"min=0"`
+		LinePressure       float32                    `json:"linepressure" validate:"min=0"`
+		BasePressure       float32                    `json:"basepressure" validate:"min=0"`
+		PressureDecay      float32                    `json:"pressuredecay" validate:"min=0.01"`
+		MaxPressure        float32                    `json:"maxpressure" validate:"min=1"`
+		MaxChannelPressure map[DiscordChannel]float32 `json:"maxchannelpressure" validate:"channel,min=1"`
 		MaxRemoveLookback  int                        `json:"MaxSpamRemoveLookback"`
-		IgnoreRole         DiscordRole                `json:"ignorerole"`
-		RaidTime           int64                      `json:"maxraidtime"`
-		RaidSize           int                        `json:"raidsize"`
-		RaidSilence        int                        `json:"raidsilence"`
-		LockdownDuration   int                        `json:"lockdownduration"`
-		SilenceTimeout     int64                      `json:"silencetimeout"`
+		IgnoreRole         DiscordRole                `json:"ignorerole" validate:"role"`
+		RaidTime           int64                      `json:"maxraidtime" validate:"min=0"`
+		RaidSize           int                        `json:"raidsize" validate:"min=0"`
+		RaidSilence        int                        `json:"raidsilence" validate:"enum=0|1|2"`
+		LockdownDuration   int                        `json:"lockdownduration" validate:"min=0"`
+		SilenceTimeout     int64                      `json:"silencetimeout" validate:"min=0"`
 	} `json:"spam"`
 	Users struct {
 		TimezoneLocation TimeLocation         `json:"timezonelocation"`
 		WelcomeMessage   string               `json:"welcomemessage"`
 		SilenceMessage   string               `json:"silencemessage"`
-		Roles            map[DiscordRole]bool `json:"userroles"`
-		NotifyChannel    DiscordChannel       `json:"joinchannel"`
+		Roles            map[DiscordRole]bool `json:"userroles" validate:"role"`
+		NotifyChannel    DiscordChannel       `json:"joinchannel" validate:"channel"`
 		TrackUserLeft    bool                 `json:"trackuserleft"`
-		NewUserRole      DiscordRole          `json:"newuserrole"`
-		NewUserDuration  int64                `json:"newuserduration"`
+		NewUserRole      DiscordRole          `json:"newuserrole" validate:"role"`
+		NewUserDuration  int64                `json:"newuserduration" validate:"min=0"`
 	} `json:"users"`
 	Bucket struct {
-		MaxItems       int             `json:"maxbucket"`
-		MaxItemLength  int             `json:"maxbucketlength"`
-		MaxFightHP     int             `json:"maxfighthp"`
-		MaxFightDamage int             `json:"maxfightdamage"`
+		MaxItems       int             `json:"maxbucket" validate:"min=0"`
+		MaxItemLength  int             `json:"maxbucketlength" validate:"min=1"`
+		MaxFightHP     int             `json:"maxfighthp" validate:"min=1"`
+		MaxFightDamage int             `json:"maxfightdamage" validate:"min=1"`
 		Items          map[string]bool `json:"items"`
 	} `json:"bucket"`
 	Markov struct {
-		MaxPMlines     int  `json:"maxpmlines"`
-		MaxLines       int  `json:"maxquotelines"`
-		DefaultLines   int  `json:"defaultmarkovlines"`
+		MaxPMlines     int  `json:"maxpmlines" validate:"min=0"`
+		MaxLines       int  `json:"maxquotelines" validate:"min=1"`
+		DefaultLines   int  `json:"defaultmarkovlines" validate:"min=1"`
 		UseMemberNames bool `json:"usemembernames"`
 	} `json:"markov"`
 	Filter struct {
 		Filters   map[string]map[string]bool         `json:"filters"`
-		Channels  map[string]map[DiscordChannel]bool `json:"channels"`
+		Channels  map[string]map[DiscordChannel]bool `json:"channels" validate:"channel"`
 		Responses map[string]string                  `json:"responses"`
 		Templates map[string]string                  `json:"templates"`
-		Pressure  map[string]float32                 `json:"pressure"`
+		Pressure  map[string]float32                 `json:"pressure" validate:"min=0"`
 	} `json:"filter"`
 	Bored struct {
-		Cooldown int64           `json:"maxbored"`
-		Exponent float64         `json:"exponent"`
+		Cooldown int64           `json:"maxbored" validate:"min=1"`
+		Exponent float64         `json:"exponent" validate:"min=0"`
 		Commands map[string]bool `json:"boredcommands"`
 	}
 	Information struct {
@@ -102,21 +109,21 @@ type BotConfig struct {
 		HideNegativeRules bool           `json:"hidenegativerules"`
 	} `json:"help"`
 	Log struct {
-		Cooldown int64          `json:"maxerror"`
-		Channel  DiscordChannel `json:"logchannel"`
+		Cooldown int64          `json:"maxerror" validate:"min=0"`
+		Channel  DiscordChannel `json:"logchannel" validate:"channel"`
 	} `json:"log"`
 	Witty struct {
 		Responses map[string]string `json:"witty"`
-		Cooldown  int64             `json:"maxwit"`
+		Cooldown  int64             `json:"maxwit" validate:"min=0"`
 	} `json:"Wit"`
 	Scheduler struct {
-		BirthdayRole DiscordRole `json:"birthdayrole"`
+		BirthdayRole DiscordRole `json:"birthdayrole" validate:"role"`
 	} `json:"scheduler"`
 	Miscellaneous struct {
-		MaxSearchResults int `json:"maxsearchresults"`
+		MaxSearchResults int `json:"maxsearchresults" validate:"min=1"`
 	} `json:"misc"`
 	Status struct {
-		Cooldown int             `json:"statusdelaytime"`
+		Cooldown int             `json:"statusdelaytime" validate:"min=1"`
 		Lines    map[string]bool `json:"lines"`
 	} `json:"status"`
 	Quote struct {
@@ -139,7 +146,10 @@ var ConfigHelp = map[string]map[string]string{
 		"botchannel":            "This allows you to designate a particular channel to point users if they are trying to run too many commands at once. Usually this channel will also be included in `basic.freechannels`. Again, this is for bot commands, not general spamming!",
 		"aliases":               "Can be used to redirect commands, such as making `!listgroup` call the `!listgroups` command. Useful for making shortcuts.\n\nExample: `!setconfig basic.aliases kawaii pick cute` sets an alias mapping `!kawaii arg1...` to `!pick cute arg1...`, preserving all arguments that are passed to the alias.",
 		"listentobots":          "If true, processes messages from other bots and allows them to run commands. Bots can never trigger anti-spam. Defaults to false.",
-		"commandprefix":         "Determines the SINGLE ASCII CHARACTER prefix used to denote bot commands. You can't set it to an emoji or any weird foreign character. The default is `!`. If this is set to an invalid value, it defaults to `!`.",
+		"commandprefix":         "Determines the prefix used to denote bot commands. It can be any length, like `?` or `sb!`, and can even be an emoji. This is the prefix shown in help messages. The default is `!`. Pinging the bot, like `@BotName help`, always works as a prefix.",
+		"extraprefixes":         "A list of additional prefixes that can be used to run commands, alongside `basic.commandprefix`. Useful if the main prefix clashes with another bot.\n\nExample: `!setconfig basic.extraprefixes ? sb!` lets users run `?help` or `sb!help`.",
+		"language":              "The language the bot uses for its messages, such as `es` or `de`. Languages are loaded from the `lang` folder of the website directory, and any message that hasn't been translated will be shown in English. Leave empty to use English.",
+		"strings":               "Replaces individual bot messages with custom text, overriding the language pack. Use `!strings` to change these instead of setting them directly.",
 	},
 	"modules": {
 		"commandroles":       "A map of which roles are allowed to run which command. If no mapping exists, everyone can run the command.",
@@ -148,6 +158,9 @@ var ConfigHelp = map[string]map[string]string{
 		"commanddisabled":    "A list of disabled commands. Disabled commands can still be run by administrators, but can't be run by the bot and will not function as a bored command.",
 		"commandperduration": "Maximum number of commands that can be run within `commandmaxduration` seconds. Default: 3",
 		"commandmaxduration": "Default: 20. This means that by default, at most 3 commands can be run every 20 seconds.",
+		"usercooldowns":      "A map of per-user cooldowns for each command and role. A user must wait this many seconds before running the same command again. Use `@everyone` to set a cooldown for all users, and `*` instead of a command name to set a cooldown between any two commands. If a user has several roles, the shortest cooldown applies.\n\nExample: `!setconfig modules.usercooldowns pick @everyone 30` sets a 30 second cooldown on `!pick` for each user.",
+		"userquotas":         "A map of per-user quotas for each command and role. A user can only run a command this many times within `modules.userquotawindow` seconds. Use `@everyone` to set a quota for all users, and `*` instead of a command name to limit all commands combined. If a user has several roles, the largest quota applies.\n\nExample: `!setconfig modules.userquotas * @everyone 20` lets each user run 20 commands an hour.",
+		"userquotawindow":    "The number of seconds that `modules.userquotas` are counted over. Default: 3600",
 		"disabled":           "A list of disabled modules. This disables any hooks the modules normally process, and also disables all commands inside that module (although commands can be selectively re-enabled without enabling the module).",
 		"channels":           "A mapping of what channels a given module can operate on. If no mapping is given, a module operates on all channels. If `!` is included as a channel, it switches from a whitelist to a blacklist, enabling you to exclude certain channels instead of allow certain channels. Restricting a module to a channel DOES NOT restrict its commands to that channel.",
 	},
@@ -248,7 +261,7 @@ func getConfigHelp(module string, option string) (string, bool) {
 }
 
 // ConfigVersion is the latest version of the config file
-var ConfigVersion = 31
+var ConfigVersion = 40
 
 // DefaultConfig returns a default BotConfig struct. We can't define this as a variable because you can't initialize nested structs in a sane way in Go
 func DefaultConfig() *BotConfig {
@@ -262,6 +275,7 @@ func DefaultConfig() *BotConfig {
 	config.Basic.CommandPrefix = "!"
 	config.Modules.CommandPerDuration = 3
 	config.Modules.CommandMaxDuration = 15
+	config.Modules.UserQuotaWindow = 3600
 	config.Spam.MaxPressure = 60
 	config.Spam.BasePressure = 10
 	config.Spam.ImagePressure = (config.Spam.MaxPressure - config.Spam.BasePressure) / 6
@@ -365,7 +379,7 @@ func setConfigValue(f reflect.Value, value string, info *GuildInfo) error {
 		f.SetString(s.String())
 	case ModuleID:
 		value = strings.ToLower(value)
-		for _, v := range info.Modules {
+		for _, v := range info.GetModules() {
 			if value == strings.ToLower(v.Name()) {
 				f.SetString(value)
 				return nil
@@ -374,7 +388,7 @@ func setConfigValue(f reflect.Value, value string, info *GuildInfo) error {
 		return fmt.Errorf("%s is not a module name!", value)
 	case CommandID:
 		value = strings.ToLower(value)
-		if _, ok := info.commands[CommandID(value)]; !ok {
+		if _, ok := info.GetCommand(CommandID(value)); !ok {
 			return fmt.Errorf("%s is not a command name!", value)
 		}
 		f.SetString(value)
@@ -487,8 +501,158 @@ func setConfigMapList(f reflect.Value, key string, values []string, info *GuildI
 	return fmt.Sprintf("%v: %s", k, s), true
 }
 
+// setConfigMapKeyValue sets a single value in a nested map, like a per-role limit for a command. A key of * is always
+// allowed, and means the limit applies to every command.
+func setConfigMapKeyValue(f reflect.Value, key string, subkey string, value string, info *GuildInfo) (string, bool) {
+	if f.IsNil() {
+		f.Set(reflect.MakeMap(f.Type()))
+	}
+	k := reflect.New(f.Type().Key()).Elem()
+	if key == "*" {
+		k.SetString(key)
+	} else if err := setConfigValue(k, key, info); err != nil {
+		return "Key error: " + err.Error(), false
+	}
+	m := f.MapIndex(k)
+	if !m.IsValid() {
+		if len(value) == 0 {
+			return fmt.Sprint(k.Interface()) + " does not exist.", false
+		}
+		m = reflect.MakeMap(f.Type().Elem())
+		f.SetMapIndex(k, m)
+	}
+	s, ok := setConfigKeyValue(m, subkey, value, info)
+	if m.Len() == 0 {
+		f.SetMapIndex(k, reflect.Value{})
+	}
+	if !ok {
+		return s, false
+	}
+	return fmt.Sprintf("%v: %s", k.Interface(), s), true
+}
+
+// getConfigField returns the config option matching the Category.Option path, along with its normalized path
+func (config *BotConfig) getConfigField(name string) (reflect.Value, string) {
+	names := strings.SplitN(strings.ToLower(name), ".", 3)
+	if len(names) < 2 {
+		return reflect.Value{}, ""
+	}
+	t := reflect.ValueOf(config).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Kind() == reflect.Struct && strings.ToLower(t.Type().Field(i).Name) == names[0] {
+			for j := 0; j < t.Field(i).NumField(); j++ {
+				if strings.ToLower(t.Field(i).Type().Field(j).Name) == names[1] {
+					return t.Field(i).Field(j), names[0] + "." + names[1]
+				}
+			}
+		}
+	}
+	return reflect.Value{}, ""
+}
+
+func recordConfigChange(info *GuildInfo, user DiscordUser, path string, old []byte, new []byte) {
+	if bytes.Equal(old, new) {
+		return
+	}
+	if info.Bot.DB.CheckStatus() {
+		info.Bot.DB.AddConfigHistory(SBatoi(info.ID), user.Convert(), path, string(old), string(new))
+	}
+	info.Bus.Publish(&ConfigChangeEvent{user, path, string(old), string(new)})
+}
+
+// saveConfigChanges saves the config, and only records the changes in the config history if the config was actually saved
+func (info *GuildInfo) saveConfigChanges(user DiscordUser, changes []ConfigChange) error {
+	if err := info.SaveConfig(); err != nil {
+		return err
+	}
+	for _, v := range changes {
+		recordConfigChange(info, user, v.Path, []byte(v.Old), []byte(v.New))
+	}
+	return nil
+}
+
 // SetConfig sets the given config option with the given value along with any extra parameters
 func (config *BotConfig) SetConfig(info *GuildInfo, args []string, indices []int, message string) (string, bool) {
+	f, path := config.getConfigField(args[0])
+	if !f.IsValid() {
+		return config.setConfig(info, args, indices, message)
+	}
+	old, _ := json.Marshal(f.Interface())
+	olderr := config.ValidateOption(info, path)
+	s, ok := config.setConfig(info, args, indices, message)
+	if err := config.validateChange(info, path, f, old, olderr); err != nil {
+		return "Error: " + err.Error(), false
+	}
+	return s, ok
+}
+
+// validateChange checks an option that was just changed, and puts back its old value if the change broke the option's
+// validation constraints. Changes to an option that was already invalid aren't blocked unless they make it worse.
+func (config *BotConfig) validateChange(info *GuildInfo, path string, f reflect.Value, old []byte, olderr error) error {
+	if err := config.ValidateOption(info, path); err != nil && (olderr == nil || err.Error() != olderr.Error()) {
+		restoreConfigField(f, old)
+		return err
+	}
+	return nil
+}
+
+// SetConfigOption sets a config option using SetConfig and saves the config. The change is only recorded in the config
+// history if the config was saved, otherwise it is undone and the error is returned.
+func (info *GuildInfo) SetConfigOption(args []string, indices []int, message string, user DiscordUser) (string, bool, error) {
+	info.ConfigLock.Lock()
+	f, path := info.Config.getConfigField(args[0])
+	var old []byte
+	if f.IsValid() {
+		old, _ = json.Marshal(f.Interface())
+	}
+	s, ok := info.Config.SetConfig(info, args, indices, message)
+	var changes []ConfigChange
+	if ok && f.IsValid() {
+		if new, err := json.Marshal(f.Interface()); err == nil {
+			changes = append(changes, ConfigChange{Path: path, Old: string(old), New: string(new)})
+		}
+	}
+	info.ConfigLock.Unlock()
+	err := info.saveConfigChanges(user, changes)
+	if err != nil && err != ErrConfigConflict && len(changes) > 0 { // A conflict already replaced the config with the stored one
+		info.ConfigLock.Lock()
+		info.Config.undoConfigChanges(changes)
+		info.ConfigLock.Unlock()
+	}
+	return s, ok, err
+}
+
+// RestoreConfig sets a config option to a JSON value taken from the config history, and returns the change it made. Like
+// SetConfig, the value is refused if it breaks the option's validation constraints.
+func (config *BotConfig) RestoreConfig(info *GuildInfo, path string, value string) (*ConfigChange, error) {
+	f, name := config.getConfigField(path)
+	if !f.IsValid() {
+		return nil, errors.New(path + " is no longer a configuration option")
+	}
+	v := reflect.New(f.Type())
+	if err := json.Unmarshal([]byte(value), v.Interface()); err != nil {
+		return nil, err
+	}
+	old, _ := json.Marshal(f.Interface())
+	olderr := config.ValidateOption(info, name)
+	f.Set(v.Elem())
+	if err := config.validateChange(info, name, f, old, olderr); err != nil {
+		return nil, err
+	}
+	new, _ := json.Marshal(f.Interface())
+	return &ConfigChange{Path: name, Old: string(old), New: string(new)}, nil
+}
+
+// undoConfigChanges puts back the old values of a list of changes, in reverse order
+func (config *BotConfig) undoConfigChanges(changes []ConfigChange) {
+	for i := len(changes) - 1; i >= 0; i-- {
+		if f, _ := config.getConfigField(changes[i].Path); f.IsValid() {
+			restoreConfigField(f, []byte(changes[i].Old))
+		}
+	}
+}
+
+func (config *BotConfig) setConfig(info *GuildInfo, args []string, indices []int, message string) (string, bool) {
 	name := args[0]
 	names := strings.SplitN(strings.ToLower(name), ".", 3)
 	t := reflect.ValueOf(config).Elem()
@@ -539,6 +703,15 @@ func (config *BotConfig) SetConfig(info *GuildInfo, args []string, indices []int
 								return "No key parameter given", false
 							}
 							return setConfigMapList(f, strings.ToLower(args[1]), args[2:], info)
+						case map[CommandID]map[DiscordRole]int64:
+							if len(indices) < 3 {
+								return "You must specify both a command and a role", false
+							}
+							value := ""
+							if len(indices) > 3 {
+								value = message[indices[3]:]
+							}
+							return setConfigMapKeyValue(f, strings.ToLower(args[1]), args[2], value, info)
 						default:
 							return "That config option has an unknown type!", false
 						}
@@ -635,7 +808,7 @@ func (config *BotConfig) GetConfig(f reflect.Value, state *discordgo.State, guil
 		s = append(s, getConfigValue(f, state, guild))
 	case map[DiscordChannel]bool, map[string]bool, map[DiscordRole]bool, map[string]string, map[CommandID]int64, map[DiscordChannel]float32, map[int]string, map[CommandID]bool, map[ModuleID]bool, map[string]float32, map[string]int64:
 		s = getConfigList(f, state, guild)
-	case map[string]map[DiscordChannel]bool, map[CommandID]map[DiscordRole]bool, map[string]map[string]bool, map[DiscordUser][]string, map[CommandID]map[DiscordChannel]bool, map[ModuleID]map[DiscordChannel]bool:
+	case map[string]map[DiscordChannel]bool, map[CommandID]map[DiscordRole]bool, map[string]map[string]bool, map[DiscordUser][]string, map[CommandID]map[DiscordChannel]bool, map[ModuleID]map[DiscordChannel]bool, map[CommandID]map[DiscordRole]int64:
 		s = getConfigMapList(f, state, guild)
 	default:
 		data, err := json.Marshal(f.Interface())
@@ -775,17 +948,44 @@ func (guild *GuildInfo) renameCommand(old CommandID, new CommandID) {
 		delete(guild.Config.Modules.CommandDisabled, old)
 	}
 
+	if val, ok := guild.Config.Modules.UserCooldowns[old]; ok {
+		guild.Config.Modules.UserCooldowns[new] = val
+		delete(guild.Config.Modules.UserCooldowns, old)
+	}
+
+	if val, ok := guild.Config.Modules.UserQuotas[old]; ok {
+		guild.Config.Modules.UserQuotas[new] = val
+		delete(guild.Config.Modules.UserQuotas, old)
+	}
+
 	// Migrate aliases by substituting old command name for new command name
 	for k, v := range guild.Config.Basic.Aliases {
 		target := strings.SplitN(v, " ", 2)
 		if strings.ToLower(target[0]) == strings.ToLower(string(old)) {
-			guild.Config.Basic.Aliases[k] = strings.ToLower(string(new)) + " " + target[1]
+			if len(target) > 1 {
+				guild.Config.Basic.Aliases[k] = strings.ToLower(string(new)) + " " + target[1]
+			} else {
+				guild.Config.Basic.Aliases[k] = strings.ToLower(string(new))
+			}
 		}
 	}
 }
 
 // MigrateSettings from earlier config version
 func (guild *GuildInfo) MigrateSettings(config []byte) error {
+	if err := guild.migrateConfig(config); err != nil {
+		return err
+	}
+
+	if guild.Config.Version != ConfigVersion {
+		guild.Config.Version = ConfigVersion // set version to most recent config version
+		guild.SaveConfig()
+	}
+	return nil
+}
+
+// migrateConfig loads the config and upgrades it from earlier config versions without saving it
+func (guild *GuildInfo) migrateConfig(config []byte) error {
 	err := json.Unmarshal(config, &guild.Config)
 	if err != nil {
 		return err
@@ -818,7 +1018,7 @@ func (guild *GuildInfo) MigrateSettings(config []byte) error {
 				guild.Config.Spam.PingPressure = 0
 			}
 		} else {
-			fmt.Println(err.Error())
+			guild.Bot.Logger.Error("Error migrating config: "+err.Error(), LogFields{"guild": guild.ID})
 		}
 	}
 
@@ -846,22 +1046,22 @@ func (guild *GuildInfo) MigrateSettings(config []byte) error {
 					for u := range v {
 						err = guild.Bot.DG.GuildMemberRoleAdd(guild.ID, u, r.ID)
 						if err != nil {
-							fmt.Println(err)
+							guild.Bot.Logger.Error("Error migrating config: "+err.Error(), LogFields{"guild": guild.ID})
 						}
 					}
 				} else {
-					fmt.Println(err)
+					guild.Bot.Logger.Error("Error migrating config: "+err.Error(), LogFields{"guild": guild.ID})
 				}
 			}
 
 			stmt, err := guild.Bot.DB.Prepare("SELECT ID, Data FROM schedule WHERE Guild = ? AND Type = 7")
 			stmt2, err := guild.Bot.DB.Prepare("UPDATE schedule SET Data = ? WHERE ID = ?")
 			if err != nil {
-				fmt.Println(err)
+				guild.Bot.Logger.Error("Error migrating config: "+err.Error(), LogFields{"guild": guild.ID})
 			} else {
 				q, err := stmt.Query(SBatoi(guild.ID))
 				if err != nil {
-					fmt.Println(err)
+					guild.Bot.Logger.Error("Error migrating config: "+err.Error(), LogFields{"guild": guild.ID})
 				} else {
 					defer q.Close()
 					for q.Next() {
@@ -878,14 +1078,14 @@ func (guild *GuildInfo) MigrateSettings(config []byte) error {
 							}
 							_, err = stmt2.Exec(strings.Join(groups, " ")+"|"+datas[1], id)
 							if err != nil {
-								fmt.Println(err)
+								guild.Bot.Logger.Error("Error migrating config: "+err.Error(), LogFields{"guild": guild.ID})
 							}
 						}
 					}
 				}
 			}
 		} else {
-			fmt.Println(err.Error())
+			guild.Bot.Logger.Error("Error migrating config: "+err.Error(), LogFields{"guild": guild.ID})
 		}
 	}
 
@@ -937,7 +1137,7 @@ func (guild *GuildInfo) MigrateSettings(config []byte) error {
 			gID := SBatoi(guild.ID)
 			for k, v := range legacy.Basic.Collections {
 				if len(v) > 0 {
-					fmt.Println("Importing:", k)
+					guild.Bot.Logger.Info("Importing collection "+k, LogFields{"guild": guild.ID})
 					guild.Bot.DB.CreateTag(k, gID)
 					tag, err := guild.Bot.DB.GetTag(k, gID)
 					if err == nil {
@@ -949,11 +1149,11 @@ func (guild *GuildInfo) MigrateSettings(config []byte) error {
 						}
 					}
 				} else {
-					fmt.Println("Skipping empty collection:", k)
+					guild.Bot.Logger.Info("Skipping empty collection "+k, LogFields{"guild": guild.ID})
 				}
 			}
 		} else {
-			fmt.Println(err.Error())
+			guild.Bot.Logger.Error("Error migrating config: "+err.Error(), LogFields{"guild": guild.ID})
 		}
 		guild.Bot.GuildsLock.Unlock()
 		restrictCommand("addset", guild.Config.Modules.CommandRoles, guild.Config.Basic.ModRole)
@@ -1125,9 +1325,63 @@ func (guild *GuildInfo) MigrateSettings(config []byte) error {
 		}
 	}
 
-	if guild.Config.Version != ConfigVersion {
-		guild.Config.Version = ConfigVersion // set version to most recent config version
-		guild.SaveConfig()
+	if guild.Config.Version <= 31 {
+		restrictCommand("confighistory", guild.Config.Modules.CommandRoles, guild.Config.Basic.ModRole)
+		restrictCommand("configrollback", guild.Config.Modules.CommandRoles, guild.Config.Basic.ModRole)
+	}
+
+	if guild.Config.Version <= 32 {
+		restrictCommand("validateconfig", guild.Config.Modules.CommandRoles, guild.Config.Basic.ModRole)
+	}
+
+	if guild.Config.Version <= 33 {
+		restrictCommand("exportconfig", guild.Config.Modules.CommandRoles, guild.Config.Basic.ModRole)
+		restrictCommand("importconfig", guild.Config.Modules.CommandRoles, guild.Config.Basic.ModRole)
+	}
+
+	if guild.Config.Version <= 34 {
+		restrictCommand("strings", guild.Config.Modules.CommandRoles, guild.Config.Basic.ModRole)
+	}
+
+	if guild.Config.Version <= 35 {
+		// The filter and counter commands were moved into command groups, so we rename their settings and add aliases for the old names
+		CheckMapNilString(&guild.Config.Basic.Aliases)
+		for old, sub := range map[CommandID]string{
+			"setfilter":     "filter set",
+			"addfilter":     "filter add",
+			"removefilter":  "filter remove",
+			"deletefilter":  "filter delete",
+			"searchfilter":  "filter search",
+			"addcounter":    "counter add",
+			"removecounter": "counter remove",
+			"increment":     "counter inc",
+			"decrement":     "counter dec",
+		} {
+			guild.renameCommand(old, CommandID(strings.Replace(sub, " ", ".", 1)))
+			if _, ok := guild.Config.Basic.Aliases[string(old)]; !ok {
+				guild.Config.Basic.Aliases[string(old)] = sub
+			}
+		}
+	}
+
+	if guild.Config.Version <= 36 {
+		guild.Config.Modules.UserQuotaWindow = 3600
+	}
+
+	if guild.Config.Version <= 37 {
+		restrictCommand("addcommand", guild.Config.Modules.CommandRoles, guild.Config.Basic.ModRole)
+		restrictCommand("removecommand", guild.Config.Modules.CommandRoles, guild.Config.Basic.ModRole)
+	}
+
+	if guild.Config.Version <= 38 {
+		restrictCommand("apitoken", guild.Config.Modules.CommandRoles, guild.Config.Basic.ModRole)
+		restrictCommand("apitoken.create", guild.Config.Modules.CommandRoles, guild.Config.Basic.ModRole)
+		restrictCommand("apitoken.revoke", guild.Config.Modules.CommandRoles, guild.Config.Basic.ModRole)
+		restrictCommand("apitoken.list", guild.Config.Modules.CommandRoles, guild.Config.Basic.ModRole)
+	}
+
+	if guild.Config.Version <= 39 {
+		restrictCommand("reloadmodules", guild.Config.Modules.CommandRoles, guild.Config.Basic.ModRole)
 	}
 	return nil
 }
@@ -1147,7 +1401,7 @@ func getSubStruct(arg []string, f reflect.Value, j int, info *GuildInfo) []strin
 		case map[int]string:
 			ival, _ := strconv.Atoi(arg[2])
 			val = f.Field(j).MapIndex(reflect.ValueOf(ival))
-		case map[CommandID]bool, map[CommandID]int64, map[CommandID]map[DiscordRole]bool, map[CommandID]map[DiscordChannel]bool:
+		case map[CommandID]bool, map[CommandID]int64, map[CommandID]map[DiscordRole]bool, map[CommandID]map[DiscordChannel]bool, map[CommandID]map[DiscordRole]int64:
 			val = f.Field(j).MapIndex(reflect.ValueOf(CommandID(arg[2])))
 		case map[ModuleID]bool, map[ModuleID]map[DiscordChannel]bool:
 			val = f.Field(j).MapIndex(reflect.ValueOf(ModuleID(arg[2])))
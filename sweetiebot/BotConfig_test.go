@@ -1,6 +1,7 @@
 package sweetiebot
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
@@ -81,12 +82,20 @@ func TestSetConfig(t *testing.T) {
 	fnImportable("BASIC.IMPORTABLE")
 
 	sb, dbmock, _ := MockSweetieBot(t)
-	info := NewGuildInfo(sb, &discordgo.Guild{})
+	guild := &discordgo.Guild{ID: "99"}
+	for _, v := range []string{"1", "1234"} {
+		guild.Roles = append(guild.Roles, &discordgo.Role{ID: v, Name: "Role " + v})
+	}
+	for _, v := range []string{"1", "123", "234", "654", "2345", "34643"} {
+		guild.Channels = append(guild.Channels, &discordgo.Channel{ID: v, GuildID: guild.ID, Name: "channel-" + v})
+	}
+	sb.DG.State.GuildAdd(guild) // Validation only accepts channels and roles that exist
+	info := NewGuildInfo(sb, guild)
 	info.commands["1234"] = mockCommand("1234")
 	info.commands["1"] = mockCommand("1")
 	info.commands[""] = mockCommand("")
 	info.Modules = []Module{mockModule(""), mockModule("1")}
-	dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM members.*").WithArgs(sqlmock.AnyArg(), "1", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(1))
+	dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM `members`.*").WithArgs(sqlmock.AnyArg(), "1", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(1))
 
 	fnSetInterface := func(name string, value interface{}) {
 		name, _ = FixRequest(name, reflect.ValueOf(config).Elem())
@@ -166,10 +175,23 @@ func TestSetConfig(t *testing.T) {
 					fmt.Println(path)
 				}
 
+				if path == "Basic.Language" { // Only accepts languages that have been loaded
+					Check(config.Basic.Language, "", t)
+					continue
+				}
+				if path == "Basic.Strings" { // Only accepts message names
+					config.internalSetConfig(info, path, "1", "1")
+					Check(len(config.Basic.Strings), 0, t)
+					config.internalSetConfig(info, path, "pm_failure", "1")
+					Check(config.Basic.Strings["pm_failure"], "1", t)
+					continue
+				}
 				switch p.Field(i).Field(j).Interface().(type) {
 				case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, float32, float64, uint64, DiscordChannel, DiscordRole, DiscordUser:
 					config.internalSetConfig(info, path, "1")
-					Check(fmt.Sprint(p.Field(i).Field(j).Interface()), "1", t)
+					if !Check(fmt.Sprint(p.Field(i).Field(j).Interface()), "1", t) {
+						fmt.Println(path)
+					}
 					continue
 				case bool:
 					config.internalSetConfig(info, path, "true")
@@ -250,6 +272,11 @@ func TestSetConfig(t *testing.T) {
 					Check(ok, true, t)
 					_, ok = v["1"]
 					Check(ok, true, t)
+				case map[CommandID]map[DiscordRole]int64:
+					config.internalSetConfig(info, path, "1", "1", "1")
+					v, ok := m["1"]
+					Check(ok, true, t)
+					Check(v["1"], int64(1), t)
 				case map[string]map[DiscordChannel]bool:
 					v, ok := m["1"]
 					Check(ok, true, t)
@@ -266,3 +293,83 @@ func TestSetConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateConfig(t *testing.T) {
+	config := DefaultConfig()
+	Check(len(config.Validate(nil)), 0, t)
+	Check(config.ValidateOption(nil, "spam.maxpressure"), nil, t)
+	config.Spam.MaxPressure = 0
+	config.Spam.RaidSilence = 5
+	Check(len(config.Validate(nil)), 2, t)
+	Check(config.ValidateOption(nil, "spam.maxpressure").Error(), "Spam.MaxPressure must be at least 1, but was set to 0", t)
+	Check(config.ValidateOption(nil, "Spam.RaidSilence").Error(), "Spam.RaidSilence must be one of 0, 1, 2, but was set to 5", t)
+	Check(config.ValidateOption(nil, "spam.nonexistent"), nil, t)
+	config.Basic.ModChannel = "100"
+	config.Basic.ModRole = "200"
+	Check(len(config.Validate(nil)), 2, t) // Channels and roles can't be checked without a server
+
+	config = DefaultConfig()
+	_, err := config.RestoreConfig(nil, "spam.maxpressure", "0")
+	Check(err.Error(), "Spam.MaxPressure must be at least 1, but was set to 0", t)
+	Check(config.Spam.MaxPressure, DefaultConfig().Spam.MaxPressure, t)
+	change, err := config.RestoreConfig(nil, "spam.maxpressure", "70")
+	Check(err, nil, t)
+	Check(change.New, "70", t)
+	Check(config.Spam.MaxPressure, float32(70), t)
+}
+
+func TestConfigRollback(t *testing.T) {
+	db := mockSQLiteDB(t)
+	defer db.Close()
+	sb := &SweetieBot{DB: db, DG: &DiscordGoSession{Session: &discordgo.Session{State: discordgo.NewState()}}, MaxConfigSize: 1000000}
+	info := NewGuildInfo(sb, &discordgo.Guild{ID: "5"})
+	set := func(args ...string) error {
+		message := strings.Join(args, " ")
+		args, indices := ParseCommandArguments(message, 0)
+		_, ok, err := info.SetConfigOption(args, indices, message, "2")
+		Check(ok, true, t)
+		return err
+	}
+	Check(set("basic.commandprefix", "?"), nil, t)
+	Check(len(db.GetConfigHistory(5, "", 10, 0)), 1, t)
+	first := db.GetConfigHistory(5, "", 10, 0)[0].ID
+	db.AddConfigHistory(5, 2, "basic.removed", "1", "2")
+	Check(set("spam.maxpressure", "70"), nil, t)
+	Check(len(db.GetConfigHistory(5, "", 10, 0)), 3, t)
+
+	sb.MaxConfigSize = 1
+	CheckNot(set("spam.maxpressure", "80"), nil, t)
+	Check(len(db.GetConfigHistory(5, "", 10, 0)), 3, t) // Changes that weren't saved aren't recorded
+	Check(info.Config.Spam.MaxPressure, float32(70), t)
+	sb.MaxConfigSize = 1000000
+
+	msg := &discordgo.Message{Author: &discordgo.User{ID: "2"}}
+	rollback := &configRollbackCommand{}
+	s, _, _ := rollback.Process([]string{fmt.Sprint(first), "all"}, msg, []int{0, 0}, info)
+	Check(strings.Contains(s, "Nothing was rolled back"), true, t)
+	Check(info.Config.Spam.MaxPressure, float32(70), t) // A rollback that fails halfway through doesn't change anything
+	Check(info.Config.Basic.CommandPrefix, "?", t)
+	Check(len(db.GetConfigHistory(5, "", 10, 0)), 3, t)
+
+	rollback.Process([]string{fmt.Sprint(first + 2)}, msg, []int{0}, info)
+	Check(info.Config.Spam.MaxPressure, DefaultConfig().Spam.MaxPressure, t)
+	Check(info.Config.Basic.CommandPrefix, "?", t)
+	Check(len(db.GetConfigHistory(5, "", 10, 0)), 4, t)
+}
+
+func TestReloadConfig(t *testing.T) {
+	db := mockSQLiteDB(t)
+	defer db.Close()
+	sb := &SweetieBot{DB: db, DG: &DiscordGoSession{Session: &discordgo.Session{State: discordgo.NewState()}}, MaxConfigSize: 1000000}
+	info := NewGuildInfo(sb, &discordgo.Guild{ID: "5"})
+	_, err := db.SaveConfig(5, []byte(`{"version":38,"basic":{"commandprefix":"?"}}`), 0, 0)
+	Check(err, nil, t)
+	Check(info.ReloadConfig(), nil, t)
+	Check(info.Config.Version, ConfigVersion, t)
+	Check(info.Config.Basic.CommandPrefix, "?", t)
+	data, _, err := db.GetConfig(5)
+	Check(err, nil, t)
+	config := BotConfig{}
+	Check(json.Unmarshal(data, &config), nil, t)
+	Check(config.Version, ConfigVersion, t) // The migrated config is saved back
+}
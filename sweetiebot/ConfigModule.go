@@ -1,9 +1,21 @@
 package sweetiebot
 
 import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -23,6 +35,16 @@ func (w *ConfigModule) Commands() []Command {
 		&setConfigCommand{},
 		&getConfigCommand{},
 		&setupCommand{},
+		&configHistoryCommand{},
+		&configRollbackCommand{},
+		&validateConfigCommand{},
+		&exportConfigCommand{},
+		&importConfigCommand{},
+		&stringsCommand{},
+		NewCommandGroup("APIToken", "Manages tokens for the read-only web API.", &listAPITokensCommand{}).
+			Add("Create", &createAPITokenCommand{}).
+			Add("Revoke", &revokeAPITokenCommand{}).
+			Add("List", &listAPITokensCommand{}),
 	}
 }
 
@@ -37,6 +59,7 @@ func (c *setConfigCommand) Info() *CommandInfo {
 		Name:      "SetConfig",
 		Usage:     "Sets a config value and saves the new configuration.",
 		Sensitive: true,
+		RawText:   true,
 	}
 }
 func (c *setConfigCommand) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
@@ -51,8 +74,10 @@ func (c *setConfigCommand) Process(args []string, msg *discordgo.Message, indice
 	if err != nil {
 		return ReturnError(err)
 	}
-	n, ok := info.Config.SetConfig(info, args, indices, msg.Content)
-	info.SaveConfig()
+	n, ok, err := info.SetConfigOption(args, indices, msg.Content, DiscordUser(msg.Author.ID))
+	if err != nil {
+		return "```\nError saving config: " + err.Error() + "```", false, nil
+	}
 	if ok {
 		return "```\nSuccessfully set " + args[0] + " to " + n + ".```", false, nil
 	}
@@ -204,9 +229,9 @@ func (c *getConfigCommand) Usage(info *GuildInfo) *CommandUsage {
 }
 
 func (c *setupCommand) DisableModule(info *GuildInfo, module string) {
-	for _, v := range info.Modules {
+	for _, v := range info.GetModules() {
 		if strings.ToLower(v.Name()) == module {
-			cmds := v.Commands()
+			cmds := ExpandCommands(v.Commands())
 			for _, v := range cmds {
 				str := strings.ToLower(v.Info().Name)
 				CheckMapNilBool(&info.Config.Modules.CommandDisabled)
@@ -239,13 +264,14 @@ func (c *setupCommand) Process(args []string, msg *discordgo.Message, indices []
 	if len(args) < 2 {
 		return "```\nYou must provide at least the Moderator Role and Mod Channel arguments to this function.```", false, nil
 	}
+	config := info.Config
 	if info.Config.SetupDone {
 		if strings.ToLower(args[0]) != "override" {
 			return "```\nWARNING: This server has already been configured. If you run " + info.Config.Basic.CommandPrefix + "setup again, it will reset ALL CONFIGURATION DATA to defaults! If you wish to proceed, use " + info.Config.Basic.CommandPrefix + "setup OVERRIDE <your arguments>```", false, nil
 		}
 		args = args[1:]
 		indices = indices[1:]
-		info.Config = *DefaultConfig()
+		config = *DefaultConfig()
 	}
 	if len(args) < 2 {
 		return "```\nYou must provide at least the Moderator Role and Mod Channel arguments to this function.```", false, nil
@@ -254,28 +280,36 @@ func (c *setupCommand) Process(args []string, msg *discordgo.Message, indices []
 		return fmt.Sprintf("```\nThis function only accepts 4 arguments, but you put in %v! Are you actually using @Role for the mod role and #channel for the channels? Alternatively, put your moderator role in \"quotes\".```", len(args)), false, nil
 	}
 
-	info.Config.Basic.ModRole, err = ParseRole(args[0], guild)
-	if err != nil || info.Config.Basic.ModRole == RoleEmpty || info.Config.Basic.ModRole == RoleExclusion {
+	// Parse and validate everything before touching the existing configuration, so a typo doesn't wipe it
+	config.Basic.ModRole, err = ParseRole(args[0], guild)
+	if err != nil || config.Basic.ModRole == RoleEmpty || config.Basic.ModRole == RoleExclusion {
 		return args[0] + " is not a valid role!", false, nil
 	}
-	info.Config.Basic.ModChannel, err = ParseChannel(args[1], guild)
-	if err != nil || info.Config.Basic.ModChannel == ChannelEmpty || info.Config.Basic.ModChannel == ChannelExclusion {
+	config.Basic.ModChannel, err = ParseChannel(args[1], guild)
+	if err != nil || config.Basic.ModChannel == ChannelEmpty || config.Basic.ModChannel == ChannelExclusion {
 		return args[1] + " is not a valid channel!", false, nil
 	}
 
 	if len(args) > 2 {
-		info.Config.Log.Channel, err = ParseChannel(args[2], guild)
-		if err != nil || info.Config.Log.Channel == ChannelEmpty || info.Config.Log.Channel == ChannelExclusion {
+		config.Log.Channel, err = ParseChannel(args[2], guild)
+		if err != nil || config.Log.Channel == ChannelEmpty || config.Log.Channel == ChannelExclusion {
 			return args[2] + " is not a valid channel!", false, nil
 		}
 	}
 
+	for _, option := range []string{"basic.modrole", "basic.modchannel", "log.channel"} {
+		if err := config.ValidateOption(info, option); err != nil {
+			return ReturnError(err)
+		}
+	}
+
+	info.Config = config
 	info.Config.Basic.Aliases = make(map[string]string)
 	info.Config.Basic.Aliases["calc"] = "roll"
 	info.Config.Basic.Aliases["calculate"] = "roll"
 	info.Config.Modules.CommandRoles = make(map[CommandID]map[DiscordRole]bool)
 
-	for k, v := range info.commands {
+	for k, v := range info.GetCommands() {
 		if v.Info().Sensitive {
 			info.Config.Modules.CommandRoles[k] = make(map[DiscordRole]bool)
 			info.Config.Modules.CommandRoles[k][info.Config.Basic.ModRole] = true
@@ -314,3 +348,530 @@ func (c *setupCommand) Usage(info *GuildInfo) *CommandUsage {
 		},
 	}
 }
+
+func truncateConfigValue(s string) string {
+	if len(s) > 80 {
+		return s[:77] + "..."
+	}
+	return s
+}
+
+type configHistoryCommand struct {
+}
+
+func (c *configHistoryCommand) Info() *CommandInfo {
+	return &CommandInfo{
+		Name:      "ConfigHistory",
+		Usage:     "Lists recent changes to the configuration.",
+		Sensitive: true,
+	}
+}
+func (c *configHistoryCommand) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	if !info.Bot.DB.CheckStatus() {
+		return "```\nA temporary database outage is preventing this command from being executed.```", false, nil
+	}
+	var maxresults uint64 = 10
+	path := ""
+	for _, arg := range args {
+		if n, err := strconv.ParseUint(arg, 10, 64); err == nil {
+			maxresults = n
+			continue
+		}
+		fixed, err := FixRequest(arg, reflect.ValueOf(&info.Config).Elem())
+		if err != nil {
+			return ReturnError(err)
+		}
+		f, name := info.Config.getConfigField(fixed)
+		if !f.IsValid() {
+			return "```\n" + arg + " is not a configuration option! Use \"Category.Option\", like \"Basic.Aliases\".```", false, nil
+		}
+		path = name
+	}
+	if maxresults > 50 {
+		maxresults = 50
+	}
+
+	r := info.Bot.DB.GetConfigHistory(SBatoi(info.ID), path, maxresults, 0)
+	if len(r) == 0 {
+		return "```\nNo configuration changes have been recorded.```", false, nil
+	}
+	ret := []string{"```\nRecent configuration changes:```"}
+	for _, v := range r {
+		ret = append(ret, fmt.Sprintf("#%v [%s] %s set %s: %s → %s", v.ID, info.ApplyTimezone(v.Timestamp, DiscordUser(msg.Author.ID)).Format("1/2 3:04:05PM"), info.GetUserName(NewDiscordUser(v.User)), v.Path, truncateConfigValue(v.Old), truncateConfigValue(v.New)))
+	}
+	return info.Sanitize(strings.Join(ret, "\n"), CleanMost), len(ret) > 12, nil
+}
+func (c *configHistoryCommand) Usage(info *GuildInfo) *CommandUsage {
+	return &CommandUsage{
+		Desc: "Lists who changed which configuration options, when they changed them, and what the old and new values were. Use the change number with `" + info.Config.Basic.CommandPrefix + "configrollback` to undo a change.",
+		Params: []CommandUsageParam{
+			{Name: "option", Desc: "If included, only lists changes to this configuration option, like `Basic.Aliases`.", Optional: true},
+			{Name: "count", Desc: "The number of changes to display, up to a maximum of 50. Defaults to 10.", Optional: true},
+		},
+	}
+}
+
+type configRollbackCommand struct {
+}
+
+func (c *configRollbackCommand) Info() *CommandInfo {
+	return &CommandInfo{
+		Name:      "ConfigRollback",
+		Usage:     "Undoes a configuration change.",
+		Sensitive: true,
+	}
+}
+func (c *configRollbackCommand) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	if len(args) < 1 {
+		return "```\nYou must provide the number of the change to roll back. Use " + info.Config.Basic.CommandPrefix + "confighistory to find it.```", false, nil
+	}
+	if !info.Bot.DB.CheckStatus() {
+		return "```\nA temporary database outage is preventing this command from being executed.```", false, nil
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(args[0], "#"), 10, 64)
+	if err != nil {
+		return "```\n" + args[0] + " is not a valid change number.```", false, nil
+	}
+	change := info.Bot.DB.GetConfigChange(SBatoi(info.ID), id)
+	if change == nil {
+		return "```\nChange #" + args[0] + " doesn't exist.```", false, nil
+	}
+	user := DiscordUser(msg.Author.ID)
+
+	changes := []ConfigChange{*change}
+	if len(args) > 1 && strings.ToLower(args[1]) == "all" {
+		changes = info.Bot.DB.GetConfigChangesSince(SBatoi(info.ID), id)
+	}
+	restored := make([]ConfigChange, 0, len(changes))
+	info.ConfigLock.Lock()
+	for _, v := range changes {
+		r, err := info.Config.RestoreConfig(info, v.Path, v.Old)
+		if err != nil {
+			info.Config.undoConfigChanges(restored) // Either every change is rolled back, or none of them are
+			info.ConfigLock.Unlock()
+			return "```\nError rolling back change #" + strconv.FormatUint(v.ID, 10) + ": " + err.Error() + "\nNothing was rolled back.```", false, nil
+		}
+		restored = append(restored, *r)
+	}
+	info.Config.FillConfig()
+	info.ConfigLock.Unlock()
+	if err := info.saveConfigChanges(user, restored); err != nil {
+		if err != ErrConfigConflict { // A conflict already replaced the config with the stored one
+			info.ConfigLock.Lock()
+			info.Config.undoConfigChanges(restored)
+			info.Config.FillConfig()
+			info.ConfigLock.Unlock()
+		}
+		return "```\nError saving config: " + err.Error() + "\nNothing was rolled back.```", false, nil
+	}
+	if len(changes) == 1 {
+		return "```\nRestored " + change.Path + " to " + truncateConfigValue(change.Old) + ".```", false, nil
+	}
+	return fmt.Sprintf("```\nRolled back %v changes, restoring the configuration to what it was before change #%v.```", len(changes), id), false, nil
+}
+func (c *configRollbackCommand) Usage(info *GuildInfo) *CommandUsage {
+	return &CommandUsage{
+		Desc: "Restores the old value of a configuration change listed by `" + info.Config.Basic.CommandPrefix + "confighistory`. The rollback is itself recorded as a new change, so it can be undone.",
+		Params: []CommandUsageParam{
+			{Name: "change", Desc: "The number of the change to undo.", Optional: false},
+			{Name: "all", Desc: "If included, undoes this change and every change made after it, restoring the entire configuration to what it was before the change.", Optional: true},
+		},
+	}
+}
+
+type validateConfigCommand struct {
+}
+
+func (c *validateConfigCommand) Info() *CommandInfo {
+	return &CommandInfo{
+		Name:      "ValidateConfig",
+		Usage:     "Checks the configuration for invalid values.",
+		Sensitive: true,
+	}
+}
+func (c *validateConfigCommand) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	violations := info.Config.Validate(info)
+	if len(violations) == 0 {
+		return "```\nNo problems found in the configuration.```", false, nil
+	}
+	return "```\nThe following configuration options are invalid:\n" + info.Sanitize(strings.Join(violations, "\n"), CleanCodeBlock) + "```", len(violations) > 12, nil
+}
+func (c *validateConfigCommand) Usage(info *GuildInfo) *CommandUsage {
+	return &CommandUsage{
+		Desc: "Checks every configuration option against its allowed values and lists any that are invalid, such as channels or roles that no longer exist on this server, or numbers that are out of range. Use `" + info.Config.Basic.CommandPrefix + "setconfig` to fix them.",
+	}
+}
+
+type exportConfigCommand struct {
+}
+
+func (c *exportConfigCommand) Info() *CommandInfo {
+	return &CommandInfo{
+		Name:      "ExportConfig",
+		Usage:     "Uploads the configuration as a file.",
+		Sensitive: true,
+	}
+}
+func (c *exportConfigCommand) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	format := "json"
+	if len(args) > 0 {
+		format = strings.ToLower(args[0])
+	}
+	data, err := info.ExportConfig(format)
+	if err != nil {
+		return ReturnError(err)
+	}
+	if format == "yml" {
+		format = "yaml"
+	}
+	_, err = info.Bot.DG.ChannelMessageSendComplex(msg.ChannelID, &discordgo.MessageSend{
+		Content: "Configuration for " + info.Name + ":",
+		Files: []*discordgo.File{{
+			Name:        "config-" + info.ID + "." + format,
+			ContentType: "application/" + format,
+			Reader:      bytes.NewReader(data),
+		}},
+	})
+	if err != nil {
+		return ReturnError(err)
+	}
+	return "", false, nil
+}
+func (c *exportConfigCommand) Usage(info *GuildInfo) *CommandUsage {
+	return &CommandUsage{
+		Desc: "Uploads the entire configuration as a file that can be loaded on another server with `" + info.Config.Basic.CommandPrefix + "importconfig`. The names of all channels and roles in the configuration are included so they can be matched up with the channels and roles on the other server.",
+		Params: []CommandUsageParam{
+			{Name: "format", Desc: "Either `json` or `yaml`. Defaults to `json`.", Optional: true},
+		},
+	}
+}
+
+type pendingImport struct {
+	user    DiscordUser
+	config  *BotConfig
+	expires time.Time
+}
+
+type importConfigCommand struct {
+	lock    sync.Mutex
+	pending map[DiscordUser]*pendingImport // Each user confirms their own upload, so two moderators importing at once can't confirm each other's file
+}
+
+// importClient downloads the files uploaded to !importconfig, so a slow server can't hold up the command forever
+var importClient = &http.Client{Timeout: 10 * time.Second}
+
+func invalidImport(info *GuildInfo, invalid []string) (string, bool, *discordgo.MessageEmbed) {
+	return info.Sanitize("```\nThe imported configuration can't be used on this server, nothing was imported:\n"+strings.Join(invalid, "\n")+"```", CleanMost), len(invalid) > 12, nil
+}
+
+func (c *importConfigCommand) Info() *CommandInfo {
+	return &CommandInfo{
+		Name:      "ImportConfig",
+		Usage:     "Replaces the configuration with an uploaded file.",
+		Sensitive: true,
+	}
+}
+func (c *importConfigCommand) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	user := DiscordUser(msg.Author.ID)
+	if len(args) > 0 && strings.ToLower(args[0]) == "confirm" {
+		c.lock.Lock()
+		pending := c.pending[user]
+		delete(c.pending, user)
+		c.lock.Unlock()
+		if pending == nil || time.Now().UTC().After(pending.expires) {
+			return "```\nYou have no pending import to confirm. Upload a configuration file with " + info.Config.Basic.CommandPrefix + "importconfig first.```", false, nil
+		}
+		return c.apply(pending, info)
+	}
+
+	if len(msg.Attachments) == 0 {
+		return "```\nYou must attach a configuration file created by " + info.Config.Basic.CommandPrefix + "exportconfig.```", false, nil
+	}
+	if msg.Attachments[0].Size > info.Bot.MaxConfigSize {
+		return "```\nThat file is too large to be a configuration file.```", false, nil
+	}
+	resp, err := importClient.Get(msg.Attachments[0].URL)
+	if err != nil {
+		return ReturnError(err)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, int64(info.Bot.MaxConfigSize)+1))
+	resp.Body.Close()
+	if err == nil && resp.StatusCode != http.StatusOK {
+		err = errors.New(resp.Status)
+	}
+	if err != nil {
+		return ReturnError(err)
+	}
+	if len(data) > info.Bot.MaxConfigSize { // The attachment size can't be trusted, because it isn't checked against the download
+		return "```\nThat file is too large to be a configuration file.```", false, nil
+	}
+	export, err := ParseConfigExport(data)
+	if err != nil {
+		return ReturnError(err)
+	}
+	remapped, missing, err := info.RemapConfigExport(export)
+	if err != nil {
+		return ReturnError(err)
+	}
+	config, err := info.PreviewImport(remapped)
+	if err != nil {
+		return ReturnError(err)
+	}
+	config.Expires = info.Config.Expires
+
+	changes := DiffConfig(&info.Config, config)
+	if len(changes) == 0 {
+		return "```\nThe imported configuration is identical to the current configuration.```", false, nil
+	}
+	if invalid := info.invalidChanges(config, changes); len(invalid) > 0 {
+		return invalidImport(info, invalid)
+	}
+	now := time.Now().UTC()
+	c.lock.Lock()
+	if c.pending == nil {
+		c.pending = make(map[DiscordUser]*pendingImport)
+	}
+	for k, v := range c.pending {
+		if now.After(v.expires) {
+			delete(c.pending, k)
+		}
+	}
+	_, replaced := c.pending[user]
+	c.pending[user] = &pendingImport{user, config, now.Add(5 * time.Minute)}
+	c.lock.Unlock()
+
+	ret := []string{"```\nImporting this configuration will make the following changes:```"}
+	if replaced {
+		ret[0] = "```\nThis replaces your previous pending import. Importing this configuration will make the following changes:```"
+	}
+	for _, v := range changes {
+		ret = append(ret, fmt.Sprintf("%s: %s → %s", v.Path, truncateConfigValue(v.Old), truncateConfigValue(v.New)))
+	}
+	if len(missing) > 0 {
+		ret = append(ret, "```\nThese channels and roles don't exist on this server and won't be remapped: "+strings.Join(missing, ", ")+"```")
+	}
+	ret = append(ret, "Use `"+info.Config.Basic.CommandPrefix+"importconfig confirm` within 5 minutes to apply these changes.")
+	return info.Sanitize(strings.Join(ret, "\n"), CleanMost), len(ret) > 12, nil
+}
+func (c *importConfigCommand) apply(pending *pendingImport, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	info.ConfigLock.Lock()
+	old := info.Config
+	pending.config.Expires = info.Config.Expires
+	changes := DiffConfig(&old, pending.config)
+	if invalid := info.invalidChanges(pending.config, changes); len(invalid) > 0 { // The server may have changed since the preview
+		info.ConfigLock.Unlock()
+		return invalidImport(info, invalid)
+	}
+	info.Config = *pending.config
+	info.ConfigLock.Unlock()
+
+	if err := info.saveConfigChanges(pending.user, changes); err != nil {
+		if err != ErrConfigConflict { // A conflict already replaced the config with the stored one
+			info.ConfigLock.Lock()
+			info.Config = old
+			info.ConfigLock.Unlock()
+		}
+		return "```\nError saving config, nothing was imported: " + err.Error() + "```", false, nil
+	}
+	s := fmt.Sprintf("```\nImported the configuration, changing %v options.", len(changes))
+	if violations := info.Config.Validate(info); len(violations) > 0 {
+		s += fmt.Sprintf(" %v options are invalid on this server, use %svalidateconfig to list them.", len(violations), info.Config.Basic.CommandPrefix)
+	}
+	return s + "```", false, nil
+}
+func (c *importConfigCommand) Usage(info *GuildInfo) *CommandUsage {
+	return &CommandUsage{
+		Desc: "Loads a configuration file created by `" + info.Config.Basic.CommandPrefix + "exportconfig`, which must be attached to the message. Channels and roles are matched up with channels and roles on this server that have the same name. Lists the changes the import would make, which are only applied once you confirm them. Each change is recorded, so the import can be undone with `" + info.Config.Basic.CommandPrefix + "configrollback`.",
+		Params: []CommandUsageParam{
+			{Name: "confirm", Desc: "Applies the changes from the last file you uploaded.", Optional: true},
+		},
+	}
+}
+
+type stringsCommand struct {
+}
+
+func (c *stringsCommand) Info() *CommandInfo {
+	return &CommandInfo{
+		Name:      "Strings",
+		Usage:     "Customizes the bot's messages.",
+		Sensitive: true,
+	}
+}
+func (c *stringsCommand) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	if len(args) < 1 {
+		if len(info.Config.Basic.Strings) == 0 {
+			return "```\nNo messages have been customized on this server.```", false, nil
+		}
+		keys := make([]string, 0, len(info.Config.Basic.Strings))
+		for k := range info.Config.Basic.Strings {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		s := make([]string, 0, len(keys))
+		for _, k := range keys {
+			s = append(s, fmt.Sprintf("%s: %s", k, truncateConfigValue(info.Config.Basic.Strings[k])))
+		}
+		return "```\nCustomized messages:\n" + info.Sanitize(strings.Join(s, "\n"), CleanCodeBlock) + "```", len(s) > 12, nil
+	}
+
+	reset := strings.ToLower(args[0]) == "reset"
+	if reset {
+		if len(args) < 2 {
+			return "```\nYou must specify which message to reset.```", false, nil
+		}
+		args = args[1:]
+		indices = indices[1:]
+	}
+	id, ok := StringID(args[0])
+	if !ok {
+		return "```\n" + info.Sanitize(args[0], CleanCodeBlock) + " is not a valid message name.```", false, nil
+	}
+	key := StringNames[id]
+	if len(args) < 2 && !reset {
+		return "```\n" + key + ":\n" + info.Sanitize(info.GetString(id), CleanCodeBlock) + "\n\nEnglish:\n" + info.Sanitize(StringMap[id], CleanCodeBlock) + "```", false, nil
+	}
+
+	old, _ := json.Marshal(info.Config.Basic.Strings)
+	CheckMapNilString(&info.Config.Basic.Strings)
+	if reset {
+		delete(info.Config.Basic.Strings, key)
+	} else {
+		text := msg.Content[indices[1]:]
+		if err := checkString(key, text); err != nil {
+			return ReturnError(err)
+		}
+		info.Config.Basic.Strings[key] = text
+	}
+	new, _ := json.Marshal(info.Config.Basic.Strings)
+	if err := info.saveConfigChanges(DiscordUser(msg.Author.ID), []ConfigChange{{Path: "basic.strings", Old: string(old), New: string(new)}}); err != nil {
+		return "```\nError saving config: " + err.Error() + "```", false, nil
+	}
+	if reset {
+		return "```\nReset " + key + " to the default.```", false, nil
+	}
+	return "```\nCustomized " + key + ".```", false, nil
+}
+func (c *stringsCommand) Usage(info *GuildInfo) *CommandUsage {
+	return &CommandUsage{
+		Desc: "Replaces one of the bot's messages with custom text on this server, overriding the language set in `basic.language`. With no arguments, lists all customized messages. With only a message name, shows the current and original text of that message. Custom messages must keep the same formatting placeholders (like %s or %v) as the original.",
+		Params: []CommandUsageParam{
+			{Name: "reset", Desc: "If included, removes the customization and goes back to the default message.", Optional: true},
+			{Name: "message", Desc: "The name of the message to change, such as `pm_failure`.", Optional: true},
+			{Name: "text", Desc: "The new text of the message. Does not need quotes.", Optional: true},
+		},
+	}
+}
+
+var apiTokenRegex = regexp.MustCompile("^[a-zA-Z0-9_-]{1,32}$")
+
+type createAPITokenCommand struct {
+}
+
+func (c *createAPITokenCommand) Info() *CommandInfo {
+	return &CommandInfo{
+		Name:      "CreateAPIToken",
+		Usage:     "Creates an API token and private messages it to you.",
+		Sensitive: true,
+	}
+}
+func (c *createAPITokenCommand) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	if !info.UserIsAdmin(DiscordUser(msg.Author.ID)) {
+		return "```\nOnly administrators can create API tokens.```", false, nil
+	}
+	if !info.Bot.DB.CheckStatus() {
+		return "```\nA temporary database outage is preventing this command from being executed.```", false, nil
+	}
+	if len(args) < 1 {
+		return "```\nYou must give the token a name, so you can revoke it later.```", false, nil
+	}
+	name := strings.ToLower(args[0])
+	if !apiTokenRegex.MatchString(name) {
+		return "```\nToken names can only contain letters, numbers, - and _, and can't be longer than 32 characters.```", false, nil
+	}
+	for _, v := range info.Bot.DB.GetAPITokens(SBatoi(info.ID)) {
+		if v.Name == name {
+			return "```\nThere is already a token called " + name + ". Revoke it first if you want to replace it.```", false, nil
+		}
+	}
+	token, err := info.NewAPIToken(name, DiscordUser(msg.Author.ID))
+	if err != nil {
+		return ReturnError(err)
+	}
+	return "API token " + name + " for " + info.Name + ": `" + token + "`\nSend it in the `Authorization: Bearer` header of requests to `/api/v1/guilds/" + info.ID + "/`. This is the only time the token will be shown, so store it somewhere safe.", true, nil
+}
+func (c *createAPITokenCommand) Usage(info *GuildInfo) *CommandUsage {
+	return &CommandUsage{
+		Desc: "Creates a token that gives read-only access to this server's schedule, tags, quotes, counters and rules through the web API, and sends it to you in a private message. Only administrators can create tokens.",
+		Params: []CommandUsageParam{
+			{Name: "name", Desc: "A name for the token, used to revoke it later.", Optional: false},
+		},
+	}
+}
+
+type revokeAPITokenCommand struct {
+}
+
+func (c *revokeAPITokenCommand) Info() *CommandInfo {
+	return &CommandInfo{
+		Name:      "RevokeAPIToken",
+		Usage:     "Revokes an API token.",
+		Sensitive: true,
+	}
+}
+func (c *revokeAPITokenCommand) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	if !info.UserIsAdmin(DiscordUser(msg.Author.ID)) {
+		return "```\nOnly administrators can revoke API tokens.```", false, nil
+	}
+	if !info.Bot.DB.CheckStatus() {
+		return "```\nA temporary database outage is preventing this command from being executed.```", false, nil
+	}
+	if len(args) < 1 {
+		return "```\nYou must specify which token to revoke.```", false, nil
+	}
+	name := strings.ToLower(args[0])
+	if err := info.Bot.DB.RemoveAPIToken(SBatoi(info.ID), name); err == sql.ErrNoRows {
+		return "```\nThere is no token called " + info.Sanitize(name, CleanCodeBlock) + ".```", false, nil
+	} else if err != nil {
+		return ReturnError(err)
+	}
+	return "```\nRevoked the API token " + name + ".```", false, nil
+}
+func (c *revokeAPITokenCommand) Usage(info *GuildInfo) *CommandUsage {
+	return &CommandUsage{
+		Desc: "Revokes an API token, so it can no longer be used. Only administrators can revoke tokens.",
+		Params: []CommandUsageParam{
+			{Name: "name", Desc: "Name of the token to revoke.", Optional: false},
+		},
+	}
+}
+
+type listAPITokensCommand struct {
+}
+
+func (c *listAPITokensCommand) Info() *CommandInfo {
+	return &CommandInfo{
+		Name:      "ListAPITokens",
+		Usage:     "Lists the API tokens on this server.",
+		Sensitive: true,
+	}
+}
+func (c *listAPITokensCommand) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	if !info.Bot.DB.CheckStatus() {
+		return "```\nA temporary database outage is preventing this command from being executed.```", false, nil
+	}
+	tokens := info.Bot.DB.GetAPITokens(SBatoi(info.ID))
+	if len(tokens) == 0 {
+		return "```\nThis server has no API tokens. Create one with " + info.Config.Basic.CommandPrefix + "apitoken create.```", false, nil
+	}
+	s := make([]string, 0, len(tokens))
+	for _, v := range tokens {
+		s = append(s, v.Name+" (created by "+info.GetUserName(NewDiscordUser(v.Author))+" on "+info.ApplyTimezone(v.Created, DiscordUser(msg.Author.ID)).Format("January 2, 2006")+")")
+	}
+	return "```\n" + info.Sanitize(strings.Join(s, "\n"), CleanCodeBlock) + "```", len(s) > 12, nil
+}
+func (c *listAPITokensCommand) Usage(info *GuildInfo) *CommandUsage {
+	return &CommandUsage{
+		Desc: "Lists the names of all API tokens on this server, who created them, and when.",
+	}
+}
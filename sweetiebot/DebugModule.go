@@ -1,11 +1,7 @@
 package sweetiebot
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
@@ -39,6 +35,7 @@ func (w *DebugModule) Commands() []Command {
 		&updateCommand{},
 		&dumpTablesCommand{},
 		&listGuildsCommand{},
+		&reloadModulesCommand{},
 		&announceCommand{},
 		&removeAliasCommand{},
 		&getAuditCommand{},
@@ -67,7 +64,7 @@ func (w *DebugModule) OnTick(info *GuildInfo, t time.Time) {
 
 	if info.Bot.IsMainGuild(info) && t.Unix()-w.lastclean > CleanInterval {
 		go func() { // Getting the user list takes forever because of discord API limits, so we do this on a new thread
-			fmt.Println("CLEANING GUILDS")
+			info.Bot.Logger.Debug("Cleaning guilds", LogFields{"module": "debug"})
 			w.lastclean = t.Unix()
 
 			lastid := ""
@@ -82,62 +79,39 @@ func (w *DebugModule) OnTick(info *GuildInfo, t time.Time) {
 			}
 			info.LogError("Error cleaning guilds: ", err)
 
-			if err == nil { // ONLY proceed if we have a complete listing of all our guilds with no errors
-				cur, _ := GetCurrentDir()
+			if err == nil && info.Bot.DB.CheckStatus() { // ONLY proceed if we have a complete listing of all our guilds with no errors
 				timeNow := time.Now().UTC().Unix()
-				results, err := ioutil.ReadDir(cur)
-				info.LogError("Error cleaning guilds: ", err)
-
-				for _, f := range results {
-					if !f.IsDir() {
-						matches := guildfileregex.FindStringSubmatch(f.Name())
-						if len(matches) > 1 {
-							id := matches[1]
-							_, ok := guilds[id]
-							config := BotConfig{}
-							cfgfile, err := ioutil.ReadFile(filepath.Join(cur, f.Name()))
-							if err == nil {
-								err = json.Unmarshal(cfgfile, &config)
-							}
-							if err != nil {
-								info.LogError("Error loading config: ", err)
-								continue
-							}
-
-							if ok || config.Expires == 0 {
-								config.Expires = timeNow + ExpireTime
-								info.Bot.GuildsLock.RLock()
-								guild, ok := info.Bot.Guilds[DiscordGuild(id)]
-								info.Bot.GuildsLock.RUnlock()
-
-								if ok {
-									guild.ConfigLock.Lock()
-									guild.Config.Expires = config.Expires
-									guild.ConfigLock.Unlock()
-								}
-
-								if data, err := json.Marshal(&config); err == nil {
-									if err = ioutil.WriteFile(id+".json", data, 0664); err != nil {
-										info.Log("Error saving config file: ", err.Error())
-									}
-								} else {
-									info.Log("Error marshalling config file: ", err.Error())
-								}
-							} else if config.Expires < timeNow {
-								fmt.Println("Server ID " + id + " has expired.")
-								info.Bot.GuildsLock.Lock()
-								delete(info.Bot.Guilds, DiscordGuild(id))
-								info.Bot.GuildsLock.Unlock()
-								if err := os.Remove(id + ".json"); err == nil {
-									if info.Bot.DB.CheckStatus() {
-										err = info.Bot.DB.RemoveGuild(SBatoi(id))
-									}
-								}
-								info.LogError("Error deleting guild: ", err)
-							}
-						}
+				expires := timeNow + ExpireTime
+				for id := range guilds {
+					info.Bot.DB.SetConfigExpiry(SBatoi(id), expires)
+					info.Bot.GuildsLock.RLock()
+					guild, ok := info.Bot.Guilds[DiscordGuild(id)]
+					info.Bot.GuildsLock.RUnlock()
+
+					if ok {
+						guild.ConfigLock.Lock()
+						guild.Config.Expires = expires
+						guild.ConfigLock.Unlock()
 					}
 				}
+				info.Bot.DB.InitConfigExpiry(expires)
+
+				for _, g := range info.Bot.DB.GetExpiredConfigs(timeNow) {
+					id := SBitoa(g)
+					if _, ok := guilds[id]; ok {
+						continue
+					}
+					info.Bot.Logger.Info("Server ID "+id+" has expired.", LogFields{"module": "debug", "guild": id})
+					info.Bot.GuildsLock.Lock()
+					delete(info.Bot.Guilds, DiscordGuild(id))
+					info.Bot.GuildsLock.Unlock()
+					err := info.Bot.DB.RemoveConfig(g)
+					if err == nil {
+						err = info.Bot.DB.RemoveGuild(g)
+					}
+					info.LogError("Error deleting guild: ", err)
+				}
+				info.Bot.DB.RemoveExpiredModuleData(timeNow)
 			}
 		}()
 	}
@@ -151,6 +125,7 @@ func (c *echoCommand) Info() *CommandInfo {
 		Name:      "Echo",
 		Usage:     "Says something in the given channel.",
 		Sensitive: true,
+		RawText:   true,
 	}
 }
 func (c *echoCommand) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
@@ -191,6 +166,7 @@ func (c *echoEmbedCommand) Info() *CommandInfo {
 		Name:      "EchoEmbed",
 		Usage:     "Makes the bot echo a rich text embed in a given channel.",
 		Sensitive: true,
+		RawText:   true,
 	}
 }
 func (c *echoEmbedCommand) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
@@ -261,9 +237,9 @@ func setCommandEnable(args []string, enable bool, success string, info *GuildInf
 		return "```\nNo module or command specified.Use " + info.Config.Basic.CommandPrefix + "help with no arguments to list all modules and commands.```", false, nil
 	}
 	name := strings.ToLower(args[0])
-	for _, v := range info.Modules {
+	for _, v := range info.GetModules() {
 		if strings.ToLower(v.Name()) == name {
-			cmds := v.Commands()
+			cmds := ExpandCommands(v.Commands())
 			for _, v := range cmds {
 				str := strings.ToLower(v.Info().Name)
 				if enable {
@@ -286,17 +262,16 @@ func setCommandEnable(args []string, enable bool, success string, info *GuildInf
 			return "", false, DumpCommandsModules(info, "", "**Success!** "+args[0]+success, msg)
 		}
 	}
-	for k := range info.commands {
-		if string(k) == name {
-			if enable {
-				delete(info.Config.Modules.CommandDisabled, k)
-			} else {
-				CheckMapNilBool(&info.Config.Modules.CommandDisabled)
-				info.Config.Modules.CommandDisabled[k] = true
-			}
-			info.SaveConfig()
-			return "", false, DumpCommandsModules(info, "", "**Success!** "+args[0]+success, msg)
+	k := CommandID(name)
+	if _, ok := info.GetCommand(k); ok {
+		if enable {
+			delete(info.Config.Modules.CommandDisabled, k)
+		} else {
+			CheckMapNilBool(&info.Config.Modules.CommandDisabled)
+			info.Config.Modules.CommandDisabled[k] = true
 		}
+		info.SaveConfig()
+		return "", false, DumpCommandsModules(info, "", "**Success!** "+args[0]+success, msg)
 	}
 	return "```\nThe " + args[0] + " module/command does not exist. Use " + info.Config.Basic.CommandPrefix + "help with no arguments to list all modules and commands.```", false, nil
 }
@@ -466,9 +441,14 @@ func (c *listGuildsCommand) Process(args []string, msg *discordgo.Message, indic
 	if !info.Bot.Owner.Equals(msg.Author.ID) {
 		return "```\nOnly the owner of the bot itself can call this!```", false, nil
 	}
-	info.Bot.DG.State.RLock()
-	guilds := append([]*discordgo.Guild{}, info.Bot.DG.State.Guilds...)
-	info.Bot.DG.State.RUnlock()
+	info.Bot.GuildsLock.RLock()
+	guilds := make([]*discordgo.Guild, 0, len(info.Bot.Guilds))
+	for k := range info.Bot.Guilds { // The state's guild list only has the guilds from whichever shard connected last
+		if g, err := info.Bot.DG.State.Guild(string(k)); err == nil {
+			guilds = append(guilds, g)
+		}
+	}
+	info.Bot.GuildsLock.RUnlock()
 	sort.Sort(guildSlice(guilds))
 	s := make([]string, 0, len(guilds))
 	private := 0
@@ -483,7 +463,11 @@ func (c *listGuildsCommand) Process(args []string, msg *discordgo.Message, indic
 			count = len(v.Members)
 		}
 		if count > 200 {
-			s = append(s, info.Sanitize(fmt.Sprintf("%v (%v) - %v", v.Name, count, username), CleanCodeBlock))
+			line := fmt.Sprintf("%v (%v) - %v", v.Name, count, username)
+			if shard := info.Bot.ShardOf(DiscordGuild(v.ID)); shard != nil && shard.ShardCount > 1 {
+				line += fmt.Sprintf(" [shard %v]", shard.ShardID)
+			}
+			s = append(s, info.Sanitize(line, CleanCodeBlock))
 		} else {
 			private++
 		}
@@ -494,6 +478,45 @@ func (c *listGuildsCommand) Usage(info *GuildInfo) *CommandUsage {
 	return &CommandUsage{Desc: "Lists the servers the bot is on."}
 }
 
+type reloadModulesCommand struct {
+}
+
+func (c *reloadModulesCommand) Info() *CommandInfo {
+	return &CommandInfo{
+		Name:              "ReloadModules",
+		Usage:             "Reloads a server's modules.",
+		Restricted:        true,
+		Sensitive:         true,
+		ServerIndependent: true,
+	}
+}
+func (c *reloadModulesCommand) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	if !info.Bot.Owner.Equals(msg.Author.ID) {
+		return "```\nOnly the owner of the bot itself can call this!```", false, nil
+	}
+	guild := info
+	if len(args) > 0 {
+		info.Bot.GuildsLock.RLock()
+		guild = info.Bot.Guilds[DiscordGuild(args[0])]
+		info.Bot.GuildsLock.RUnlock()
+		if guild == nil {
+			return "```\nThe bot isn't on a server with that ID.```", false, nil
+		}
+	} else if info == info.Bot.EmptyGuild {
+		return "```\nYou have to give a server ID when using this in a private message.```", false, nil
+	}
+	modules := guild.ReloadModules()
+	return "```\nReloaded " + strconv.Itoa(len(modules)) + " modules on " + info.Sanitize(guild.Name, CleanCodeBlock) + ".```", false, nil
+}
+func (c *reloadModulesCommand) Usage(info *GuildInfo) *CommandUsage {
+	return &CommandUsage{
+		Desc: "Replaces every module on a server with a new instance and rebuilds its commands, without interrupting anything else the bot is doing. Module state, like spam pressure and silences, is carried over to the new modules. Can only be used by the owner of the bot.",
+		Params: []CommandUsageParam{
+			{Name: "server", Desc: "The ID of the server to reload. Defaults to the current server.", Optional: true},
+		},
+	}
+}
+
 type announceCommand struct {
 }
 
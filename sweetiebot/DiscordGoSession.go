@@ -292,7 +292,7 @@ func ParseUser(s string, info *GuildInfo) (DiscordUser, error) {
 
 // DiscordGoSession overrides the discordgo session, allowing us to extend it and also lets us mock the base class methods for testing
 type DiscordGoSession struct {
-	discordgo.Session
+	*discordgo.Session
 }
 
 // RemoveRole removes a role from the state and then sends a request to discord to remove it
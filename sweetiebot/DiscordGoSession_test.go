@@ -11,10 +11,16 @@ import (
 )
 
 func (s *DiscordGoSession) AddHandler(handler interface{}) func() {
+	if mock == nil {
+		return s.Session.AddHandler(handler)
+	}
 	mock.Input(interface{}(s.AddHandler), handler)
 	return func() {}
 }
 func (s *DiscordGoSession) Channel(channelID string) (st *discordgo.Channel, err error) {
+	if mock == nil {
+		return s.Session.Channel(channelID)
+	}
 	if channelID == strconv.Itoa(TestChannelPrivate) {
 		return &discordgo.Channel{
 			ID:      strconv.Itoa(TestChannelPrivate),
@@ -39,78 +45,132 @@ func (s *DiscordGoSession) Channel(channelID string) (st *discordgo.Channel, err
 	return s.State.Channel(channelID)
 }
 func (s *DiscordGoSession) ChannelMessages(channelID string, limit int, beforeID, afterID, aroundID string) (st []*discordgo.Message, err error) {
+	if mock == nil {
+		return s.Session.ChannelMessages(channelID, limit, beforeID, afterID, aroundID)
+	}
 	mock.Input(interface{}(s.ChannelMessages), channelID, limit, beforeID, afterID, aroundID)
 	return
 }
 func (s *DiscordGoSession) ChannelMessage(channelID, messageID string) (st *discordgo.Message, err error) {
+	if mock == nil {
+		return s.Session.ChannelMessage(channelID, messageID)
+	}
 	mock.Input(interface{}(s.ChannelMessage), channelID, messageID)
 	return
 }
 func (s *DiscordGoSession) ChannelMessageSend(channelID string, content string) (st *discordgo.Message, err error) {
+	if mock == nil {
+		return s.Session.ChannelMessageSend(channelID, content)
+	}
 	mock.Input(interface{}(s.ChannelMessageSend), channelID, content)
 	return
 }
 func (s *DiscordGoSession) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend) (st *discordgo.Message, err error) {
+	if mock == nil {
+		return s.Session.ChannelMessageSendComplex(channelID, data)
+	}
 	mock.Input(interface{}(s.ChannelMessageSendComplex), channelID, data)
 	return
 }
 func (s *DiscordGoSession) ChannelMessagesBulkDelete(channelID string, messages []string) (err error) {
+	if mock == nil {
+		return s.Session.ChannelMessagesBulkDelete(channelID, messages)
+	}
 	mock.Input(interface{}(s.ChannelMessagesBulkDelete), channelID, messages)
 	return nil
 }
 
 func (s *DiscordGoSession) ChannelMessageDelete(channelID, messageID string) (err error) {
+	if mock == nil {
+		return s.Session.ChannelMessageDelete(channelID, messageID)
+	}
 	mock.Input(interface{}(s.ChannelMessageDelete), channelID, messageID)
 	return nil
 }
 func (s *DiscordGoSession) ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed) (m *discordgo.Message, err error) {
+	if mock == nil {
+		return s.Session.ChannelMessageSendEmbed(channelID, embed)
+	}
 	mock.Input(interface{}(s.ChannelMessageSendEmbed), channelID, embed)
 	return
 }
 func (s *DiscordGoSession) ChannelPermissionSet(channelID, targetID string, targetType discordgo.PermissionOverwriteType, allow, deny int64) (err error) {
+	if mock == nil {
+		return s.Session.ChannelPermissionSet(channelID, targetID, targetType, allow, deny)
+	}
 	mock.Input(interface{}(s.ChannelPermissionSet), channelID, targetID, targetType, allow, deny)
 	return
 }
 func (s *DiscordGoSession) Guild(guildID string) (st *discordgo.Guild, err error) {
+	if mock == nil {
+		return s.Session.Guild(guildID)
+	}
 	mock.Input(interface{}(s.Guild), guildID)
 	return s.State.Guild(guildID)
 }
-func (s *DiscordGoSession) GuildEdit(guildID string, g discordgo.GuildParams) (st *discordgo.Guild, err error) {
+func (s *DiscordGoSession) GuildEdit(guildID string, g *discordgo.GuildParams) (st *discordgo.Guild, err error) {
+	if mock == nil {
+		return s.Session.GuildEdit(guildID, g)
+	}
 	mock.Input(interface{}(s.GuildEdit), guildID, g)
 	return
 }
 
 func (s *DiscordGoSession) GuildBanCreate(guildID, userID string, days int) (err error) {
+	if mock == nil {
+		return s.Session.GuildBanCreate(guildID, userID, days)
+	}
 	mock.Input(interface{}(s.GuildBanCreate), guildID, userID, days)
 	return nil
 }
 func (s *DiscordGoSession) GuildBanCreateWithReason(guildID, userID, reason string, days int) (err error) {
+	if mock == nil {
+		return s.Session.GuildBanCreateWithReason(guildID, userID, reason, days)
+	}
 	mock.Input(interface{}(s.GuildBanCreateWithReason), guildID, userID, reason, days)
 	return nil
 }
 
 func (s *DiscordGoSession) GuildBanDelete(guildID, userID string) (err error) {
+	if mock == nil {
+		return s.Session.GuildBanDelete(guildID, userID)
+	}
 	mock.Input(interface{}(s.GuildBanDelete), guildID, userID)
 	return nil
 }
 func (s *DiscordGoSession) GuildMembers(guildID string, after string, limit int) (st []*discordgo.Member, err error) {
+	if mock == nil {
+		return s.Session.GuildMembers(guildID, after, limit)
+	}
 	mock.Input(interface{}(s.GuildMembers), guildID, after, limit)
 	return
 }
 
 func (s *DiscordGoSession) GuildMember(guildID, userID string) (st *discordgo.Member, err error) {
+	if mock == nil {
+		return s.Session.GuildMember(guildID, userID)
+	}
 	mock.Input(interface{}(s.GuildMember), guildID, userID)
 	return s.State.Member(guildID, userID)
 }
 func (s *DiscordGoSession) GuildMemberRoleAdd(guildID, userID, roleID string) (err error) {
+	if mock == nil {
+		return s.Session.GuildMemberRoleAdd(guildID, userID, roleID)
+	}
 	mock.Input(interface{}(s.GuildMemberRoleAdd), guildID, userID, roleID)
 	return
 }
 func (s *DiscordGoSession) GuildMemberRoleRemove(guildID, userID, roleID string) (err error) {
+	if mock == nil {
+		return s.Session.GuildMemberRoleRemove(guildID, userID, roleID)
+	}
 	mock.Input(interface{}(s.GuildMemberRoleRemove), guildID, userID, roleID)
 	return
 }
 func (s *DiscordGoSession) GuildChannels(guildID string) (st []*discordgo.Channel, err error) {
+	if mock == nil {
+		return s.Session.GuildChannels(guildID)
+	}
 	mock.Input(interface{}(s.GuildChannels), guildID)
 	var g *discordgo.Guild
 	g, err = s.State.Guild(guildID)
@@ -120,6 +180,9 @@ func (s *DiscordGoSession) GuildChannels(guildID string) (st []*discordgo.Channe
 	return
 }
 func (s *DiscordGoSession) GuildRoles(guildID string) (st []*discordgo.Role, err error) {
+	if mock == nil {
+		return s.Session.GuildRoles(guildID)
+	}
 	mock.Input(interface{}(s.GuildRoles), guildID)
 	var g *discordgo.Guild
 	g, err = s.State.Guild(guildID)
@@ -128,8 +191,11 @@ func (s *DiscordGoSession) GuildRoles(guildID string) (st []*discordgo.Role, err
 	}
 	return
 }
-func (s *DiscordGoSession) GuildRoleCreate(guildID string) (st *discordgo.Role, err error) {
-	mock.Input(interface{}(s.GuildRoleCreate), guildID)
+func (s *DiscordGoSession) GuildRoleCreate(guildID string, data *discordgo.RoleParams) (st *discordgo.Role, err error) {
+	if mock == nil {
+		return s.Session.GuildRoleCreate(guildID, data)
+	}
+	mock.Input(interface{}(s.GuildRoleCreate), guildID, data)
 	return
 }
 func (s *DiscordGoSession) GuildRoleEdit(guildID, roleID, name string, color int, hoist bool, perm int64, mention bool) (st *discordgo.Role, err error) {
@@ -137,11 +203,17 @@ func (s *DiscordGoSession) GuildRoleEdit(guildID, roleID, name string, color int
 	return
 }
 func (s *DiscordGoSession) GuildRoleDelete(guildID, roleID string) (err error) {
+	if mock == nil {
+		return s.Session.GuildRoleDelete(guildID, roleID)
+	}
 	mock.Input(interface{}(s.GuildRoleDelete), guildID, roleID)
 	return
 }
 
 func (s *DiscordGoSession) User(userID string) (st *discordgo.User, err error) {
+	if mock == nil {
+		return s.Session.User(userID)
+	}
 	mock.Input(interface{}(s.User), userID)
 	for _, g := range s.State.Guilds {
 		for _, m := range g.Members {
@@ -153,10 +225,16 @@ func (s *DiscordGoSession) User(userID string) (st *discordgo.User, err error) {
 	return nil, discordgo.ErrStateNotFound
 }
 func (s *DiscordGoSession) UserUpdate(username, avatar string) (st *discordgo.User, err error) {
+	if mock == nil {
+		return s.Session.UserUpdate(username, avatar)
+	}
 	mock.Input(interface{}(s.UserUpdate), username, avatar)
 	return
 }
 func (s *DiscordGoSession) UserChannelCreate(recipientID string) (st *discordgo.Channel, err error) {
+	if mock == nil {
+		return s.Session.UserChannelCreate(recipientID)
+	}
 	mock.Input(interface{}(s.UserChannelCreate), recipientID)
 	return &discordgo.Channel{
 		ID:   recipientID + "10",
@@ -168,14 +246,23 @@ func (s *DiscordGoSession) UpdateStatus(idle int, game string) (err error) {
 	return nil
 }
 func (s *DiscordGoSession) Open() (err error) {
+	if mock == nil {
+		return s.Session.Open()
+	}
 	mock.Input(interface{}(s.Open))
 	return nil
 }
 func (s *DiscordGoSession) Close() (err error) {
+	if mock == nil {
+		return s.Session.Close()
+	}
 	mock.Input(interface{}(s.Close))
 	return nil
 }
 func (s *DiscordGoSession) RequestWithLockedBucket(method, urlStr, contentType string, b []byte, bucket *discordgo.Bucket, sequence int) (response []byte, err error) {
+	if mock == nil {
+		return s.Session.RequestWithLockedBucket(method, urlStr, contentType, b, bucket, sequence)
+	}
 	defer bucket.Unlock()
 	mock.Input(interface{}(s.RequestWithLockedBucket), method, urlStr, contentType, b, bucket, sequence)
 	return
@@ -279,15 +366,15 @@ func TestUserPermissions(t *testing.T) {
 		_, err := v.Bot.DG.UserPermissions("0", v.ID)
 		Check(err, discordgo.ErrStateNotFound, t)
 		p, _ := v.Bot.DG.UserPermissions(NewDiscordUser(TestSelfID), v.ID)
-		Check(p, 0, t)
+		Check(p, int64(0), t)
 		p, _ = v.Bot.DG.UserPermissions(NewDiscordUser(TestOwnerBot), v.ID)
-		Check(p, 0, t)
+		Check(p, int64(0), t)
 		p, _ = v.Bot.DG.UserPermissions(NewDiscordUser(TestOwnerServer|i), v.ID)
-		Check(p, discordgo.PermissionAll, t)
+		Check(p, int64(discordgo.PermissionAll), t)
 		p, _ = v.Bot.DG.UserPermissions(NewDiscordUser(TestAdminMod|i), v.ID)
-		Check(p, discordgo.PermissionAllChannel|discordgo.PermissionAdministrator, t)
+		Check(p, int64(discordgo.PermissionAllChannel|discordgo.PermissionAdministrator), t)
 		p, _ = v.Bot.DG.UserPermissions(NewDiscordUser(TestAdmin|i), v.ID)
-		Check(p, discordgo.PermissionAllChannel|discordgo.PermissionAdministrator, t)
+		Check(p, int64(discordgo.PermissionAllChannel|discordgo.PermissionAdministrator), t)
 		p, _ = v.Bot.DG.UserPermissions(NewDiscordUser(TestMod|i), v.ID)
 		Check(p, mockDiscordRole(TestRoleMod, int(i)).Permissions, t)
 		p, _ = v.Bot.DG.UserPermissions(NewDiscordUser(TestUserAssigned|i), v.ID)
@@ -297,11 +384,11 @@ func TestUserPermissions(t *testing.T) {
 		p, _ = v.Bot.DG.UserPermissions(NewDiscordUser(TestUserBoring|i), v.ID)
 		Check(p, mockDiscordRole(TestRoleMember, int(i)).Permissions, t)
 		p, _ = v.Bot.DG.UserPermissions(NewDiscordUser(TestUserSilence|i), v.ID)
-		Check(p, 0, t)
+		Check(p, int64(0), t)
 		p, _ = v.Bot.DG.UserPermissions(NewDiscordUser(TestUserNew|i), v.ID)
-		Check(p, 0, t)
+		Check(p, int64(0), t)
 		p, _ = v.Bot.DG.UserPermissions(NewDiscordUser(TestUserBot|i), v.ID)
-		Check(p, 0, t)
+		Check(p, int64(0), t)
 	}
 }
 
@@ -557,20 +644,20 @@ func TestParseUser(t *testing.T) {
 		for _, v := range nilresults {
 			if len(v) > 0 && v[0] != '<' {
 				v2 := "%" + v + "%"
-				dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM members.*").WithArgs(k.Convert(), v, v, 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}))
-				dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM members.*").WithArgs(k.Convert(), v2, v2, 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}))
+				dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM `members`.*").WithArgs(k.Convert(), v, v, 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}))
+				dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM `members`.*").WithArgs(k.Convert(), v2, v2, 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}))
 				if v[0] == '@' {
 					v2 := "%" + v[1:] + "%"
-					dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM members.*").WithArgs(k.Convert(), v[1:], v[1:], 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}))
-					dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM members.*").WithArgs(k.Convert(), v2, v2, 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}))
+					dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM `members`.*").WithArgs(k.Convert(), v[1:], v[1:], 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}))
+					dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM `members`.*").WithArgs(k.Convert(), v2, v2, 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}))
 				}
 			}
 			u, err := ParseUser(v, g)
 			Check(u, UserEmpty, t)
 			CheckNot(err, nil, t)
 		}
-		dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM members.*").WithArgs(k.Convert(), "0", "0", 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}))
-		dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM members.*").WithArgs(k.Convert(), "%0%", "%0%", 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}))
+		dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM `members`.*").WithArgs(k.Convert(), "0", "0", 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}))
+		dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM `members`.*").WithArgs(k.Convert(), "%0%", "%0%", 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}))
 		u, err = ParseUser("0", g)
 		Check(u, UserEmpty, t)
 		CheckNot(err, nil, t)
@@ -580,17 +667,17 @@ func TestParseUser(t *testing.T) {
 		u, err = ParseUser(fmt.Sprintf("<@%v>", TestUserAssigned|i), g)
 		Check(u, DiscordUser(fmt.Sprintf("%v", TestUserAssigned|i)), t)
 		Check(err, nil, t)
-		dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM members.*").WithArgs(k.Convert(), "boring user", "boring user", 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(TestUserBoring | i))
+		dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM `members`.*").WithArgs(k.Convert(), "boring user", "boring user", 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(TestUserBoring | i))
 		u, err = ParseUser("Boring User", g)
 		Check(u, DiscordUser(fmt.Sprintf("%v", TestUserBoring|i)), t)
 		Check(err, nil, t)
-		dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM members.*").WithArgs(k.Convert(), "@boring user", "@boring user", 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}))
-		dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM members.*").WithArgs(k.Convert(), "%@boring user%", "%@boring user%", 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}))
-		dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM members.*").WithArgs(k.Convert(), "boring user", "boring user", 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(TestUserBoring | i))
+		dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM `members`.*").WithArgs(k.Convert(), "@boring user", "@boring user", 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}))
+		dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM `members`.*").WithArgs(k.Convert(), "%@boring user%", "%@boring user%", 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}))
+		dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM `members`.*").WithArgs(k.Convert(), "boring user", "boring user", 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(TestUserBoring | i))
 		u, err = ParseUser("@Boring User", g)
 		Check(u, DiscordUser(fmt.Sprintf("%v", TestUserBoring|i)), t)
 		Check(err, nil, t)
-		dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM members.*").WithArgs(k.Convert(), "user", "user", 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(TestUserBoring | i).AddRow(TestUserBot | i).AddRow(TestUserAssigned | i))
+		dbmock.ExpectQuery("SELECT DISTINCT M.ID FROM `members`.*").WithArgs(k.Convert(), "user", "user", 20, 0).WillReturnRows(sqlmock.NewRows([]string{"ID"}).AddRow(TestUserBoring | i).AddRow(TestUserBot | i).AddRow(TestUserAssigned | i))
 		u, err = ParseUser("User", g)
 		Check(u, UserEmpty, t)
 		CheckNot(err, nil, t)
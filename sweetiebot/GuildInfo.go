@@ -1,6 +1,7 @@
 package sweetiebot
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,7 +10,6 @@ import (
 	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"4d63.com/tz"
@@ -18,22 +18,31 @@ import (
 
 // GuildInfo Stores state information about a guild
 type GuildInfo struct {
-	ID           string // Cache the ID because it doesn't change
-	Name         string // Cache the name to reduce locking
-	OwnerID      DiscordUser
-	BotNick      string // If not empty, the nickname assigned to the bot in this server
-	lastlogerr   int64
-	LastRaid     int64 // Last time a raid was recorded by the spam module (or any other module that records raids)
-	commandLock  sync.RWMutex
-	commandLast  map[string]int64
-	commandlimit *SaturationLimit
-	ConfigLock   sync.RWMutex
-	Config       BotConfig
-	hooks        moduleHooks
-	Modules      []Module
-	commands     map[CommandID]Command
-	commandmap   map[CommandID]ModuleID // Exists entirely so the help command can match commands to their parent module
-	Bot          *SweetieBot
+	ID               string // Cache the ID because it doesn't change
+	Name             string // Cache the name to reduce locking
+	OwnerID          DiscordUser
+	BotNick          string // If not empty, the nickname assigned to the bot in this server
+	lastlogerr       int64
+	LastRaid         int64 // Last time a raid was recorded by the spam module (or any other module that records raids)
+	commandLock      sync.RWMutex
+	moduleLock       sync.RWMutex // Protects Modules and hooks, which are replaced when the modules are reloaded
+	moduleReload     sync.Mutex   // Serializes module reloads
+	commandLast      map[string]int64
+	commandlimit     *SaturationLimit
+	userlimit        *UserLimiter
+	logger           *Logger
+	ConfigLock       sync.RWMutex
+	Config           BotConfig
+	configSave       sync.Mutex // Serializes database saves so they don't conflict with each other
+	configVer        uint64     // Version of the stored config this guild last loaded or saved, 0 if it has never been stored
+	hooks            moduleHooks
+	middleware       []CommandMiddleware
+	moduleMiddleware map[ModuleID][]CommandMiddleware
+	Modules          []Module
+	commands         map[CommandID]Command
+	commandmap       map[CommandID]ModuleID // Exists entirely so the help command can match commands to their parent module
+	Bus              *EventBus              // Lets modules publish events to each other without importing each other
+	Bot              *SweetieBot
 }
 
 var errOwnerExclusive = errors.New("Only the owner of the bot can run this command!")
@@ -44,39 +53,111 @@ var errDisabled = errors.New("this command is disabled")
 var errSilenced = errors.New("silenced users cannot use commands")
 var errInvalidChannel = errors.New("Attempted to send message to channel on a different server.")
 var errConfigFileTooLarge = errors.New("Error saving config file: Config file is too large!")
+var errConfigDatabaseDown = errors.New("Error saving config file: The database is unavailable!")
 
 // NewGuildInfo spawns a new GuildInfo object with a default configuration
 func NewGuildInfo(sb *SweetieBot, g *discordgo.Guild) *GuildInfo {
-	return &GuildInfo{
+	info := &GuildInfo{
 		ID:           g.ID,
 		Name:         g.Name,
 		OwnerID:      DiscordUser(g.OwnerID),
 		commandLast:  make(map[string]int64),
 		commandlimit: &SaturationLimit{[]int64{}, 0, AtomicFlag{0}},
+		userlimit:    NewUserLimiter(),
 		commands:     make(map[CommandID]Command),
 		commandmap:   make(map[CommandID]ModuleID),
 		lastlogerr:   0,
 		Bot:          sb,
 		Config:       *DefaultConfig(),
 	}
+	info.logger = sb.Logger.With(LogFields{"guild": g.ID}).WithChannel(info.postLog)
+	info.Bus = NewEventBus(info)
+	return info
 }
 
 // AddCommand adds a command to the guild
 func (info *GuildInfo) AddCommand(c Command, m Module) {
-	name := CommandID(strings.ToLower(c.Info().Name))
-	info.commands[name] = c
-	info.commandmap[name] = ModuleID(strings.ToLower(m.Name()))
+	info.commandLock.Lock()
+	defer info.commandLock.Unlock()
+	addCommand(info.commands, info.commandmap, c, m)
+}
+
+func addCommand(commands map[CommandID]Command, commandmap map[CommandID]ModuleID, c Command, m Module) {
+	for _, v := range ExpandCommands([]Command{c}) { // Subcommands of a command group are registered as group.subcommand
+		name := CommandID(strings.ToLower(v.Info().Name))
+		commands[name] = v
+		commandmap[name] = ModuleID(strings.ToLower(m.Name()))
+	}
+}
+
+// RemoveCommand removes a command that was added with AddCommand, along with any subcommands it has
+func (info *GuildInfo) RemoveCommand(c Command) {
+	info.commandLock.Lock()
+	defer info.commandLock.Unlock()
+	for _, v := range ExpandCommands([]Command{c}) {
+		name := CommandID(strings.ToLower(v.Info().Name))
+		if info.commands[name] == v {
+			delete(info.commands, name)
+			delete(info.commandmap, name)
+		}
+	}
+}
+
+// GetCommand returns the command with the given name, if it exists
+func (info *GuildInfo) GetCommand(name CommandID) (Command, bool) {
+	info.commandLock.RLock()
+	defer info.commandLock.RUnlock()
+	c, ok := info.commands[name]
+	return c, ok
+}
+
+// isRawTextCommand returns true if the command or alias with this name takes raw text as its arguments
+func (info *GuildInfo) isRawTextCommand(name string) bool {
+	name = strings.ToLower(name)
+	c, ok := info.GetCommand(CommandID(name))
+	if !ok {
+		if alias, aliasok := info.Config.Basic.Aliases[name]; aliasok {
+			if args, _ := ParseArguments(alias); len(args) > 0 {
+				c, ok = info.GetCommand(CommandID(strings.ToLower(args[0])))
+			}
+		}
+	}
+	return ok && c.Info().RawText
 }
 
-// SaveConfig saves the config file to disk
+// GetCommands returns a copy of every command in the guild, which can be safely iterated over while modules reload
+func (info *GuildInfo) GetCommands() map[CommandID]Command {
+	info.commandLock.RLock()
+	defer info.commandLock.RUnlock()
+	commands := make(map[CommandID]Command, len(info.commands))
+	for k, v := range info.commands {
+		commands[k] = v
+	}
+	return commands
+}
+
+// SaveConfig saves the config to the database. Changes are refused while the database is unavailable, because a stored
+// config always takes precedence over a <guild>.json file once the database is back.
 func (info *GuildInfo) SaveConfig() (err error) {
 	data, err := json.Marshal(info.Config)
 	if err == nil {
 		if len(data) > info.Bot.MaxConfigSize {
 			info.Log("Error saving config file: Config file is too large! Config files cannot exceed " + strconv.Itoa(info.Bot.MaxConfigSize) + " bytes.")
 			err = errConfigFileTooLarge
+		} else if !info.Bot.DB.CheckStatus() { // A file written now would be ignored once the database is back, so refuse the change instead
+			info.Log("Error saving config file: The database is unavailable, so the change wasn't saved. Please try again later.")
+			err = errConfigDatabaseDown
 		} else {
-			if err = ioutil.WriteFile(info.ID+".json", data, 0664); err != nil {
+			info.configSave.Lock()
+			var ver uint64
+			if ver, err = info.Bot.DB.SaveConfig(SBatoi(info.ID), data, info.Config.Expires, info.configVer); err == nil {
+				info.configVer = ver
+			}
+			info.configSave.Unlock()
+			if err == ErrConfigConflict {
+				info.Log("Error saving config file: The configuration was changed by another process, so it will be reloaded. Please try your change again.")
+				info.LogError("Error reloading config: ", info.ReloadConfig())
+			} else if err != nil {
 				info.Log("Error saving config file: ", err.Error())
 			}
 		}
@@ -86,10 +167,84 @@ func (info *GuildInfo) SaveConfig() (err error) {
 	return
 }
 
+// LoadConfig returns the stored config for this guild, or the contents of <guild>.json if the database is unavailable.
+// If the guild has no config, returns sql.ErrNoRows or a file not found error.
+func (info *GuildInfo) LoadConfig() ([]byte, error) {
+	if !info.Bot.DB.CheckStatus() {
+		return ioutil.ReadFile(info.ID + ".json")
+	}
+	data, version, err := info.Bot.DB.GetConfig(SBatoi(info.ID))
+	if err == nil {
+		info.configSave.Lock()
+		info.configVer = version
+		info.configSave.Unlock()
+	}
+	return data, err
+}
+
+// SaveModuleState stores the state of every module that implements ModuleState. Module state isn't critical, so
+// nothing is saved if the database is unavailable.
+func (info *GuildInfo) SaveModuleState() {
+	if !info.Bot.DB.CheckStatus() {
+		return
+	}
+	hooks := info.getHooks()
+	defer hooks.done()
+	for _, h := range hooks.State {
+		data, err := h.SaveState(info)
+		if err == nil {
+			err = info.Bot.DB.SetModuleState(SBatoi(info.ID), strings.ToLower(h.Name()), data)
+		}
+		if err != nil {
+			info.Bot.Logger.Error("Error saving "+h.Name()+" state: "+err.Error(), LogFields{"guild": info.ID})
+		}
+	}
+}
+
+// LoadModuleState restores the last saved state of every module that implements ModuleState
+func (info *GuildInfo) LoadModuleState() {
+	if !info.Bot.DB.CheckStatus() {
+		return
+	}
+	hooks := info.getHooks()
+	defer hooks.done()
+	for _, h := range hooks.State {
+		data, err := info.Bot.DB.GetModuleState(SBatoi(info.ID), strings.ToLower(h.Name()))
+		if err == nil {
+			err = h.LoadState(info, data)
+		}
+		if err != nil && err != sql.ErrNoRows {
+			info.Bot.Logger.Error("Error loading "+h.Name()+" state: "+err.Error(), LogFields{"guild": info.ID})
+		}
+	}
+}
+
+// ReloadConfig replaces the current config with the stored one
+func (info *GuildInfo) ReloadConfig() error {
+	data, err := info.LoadConfig()
+	if err != nil {
+		return err
+	}
+	info.ConfigLock.Lock()
+	info.Config = *DefaultConfig()
+	err = info.migrateConfig(data)
+	migrated := err == nil && info.Config.Version != ConfigVersion
+	if migrated {
+		info.Config.Version = ConfigVersion
+	}
+	info.Config.FillConfig()
+	info.ConfigLock.Unlock()
+	// The migrated config is saved after unlocking, because a save conflict reloads the config again
+	if migrated {
+		info.SaveConfig()
+	}
+	return err
+}
+
 // SendEmbed sends an embed message to the channel, splitting it into multiple messages if necessary
 func (info *GuildInfo) SendEmbed(channelID DiscordChannel, embed *discordgo.MessageEmbed) error {
 	if channelID == "heartbeat" {
-		atomic.AddUint32(&info.Bot.heartbeat, 1)
+		info.Bot.beat()
 		return nil
 	}
 	if ch, private := info.Bot.ChannelIsPrivate(channelID); !private && (ch == nil || ch.GuildID != info.ID) {
@@ -107,9 +262,13 @@ func (info *GuildInfo) SendEmbed(channelID DiscordChannel, embed *discordgo.Mess
 		if _, err := info.Bot.DG.ChannelMessageSendEmbed(channelID.String(), embed); err != nil {
 			return err
 		}
+		metricMessagesSent.WithLabelValues(info.ID, "embed").Inc()
 	}
 	embed.Fields = fields
 	_, err := info.Bot.DG.ChannelMessageSendEmbed(channelID.String(), embed)
+	if err == nil {
+		metricMessagesSent.WithLabelValues(info.ID, "embed").Inc()
+	}
 	return err
 	//info.Bot.DG.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
 	//	Content: "Test content",
@@ -180,40 +339,54 @@ func (info *GuildInfo) sendContent(channelID DiscordChannel, message string, min
 		Content: message,
 	}, minRequest)
 	if err != nil {
-		fmt.Println("Failed to send message: ", err.Error())
+		info.Bot.Logger.Error("Failed to send message: "+err.Error(), LogFields{"guild": info.ID, "channel": channelID}) // Never log this to log.channel, because sending that could fail too
+	} else {
+		metricMessagesSent.WithLabelValues(info.ID, "message").Inc()
 	}
 }
 
 // SendMessage sends a message to the given channel, splitting it into multiple messages if necessary, and combining smaller messages if a rate limit is about to be hit
 func (info *GuildInfo) SendMessage(channelID DiscordChannel, message string) error {
 	if channelID == "heartbeat" {
-		atomic.AddUint32(&info.Bot.heartbeat, 1)
+		info.Bot.beat()
 		return nil
 	}
 	if ch, private := info.Bot.ChannelIsPrivate(channelID); !private && (ch == nil || ch.GuildID != info.ID || ch.Type == discordgo.ChannelTypeGuildVoice || ch.Type == discordgo.ChannelTypeGuildCategory) {
 		return errInvalidChannel
 	}
 
+	chunks := SplitMessage(message)
+	for i, chunk := range chunks {
+		if i+1 < len(chunks) {
+			info.sendContent(channelID, chunk, 1)
+		} else {
+			info.sendContent(channelID, chunk, 2)
+		}
+	}
+
+	return nil
+}
+
+// SplitMessage splits a message into chunks that fit inside discord's 2000 character limit, preserving code blocks
+func SplitMessage(message string) (chunks []string) {
 	for len(message) > 1999 { // discord has a 2000 character limit
 		if message[0:3] == "```" && message[len(message)-3:] == "```" {
 			index := strings.LastIndex(message[:1995], "\n")
 			if index < 10 { // Ensure we process at least 10 characters to prevent an infinite loop
 				index = 1995
 			}
-			info.sendContent(channelID, message[:index]+"```", 1)
+			chunks = append(chunks, message[:index]+"```")
 			message = "```\n" + message[index:]
 		} else {
 			index := strings.LastIndex(message[:1999], "\n")
 			if index < 10 {
 				index = 1999
 			}
-			info.sendContent(channelID, message[:index], 1)
+			chunks = append(chunks, message[:index])
 			message = message[index:]
 		}
 	}
-	info.sendContent(channelID, message, 2)
-
-	return nil
+	return append(chunks, message)
 }
 
 // ProcessModule returns true if a module should process events on this channel
@@ -236,6 +409,34 @@ func (info *GuildInfo) ProcessModule(channelID DiscordChannel, m Module) bool {
 	return err == nil
 }
 
+// MatchPrefix returns the length of the command prefix at the start of the message, or 0 if the message isn't a command.
+// A command can start with the server's command prefix, any of its extra prefixes, or a ping of the bot. If several
+// prefixes match, the longest one is used. If info is nil, the message was sent in a private message, so only the
+// default prefix is recognized.
+func (sb *SweetieBot) MatchPrefix(content string, info *GuildInfo) int {
+	prefixes := []string{"!"}
+	if info != nil {
+		prefixes = []string{info.Config.Basic.CommandPrefix}
+		for k := range info.Config.Basic.ExtraPrefixes {
+			prefixes = append(prefixes, k)
+		}
+	}
+	n := 0
+	for _, prefix := range prefixes {
+		if len(prefix) > n && strings.HasPrefix(content, prefix) && !strings.HasPrefix(content[len(prefix):], prefix) && len(strings.TrimSpace(content[len(prefix):])) > 0 {
+			n = len(prefix)
+		}
+	}
+	if n == 0 && sb.SelfID != UserEmpty {
+		for _, ping := range []string{"<@" + sb.SelfID.String() + ">", "<@!" + sb.SelfID.String() + ">"} {
+			if strings.HasPrefix(content, ping) && len(strings.TrimSpace(content[len(ping):])) > 0 {
+				return len(ping)
+			}
+		}
+	}
+	return n
+}
+
 // IsDebug returns true if the channel is a debug channel
 func (info *GuildInfo) IsDebug(channelID DiscordChannel) bool {
 	debugchannel, isdebug := info.Bot.DebugChannels[DiscordGuild(info.ID)]
@@ -259,13 +460,14 @@ func (info *GuildInfo) userBulkUpdate(members []*discordgo.Member) {
 	valueArgs := make([]interface{}, 0, len(members)*6)
 	valueStrings := make([]string, 0, len(members))
 
+	now := info.Bot.DB.utcNow()
 	for _, m := range members {
-		valueStrings = append(valueStrings, "(?,?,?,UTC_TIMESTAMP(), UTC_TIMESTAMP())")
+		valueStrings = append(valueStrings, fmt.Sprintf("(?,?,?,%s, %s)", now, now))
 		discriminator, _ := strconv.Atoi(m.User.Discriminator)
 		valueArgs = append(valueArgs, SBatoi(m.User.ID), m.User.Username, discriminator)
 	}
 
-	stmt := fmt.Sprintf("INSERT IGNORE INTO users (ID, Username, Discriminator, LastSeen, LastNameChange) VALUES %s", strings.Join(valueStrings, ","))
+	stmt := fmt.Sprintf("%s INTO users (ID, Username, Discriminator, LastSeen, LastNameChange) VALUES %s", info.Bot.DB.insertIgnore(), strings.Join(valueStrings, ","))
 	_, err := info.Bot.DB.db.Exec(stmt, valueArgs...)
 	info.LogError("Error in UserBulkUpdate", err)
 }
@@ -278,7 +480,7 @@ func (info *GuildInfo) memberBulkUpdate(members []*discordgo.Member) {
 		valueStrings = append(valueStrings, "(?,?,?,?)")
 		valueArgs = append(valueArgs, SBatoi(m.User.ID), SBatoi(info.ID), GetJoinedAt(m), m.Nick)
 	}
-	stmt := fmt.Sprintf("INSERT IGNORE INTO members (ID, Guild, FirstSeen, Nickname) VALUES %s", strings.Join(valueStrings, ","))
+	stmt := fmt.Sprintf("%s INTO members (ID, Guild, FirstSeen, Nickname) VALUES %s", info.Bot.DB.insertIgnore(), strings.Join(valueStrings, ","))
 	_, err := info.Bot.DB.db.Exec(stmt, valueArgs...)
 	info.LogError("Error in MemberBulkUpdate", err)
 }
@@ -323,20 +525,33 @@ func (info *GuildInfo) FindChannelID(name string) string {
 	return ""
 }
 
-// Log the given arguments to the server and the command line
-func (info *GuildInfo) Log(args ...interface{}) {
-	s := fmt.Sprint(args...)
-	fmt.Printf("[%s] %s\n", time.Now().Format(time.Stamp), s)
-	if info != nil && info.Config.Log.Channel != ChannelEmpty {
-		info.SendMessage(info.Config.Log.Channel, "```\n"+s+"```")
+// Logger returns the logger for this guild, which adds the guild ID to every entry and posts to log.channel
+func (info *GuildInfo) Logger() *Logger {
+	if info == nil {
+		return nil
+	}
+	return info.logger
+}
+
+// ModuleLogger returns the logger for this guild with the name of the module added to every entry
+func (info *GuildInfo) ModuleLogger(m Module) *Logger {
+	return info.Logger().With(LogFields{"module": strings.ToLower(m.Name())})
+}
+
+func (info *GuildInfo) postLog(msg string) {
+	if info.Config.Log.Channel != ChannelEmpty {
+		info.SendMessage(info.Config.Log.Channel, "```\n"+msg+"```")
 	}
 }
 
+// Log the given arguments as an info message, which goes to log.channel and the command line by default
+func (info *GuildInfo) Log(args ...interface{}) {
+	info.Logger().Info(fmt.Sprint(args...))
+}
+
 // LogError logs an error only if it exists
 func (info *GuildInfo) LogError(msg string, err error) {
-	if err != nil {
-		info.Log(msg, err.Error())
-	}
+	info.Logger().LogError(msg, err)
 }
 
 // SendError prints an error message with a saturation limit
@@ -396,6 +611,36 @@ func (info *GuildInfo) UserCanUseCommand(userID DiscordUser, command Command, ig
 	return
 }
 
+// GetUserLimits returns the per-user cooldowns and quotas that apply when the user runs the given command, based on their roles.
+// If several of their roles have limits, the shortest cooldown and the largest quota are used.
+func (info *GuildInfo) GetUserLimits(userID DiscordUser, name CommandID) (limits []UserLimit) {
+	if len(info.Config.Modules.UserCooldowns[name]) == 0 && len(info.Config.Modules.UserQuotas[name]) == 0 && len(info.Config.Modules.UserCooldowns["*"]) == 0 && len(info.Config.Modules.UserQuotas["*"]) == 0 {
+		return nil // Don't look up the member if there aren't any limits to check
+	}
+	roles := []string{info.ID} // The @everyone role has the same ID as the server
+	if m, err := info.Bot.DG.GetMember(userID, info.ID); err == nil {
+		roles = append(roles, m.Roles...)
+	}
+	for _, command := range []CommandID{name, "*"} {
+		limit := UserLimit{Command: command, Window: info.Config.Modules.UserQuotaWindow}
+		cooldowns := info.Config.Modules.UserCooldowns[command]
+		quotas := info.Config.Modules.UserQuotas[command]
+		hasCooldown, hasQuota := false, false
+		for _, role := range roles {
+			if v, ok := cooldowns[DiscordRole(role)]; ok && (!hasCooldown || v < limit.Cooldown) {
+				limit.Cooldown, hasCooldown = v, true
+			}
+			if v, ok := quotas[DiscordRole(role)]; ok && (!hasQuota || (limit.Quota != 0 && (v == 0 || v > limit.Quota))) { // A quota of 0 means the role is unlimited
+				limit.Quota, hasQuota = v, true
+			}
+		}
+		if limit.Cooldown > 0 || limit.Quota > 0 {
+			limits = append(limits, limit)
+		}
+	}
+	return
+}
+
 // UserIsAdmin returns true if the user is an admin or the owner of the bot. Always prefers returning false if any kind of error happens.
 func (info *GuildInfo) UserIsAdmin(userID DiscordUser) bool {
 	if userID == info.Bot.Owner {
@@ -463,18 +708,14 @@ func (info *GuildInfo) FormatUsage(c Command, usage *CommandUsage) *discordgo.Me
 	r := info.GetRoles(name)
 	ch := info.GetChannels(name)
 	fields := make([]*discordgo.MessageEmbedField, 0, len(usage.Params))
-	use := "> " + info.Config.Basic.CommandPrefix + string(name)
+	use := "> " + info.FormatUsageLine(name, usage)
 	for _, v := range usage.Params {
 		opt := ""
 		if v.Optional {
 			opt = " [OPTIONAL]"
-			use += fmt.Sprintf(" [%s]", v.Name)
-		} else {
-			use += fmt.Sprintf(" {%s}", v.Name)
 		}
 		if v.Variadic {
 			opt = " (...) " + opt
-			use += "..."
 		}
 		fields = append(fields, &discordgo.MessageEmbedField{Name: "**" + v.Name + "**" + opt, Value: v.Desc, Inline: false})
 	}
@@ -482,7 +723,9 @@ func (info *GuildInfo) FormatUsage(c Command, usage *CommandUsage) *discordgo.Me
 	if len(ch) > 0 {
 		ch = fmt.Sprintf("Available on: %s", ch)
 	}
-	module, _ := info.commandmap[name]
+	info.commandLock.RLock()
+	module := info.commandmap[name]
+	info.commandLock.RUnlock()
 	embed := &discordgo.MessageEmbed{
 		Type: "rich",
 		Author: &discordgo.MessageEmbedAuthor{
@@ -606,7 +849,7 @@ func (info *GuildInfo) GetUserName(user DiscordUser) string {
 	m, err := info.Bot.DG.State.Member(info.ID, u)
 	if m == nil {
 		if err != nil {
-			fmt.Println("GetUserName ERROR:", err.Error())
+			info.Bot.Logger.Debug("GetUserName error: "+err.Error(), LogFields{"guild": info.ID, "user": u})
 		}
 		return "<@" + u + ">"
 	}
@@ -716,8 +959,9 @@ func (info *GuildInfo) ChannelPermissionSet(channel *discordgo.Channel, targetID
 
 // Clean out all commands or modules that no longer exist
 func (info *GuildInfo) Clean() {
+	commands := info.GetCommands()
 	for k := range info.Config.Modules.Channels {
-		for _, m := range info.Modules {
+		for _, m := range info.GetModules() {
 			if k == ModuleID(strings.ToLower(m.Name())) {
 				k = ModuleID("")
 				break
@@ -727,7 +971,7 @@ func (info *GuildInfo) Clean() {
 	}
 
 	for k := range info.Config.Modules.Disabled {
-		for _, m := range info.Modules {
+		for _, m := range info.GetModules() {
 			if k == ModuleID(strings.ToLower(m.Name())) {
 				k = ModuleID("")
 				break
@@ -736,25 +980,35 @@ func (info *GuildInfo) Clean() {
 		delete(info.Config.Modules.Disabled, k)
 	}
 	for k := range info.Config.Modules.CommandRoles {
-		if _, ok := info.commands[k]; !ok {
+		if _, ok := commands[k]; !ok {
 			delete(info.Config.Modules.CommandRoles, k)
 		}
 	}
 	for k := range info.Config.Modules.CommandChannels {
-		if _, ok := info.commands[k]; !ok {
+		if _, ok := commands[k]; !ok {
 			delete(info.Config.Modules.CommandChannels, k)
 		}
 	}
 	for k := range info.Config.Modules.CommandLimits {
-		if _, ok := info.commands[k]; !ok {
+		if _, ok := commands[k]; !ok {
 			delete(info.Config.Modules.CommandLimits, k)
 		}
 	}
 	for k := range info.Config.Modules.CommandDisabled {
-		if _, ok := info.commands[k]; !ok {
+		if _, ok := commands[k]; !ok {
 			delete(info.Config.Modules.CommandDisabled, k)
 		}
 	}
+	for k := range info.Config.Modules.UserCooldowns {
+		if _, ok := commands[k]; !ok && k != "*" {
+			delete(info.Config.Modules.UserCooldowns, k)
+		}
+	}
+	for k := range info.Config.Modules.UserQuotas {
+		if _, ok := commands[k]; !ok && k != "*" {
+			delete(info.Config.Modules.UserQuotas, k)
+		}
+	}
 }
 
 func (info *GuildInfo) ResolveRoleAddError(err error) error {
@@ -770,10 +1024,27 @@ func (info *GuildInfo) ResolveRoleAddError(err error) error {
 	return err
 }
 
+// tick runs the OnTick hook of every module
+func (info *GuildInfo) tick(tm time.Time) {
+	hooks := info.getHooks()
+	defer hooks.done()
+	for _, h := range hooks.OnTick {
+		if info.ProcessModule("", h) {
+			start := time.Now()
+			h.OnTick(info, tm)
+			observeHook(h, "OnTick", start)
+		}
+	}
+}
+
 func (info *GuildInfo) checkOnCommand(m *discordgo.Message) (ignore bool) {
-	for _, h := range info.hooks.OnCommand {
+	hooks := info.getHooks()
+	defer hooks.done()
+	for _, h := range hooks.OnCommand {
 		if info.ProcessModule(DiscordChannel(m.ChannelID), h) {
+			start := time.Now()
 			ignore = ignore || h.OnCommand(info, m)
+			observeHook(h, "OnCommand", start)
 		}
 	}
 	return
@@ -33,11 +33,24 @@ func TestAddCommand(t *testing.T) {
 func TestSaveConfig(t *testing.T) {
 	sb, dbmock, _ := MockSweetieBot(t)
 	for k, v := range sb.Guilds {
+		dbmock.ExpectExec("INSERT INTO guild_config.*").WithArgs(SBatoi(v.ID), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
 		Check(v.SaveConfig(), nil, t)
-		if _, err := os.Stat(fmt.Sprintf("%v.json", k)); err != nil {
-			t.Errorf("%v.json does not exist", k)
+		Check(v.configVer, uint64(1), t)
+		if _, err := os.Stat(fmt.Sprintf("%v.json", k)); err == nil {
+			t.Errorf("%v.json exists", k)
+		}
+	}
+
+	sb.DB.Status.Set(false)
+	sb.DB.lastattempt = time.Now().UTC()
+	for k, v := range sb.Guilds {
+		mock.Expect(v.Bot.DG.RequestWithLockedBucket, "POST", MockAny{}, MockAny{}, MockAny{}, MockAny{}, 0)
+		Check(v.SaveConfig(), errConfigDatabaseDown, t)
+		if _, err := os.Stat(fmt.Sprintf("%v.json", k)); err == nil {
+			t.Errorf("%v.json exists", k)
 		}
 	}
+	sb.DB.Status.Set(true)
 
 	for k := range sb.Guilds {
 		os.Remove(fmt.Sprintf("%v.json", k))
@@ -246,9 +259,11 @@ func TestProcessGuild(t *testing.T) {
 	}
 
 	for _, v := range sb.Guilds {
+		update := *g
+		update.ID = v.ID
 		dbmock.ExpectExec("INSERT IGNORE INTO users.*").WithArgs(args2...).WillReturnResult(sqlmock.NewResult(0, 0))
 		dbmock.ExpectExec("INSERT IGNORE INTO members.*").WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 0))
-		v.ProcessMembers(g.Members)
+		sb.GuildUpdate(nil, &discordgo.GuildUpdate{Guild: &update})
 		Check(v.Name, "Test Server 12", t)
 		Check(v.OwnerID, NewDiscordUser(TestOwnerServer|12), t)
 	}
@@ -438,13 +453,13 @@ func TestGetTimezone(t *testing.T) {
 
 	for _, v := range sb.Guilds {
 		Check(v.GetTimezone(UserEmpty), time.UTC, t)
-		dbmock.ExpectQuery("SELECT Location FROM users WHERE ID = \\?").WithArgs(0).WillReturnRows(sqlmock.NewRows([]string{"Location"}).AddRow("America/New_York"))
+		dbmock.ExpectQuery("SELECT Location FROM `users` WHERE ID = \\?").WithArgs(0).WillReturnRows(sqlmock.NewRows([]string{"Location"}).AddRow("America/New_York"))
 		Check(v.GetTimezone(DiscordUser("0")).String(), loc.String(), t)
-		dbmock.ExpectQuery("SELECT Location FROM users WHERE ID = \\?").WithArgs(0).WillReturnRows(sqlmock.NewRows([]string{"Location"}))
+		dbmock.ExpectQuery("SELECT Location FROM `users` WHERE ID = \\?").WithArgs(0).WillReturnRows(sqlmock.NewRows([]string{"Location"}))
 		Check(v.GetTimezone(DiscordUser("0")), time.UTC, t)
 		v.Config.Users.TimezoneLocation = "America/New_York"
 		Check(v.GetTimezone(UserEmpty).String(), loc.String(), t)
-		dbmock.ExpectQuery("SELECT Location FROM users WHERE ID = \\?").WithArgs(0).WillReturnRows(sqlmock.NewRows([]string{"Location"}))
+		dbmock.ExpectQuery("SELECT Location FROM `users` WHERE ID = \\?").WithArgs(0).WillReturnRows(sqlmock.NewRows([]string{"Location"}))
 		Check(v.GetTimezone(DiscordUser("0")).String(), loc.String(), t)
 	}
 }
@@ -567,9 +582,9 @@ func TestChannelPermissionSet(t *testing.T) {
 		ch := mockDiscordChannel(TestChannelFree, i)
 		Check(v.ChannelPermissionSet(nil, "", discordgo.PermissionOverwriteTypeRole, 0, 0), errInvalidChannel, t)
 		Check(v.ChannelPermissionSet(mockDiscordChannel(1234, 999), "", discordgo.PermissionOverwriteTypeRole, 0, 0), errInvalidChannel, t)
-		mock.Expect(v.Bot.DG.ChannelPermissionSet, ch.ID, "", discordgo.PermissionOverwriteTypeRole, 0, 0)
+		mock.Expect(v.Bot.DG.ChannelPermissionSet, ch.ID, "", discordgo.PermissionOverwriteTypeRole, int64(0), int64(0))
 		Check(v.ChannelPermissionSet(ch, "", discordgo.PermissionOverwriteTypeRole, 0, 0), nil, t)
-		mock.Expect(v.Bot.DG.ChannelPermissionSet, ch.ID, "1", discordgo.PermissionOverwriteTypeMember, 3, 4)
+		mock.Expect(v.Bot.DG.ChannelPermissionSet, ch.ID, "1", discordgo.PermissionOverwriteTypeMember, int64(3), int64(4))
 		Check(v.ChannelPermissionSet(ch, "1", discordgo.PermissionOverwriteTypeMember, 3, 4), nil, t)
 	}
 }
@@ -48,12 +48,13 @@ func (c *helpCommand) Info() *CommandInfo {
 // DumpCommandsModules dumps information about all commands and modules
 func DumpCommandsModules(info *GuildInfo, footer string, description string, msg *discordgo.Message) *discordgo.MessageEmbed {
 	showdisabled := info.UserIsMod(DiscordUser(msg.Author.ID))
-	fields := make([]*discordgo.MessageEmbedField, 0, len(info.Modules))
-	for _, v := range info.Modules {
+	modules := info.GetModules()
+	fields := make([]*discordgo.MessageEmbedField, 0, len(modules))
+	for _, v := range modules {
 		if strings.ToLower(v.Name()) == "status" && DiscordGuild(info.ID) != info.Bot.MainGuildID {
 			continue // Never show the status module outside of the main guild
 		}
-		rawcmds := v.Commands()
+		rawcmds := ExpandCommands(v.Commands())
 		cmds := make([]Command, 0, len(rawcmds))
 		for _, c := range rawcmds {
 			if _, err := info.UserCanUseCommand(DiscordUser(msg.Author.ID), c, false); err == nil {
@@ -93,10 +94,10 @@ func (c *helpCommand) Process(args []string, msg *discordgo.Message, indices []i
 	if len(args) == 0 {
 		return "", true, DumpCommandsModules(info, "For more information on a specific command, type "+info.Config.Basic.CommandPrefix+"help [command].", "", msg)
 	}
-	arg := strings.ToLower(args[0])
-	for _, v := range info.Modules {
+	arg := strings.ToLower(strings.Join(args, ".")) // "!help filter add" looks up the filter.add subcommand
+	for _, v := range info.GetModules() {
 		if strings.Compare(strings.ToLower(v.Name()), arg) == 0 {
-			cmds := v.Commands()
+			cmds := ExpandCommands(v.Commands())
 			fields := make([]*discordgo.MessageEmbedField, 0, len(cmds))
 			for _, c := range cmds {
 				if _, err := info.UserCanUseCommand(DiscordUser(msg.Author.ID), c, false); err == nil {
@@ -125,7 +126,7 @@ func (c *helpCommand) Process(args []string, msg *discordgo.Message, indices []i
 			return "", true, embed
 		}
 	}
-	v, ok := info.commands[CommandID(arg)]
+	v, ok := info.GetCommand(CommandID(arg))
 	if !ok {
 		parts := strings.Split(arg, ".")
 		if len(parts) > 1 {
@@ -1,6 +1,9 @@
 package sweetiebot
 
 import (
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -101,12 +104,29 @@ type ModuleOnTick interface {
 	OnTick(*GuildInfo, time.Time)
 }
 
+// ModuleSubscriber is implemented by modules that subscribe to events on the guild's EventBus. Subscribe is called
+// when the module is registered, and must add its handlers with the given subscribe function instead of
+// info.Bus.Subscribe, so they can be replaced when the guild's modules are reloaded.
+type ModuleSubscriber interface {
+	Module
+	Subscribe(info *GuildInfo, subscribe func(handler interface{}) func())
+}
+
+// ModuleState is implemented by modules that keep state in memory which should survive a restart. SaveState is
+// called periodically and on shutdown, and LoadState is called with the last saved state when the guild is attached.
+type ModuleState interface {
+	Module
+	SaveState(*GuildInfo) ([]byte, error)
+	LoadState(*GuildInfo, []byte) error
+}
+
 // CommandUsageParam describes a single parameter to a command
 type CommandUsageParam struct {
 	Name     string
 	Desc     string
 	Optional bool
 	Variadic bool
+	Type     ParamType
 }
 
 // CommandUsage defines the help parameters for a command
@@ -123,6 +143,7 @@ type CommandInfo struct {
 	Sensitive         bool
 	Restricted        bool
 	MainInstance      bool
+	RawText           bool // The arguments are stored as raw text, so | and ; don't split them into a command chain
 }
 
 // Command is any command that is addressed to the bot, optionally restricted by role.
@@ -146,47 +167,162 @@ type moduleHooks struct {
 	OnGuildRoleDelete   []ModuleOnGuildRoleDelete
 	OnCommand           []ModuleOnCommand
 	OnTick              []ModuleOnTick
+	State               []ModuleState
+	running             *sync.WaitGroup // Counts the events still being dispatched to these hooks
 }
 
-// RegisterModule registers a module with this guild
-func (info *GuildInfo) RegisterModule(m Module) {
+// done marks an event that was dispatched with hooks from getHooks as finished
+func (hooks moduleHooks) done() {
+	if hooks.running != nil {
+		hooks.running.Done()
+	}
+}
+
+// add appends the module to every hook it implements
+func (hooks *moduleHooks) add(m Module) {
 	if h, ok := m.(ModuleOnEvent); ok {
-		info.hooks.OnEvent = append(info.hooks.OnEvent, h)
+		hooks.OnEvent = append(hooks.OnEvent, h)
 	}
 	if h, ok := m.(ModuleOnMessageCreate); ok {
-		info.hooks.OnMessageCreate = append(info.hooks.OnMessageCreate, h)
+		hooks.OnMessageCreate = append(hooks.OnMessageCreate, h)
 	}
 	if h, ok := m.(ModuleOnMessageUpdate); ok {
-		info.hooks.OnMessageUpdate = append(info.hooks.OnMessageUpdate, h)
+		hooks.OnMessageUpdate = append(hooks.OnMessageUpdate, h)
 	}
 	if h, ok := m.(ModuleOnMessageDelete); ok {
-		info.hooks.OnMessageDelete = append(info.hooks.OnMessageDelete, h)
+		hooks.OnMessageDelete = append(hooks.OnMessageDelete, h)
 	}
 	if h, ok := m.(ModuleOnGuildUpdate); ok {
-		info.hooks.OnGuildUpdate = append(info.hooks.OnGuildUpdate, h)
+		hooks.OnGuildUpdate = append(hooks.OnGuildUpdate, h)
 	}
 	if h, ok := m.(ModuleOnGuildMemberAdd); ok {
-		info.hooks.OnGuildMemberAdd = append(info.hooks.OnGuildMemberAdd, h)
+		hooks.OnGuildMemberAdd = append(hooks.OnGuildMemberAdd, h)
 	}
 	if h, ok := m.(ModuleOnGuildMemberRemove); ok {
-		info.hooks.OnGuildMemberRemove = append(info.hooks.OnGuildMemberRemove, h)
+		hooks.OnGuildMemberRemove = append(hooks.OnGuildMemberRemove, h)
 	}
 	if h, ok := m.(ModuleOnGuildMemberUpdate); ok {
-		info.hooks.OnGuildMemberUpdate = append(info.hooks.OnGuildMemberUpdate, h)
+		hooks.OnGuildMemberUpdate = append(hooks.OnGuildMemberUpdate, h)
 	}
 	if h, ok := m.(ModuleOnGuildBanAdd); ok {
-		info.hooks.OnGuildBanAdd = append(info.hooks.OnGuildBanAdd, h)
+		hooks.OnGuildBanAdd = append(hooks.OnGuildBanAdd, h)
 	}
 	if h, ok := m.(ModuleOnGuildBanRemove); ok {
-		info.hooks.OnGuildBanRemove = append(info.hooks.OnGuildBanRemove, h)
+		hooks.OnGuildBanRemove = append(hooks.OnGuildBanRemove, h)
 	}
 	if h, ok := m.(ModuleOnGuildRoleDelete); ok {
-		info.hooks.OnGuildRoleDelete = append(info.hooks.OnGuildRoleDelete, h)
+		hooks.OnGuildRoleDelete = append(hooks.OnGuildRoleDelete, h)
 	}
 	if h, ok := m.(ModuleOnCommand); ok {
-		info.hooks.OnCommand = append(info.hooks.OnCommand, h)
+		hooks.OnCommand = append(hooks.OnCommand, h)
 	}
 	if h, ok := m.(ModuleOnTick); ok {
-		info.hooks.OnTick = append(info.hooks.OnTick, h)
+		hooks.OnTick = append(hooks.OnTick, h)
+	}
+	if h, ok := m.(ModuleState); ok {
+		hooks.State = append(hooks.State, h)
+	}
+}
+
+// RegisterModule registers a module with this guild
+func (info *GuildInfo) RegisterModule(m Module) {
+	info.moduleLock.Lock()
+	if info.hooks.running == nil {
+		info.hooks.running = &sync.WaitGroup{}
+	}
+	info.hooks.add(m)
+	info.moduleLock.Unlock()
+	if h, ok := m.(ModuleSubscriber); ok {
+		info.Bus.subscribeModule(h, false)
+	}
+	if h, ok := m.(ModuleCommandMiddleware); ok {
+		info.commandLock.Lock()
+		if info.moduleMiddleware == nil {
+			info.moduleMiddleware = make(map[ModuleID][]CommandMiddleware)
+		}
+		id := ModuleID(strings.ToLower(m.Name()))
+		info.moduleMiddleware[id] = append(info.moduleMiddleware[id], h.CommandMiddleware)
+		info.commandLock.Unlock()
+	}
+}
+
+// ReloadModules replaces this guild's modules with new instances from the bot's loader, then rebuilds its hooks,
+// commands and event bus subscriptions. Events keep going to the old modules until the new ones are swapped in. Once
+// the old modules have finished handling those events, modules that implement ModuleState are given the state of the
+// module they replace.
+func (info *GuildInfo) ReloadModules() []Module {
+	info.moduleReload.Lock()
+	defer info.moduleReload.Unlock()
+	modules := info.Bot.loader(info)
+	sort.Sort(moduleArray(modules))
+
+	hooks := moduleHooks{running: &sync.WaitGroup{}}
+	commands := make(map[CommandID]Command)
+	commandmap := make(map[CommandID]ModuleID)
+	middleware := make(map[ModuleID][]CommandMiddleware)
+	for _, m := range modules {
+		hooks.add(m)
+		if h, ok := m.(ModuleSubscriber); ok {
+			info.Bus.subscribeModule(h, true)
+		}
+		if h, ok := m.(ModuleCommandMiddleware); ok {
+			id := ModuleID(strings.ToLower(m.Name()))
+			middleware[id] = append(middleware[id], h.CommandMiddleware)
+		}
+		for _, c := range m.Commands() {
+			addCommand(commands, commandmap, c, m)
+		}
+	}
+
+	info.moduleLock.Lock()
+	info.commandLock.Lock()
+	old := info.hooks
+	info.Modules = modules
+	info.hooks = hooks
+	info.commands = commands
+	info.commandmap = commandmap
+	info.moduleMiddleware = middleware
+	info.commandLock.Unlock()
+	info.moduleLock.Unlock()
+	info.Bus.commit()
+
+	// No new events can reach the old modules once they're swapped out, but some might still be handling one, so wait
+	// for them to finish before moving their state.
+	if old.running != nil {
+		old.running.Wait()
+	}
+	for _, h := range hooks.State {
+		for _, o := range old.State {
+			if strings.EqualFold(o.Name(), h.Name()) {
+				data, err := o.SaveState(info)
+				if err == nil {
+					err = h.LoadState(info, data)
+				}
+				if err != nil {
+					info.Bot.Logger.Error("Error moving "+h.Name()+" state to the reloaded module: "+err.Error(), LogFields{"guild": info.ID})
+				}
+			}
+		}
+	}
+	info.UpdateApplicationCommands()
+	return modules
+}
+
+// getHooks returns a copy of the guild's hook tables, so events can be dispatched while the modules are reloaded. The
+// caller must call done() on the result once it has finished dispatching the event, so ReloadModules knows when the
+// old modules are no longer in use.
+func (info *GuildInfo) getHooks() moduleHooks {
+	info.moduleLock.RLock()
+	defer info.moduleLock.RUnlock()
+	if info.hooks.running != nil {
+		info.hooks.running.Add(1)
 	}
+	return info.hooks
+}
+
+// GetModules returns the modules currently loaded on this guild
+func (info *GuildInfo) GetModules() []Module {
+	info.moduleLock.RLock()
+	defer info.moduleLock.RUnlock()
+	return info.Modules
 }
@@ -0,0 +1,313 @@
+package sweetiebot
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	apiPrefix       = "/api/v1/"
+	apiDefaultLimit = 50
+	apiMaxLimit     = 200
+	apiRateLimit    = 60 // Each token can make this many requests every apiRatePeriod seconds
+	apiRatePeriod   = 60
+)
+
+// APIPage is the response body of every endpoint that returns a list
+type APIPage struct {
+	Items  interface{} `json:"items"`
+	Offset int         `json:"offset"`
+	Limit  int         `json:"limit"`
+	Next   *int        `json:"next"` // Offset of the next page, or null if this is the last one
+}
+
+// APIGuild is the response body of /api/v1/guilds/{id}
+type APIGuild struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// APIEvent is a scheduled event. Private events like reminders are never included.
+type APIEvent struct {
+	ID   uint64    `json:"id"`
+	Date time.Time `json:"date"`
+	Type uint8     `json:"type"`
+	Data string    `json:"data"`
+}
+
+// APITag is a tag along with how many items it has
+type APITag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// APIItem is an item along with all of its tags on the guild
+type APIItem struct {
+	ID      uint64   `json:"id"`
+	Content string   `json:"content"`
+	Tags    []string `json:"tags"`
+}
+
+// APIQuote is a quote, numbered the same way !quote numbers them
+type APIQuote struct {
+	User  DiscordUser `json:"user"`
+	Index int         `json:"index"`
+	Quote string      `json:"quote"`
+}
+
+// APICounter is a counter and its description
+type APICounter struct {
+	Name        string `json:"name"`
+	Value       int64  `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+// APIRule is one of the server rules, as shown by !rules
+type APIRule struct {
+	Number int    `json:"number"`
+	Rule   string `json:"rule"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// HashAPIToken returns the hash that an API token is stored and looked up by, so the database never contains usable tokens
+func HashAPIToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+// NewAPIToken creates a new API token for the guild and returns it. The token can't be recovered after this.
+func (info *GuildInfo) NewAPIToken(name string, author DiscordUser) (string, error) {
+	token := randomToken()
+	return token, info.Bot.DB.AddAPIToken(SBatoi(info.ID), name, HashAPIToken(token), author.Convert())
+}
+
+type api struct {
+	sb     *SweetieBot
+	lock   sync.Mutex
+	limits map[string]*SaturationLimit
+}
+
+func newAPI(sb *SweetieBot) *api {
+	return &api{sb: sb, limits: make(map[string]*SaturationLimit)}
+}
+
+func writeAPI(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func apiFail(w http.ResponseWriter, code int, msg string) {
+	writeAPI(w, code, &apiError{msg})
+}
+
+// limited records a request made with the token and returns true if the token has made too many requests recently
+func (a *api) limited(hash string, now int64) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	limit, ok := a.limits[hash]
+	if !ok {
+		limit = &SaturationLimit{[]int64{}, 0, AtomicFlag{0}}
+		limit.resize(apiRateLimit)
+		a.limits[hash] = limit
+	}
+	if limit.check(apiRateLimit, apiRatePeriod, now) {
+		return true
+	}
+	limit.append(now)
+	return false
+}
+
+// authenticate returns the guild the request's bearer token belongs to, or writes an error and returns 0
+func (a *api) authenticate(w http.ResponseWriter, r *http.Request) (uint64, string) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		apiFail(w, http.StatusUnauthorized, "Missing API token")
+		return 0, ""
+	}
+	if !a.sb.DB.CheckStatus() {
+		apiFail(w, http.StatusServiceUnavailable, "The database is unavailable")
+		return 0, ""
+	}
+	hash := HashAPIToken(strings.TrimSpace(auth[7:]))
+	guild, _, err := a.sb.DB.GetAPIToken(hash)
+	if err == sql.ErrNoRows {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		apiFail(w, http.StatusUnauthorized, "Invalid API token")
+		return 0, ""
+	} else if err != nil {
+		apiFail(w, http.StatusServiceUnavailable, "The database is unavailable")
+		return 0, ""
+	}
+	return guild, hash
+}
+
+// paging reads the limit and offset query parameters
+func paging(r *http.Request) (int, int, bool) {
+	limit, offset := apiDefaultLimit, 0
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return 0, 0, false
+		}
+		limit = n
+	}
+	if limit > apiMaxLimit {
+		limit = apiMaxLimit
+	}
+	if s := r.URL.Query().Get("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return 0, 0, false
+		}
+		offset = n
+	}
+	return limit, offset, true
+}
+
+// page returns the bounds of the requested page in a list of length n, and the offset of the next page if there is one
+func page(n int, limit int, offset int) (int, int, *int) {
+	if offset > n {
+		offset = n
+	}
+	end := offset + limit
+	if end >= n {
+		return offset, n, nil
+	}
+	return offset, end, &end
+}
+
+func (a *api) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, apiPrefix) {
+		apiFail(w, http.StatusNotFound, "Unknown API version")
+		return
+	}
+	parts := splitURL(r.URL.Path[len(apiPrefix):])
+	if len(parts) < 2 || parts[0] != "guilds" {
+		apiFail(w, http.StatusNotFound, "Not found")
+		return
+	}
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		apiFail(w, http.StatusMethodNotAllowed, "The API is read-only")
+		return
+	}
+	guild, hash := a.authenticate(w, r)
+	if guild == 0 {
+		return
+	}
+	if a.limited(hash, time.Now().Unix()) {
+		w.Header().Set("Retry-After", strconv.Itoa(apiRatePeriod))
+		apiFail(w, http.StatusTooManyRequests, "Too many requests")
+		return
+	}
+	a.sb.GuildsLock.RLock()
+	info, ok := a.sb.Guilds[DiscordGuild(parts[1])]
+	a.sb.GuildsLock.RUnlock()
+	if !ok || SBatoi(info.ID) != guild { // Tokens only work on their own guild, and don't reveal which other guilds the bot is in
+		apiFail(w, http.StatusNotFound, "Guild not found")
+		return
+	}
+	if len(parts) == 2 {
+		writeAPI(w, http.StatusOK, &APIGuild{info.ID, info.Name})
+		return
+	}
+
+	limit, offset, ok := paging(r)
+	if !ok {
+		apiFail(w, http.StatusBadRequest, "limit must be a positive integer and offset must be a non-negative integer")
+		return
+	}
+	var items interface{}
+	var next *int
+	switch {
+	case len(parts) == 3 && parts[2] == "events":
+		events := a.sb.DB.GetEvents(guild, offset+limit+1) // Fetch one extra event to find out if there's another page
+		start, end, n := page(len(events), limit, offset)
+		list := make([]APIEvent, 0, end-start)
+		for _, v := range events[start:end] {
+			list = append(list, APIEvent{v.ID, v.Date, v.Type, v.Data})
+		}
+		items, next = list, n
+	case len(parts) == 3 && parts[2] == "tags":
+		tags := a.sb.DB.GetTags(guild)
+		sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+		start, end, n := page(len(tags), limit, offset)
+		list := make([]APITag, 0, end-start)
+		for _, v := range tags[start:end] {
+			list = append(list, APITag{v.Name, v.Count})
+		}
+		items, next = list, n
+	case len(parts) == 5 && parts[2] == "tags" && parts[4] == "items":
+		tag, err := a.sb.DB.GetTag(strings.ToLower(parts[3]), guild)
+		if err != nil {
+			apiFail(w, http.StatusNotFound, "Tag not found")
+			return
+		}
+		tagitems := a.sb.DB.GetTagItems(tag, limit+1, offset)
+		_, end, n := page(len(tagitems), limit, 0)
+		list := make([]APIItem, 0, end)
+		for _, v := range tagitems[:end] {
+			list = append(list, APIItem{v.ID, v.Content, a.sb.DB.GetItemTags(v.ID, guild)})
+		}
+		if n != nil {
+			*n += offset
+		}
+		items, next = list, n
+	case len(parts) == 3 && parts[2] == "quotes":
+		info.ConfigLock.RLock()
+		users := make([]DiscordUser, 0, len(info.Config.Quote.Quotes))
+		for k := range info.Config.Quote.Quotes {
+			users = append(users, k)
+		}
+		sort.Slice(users, func(i, j int) bool { return users[i].Convert() < users[j].Convert() })
+		quotes := []APIQuote{}
+		for _, u := range users {
+			for i, q := range info.Config.Quote.Quotes[u] {
+				quotes = append(quotes, APIQuote{u, i + 1, q})
+			}
+		}
+		info.ConfigLock.RUnlock()
+		start, end, n := page(len(quotes), limit, offset)
+		items, next = quotes[start:end], n
+	case len(parts) == 3 && parts[2] == "counters":
+		info.ConfigLock.RLock()
+		counters := make([]APICounter, 0, len(info.Config.Counters.Map))
+		for k, v := range info.Config.Counters.Map {
+			counters = append(counters, APICounter{k, v, info.Config.Counters.Descriptions[k]})
+		}
+		info.ConfigLock.RUnlock()
+		sort.Slice(counters, func(i, j int) bool { return counters[i].Name < counters[j].Name })
+		start, end, n := page(len(counters), limit, offset)
+		items, next = counters[start:end], n
+	case len(parts) == 3 && parts[2] == "rules":
+		info.ConfigLock.RLock()
+		rules := make([]APIRule, 0, len(info.Config.Information.Rules))
+		for k, v := range info.Config.Information.Rules {
+			if k >= 0 || !info.Config.Information.HideNegativeRules {
+				rules = append(rules, APIRule{k, v})
+			}
+		}
+		info.ConfigLock.RUnlock()
+		sort.Slice(rules, func(i, j int) bool { return rules[i].Number < rules[j].Number })
+		start, end, n := page(len(rules), limit, offset)
+		items, next = rules[start:end], n
+	default:
+		apiFail(w, http.StatusNotFound, "Not found")
+		return
+	}
+	writeAPI(w, http.StatusOK, &APIPage{items, offset, limit, next})
+}
@@ -0,0 +1,125 @@
+package sweetiebot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestAPI(t *testing.T) {
+	db := mockSQLiteDB(t)
+	defer db.Close()
+	state := discordgo.NewState()
+	g := &discordgo.Guild{ID: "10", Name: "Test Server", OwnerID: "1"}
+	state.GuildAdd(g)
+	state.GuildAdd(&discordgo.Guild{ID: "20", Name: "Other Server", OwnerID: "1"})
+	sb := &SweetieBot{
+		DB:     db,
+		DG:     &DiscordGoSession{Session: &discordgo.Session{State: state}},
+		Guilds: make(map[DiscordGuild]*GuildInfo),
+	}
+	info := NewGuildInfo(sb, g)
+	sb.Guilds["10"] = info
+	sb.Guilds["20"] = NewGuildInfo(sb, &discordgo.Guild{ID: "20", Name: "Other Server"})
+	info.Config.Information.Rules = map[int]string{2: "Be nice", 1: "No spam", -1: "Secret"}
+	info.Config.Information.HideNegativeRules = true
+	info.Config.Counters.Map = map[string]int64{"boops": 3, "hugs": 5}
+	info.Config.Quote.Quotes = map[DiscordUser][]string{"2": {"first", "second"}}
+
+	Check(db.CreateTag("pony", 10), nil, t)
+	tag, _ := db.GetTag("pony", 10)
+	for _, v := range []string{"Rarity", "Applejack", "Fluttershy"} {
+		item, _ := db.AddItem(v)
+		db.AddTag(item, tag)
+	}
+	now := time.Now().UTC()
+	for i := 0; i < 5; i++ {
+		db.AddSchedule(10, now.Add(time.Duration(i)*time.Hour), 1, "event")
+	}
+	db.AddSchedule(10, now, 6, "private reminder")
+
+	token, err := info.NewAPIToken("stats", "1")
+	Check(err, nil, t)
+	_, name, err := db.GetAPIToken(HashAPIToken(token))
+	Check(name, "stats", t)
+	Check(db.AddAPIToken(10, "stats", HashAPIToken("other"), 1) != nil, true, t) // Names are unique per guild
+
+	a := newAPI(sb)
+	get := func(path string, token string, v interface{}) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", path, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		a.ServeHTTP(w, r)
+		if v != nil && w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+				t.Error(err)
+			}
+		}
+		return w.Code
+	}
+
+	Check(get("/api/v1/guilds/10", "", nil), http.StatusUnauthorized, t)
+	Check(get("/api/v1/guilds/10", "wrong", nil), http.StatusUnauthorized, t)
+	Check(get("/api/v1/guilds/20", token, nil), http.StatusNotFound, t) // Tokens only work on their own guild
+	Check(get("/api/v2/guilds/10", token, nil), http.StatusNotFound, t)
+	var guild APIGuild
+	Check(get("/api/v1/guilds/10", token, &guild), http.StatusOK, t)
+	Check(guild.Name, "Test Server", t)
+
+	var events struct {
+		Items []APIEvent
+		Next  *int
+	}
+	Check(get("/api/v1/guilds/10/events?limit=2", token, &events), http.StatusOK, t)
+	Check(len(events.Items), 2, t)
+	Check(*events.Next, 2, t)
+	Check(get("/api/v1/guilds/10/events?limit=2&offset=4", token, &events), http.StatusOK, t)
+	Check(len(events.Items), 1, t)
+	Check(events.Next == nil, true, t)
+	Check(get("/api/v1/guilds/10/events?limit=-1", token, nil), http.StatusBadRequest, t)
+
+	var tags struct{ Items []APITag }
+	Check(get("/api/v1/guilds/10/tags", token, &tags), http.StatusOK, t)
+	Check(len(tags.Items), 1, t)
+	Check(tags.Items[0].Count, 3, t)
+
+	var items struct {
+		Items []APIItem
+		Next  *int
+	}
+	Check(get("/api/v1/guilds/10/tags/pony/items?limit=2&offset=1", token, &items), http.StatusOK, t)
+	Check(len(items.Items), 2, t)
+	Check(items.Items[0].Content, "Applejack", t)
+	Check(items.Items[0].Tags[0], "pony", t)
+	Check(items.Next == nil, true, t)
+	Check(get("/api/v1/guilds/10/tags/missing/items", token, nil), http.StatusNotFound, t)
+
+	var quotes struct{ Items []APIQuote }
+	Check(get("/api/v1/guilds/10/quotes", token, &quotes), http.StatusOK, t)
+	Check(len(quotes.Items), 2, t)
+	Check(quotes.Items[1].Index, 2, t)
+
+	var counters struct{ Items []APICounter }
+	Check(get("/api/v1/guilds/10/counters?offset=1", token, &counters), http.StatusOK, t)
+	Check(len(counters.Items), 1, t)
+	Check(counters.Items[0].Value, int64(5), t)
+
+	var rules struct{ Items []APIRule }
+	Check(get("/api/v1/guilds/10/rules", token, &rules), http.StatusOK, t)
+	Check(len(rules.Items), 2, t)
+	Check(rules.Items[0].Rule, "No spam", t)
+
+	for i := 0; i < apiRateLimit; i++ {
+		get("/api/v1/guilds/10", token, nil)
+	}
+	Check(get("/api/v1/guilds/10", token, nil), http.StatusTooManyRequests, t)
+
+	Check(db.RemoveAPIToken(10, "stats"), nil, t)
+	Check(get("/api/v1/guilds/10", token, nil), http.StatusUnauthorized, t)
+}
@@ -0,0 +1,309 @@
+package sweetiebot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ParamType determines how the framework parses a CommandUsageParam before it is passed to a TypedCommand
+type ParamType uint8
+
+// Parameter types. ParamString is the default, and takes a single argument (or every remaining argument if it's variadic).
+const (
+	ParamString   ParamType = iota
+	ParamUser               // A ping, ID, or username
+	ParamChannel            // A channel ping, ID, or name
+	ParamRole               // A role ping, ID, or name
+	ParamDuration           // A duration like "5 days", "for: 5 days" or "5d"
+	ParamInt                // An integer
+	ParamTime               // Any time format understood by ParseCommonTime
+	ParamRest               // The rest of the message, exactly as it was typed. Must be the last parameter.
+)
+
+var paramTypeNames = []string{"text", "user", "channel", "role", "duration", "number", "time", "text"}
+
+func (p ParamType) String() string {
+	if int(p) < len(paramTypeNames) {
+		return paramTypeNames[p]
+	}
+	return "argument"
+}
+
+// TypedCommand is a command whose arguments are parsed and validated by the framework, using the types declared in its Usage, before it is run
+type TypedCommand interface {
+	Command
+	ProcessArgs(*CommandArgs, *discordgo.Message, *GuildInfo) (string, bool, *discordgo.MessageEmbed)
+}
+
+// CommandArgs holds the parsed arguments of a TypedCommand, indexed by the lowercase name of each parameter
+type CommandArgs struct {
+	Args    []string
+	Indices []int
+	values  map[string]interface{}
+}
+
+func (a *CommandArgs) get(name string) interface{} {
+	return a.values[strings.ToLower(name)]
+}
+
+// Has returns true if the given optional parameter was provided
+func (a *CommandArgs) Has(name string) bool {
+	_, ok := a.values[strings.ToLower(name)]
+	return ok
+}
+
+// String returns a ParamString or ParamRest parameter
+func (a *CommandArgs) String(name string) string {
+	switch v := a.get(name).(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, " ")
+	}
+	return ""
+}
+
+// Strings returns every value of a variadic ParamString parameter
+func (a *CommandArgs) Strings(name string) []string {
+	switch v := a.get(name).(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// User returns a ParamUser parameter
+func (a *CommandArgs) User(name string) DiscordUser {
+	v, _ := a.get(name).(DiscordUser)
+	return v
+}
+
+// Channel returns a ParamChannel parameter
+func (a *CommandArgs) Channel(name string) DiscordChannel {
+	v, _ := a.get(name).(DiscordChannel)
+	return v
+}
+
+// Role returns a ParamRole parameter
+func (a *CommandArgs) Role(name string) DiscordRole {
+	v, _ := a.get(name).(DiscordRole)
+	return v
+}
+
+// Duration returns a ParamDuration parameter
+func (a *CommandArgs) Duration(name string) time.Duration {
+	v, _ := a.get(name).(time.Duration)
+	return v
+}
+
+// Int returns a ParamInt parameter
+func (a *CommandArgs) Int(name string) int64 {
+	v, _ := a.get(name).(int64)
+	return v
+}
+
+// Time returns a ParamTime parameter
+func (a *CommandArgs) Time(name string) time.Time {
+	v, _ := a.get(name).(time.Time)
+	return v
+}
+
+// ParseDuration parses a duration of the form "5 days", "5days" or "5d" from the start of args, and returns how many arguments it used
+func ParseDuration(args []string) (time.Duration, int, error) {
+	if len(args) == 0 {
+		return 0, 0, errors.New("missing duration")
+	}
+	num := args[0]
+	unit := ""
+	used := 1
+	if i := strings.IndexFunc(num, func(r rune) bool { return r < '0' || r > '9' }); i > 0 {
+		num, unit = num[:i], num[i:]
+	} else if len(args) > 1 {
+		unit = args[1]
+		used = 2
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("duration should be specified like '5 days' or '72 hours'")
+	}
+	var d time.Duration
+	switch strings.ToLower(unit) {
+	case "s":
+		d = time.Second
+	case "m":
+		d = time.Minute
+	case "h":
+		d = time.Hour
+	case "d":
+		d = 24 * time.Hour
+	case "w":
+		d = 7 * 24 * time.Hour
+	default:
+		switch ParseRepeatInterval(unit) {
+		case 1:
+			d = time.Second
+		case 2:
+			d = time.Minute
+		case 3:
+			d = time.Hour
+		case 4:
+			d = 24 * time.Hour
+		case 5:
+			d = 7 * 24 * time.Hour
+		case 6:
+			d = 30 * 24 * time.Hour
+		case 7:
+			d = 91 * 24 * time.Hour
+		case 8:
+			d = 365 * 24 * time.Hour
+		default:
+			return 0, 0, errors.New(unit + " is not a unit of time")
+		}
+	}
+	return time.Duration(n) * d, used, nil
+}
+
+// FormatUsageLine returns the command followed by its parameters, like "!silence {user} [duration]"
+func (info *GuildInfo) FormatUsageLine(name CommandID, usage *CommandUsage) string {
+	use := info.Config.Basic.CommandPrefix + string(name)
+	for _, v := range usage.Params {
+		if v.Optional {
+			use += fmt.Sprintf(" [%s]", v.Name)
+		} else {
+			use += fmt.Sprintf(" {%s}", v.Name)
+		}
+		if v.Variadic {
+			use += "..."
+		}
+	}
+	return use
+}
+
+func (info *GuildInfo) parseParam(p CommandUsageParam, args []string, indices []int, max int, msg *discordgo.Message) (interface{}, int, error) {
+	switch p.Type {
+	case ParamUser:
+		if len(args[0]) > 0 && args[0][0] == '<' { // A ping is always a single argument
+			max = 1
+		}
+		for n := 1; n < max; n++ {
+			if strings.ToLower(args[n]) == "for:" {
+				max = n
+				break
+			}
+		}
+		var err error
+		for n := max; n > 0; n-- { // Try the longest name first, so "Sweetie Bot" doesn't match someone named "Sweetie"
+			var user DiscordUser
+			if user, err = ParseUser(strings.Join(args[:n], " "), info); err == nil {
+				return user, n, nil
+			}
+		}
+		return nil, 0, err
+	case ParamChannel:
+		g, _ := info.GetGuild()
+		ch, err := ParseChannel(args[0], g)
+		if err == nil && ch == ChannelExclusion { // Only config options can exclude channels
+			err = errNotChannel
+		}
+		return ch, 1, err
+	case ParamRole:
+		if len(args[0]) > 0 && args[0][0] == '<' {
+			max = 1
+		}
+		g, _ := info.GetGuild()
+		var err error
+		for n := max; n > 0; n-- { // Role names can have spaces too, so they're matched longest-first like user names
+			var r DiscordRole
+			if r, err = ParseRole(strings.Join(args[:n], " "), g); err == nil && r == RoleExclusion {
+				err = errNotRole
+			}
+			if err == nil {
+				return r, n, nil
+			}
+		}
+		return nil, 0, err
+	case ParamDuration:
+		if strings.ToLower(args[0]) == "for:" {
+			d, n, err := ParseDuration(args[1:max])
+			return d, n + 1, err
+		}
+		return ParseDuration(args[:max])
+	case ParamInt:
+		i, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			err = errors.New("not an integer")
+		}
+		return i, 1, err
+	case ParamTime:
+		var err error
+		for n := max; n > 0; n-- {
+			var t time.Time
+			if t, err = info.ParseCommonTime(strings.Join(args[:n], " "), DiscordUser(msg.Author.ID), GetTimestamp(msg)); err == nil {
+				return t, n, nil
+			}
+		}
+		return nil, 0, errors.New("unrecognized time format")
+	case ParamRest:
+		return msg.Content[indices[0]:], len(args), nil
+	}
+	if p.Variadic {
+		return args[:max], max, nil
+	}
+	return args[0], 1, nil
+}
+
+// ParseCommandArgs parses and validates the arguments of a command according to the parameter types declared in its usage
+func (info *GuildInfo) ParseCommandArgs(c Command, args []string, indices []int, msg *discordgo.Message) (*CommandArgs, error) {
+	usage := c.Usage(info)
+	result := &CommandArgs{args, indices, make(map[string]interface{})}
+	usageError := func(s string) error {
+		return errors.New(s + "\nUsage: " + info.FormatUsageLine(CommandID(strings.ToLower(c.Info().Name)), usage))
+	}
+
+	pos := 0
+	for i, p := range usage.Params {
+		required := 0 // Leave enough arguments for any required parameters that come after this one
+		for _, v := range usage.Params[i+1:] {
+			if !v.Optional {
+				required++
+			}
+		}
+		max := len(args) - pos - required
+		if max <= 0 {
+			if !p.Optional {
+				return nil, usageError("Missing the " + p.Name + " parameter.")
+			}
+			continue
+		}
+		if p.Optional && len(args[pos]) == 0 { // An empty argument, like "", leaves out an optional parameter
+			pos++
+			continue
+		}
+		value, n, err := info.parseParam(p, args[pos:], indices[pos:], max, msg)
+		if err != nil {
+			if p.Optional && i+1 < len(usage.Params) { // Skip an optional parameter if it doesn't match so a later parameter can use the argument instead
+				continue
+			}
+			return nil, usageError(fmt.Sprintf("%s is not a valid %s for %s: %s", info.Sanitize(args[pos], CleanCodeBlock|CleanPings), p.Type, p.Name, err.Error()))
+		}
+		result.values[strings.ToLower(p.Name)] = value
+		pos += n
+	}
+	return result, nil
+}
+
+// ProcessTypedCommand parses the arguments of a TypedCommand and then runs it. Typed commands call this from Process.
+func ProcessTypedCommand(c TypedCommand, args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	parsed, err := info.ParseCommandArgs(c, args, indices, msg)
+	if err != nil {
+		return "```\n" + err.Error() + "```", false, nil
+	}
+	return c.ProcessArgs(parsed, msg, info)
+}
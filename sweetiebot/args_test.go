@@ -0,0 +1,193 @@
+package sweetiebot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type typedTestCommand struct {
+	params []CommandUsageParam
+}
+
+func (c *typedTestCommand) Info() *CommandInfo {
+	return &CommandInfo{Name: "Typed"}
+}
+func (c *typedTestCommand) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	return ProcessTypedCommand(c, args, msg, indices, info)
+}
+func (c *typedTestCommand) ProcessArgs(args *CommandArgs, msg *discordgo.Message, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	return "", false, nil
+}
+func (c *typedTestCommand) Usage(info *GuildInfo) *CommandUsage {
+	return &CommandUsage{Params: c.params}
+}
+
+func TestParseDuration(t *testing.T) {
+	d, n, err := ParseDuration([]string{"5", "days", "extra"})
+	Check(err, nil, t)
+	Check(d, 5*24*time.Hour, t)
+	Check(n, 2, t)
+	d, n, err = ParseDuration([]string{"90m", "extra"})
+	Check(err, nil, t)
+	Check(d, 90*time.Minute, t)
+	Check(n, 1, t)
+	_, _, err = ParseDuration([]string{"5", "fortnights"})
+	Check(err != nil, true, t)
+	_, _, err = ParseDuration([]string{"five", "days"})
+	Check(err != nil, true, t)
+}
+
+func TestParseCommandArgs(t *testing.T) {
+	info := &GuildInfo{Config: *DefaultConfig()}
+	c := &typedTestCommand{[]CommandUsageParam{
+		{Name: "count", Type: ParamInt},
+		{Name: "duration", Type: ParamDuration, Optional: true},
+		{Name: "reason", Type: ParamRest, Optional: true},
+	}}
+	parse := func(content string) (*CommandArgs, error) {
+		content = "!typed " + content
+		args, indices := ParseArguments(content[1:])
+		return info.ParseCommandArgs(c, args[1:], indices[1:], &discordgo.Message{Content: content})
+	}
+
+	a, err := parse("3 for: 2 hours being  rude")
+	Check(err, nil, t)
+	Check(a.Int("count"), int64(3), t)
+	Check(a.Duration("duration"), 2*time.Hour, t)
+	Check(a.String("reason"), "being  rude", t)
+
+	a, err = parse("3 being rude")
+	Check(err, nil, t)
+	Check(a.Has("duration"), false, t)
+	Check(a.String("reason"), "being rude", t)
+
+	a, err = parse("3")
+	Check(err, nil, t)
+	Check(a.Has("reason"), false, t)
+
+	_, err = parse("")
+	Check(err.Error(), "Missing the count parameter.\nUsage: !typed {count} [duration] [reason]", t)
+	_, err = parse("three")
+	Check(err.Error(), "three is not a valid number for count: not an integer\nUsage: !typed {count} [duration] [reason]", t)
+
+	c.params = []CommandUsageParam{{Name: "items", Variadic: true}, {Name: "last"}}
+	a, err = parse("a b c")
+	Check(err, nil, t)
+	Check(len(a.Strings("items")), 2, t)
+	Check(a.String("last"), "c", t)
+}
+
+func TestParseUserParam(t *testing.T) {
+	db := mockSQLiteDB(t)
+	defer db.Close()
+	sb := &SweetieBot{DB: db, DG: &DiscordGoSession{Session: &discordgo.Session{State: discordgo.NewState()}}}
+	info := NewGuildInfo(sb, &discordgo.Guild{ID: "5"})
+	db.AddUser(1, "Sweetie", 1, true)
+	db.AddUser(2, "Sweetie Bot", 2, true)
+	db.AddMember(1, 5, time.Now().UTC(), "")
+	db.AddMember(2, 5, time.Now().UTC(), "")
+	c := &typedTestCommand{[]CommandUsageParam{
+		{Name: "user", Type: ParamUser},
+		{Name: "count", Type: ParamInt},
+	}}
+	parse := func(content string) (*CommandArgs, error) {
+		content = "!typed " + content
+		args, indices := ParseArguments(content[1:])
+		return info.ParseCommandArgs(c, args[1:], indices[1:], &discordgo.Message{Content: content})
+	}
+
+	a, err := parse("Sweetie Bot 5")
+	Check(err, nil, t)
+	Check(a.User("user"), DiscordUser("2"), t) // The longest name that matches wins
+	Check(a.Int("count"), int64(5), t)
+	a, err = parse("Sweetie 5")
+	Check(err, nil, t)
+	Check(a.User("user"), DiscordUser("1"), t)
+	a, err = parse("<@2> 5")
+	Check(err, nil, t)
+	Check(a.User("user"), DiscordUser("2"), t)
+}
+
+func TestMatchPrefix(t *testing.T) {
+	sb := &SweetieBot{SelfID: "123"}
+	info := &GuildInfo{Config: *DefaultConfig()}
+	info.Config.Basic.CommandPrefix = "sb!"
+	info.Config.Basic.ExtraPrefixes = map[string]bool{"?": true, "🍰": true}
+
+	Check(sb.MatchPrefix("sb!help", info), 3, t)
+	Check(sb.MatchPrefix("?help", info), 1, t)
+	Check(sb.MatchPrefix("🍰 help", info), len("🍰"), t)
+	Check(sb.MatchPrefix("<@123> help", info), 6, t)
+	Check(sb.MatchPrefix("<@!123>help", info), 7, t)
+	Check(sb.MatchPrefix("!help", info), 0, t)
+	Check(sb.MatchPrefix("??", info), 0, t)
+	Check(sb.MatchPrefix("?", info), 0, t)
+	Check(sb.MatchPrefix("?  ", info), 0, t)
+	Check(sb.MatchPrefix("<@123>", info), 0, t)
+	Check(sb.MatchPrefix("<@456> help", info), 0, t)
+	Check(sb.MatchPrefix("!help", nil), 1, t)
+
+	content := "<@123>  echo a  b"
+	args, indices := ParseCommandArguments(content, sb.MatchPrefix(content, info))
+	Check(len(args), 3, t)
+	Check(args[0], "echo", t)
+	Check(content[indices[1]:], "a  b", t)
+	args, indices = ParseCommandArguments("sb!echo x", 3)
+	Check(args[1], "x", t)
+	Check(indices[1], 8, t)
+}
+
+func TestSplitCommandChain(t *testing.T) {
+	stages := SplitCommandChain("pick cute|fluffy | echo #general", nil)
+	Check(len(stages), 2, t)
+	Check(stages[0].Content, "pick cute|fluffy", t)
+	Check(stages[0].Pipe, false, t)
+	Check(stages[1].Content, "echo #general", t)
+	Check(stages[1].Pipe, true, t)
+
+	stages = SplitCommandChain(`about ; echo "|" ";" ; roll 1d6|`, nil)
+	Check(len(stages), 3, t)
+	Check(stages[1].Content, `echo "|" ";"`, t)
+	Check(stages[1].Pipe, false, t)
+	Check(stages[2].Content, "roll 1d6|", t)
+
+	stages = SplitCommandChain("about |", nil)
+	Check(len(stages), 2, t)
+	Check(stages[1].Content, "", t)
+	Check(len(SplitCommandChain("about", nil)), 1, t)
+
+	raw := func(name string) bool { return name == "addcommand" }
+	stages = SplitCommandChain("about | addcommand hug {author} hugs | {1}; echo", raw)
+	Check(len(stages), 2, t)
+	Check(stages[0].Content, "about", t)
+	Check(stages[1].Content, "addcommand hug {author} hugs | {1}; echo", t)
+	Check(stages[1].Pipe, true, t)
+
+	Check(StripCodeBlock("```\nsome text```"), "some text", t)
+	Check(StripCodeBlock(" plain "), "plain", t)
+	Check(StripCodeBlock("```"), "```", t)
+}
+
+func TestParseRoleParam(t *testing.T) {
+	state := discordgo.NewState()
+	state.GuildAdd(&discordgo.Guild{ID: "5", Roles: []*discordgo.Role{{ID: "10", Name: "Role With Spaces"}, {ID: "11", Name: "Role"}}})
+	info := &GuildInfo{ID: "5", Config: *DefaultConfig(), Bot: &SweetieBot{DG: &DiscordGoSession{Session: &discordgo.Session{State: state}}}}
+	c := &typedTestCommand{[]CommandUsageParam{{Name: "role", Type: ParamRole}, {Name: "reason", Type: ParamRest, Optional: true}}}
+	parse := func(content string) (*CommandArgs, error) {
+		content = "!typed " + content
+		args, indices := ParseArguments(content[1:])
+		return info.ParseCommandArgs(c, args[1:], indices[1:], &discordgo.Message{Content: content})
+	}
+
+	a, err := parse("Role With Spaces")
+	Check(err, nil, t)
+	Check(a.Role("role"), DiscordRole("10"), t)
+	a, err = parse("<@&11> Role With Spaces")
+	Check(err, nil, t)
+	Check(a.Role("role"), DiscordRole("11"), t) // A ping is always a single argument
+	Check(a.String("reason"), "Role With Spaces", t)
+	_, err = parse("!")
+	Check(err != nil, true, t)
+}
@@ -0,0 +1,233 @@
+package sweetiebot
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// PressureEvent asks for extra pressure to be added to the author of a message. The spam module adds it to the
+// author's pressure and silences them if it goes over the limit.
+type PressureEvent struct {
+	Message  *discordgo.Message
+	Pressure float32
+	Reason   string
+}
+
+// SilenceEvent is published after a user is silenced
+type SilenceEvent struct {
+	User   DiscordUser
+	Reason string
+	Until  time.Time // Zero if the silence doesn't expire by itself
+}
+
+// RaidEvent is published when a raid is detected
+type RaidEvent struct {
+	Users []*discordgo.User // Users that joined during the raid
+}
+
+// ScheduleFiredEvent is published after a scheduled event fires
+type ScheduleFiredEvent struct {
+	Event ScheduleEvent
+}
+
+// ConfigChangeEvent is published after a config option is changed. Old and New are the JSON encoded values.
+type ConfigChangeEvent struct {
+	User DiscordUser
+	Path string
+	Old  string
+	New  string
+}
+
+const (
+	pressureEventType      = "pressure"
+	silenceEventType       = "silence"
+	raidEventType          = "raid"
+	scheduleFiredEventType = "schedulefired"
+	configChangeEventType  = "configchange"
+)
+
+// busHandler wraps a typed handler function, just like discordgo's EventHandler
+type busHandler interface {
+	Type() string
+	Handle(*GuildInfo, interface{})
+}
+
+type pressureEventHandler func(*GuildInfo, *PressureEvent)
+
+func (h pressureEventHandler) Type() string { return pressureEventType }
+func (h pressureEventHandler) Handle(info *GuildInfo, e interface{}) {
+	if t, ok := e.(*PressureEvent); ok {
+		h(info, t)
+	}
+}
+
+type silenceEventHandler func(*GuildInfo, *SilenceEvent)
+
+func (h silenceEventHandler) Type() string { return silenceEventType }
+func (h silenceEventHandler) Handle(info *GuildInfo, e interface{}) {
+	if t, ok := e.(*SilenceEvent); ok {
+		h(info, t)
+	}
+}
+
+type raidEventHandler func(*GuildInfo, *RaidEvent)
+
+func (h raidEventHandler) Type() string { return raidEventType }
+func (h raidEventHandler) Handle(info *GuildInfo, e interface{}) {
+	if t, ok := e.(*RaidEvent); ok {
+		h(info, t)
+	}
+}
+
+type scheduleFiredEventHandler func(*GuildInfo, *ScheduleFiredEvent)
+
+func (h scheduleFiredEventHandler) Type() string { return scheduleFiredEventType }
+func (h scheduleFiredEventHandler) Handle(info *GuildInfo, e interface{}) {
+	if t, ok := e.(*ScheduleFiredEvent); ok {
+		h(info, t)
+	}
+}
+
+type configChangeEventHandler func(*GuildInfo, *ConfigChangeEvent)
+
+func (h configChangeEventHandler) Type() string { return configChangeEventType }
+func (h configChangeEventHandler) Handle(info *GuildInfo, e interface{}) {
+	if t, ok := e.(*ConfigChangeEvent); ok {
+		h(info, t)
+	}
+}
+
+func busHandlerForInterface(handler interface{}) busHandler {
+	switch v := handler.(type) {
+	case func(*GuildInfo, *PressureEvent):
+		return pressureEventHandler(v)
+	case func(*GuildInfo, *SilenceEvent):
+		return silenceEventHandler(v)
+	case func(*GuildInfo, *RaidEvent):
+		return raidEventHandler(v)
+	case func(*GuildInfo, *ScheduleFiredEvent):
+		return scheduleFiredEventHandler(v)
+	case func(*GuildInfo, *ConfigChangeEvent):
+		return configChangeEventHandler(v)
+	}
+	return nil
+}
+
+func busEventType(event interface{}) string {
+	switch event.(type) {
+	case *PressureEvent:
+		return pressureEventType
+	case *SilenceEvent:
+		return silenceEventType
+	case *RaidEvent:
+		return raidEventType
+	case *ScheduleFiredEvent:
+		return scheduleFiredEventType
+	case *ConfigChangeEvent:
+		return configChangeEventType
+	}
+	return ""
+}
+
+type busHandlerInstance struct {
+	handler busHandler
+	module  bool // True if the handler was added by a module's Subscribe function
+}
+
+// EventBus lets modules on a guild publish events to each other without importing each other
+type EventBus struct {
+	info     *GuildInfo
+	lock     sync.RWMutex
+	handlers map[string][]*busHandlerInstance
+	staged   map[string][]*busHandlerInstance // Handlers of reloaded modules that aren't called until commit
+}
+
+const (
+	busSubscribeAny = iota
+	busSubscribeModule
+	busSubscribeStaged
+)
+
+// NewEventBus creates an empty event bus for the guild
+func NewEventBus(info *GuildInfo) *EventBus {
+	return &EventBus{info: info, handlers: make(map[string][]*busHandlerInstance), staged: make(map[string][]*busHandlerInstance)}
+}
+
+// Subscribe adds a handler for one type of event, such as func(*GuildInfo, *PressureEvent), and returns a function
+// that removes it again. A handler for anything that isn't an event type will never be called.
+func (b *EventBus) Subscribe(handler interface{}) func() {
+	return b.add(handler, busSubscribeAny)
+}
+
+func (b *EventBus) add(handler interface{}, kind int) func() {
+	h := busHandlerForInterface(handler)
+	if h == nil {
+		b.info.Bot.Logger.Error("Invalid event bus handler type, handler will never be called", LogFields{"guild": b.info.ID})
+		return func() {}
+	}
+	ins := &busHandlerInstance{handler: h, module: kind != busSubscribeAny}
+	b.lock.Lock()
+	if kind == busSubscribeStaged {
+		b.staged[h.Type()] = append(b.staged[h.Type()], ins)
+	} else {
+		b.handlers[h.Type()] = append(b.handlers[h.Type()], ins)
+	}
+	b.lock.Unlock()
+	return func() { b.remove(h.Type(), ins) }
+}
+
+// subscribeModule calls the module's Subscribe function with a subscribe function that marks every handler it adds
+// as belonging to a module, so commit can replace them. If staged is true, the handlers aren't called until commit.
+func (b *EventBus) subscribeModule(m ModuleSubscriber, staged bool) {
+	kind := busSubscribeModule
+	if staged {
+		kind = busSubscribeStaged
+	}
+	m.Subscribe(b.info, func(handler interface{}) func() { return b.add(handler, kind) })
+}
+
+// commit removes every handler added by a module that isn't staged, then starts calling the staged handlers instead
+func (b *EventBus) commit() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for t, handlers := range b.handlers {
+		kept := make([]*busHandlerInstance, 0, len(handlers))
+		for _, ins := range handlers {
+			if !ins.module {
+				kept = append(kept, ins)
+			}
+		}
+		b.handlers[t] = kept
+	}
+	for t, handlers := range b.staged {
+		b.handlers[t] = append(b.handlers[t], handlers...)
+	}
+	b.staged = make(map[string][]*busHandlerInstance)
+}
+
+func (b *EventBus) remove(t string, ins *busHandlerInstance) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, m := range []map[string][]*busHandlerInstance{b.handlers, b.staged} {
+		handlers := m[t]
+		for i := range handlers {
+			if handlers[i] == ins {
+				m[t] = append(handlers[:i:i], handlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish calls every handler subscribed to the event's type, in the order they subscribed, before returning
+func (b *EventBus) Publish(event interface{}) {
+	t := busEventType(event)
+	b.lock.RLock()
+	handlers := b.handlers[t]
+	b.lock.RUnlock()
+	for _, ins := range handlers {
+		ins.handler.Handle(b.info, event)
+	}
+}
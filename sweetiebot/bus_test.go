@@ -0,0 +1,46 @@
+package sweetiebot
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestEventBus(t *testing.T) {
+	db := mockSQLiteDB(t)
+	defer db.Close()
+	sb := &SweetieBot{DB: db, DG: &DiscordGoSession{&discordgo.Session{State: discordgo.NewState()}}, MaxConfigSize: 1000000}
+	info := NewGuildInfo(sb, &discordgo.Guild{ID: "1"})
+
+	order := ""
+	info.Bus.Subscribe(func(i *GuildInfo, e *PressureEvent) {
+		Check(i, info, t)
+		order += "a" + e.Reason
+	})
+	remove := info.Bus.Subscribe(func(i *GuildInfo, e *PressureEvent) { order += "b" + e.Reason })
+	info.Bus.Subscribe(func(i *GuildInfo, e *RaidEvent) { order += "raid" })
+	info.Bus.Subscribe(func(i *GuildInfo, e *ScheduleEvent) { order += "invalid" }) // Not an event, so never called
+	info.Bus.Publish(&PressureEvent{Reason: "1"})
+	Check(order, "a1b1", t)
+	remove()
+	remove()
+	info.Bus.Publish(&PressureEvent{Reason: "2"})
+	Check(order, "a1b1a2", t)
+	info.Bus.Publish(&RaidEvent{})
+	info.Bus.Publish(&SilenceEvent{})
+	info.Bus.Publish(&ScheduleEvent{})
+	Check(order, "a1b1a2raid", t)
+
+	var change *ConfigChangeEvent
+	info.Bus.Subscribe(func(i *GuildInfo, e *ConfigChangeEvent) { change = e })
+	_, ok, err := info.SetConfigOption([]string{"basic.commandprefix", "?"}, []int{11, 31}, "!setconfig basic.commandprefix ?", "2")
+	Check(ok, true, t)
+	Check(err, nil, t)
+	if change == nil {
+		t.Fatal("config change was not published")
+	}
+	Check(change.Path, "basic.commandprefix", t)
+	Check(change.Old, `"!"`, t)
+	Check(change.New, `"?"`, t)
+	Check(change.User, DiscordUser("2"), t)
+}
@@ -0,0 +1,104 @@
+package sweetiebot
+
+import (
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// CommandGroup bundles related commands under one name, so they can be run as "!group subcommand". Each subcommand
+// is registered as "group.subcommand", which is the name used for its permissions, limits, and disabling.
+type CommandGroup struct {
+	Name        string
+	Desc        string
+	Default     Command // If not nil, runs when the first argument isn't a subcommand
+	subcommands []*subCommand
+}
+
+// subCommand renames a command to "Group.Sub" while otherwise behaving exactly like the original command
+type subCommand struct {
+	Command
+	name string
+}
+
+func (c *subCommand) Info() *CommandInfo {
+	info := *c.Command.Info()
+	info.Name = c.name
+	return &info
+}
+
+// NewCommandGroup creates an empty command group
+func NewCommandGroup(name string, desc string, def Command) *CommandGroup {
+	return &CommandGroup{Name: name, Desc: desc, Default: def}
+}
+
+// Add adds a subcommand to the group under the given name
+func (g *CommandGroup) Add(name string, c Command) *CommandGroup {
+	g.subcommands = append(g.subcommands, &subCommand{c, g.Name + "." + name})
+	return g
+}
+
+// Subcommands returns all subcommands in the group, each named "Group.Sub"
+func (g *CommandGroup) Subcommands() []Command {
+	cmds := make([]Command, len(g.subcommands))
+	for i, v := range g.subcommands {
+		cmds[i] = v
+	}
+	return cmds
+}
+
+// Find returns the subcommand with the given name, or nil if it doesn't exist
+func (g *CommandGroup) Find(name string) Command {
+	name = strings.ToLower(g.Name + "." + name)
+	for _, v := range g.subcommands {
+		if strings.ToLower(v.name) == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Info for the group. A group is only sensitive if its default command is.
+func (g *CommandGroup) Info() *CommandInfo {
+	info := &CommandInfo{Name: g.Name, Usage: g.Desc}
+	if g.Default != nil {
+		info.Sensitive = g.Default.Info().Sensitive
+	}
+	return info
+}
+
+// Process runs the default command, or lists the subcommands if there isn't one. Subcommands are resolved by ProcessCommand before this is called.
+func (g *CommandGroup) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	if g.Default != nil {
+		return g.Default.Process(args, msg, indices, info)
+	}
+	return "", false, info.FormatUsage(g, g.Usage(info))
+}
+
+// Usage lists the subcommands of the group
+func (g *CommandGroup) Usage(info *GuildInfo) *CommandUsage {
+	usage := &CommandUsage{Desc: g.Desc}
+	if g.Default != nil {
+		def := g.Default.Usage(info)
+		usage.Desc += "\n\n" + def.Desc
+		usage.Params = append(usage.Params, def.Params...)
+	}
+	s := make([]string, 0, len(g.subcommands))
+	for _, v := range g.subcommands {
+		s = append(s, "`"+info.Config.Basic.CommandPrefix+strings.ToLower(strings.Replace(v.name, ".", " ", 1))+"` "+v.Command.Info().Usage)
+	}
+	usage.Desc += "\n\n**Subcommands:**\n" + strings.Join(s, "\n") + "\n\nUse `" + info.Config.Basic.CommandPrefix + "help " + strings.ToLower(g.Name) + " [subcommand]` for more information on a subcommand."
+	return usage
+}
+
+// ExpandCommands replaces every command group in the list with the group followed by its subcommands
+func ExpandCommands(cmds []Command) []Command {
+	r := make([]Command, 0, len(cmds))
+	for _, c := range cmds {
+		r = append(r, c)
+		if g, ok := c.(*CommandGroup); ok {
+			r = append(r, g.Subcommands()...)
+		}
+	}
+	return r
+}
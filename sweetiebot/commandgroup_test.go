@@ -0,0 +1,47 @@
+package sweetiebot
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type groupTestCommand struct {
+	name string
+}
+
+func (c *groupTestCommand) Info() *CommandInfo {
+	return &CommandInfo{Name: c.name, Usage: "Test command.", Sensitive: true}
+}
+func (c *groupTestCommand) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	return c.name, false, nil
+}
+func (c *groupTestCommand) Usage(info *GuildInfo) *CommandUsage {
+	return &CommandUsage{Desc: "Test command."}
+}
+
+func TestCommandGroup(t *testing.T) {
+	group := NewCommandGroup("Filter", "Manages filters.", nil).
+		Add("Add", &groupTestCommand{"AddFilter"}).
+		Add("Remove", &groupTestCommand{"RemoveFilter"})
+
+	Check(group.Info().Sensitive, false, t)
+	Check(group.Find("add").Info().Name, "Filter.Add", t)
+	Check(group.Find("add").Info().Sensitive, true, t)
+	Check(group.Find("REMOVE").Info().Name, "Filter.Remove", t)
+	Check(group.Find("delete") == nil, true, t)
+
+	s, _, _ := group.Find("remove").Process(nil, nil, nil, nil)
+	Check(s, "RemoveFilter", t)
+
+	cmds := ExpandCommands([]Command{&groupTestCommand{"Other"}, group})
+	Check(len(cmds), 4, t)
+	Check(cmds[1].Info().Name, "Filter", t)
+	Check(cmds[2].Info().Name, "Filter.Add", t)
+	Check(cmds[3].Info().Name, "Filter.Remove", t)
+
+	withDefault := NewCommandGroup("Counter", "Manages counters.", &groupTestCommand{"Counter"})
+	Check(withDefault.Info().Sensitive, true, t)
+	s, _, _ = withDefault.Process(nil, nil, nil, nil)
+	Check(s, "Counter", t)
+}
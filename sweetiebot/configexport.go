@@ -0,0 +1,269 @@
+package sweetiebot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigExport is the file format used by !exportconfig and !importconfig. Channels and roles are stored along
+// with their names so they can be remapped onto the channels and roles of a different server.
+type ConfigExport struct {
+	Guild    string                    `json:"guild"`
+	Channels map[DiscordChannel]string `json:"channels"`
+	Roles    map[DiscordRole]string    `json:"roles"`
+	Config   json.RawMessage           `json:"config"`
+}
+
+// ExportConfig serializes the current config along with the names of every channel and role it references. Format can be "json" or "yaml".
+func (info *GuildInfo) ExportConfig(format string) ([]byte, error) {
+	config, err := json.Marshal(info.Config)
+	if err != nil {
+		return nil, err
+	}
+	export := ConfigExport{
+		Guild:    info.Name,
+		Channels: make(map[DiscordChannel]string),
+		Roles:    make(map[DiscordRole]string),
+		Config:   config,
+	}
+
+	guild, err := info.GetGuild()
+	if err != nil {
+		return nil, err
+	}
+	info.Bot.DG.State.RLock()
+	channels := make(map[string]string, len(guild.Channels))
+	for _, v := range guild.Channels {
+		channels[v.ID] = v.Name
+	}
+	roles := make(map[string]string, len(guild.Roles))
+	for _, v := range guild.Roles {
+		roles[v.ID] = v.Name
+	}
+	info.Bot.DG.State.RUnlock()
+
+	cfg := reflect.ValueOf(&info.Config).Elem()
+	for _, v := range collectConfigValues(cfg, reflect.TypeOf(ChannelEmpty)) {
+		if name, ok := channels[v]; ok {
+			export.Channels[DiscordChannel(v)] = name
+		}
+	}
+	for _, v := range collectConfigValues(cfg, reflect.TypeOf(RoleEmpty)) {
+		if name, ok := roles[v]; ok {
+			export.Roles[DiscordRole(v)] = name
+		}
+	}
+
+	switch strings.ToLower(format) {
+	case "json":
+		return json.MarshalIndent(export, "", "  ")
+	case "yaml", "yml":
+		data, err := json.Marshal(export)
+		if err != nil {
+			return nil, err
+		}
+		var generic interface{}
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.UseNumber()
+		if err = d.Decode(&generic); err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(jsonNumbersToYAML(generic))
+	}
+	return nil, errors.New(format + " is not a supported format. Use either json or yaml.")
+}
+
+// jsonNumbersToYAML converts json.Number values into integers or floats so they aren't quoted in the YAML output
+func jsonNumbersToYAML(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, e := range x {
+			x[k] = jsonNumbersToYAML(e)
+		}
+	case []interface{}:
+		for i, e := range x {
+			x[i] = jsonNumbersToYAML(e)
+		}
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i
+		}
+		if f, err := x.Float64(); err == nil {
+			return f
+		}
+	}
+	return v
+}
+
+// yamlToJSON converts any map[interface{}]interface{} produced by the YAML parser into map[string]interface{}
+func yamlToJSON(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			m[fmt.Sprint(k)] = yamlToJSON(e)
+		}
+		return m
+	case map[string]interface{}:
+		for k, e := range x {
+			x[k] = yamlToJSON(e)
+		}
+	case []interface{}:
+		for i, e := range x {
+			x[i] = yamlToJSON(e)
+		}
+	}
+	return v
+}
+
+// ParseConfigExport parses a JSON or YAML config export. A plain config file without channel or role names is also accepted.
+func ParseConfigExport(data []byte) (*ConfigExport, error) {
+	data = bytes.TrimSpace(data)
+	if !json.Valid(data) {
+		var generic interface{}
+		if err := yaml.Unmarshal(data, &generic); err != nil {
+			return nil, errors.New("The file is not valid JSON or YAML: " + err.Error())
+		}
+		var err error
+		if data, err = json.Marshal(yamlToJSON(generic)); err != nil {
+			return nil, err
+		}
+	}
+
+	export := &ConfigExport{}
+	if err := json.Unmarshal(data, export); err != nil {
+		return nil, errors.New("The file is not a valid configuration export: " + err.Error())
+	}
+	if len(export.Config) == 0 {
+		export.Config = data
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(export.Config, &probe); err != nil || probe["version"] == nil {
+		return nil, errors.New("The file is not a valid configuration export: missing config version")
+	}
+	return export, nil
+}
+
+// RemapConfigExport replaces the channel and role IDs in the export with the IDs of channels and roles with the same name on this server.
+// It returns the remapped config and the names of any channels or roles that could not be found.
+func (info *GuildInfo) RemapConfigExport(export *ConfigExport) ([]byte, []string, error) {
+	guild, err := info.GetGuild()
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make(map[string]string)
+	missing := []string{}
+	info.Bot.DG.State.RLock()
+	for id, name := range export.Channels {
+		found := false
+		for _, v := range guild.Channels {
+			if strings.EqualFold(v.Name, name) {
+				ids[string(id)] = v.ID
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, "#"+name)
+		}
+	}
+	for id, name := range export.Roles {
+		found := false
+		for _, v := range guild.Roles {
+			if strings.EqualFold(v.Name, name) {
+				ids[string(id)] = v.ID
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, "@"+name)
+		}
+	}
+	info.Bot.DG.State.RUnlock()
+	sort.Strings(missing)
+
+	var generic interface{}
+	d := json.NewDecoder(bytes.NewReader(export.Config))
+	d.UseNumber()
+	if err := d.Decode(&generic); err != nil {
+		return nil, nil, err
+	}
+	config, err := json.Marshal(remapConfigIDs(generic, ids))
+	return config, missing, err
+}
+
+func remapConfigIDs(v interface{}, ids map[string]string) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			if id, ok := ids[k]; ok {
+				k = id
+			}
+			m[k] = remapConfigIDs(e, ids)
+		}
+		return m
+	case []interface{}:
+		for i, e := range x {
+			x[i] = remapConfigIDs(e, ids)
+		}
+	case string:
+		if id, ok := ids[x]; ok {
+			return id
+		}
+	}
+	return v
+}
+
+// PreviewImport migrates an imported config to the current config version without applying it to this server
+func (info *GuildInfo) PreviewImport(config []byte) (*BotConfig, error) {
+	shadow := &GuildInfo{ID: info.ID, Name: info.Name, Bot: info.Bot, Config: *DefaultConfig()}
+	if err := shadow.migrateConfig(config); err != nil {
+		return nil, err
+	}
+	shadow.Config.Version = ConfigVersion
+	shadow.Config.FillConfig()
+	return &shadow.Config, nil
+}
+
+// invalidChanges validates every changed option of config, and returns the errors of any that break their validation
+// constraints. Like setconfig, an option that was already invalid in the current config isn't an error.
+func (info *GuildInfo) invalidChanges(config *BotConfig, changes []ConfigChange) (invalid []string) {
+	for _, v := range changes {
+		if err := config.ValidateOption(info, v.Path); err != nil && info.Config.ValidateOption(info, v.Path) == nil {
+			invalid = append(invalid, err.Error())
+		}
+	}
+	return
+}
+
+// DiffConfig returns every Category.Option that differs between two configs, along with the old and new values
+func DiffConfig(old *BotConfig, new *BotConfig) (changes []ConfigChange) {
+	a := reflect.ValueOf(old).Elem()
+	b := reflect.ValueOf(new).Elem()
+	for i := 0; i < a.NumField(); i++ {
+		if a.Field(i).Kind() != reflect.Struct {
+			continue
+		}
+		for j := 0; j < a.Field(i).NumField(); j++ {
+			x, _ := json.Marshal(a.Field(i).Field(j).Interface())
+			y, _ := json.Marshal(b.Field(i).Field(j).Interface())
+			if !bytes.Equal(x, y) {
+				changes = append(changes, ConfigChange{
+					Path: strings.ToLower(a.Type().Field(i).Name + "." + a.Field(i).Type().Field(j).Name),
+					Old:  string(x),
+					New:  string(y),
+				})
+			}
+		}
+	}
+	return
+}
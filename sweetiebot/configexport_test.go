@@ -0,0 +1,98 @@
+package sweetiebot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestParseConfigExport(t *testing.T) {
+	yml := "guild: Test\nchannels:\n  \"100\": general\nroles:\n  \"200\": mods\nconfig:\n  version: 20\n  basic:\n    modchannel: \"100\"\n    modrole: \"200\"\n    commandprefix: \"?\"\n"
+	export, err := ParseConfigExport([]byte(yml))
+	Check(err, nil, t)
+	Check(export.Guild, "Test", t)
+	Check(export.Channels["100"], "general", t)
+	Check(export.Roles["200"], "mods", t)
+
+	var config BotConfig
+	Check(json.Unmarshal(export.Config, &config), nil, t)
+	Check(config.Version, 20, t)
+	Check(config.Basic.ModChannel, DiscordChannel("100"), t)
+
+	export, err = ParseConfigExport([]byte(`{"version":33,"basic":{"commandprefix":"?"}}`))
+	Check(err, nil, t)
+	Check(len(export.Channels), 0, t)
+	Check(string(export.Config), `{"version":33,"basic":{"commandprefix":"?"}}`, t)
+
+	_, err = ParseConfigExport([]byte(`{"guild":"Test"}`))
+	Check(err != nil, true, t)
+	_, err = ParseConfigExport([]byte("{not json: ["))
+	Check(err != nil, true, t)
+}
+
+func TestRemapConfigIDs(t *testing.T) {
+	var generic interface{}
+	json.Unmarshal([]byte(`{"basic":{"modchannel":"100","freechannels":{"100":true,"101":true}},"spam":{"maxremovelookback":12345678901234}}`), &generic)
+	data, _ := json.Marshal(remapConfigIDs(generic, map[string]string{"100": "900"}))
+	Check(string(data), `{"basic":{"freechannels":{"101":true,"900":true},"modchannel":"900"},"spam":{"maxremovelookback":12345678901234}}`, t)
+}
+
+func TestDiffConfig(t *testing.T) {
+	a := DefaultConfig()
+	b := DefaultConfig()
+	Check(len(DiffConfig(a, b)), 0, t)
+	b.Basic.CommandPrefix = "?"
+	b.Spam.MaxPressure = 70
+	changes := DiffConfig(a, b)
+	Check(len(changes), 2, t)
+	Check(changes[0].Path, "basic.commandprefix", t)
+	Check(changes[0].New, `"?"`, t)
+	Check(changes[1].Path, "spam.maxpressure", t)
+}
+
+func TestImportConfig(t *testing.T) {
+	db := mockSQLiteDB(t)
+	defer db.Close()
+	state := discordgo.NewState()
+	g := &discordgo.Guild{ID: "5", Channels: []*discordgo.Channel{{ID: "100", GuildID: "5", Name: "general"}}}
+	state.GuildAdd(g)
+	sb := &SweetieBot{DB: db, DG: &DiscordGoSession{Session: &discordgo.Session{State: state}}, MaxConfigSize: 1000000}
+	info := NewGuildInfo(sb, g)
+	file := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(file)) }))
+	defer server.Close()
+
+	c := &importConfigCommand{}
+	msg := &discordgo.Message{Author: &discordgo.User{ID: "2"}, Attachments: []*discordgo.MessageAttachment{{URL: server.URL}}}
+	upload := func(config string) string {
+		file = config
+		msg.Attachments[0].Size = len(file)
+		s, _, _ := c.Process([]string{}, msg, []int{}, info)
+		return s
+	}
+
+	s := upload(`{"version":40,"basic":{"modchannel":"999"}}`)
+	Check(strings.Contains(s, "can't be used on this server"), true, t) // Options that break their constraints are refused
+	Check(len(c.pending), 0, t)
+
+	sb.MaxConfigSize = 10
+	s = upload(`{"version":40,"basic":{"commandprefix":"?"}}`)
+	Check(strings.Contains(s, "too large"), true, t)
+	sb.MaxConfigSize = 1000000
+
+	s = upload(`{"version":40,"basic":{"commandprefix":"!","modchannel":"100"}}`)
+	Check(strings.Contains(s, "replaces your previous"), false, t)
+	s = upload(`{"version":40,"basic":{"commandprefix":"?","modchannel":"100"}}`)
+	Check(strings.Contains(s, "basic.commandprefix"), true, t)
+	Check(strings.Contains(s, "replaces your previous"), true, t)
+	s, _, _ = c.Process([]string{"confirm"}, &discordgo.Message{Author: &discordgo.User{ID: "3"}}, []int{0}, info)
+	Check(strings.Contains(s, "no pending import"), true, t) // Only the user who uploaded the file can confirm it
+	s, _, _ = c.Process([]string{"confirm"}, msg, []int{0}, info)
+	Check(strings.Contains(s, "Imported the configuration"), true, t)
+	Check(info.Config.Basic.CommandPrefix, "?", t)
+	Check(info.Config.Basic.ModChannel, DiscordChannel("100"), t)
+}
@@ -0,0 +1,540 @@
+package sweetiebot
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	dashboardSessionCookie = "sbsession"
+	dashboardStateCookie   = "sbstate"
+	dashboardSessionLength = 24 * time.Hour
+)
+
+var errOAuthFailed = errors.New("Discord rejected the login attempt")
+
+// OAuthUser is the discord account of someone who logged in to the dashboard
+type OAuthUser struct {
+	ID       DiscordUser
+	Username string
+}
+
+// OAuthProvider logs users in to the web dashboard. Discord is used by default, but tests can swap in a local stub.
+type OAuthProvider interface {
+	AuthURL(state string, redirect string) string              // URL the user is sent to so they can log in
+	Exchange(code string, redirect string) (*OAuthUser, error) // Turns the code sent back to the redirect URL into a user
+}
+
+// DiscordOAuth logs users in with discord's OAuth2 authorization code flow
+type DiscordOAuth struct {
+	ClientID     string
+	ClientSecret string
+	Client       *http.Client
+}
+
+// AuthURL returns discord's authorization page, which only asks for permission to see the user's identity
+func (o *DiscordOAuth) AuthURL(state string, redirect string) string {
+	return discordgo.EndpointOAuth2 + "authorize?" + url.Values{
+		"client_id":     {o.ClientID},
+		"redirect_uri":  {redirect},
+		"response_type": {"code"},
+		"scope":         {"identify"},
+		"state":         {state},
+		"prompt":        {"none"},
+	}.Encode()
+}
+
+// Exchange trades the authorization code for an access token, then uses it to look up who the user is
+func (o *DiscordOAuth) Exchange(code string, redirect string) (*OAuthUser, error) {
+	client := o.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.PostForm(discordgo.EndpointOAuth2+"token", url.Values{
+		"client_id":     {o.ClientID},
+		"client_secret": {o.ClientSecret},
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirect},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errOAuthFailed
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", discordgo.EndpointUser("@me"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", token.TokenType+" "+token.AccessToken)
+	user, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer user.Body.Close()
+	if user.StatusCode != http.StatusOK {
+		return nil, errOAuthFailed
+	}
+	var u discordgo.User
+	if err = json.NewDecoder(user.Body).Decode(&u); err != nil {
+		return nil, err
+	}
+	return &OAuthUser{DiscordUser(u.ID), u.Username}, nil
+}
+
+type dashboardSession struct {
+	user    OAuthUser
+	csrf    string
+	expires time.Time
+}
+
+type dashboard struct {
+	sb        *SweetieBot
+	templates *template.Template
+	lock      sync.Mutex
+	sessions  map[string]*dashboardSession
+}
+
+type dashboardGuild struct {
+	ID   string
+	Name string
+}
+
+type dashboardOption struct {
+	Path    string
+	Name    string
+	Help    string
+	Kind    string   // value, bool, list, map, maplist or mapmap, which decides what the form for the option looks like
+	Value   string   // Current value in the same format !setconfig uses, for options that are replaced all at once
+	Entries []string // Current entries of a map, which are changed one at a time
+}
+
+type dashboardCategory struct {
+	Name    string
+	Options []dashboardOption
+}
+
+type dashboardData struct {
+	Name       string
+	User       *OAuthUser
+	CSRF       string
+	Guilds     []dashboardGuild
+	Guild      *dashboardGuild
+	Categories []dashboardCategory
+	Result     string
+	Success    bool
+	Error      string
+}
+
+func newDashboard(sb *SweetieBot, webdir string) (*dashboard, error) {
+	t, err := template.ParseFiles(filepath.Join(webdir, "dashboard.html"))
+	if err != nil {
+		return nil, err
+	}
+	return &dashboard{sb: sb, templates: t, sessions: make(map[string]*dashboardSession)}, nil
+}
+
+// dashboardProvider returns the OAuth provider the dashboard should use, or nil if it can't log anyone in. The
+// application ID isn't known until the bot connects, so this has to be checked on every login.
+func (sb *SweetieBot) dashboardProvider() OAuthProvider {
+	if sb.OAuth != nil {
+		return sb.OAuth
+	}
+	if len(sb.OAuthSecret) == 0 || sb.AppID == 0 {
+		return nil
+	}
+	return &DiscordOAuth{ClientID: SBitoa(sb.AppID), ClientSecret: sb.OAuthSecret}
+}
+
+func randomToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // If the system can't generate random numbers, nothing using them is safe to run
+	}
+	return hex.EncodeToString(b)
+}
+
+func redirectURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/dashboard/callback"
+}
+
+func (d *dashboard) render(w http.ResponseWriter, name string, data *dashboardData) {
+	data.Name = d.sb.AppName
+	w.Header().Set("Cache-Control", "no-store")
+	if err := d.templates.ExecuteTemplate(w, name, data); err != nil {
+		d.sb.Logger.Error("Error rendering dashboard: "+err.Error(), LogFields{"template": name})
+	}
+}
+
+func (d *dashboard) getSession(r *http.Request) *dashboardSession {
+	c, err := r.Cookie(dashboardSessionCookie)
+	if err != nil {
+		return nil
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	s, ok := d.sessions[c.Value]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(s.expires) {
+		delete(d.sessions, c.Value)
+		return nil
+	}
+	return s
+}
+
+func (d *dashboard) setCookie(w http.ResponseWriter, r *http.Request, name string, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/dashboard",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   d.sb.WebSecure || r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (d *dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := splitURL(r.URL.Path)[1:]
+	if len(parts) == 0 {
+		d.guildsHandler(w, r)
+		return
+	}
+	switch parts[0] {
+	case "login":
+		oauth := d.sb.dashboardProvider()
+		if oauth == nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			d.render(w, "login", &dashboardData{Error: "Logging in to the dashboard hasn't been set up. The bot owner needs to add the application's OAuth2 client secret to selfhost.json as oauthsecret."})
+			return
+		}
+		state := randomToken()
+		d.setCookie(w, r, dashboardStateCookie, state, 600)
+		http.Redirect(w, r, oauth.AuthURL(state, redirectURL(r)), http.StatusFound)
+	case "callback":
+		d.callbackHandler(w, r)
+	case "logout":
+		if r.Method != "POST" {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+			return
+		}
+		if s := d.getSession(r); s != nil && d.checkCSRF(s, r) {
+			c, _ := r.Cookie(dashboardSessionCookie)
+			d.lock.Lock()
+			delete(d.sessions, c.Value)
+			d.lock.Unlock()
+		}
+		d.setCookie(w, r, dashboardSessionCookie, "", -1)
+		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+	default:
+		d.configHandler(w, r, parts[0])
+	}
+}
+
+func (d *dashboard) callbackHandler(w http.ResponseWriter, r *http.Request) {
+	state, err := r.Cookie(dashboardStateCookie)
+	d.setCookie(w, r, dashboardStateCookie, "", -1)
+	query := r.URL.Query()
+	if err != nil || subtle.ConstantTimeCompare([]byte(state.Value), []byte(query.Get("state"))) != 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		d.render(w, "login", &dashboardData{Error: "Your login attempt expired. Please try again."})
+		return
+	}
+	oauth := d.sb.dashboardProvider()
+	if oauth == nil {
+		http.Redirect(w, r, "/dashboard/login", http.StatusSeeOther)
+		return
+	}
+	if len(query.Get("code")) == 0 {
+		w.WriteHeader(http.StatusUnauthorized)
+		d.render(w, "login", &dashboardData{Error: "You have to authorize the login on Discord to use the dashboard."})
+		return
+	}
+	user, err := oauth.Exchange(query.Get("code"), redirectURL(r))
+	if err != nil {
+		d.sb.Logger.Warn("Dashboard login failed: " + err.Error())
+		w.WriteHeader(http.StatusUnauthorized)
+		d.render(w, "login", &dashboardData{Error: "Login failed. Please try again."})
+		return
+	}
+
+	token := randomToken()
+	now := time.Now()
+	d.lock.Lock()
+	for k, v := range d.sessions {
+		if now.After(v.expires) {
+			delete(d.sessions, k)
+		}
+	}
+	d.sessions[token] = &dashboardSession{*user, randomToken(), now.Add(dashboardSessionLength)}
+	d.lock.Unlock()
+	d.setCookie(w, r, dashboardSessionCookie, token, int(dashboardSessionLength.Seconds()))
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+func (d *dashboard) checkCSRF(s *dashboardSession, r *http.Request) bool {
+	return subtle.ConstantTimeCompare([]byte(s.csrf), []byte(r.PostFormValue("csrf"))) == 1
+}
+
+// canConfigure returns true if the user is allowed to change the configuration of a guild. Only admins and moderators
+// can, and only if they could also run !setconfig. The dashboard isn't in any channel, so if modules.commandchannels
+// limits setconfig to certain channels, it is treated like any other channel, which only moderators can bypass.
+func canConfigure(info *GuildInfo, user DiscordUser) bool {
+	if !info.UserIsAdmin(user) && !info.UserIsMod(user) {
+		return false
+	}
+	c, ok := info.GetCommand("setconfig")
+	if !ok {
+		return false
+	}
+	info.ConfigLock.RLock()
+	ignore := len(info.Config.Modules.CommandChannels["setconfig"]) > 0
+	_, err := info.UserCanUseCommand(user, c, ignore)
+	info.ConfigLock.RUnlock()
+	return err == nil
+}
+
+func (d *dashboard) guildsHandler(w http.ResponseWriter, r *http.Request) {
+	s := d.getSession(r)
+	if s == nil {
+		d.render(w, "login", &dashboardData{})
+		return
+	}
+	data := &dashboardData{User: &s.user, CSRF: s.csrf}
+	d.sb.GuildsLock.RLock()
+	for _, info := range d.sb.Guilds {
+		if canConfigure(info, s.user.ID) {
+			data.Guilds = append(data.Guilds, dashboardGuild{info.ID, info.Name})
+		}
+	}
+	d.sb.GuildsLock.RUnlock()
+	sort.Slice(data.Guilds, func(i, j int) bool {
+		return strings.ToLower(data.Guilds[i].Name) < strings.ToLower(data.Guilds[j].Name)
+	})
+	d.render(w, "guilds", data)
+}
+
+func (d *dashboard) configHandler(w http.ResponseWriter, r *http.Request, guild string) {
+	s := d.getSession(r)
+	if s == nil {
+		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+		return
+	}
+	d.sb.GuildsLock.RLock()
+	info, ok := d.sb.Guilds[DiscordGuild(guild)]
+	d.sb.GuildsLock.RUnlock()
+	if !ok || !canConfigure(info, s.user.ID) { // Don't reveal which servers the bot is in to people who can't configure them
+		http.Error(w, "Server not found", http.StatusNotFound)
+		return
+	}
+
+	data := &dashboardData{User: &s.user, CSRF: s.csrf, Guild: &dashboardGuild{info.ID, info.Name}}
+	switch r.Method {
+	case "GET":
+	case "POST":
+		if !d.checkCSRF(s, r) {
+			http.Error(w, "Invalid form token, reload the page and try again", http.StatusForbidden)
+			return
+		}
+		if err := info.useUserLimits(s.user.ID, "setconfig", time.Now().UTC().Unix()); err != nil {
+			data.Result = err.Error()
+			w.WriteHeader(http.StatusTooManyRequests)
+			break
+		}
+		var err error
+		data.Result, data.Success, err = d.setOption(info, &s.user, r)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		} else if !data.Success {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	default:
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	info.ConfigLock.RLock()
+	data.Categories = dashboardConfig(info)
+	info.ConfigLock.RUnlock()
+	d.render(w, "config", data)
+}
+
+// setOption builds the equivalent !setconfig command out of a submitted form, so it goes through the same parsing and
+// validation as a change made in discord. Returns an error if the change was valid but couldn't be saved.
+func (d *dashboard) setOption(info *GuildInfo, user *OAuthUser, r *http.Request) (string, bool, error) {
+	path := r.PostFormValue("option")
+	f, name := info.Config.getConfigField(path)
+	if !f.IsValid() {
+		return "Could not find configuration parameter " + path + "!", false, nil
+	}
+	message := name
+	switch configKind(f) {
+	case "map", "maplist":
+		if len(r.PostFormValue("key")) == 0 {
+			return "No key parameter given", false, nil
+		}
+		message += " " + configQuote(r.PostFormValue("key"))
+	case "mapmap":
+		if len(r.PostFormValue("key")) == 0 || len(r.PostFormValue("subkey")) == 0 {
+			return "You must specify both keys", false, nil
+		}
+		message += " " + configQuote(r.PostFormValue("key")) + " " + configQuote(r.PostFormValue("subkey"))
+	}
+	if value := strings.TrimSpace(r.PostFormValue("value")); len(value) > 0 {
+		message += " " + value
+	}
+	args, indices := ParseCommandArguments(message, 0)
+
+	result, ok, err := info.SetConfigOption(args, indices, message, user.ID)
+	if d.sb.DB.Status.Get() {
+		d.sb.DB.Audit(AuditTypeCommand, &discordgo.User{ID: user.ID.String(), Username: user.Username}, "[dashboard] setconfig "+message, SBatoi(info.ID))
+	}
+	if err != nil {
+		return "Error saving config: " + err.Error(), false, err
+	}
+	if ok {
+		return "Successfully set " + name + " to " + result + ".", true, nil
+	}
+	return result, false, nil
+}
+
+func configKind(f reflect.Value) string {
+	switch f.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.Slice:
+		return "list"
+	case reflect.Map:
+		switch f.Type().Elem().Kind() {
+		case reflect.Bool:
+			return "list"
+		case reflect.Slice:
+			return "maplist"
+		case reflect.Map:
+			if f.Type().Elem().Elem().Kind() == reflect.Bool {
+				return "maplist"
+			}
+			return "mapmap"
+		}
+		return "map"
+	}
+	return "value"
+}
+
+// configQuote quotes a value if !setconfig would otherwise split it into multiple arguments
+func configQuote(s string) string {
+	if len(s) == 0 || strings.ContainsAny(s, " \t\n\"") {
+		return "\"" + strings.Replace(s, "\"", "\\\"", -1) + "\""
+	}
+	return s
+}
+
+// configArgument formats a single value the way it would be typed into !setconfig
+func configArgument(v reflect.Value) string {
+	switch x := v.Interface().(type) {
+	case DiscordChannel:
+		if x == ChannelEmpty || x == ChannelExclusion {
+			return string(x)
+		}
+		return x.Display()
+	case DiscordRole:
+		if x == RoleEmpty || x == RoleExclusion {
+			return string(x)
+		}
+		return x.Display()
+	case DiscordUser:
+		if x == UserEmpty {
+			return ""
+		}
+		return x.Display()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func configListArguments(f reflect.Value) string {
+	values := []string{}
+	switch f.Kind() {
+	case reflect.Slice:
+		for i := 0; i < f.Len(); i++ {
+			values = append(values, configQuote(configArgument(f.Index(i))))
+		}
+	case reflect.Map:
+		for _, k := range f.MapKeys() {
+			values = append(values, configQuote(configArgument(k)))
+		}
+		sort.Strings(values)
+	}
+	return strings.Join(values, " ")
+}
+
+func dashboardConfig(info *GuildInfo) []dashboardCategory {
+	state := info.Bot.DG.State
+	t := reflect.ValueOf(&info.Config).Elem()
+	categories := []dashboardCategory{}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Kind() != reflect.Struct {
+			continue
+		}
+		category := dashboardCategory{Name: t.Type().Field(i).Name}
+		for j := 0; j < t.Field(i).NumField(); j++ {
+			f := t.Field(i).Field(j)
+			help, ok := getConfigHelp(category.Name, t.Field(i).Type().Field(j).Name)
+			if !ok {
+				continue // Options without help are internal, like the ones used by the config migration
+			}
+			option := dashboardOption{
+				Path: strings.ToLower(category.Name + "." + t.Field(i).Type().Field(j).Name),
+				Name: t.Field(i).Type().Field(j).Name,
+				Help: help,
+				Kind: configKind(f),
+			}
+			switch option.Kind {
+			case "value", "bool":
+				option.Value = configArgument(f)
+			case "list":
+				option.Value = configListArguments(f)
+			case "map":
+				option.Entries = getConfigList(f, state, info.ID)
+			default:
+				keys := f.MapKeys()
+				sort.Sort(valueArray(keys))
+				for _, k := range keys {
+					option.Entries = append(option.Entries, getConfigValue(k, state, info.ID)+": "+strings.Join(getConfigList(f.MapIndex(k), state, info.ID), ", "))
+				}
+			}
+			category.Options = append(category.Options, option)
+		}
+		categories = append(categories, category)
+	}
+	return categories
+}
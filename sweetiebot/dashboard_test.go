@@ -0,0 +1,170 @@
+package sweetiebot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// stubOAuth logs in whoever's user ID is used as the authorization code
+type stubOAuth struct{}
+
+func (o *stubOAuth) AuthURL(state string, redirect string) string {
+	return "/stub?" + url.Values{"state": {state}, "redirect_uri": {redirect}}.Encode()
+}
+
+func (o *stubOAuth) Exchange(code string, redirect string) (*OAuthUser, error) {
+	if code == "bad" {
+		return nil, errOAuthFailed
+	}
+	return &OAuthUser{DiscordUser(code), "User " + code}, nil
+}
+
+var csrfRegex = regexp.MustCompile(`name="csrf" value="([0-9a-f]+)"`)
+
+type dashboardClient struct {
+	t       *testing.T
+	d       *dashboard
+	cookies map[string]*http.Cookie
+}
+
+func (c *dashboardClient) do(method string, path string, form url.Values) *httptest.ResponseRecorder {
+	var r *http.Request
+	if form != nil {
+		r = httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		r = httptest.NewRequest(method, path, nil)
+	}
+	for _, v := range c.cookies {
+		r.AddCookie(v)
+	}
+	w := httptest.NewRecorder()
+	c.d.ServeHTTP(w, r)
+	for _, v := range w.Result().Cookies() {
+		c.cookies[v.Name] = v
+		if v.MaxAge < 0 {
+			delete(c.cookies, v.Name)
+		}
+	}
+	return w
+}
+
+func (c *dashboardClient) login(user string) {
+	w := c.do("GET", "/dashboard/login", nil)
+	Check(w.Code, http.StatusFound, c.t)
+	u, _ := url.Parse(w.Header().Get("Location"))
+	Check(u.Query().Get("redirect_uri"), "http://example.com/dashboard/callback", c.t)
+	w = c.do("GET", "/dashboard/callback?"+url.Values{"state": {u.Query().Get("state")}, "code": {user}}.Encode(), nil)
+	Check(w.Code, http.StatusSeeOther, c.t)
+}
+
+func (c *dashboardClient) csrf(path string) string {
+	m := csrfRegex.FindStringSubmatch(c.do("GET", path, nil).Body.String())
+	if m == nil {
+		c.t.Fatal("no csrf token on " + path)
+	}
+	return m[1]
+}
+
+func TestDashboard(t *testing.T) {
+	db := mockSQLiteDB(t)
+	defer db.Close()
+	db.AddUser(2, "Mod", 1, true)
+	state := discordgo.NewState()
+	g := &discordgo.Guild{ID: "10", Name: "Test Server", OwnerID: "1", Roles: []*discordgo.Role{{ID: "10", Name: "everyone"}, {ID: "11", Name: "Mods"}}}
+	state.GuildAdd(g)
+	state.ChannelAdd(&discordgo.Channel{ID: "12", GuildID: "10", Name: "general", Type: discordgo.ChannelTypeGuildText})
+	state.MemberAdd(&discordgo.Member{GuildID: "10", User: &discordgo.User{ID: "2", Username: "Mod"}, Roles: []string{"11"}})
+	state.MemberAdd(&discordgo.Member{GuildID: "10", User: &discordgo.User{ID: "3", Username: "Member"}})
+	sb := &SweetieBot{
+		DB:            db,
+		DG:            &DiscordGoSession{Session: &discordgo.Session{State: state}},
+		Guilds:        make(map[DiscordGuild]*GuildInfo),
+		OAuth:         &stubOAuth{},
+		MaxConfigSize: 1000000,
+	}
+	info := NewGuildInfo(sb, g)
+	info.Config.Basic.ModRole = "11"
+	config := &ConfigModule{}
+	for _, command := range config.Commands() {
+		info.AddCommand(command, config)
+	}
+	sb.Guilds["10"] = info
+
+	d, err := newDashboard(sb, "../docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &dashboardClient{t, d, make(map[string]*http.Cookie)}
+	w := c.do("GET", "/dashboard", nil)
+	Check(w.Code, http.StatusOK, t)
+	Check(strings.Contains(w.Body.String(), "/dashboard/login"), true, t)
+	Check(c.do("GET", "/dashboard/10", nil).Code, http.StatusSeeOther, t)
+
+	// The state has to match the one in the cookie, and discord has to accept the code
+	Check(c.do("GET", "/dashboard/callback?state=wrong&code=2", nil).Code, http.StatusBadRequest, t)
+	c.do("GET", "/dashboard/login", nil)
+	Check(c.do("GET", "/dashboard/callback?state="+c.cookies[dashboardStateCookie].Value+"&code=bad", nil).Code, http.StatusUnauthorized, t)
+	_, ok := c.cookies[dashboardSessionCookie]
+	Check(ok, false, t)
+
+	// A regular member can log in, but can't see or change the server
+	c.login("3")
+	w = c.do("GET", "/dashboard", nil)
+	Check(strings.Contains(w.Body.String(), "Test Server"), false, t)
+	Check(c.do("GET", "/dashboard/10", nil).Code, http.StatusNotFound, t)
+	Check(c.do("POST", "/dashboard/10", url.Values{"csrf": {c.csrf("/dashboard")}, "option": {"basic.commandprefix"}, "value": {"?"}}).Code, http.StatusNotFound, t)
+	Check(info.Config.Basic.CommandPrefix, "!", t)
+
+	c.login("2")
+	w = c.do("GET", "/dashboard", nil)
+	Check(strings.Contains(w.Body.String(), `href="/dashboard/10"`), true, t)
+	w = c.do("GET", "/dashboard/10", nil)
+	Check(w.Code, http.StatusOK, t)
+	Check(strings.Contains(w.Body.String(), `value="basic.commandprefix"`), true, t)
+	csrf := c.csrf("/dashboard/10")
+
+	Check(c.do("POST", "/dashboard/10", url.Values{"csrf": {"wrong"}, "option": {"basic.commandprefix"}, "value": {"?"}}).Code, http.StatusForbidden, t)
+	Check(info.Config.Basic.CommandPrefix, "!", t)
+	Check(c.do("POST", "/dashboard/10", url.Values{"csrf": {csrf}, "option": {"basic.commandprefix"}, "value": {"?"}}).Code, http.StatusOK, t)
+	Check(info.Config.Basic.CommandPrefix, "?", t)
+	Check(c.do("POST", "/dashboard/10", url.Values{"csrf": {csrf}, "option": {"spam.maxpressure"}, "value": {"lots"}}).Code, http.StatusBadRequest, t)
+	Check(c.do("POST", "/dashboard/10", url.Values{"csrf": {csrf}, "option": {"basic.freechannels"}, "value": {"#general"}}).Code, http.StatusOK, t)
+	_, ok = info.Config.Basic.FreeChannels["12"]
+	Check(ok, true, t)
+	Check(c.do("POST", "/dashboard/10", url.Values{"csrf": {csrf}, "option": {"spam.maxchannelpressure"}, "key": {"#general"}, "value": {"30"}}).Code, http.StatusOK, t)
+	Check(info.Config.Spam.MaxChannelPressure["12"], float32(30), t)
+	Check(strings.Contains(c.do("GET", "/dashboard/10", nil).Body.String(), "&lt;#12&gt;"), true, t) // Lists are filled in with the values !setconfig expects
+	if changes := db.GetConfigHistory(10, "", 10, 0); len(changes) < 3 {
+		t.Errorf("expected the changes to be in the config history, but found %v", len(changes))
+	}
+
+	// A change that can't be saved is reported as an error and undone
+	sb.MaxConfigSize = 1
+	w = c.do("POST", "/dashboard/10", url.Values{"csrf": {csrf}, "option": {"basic.commandprefix"}, "value": {"$"}})
+	Check(w.Code, http.StatusInternalServerError, t)
+	Check(strings.Contains(w.Body.String(), "Error saving config"), true, t)
+	Check(info.Config.Basic.CommandPrefix, "?", t)
+	sb.MaxConfigSize = 1000000
+
+	// Moderators can only use the dashboard if they could also run !setconfig
+	info.Config.Modules.CommandDisabled = map[CommandID]bool{"setconfig": true}
+	Check(c.do("GET", "/dashboard/10", nil).Code, http.StatusNotFound, t)
+	Check(c.do("POST", "/dashboard/10", url.Values{"csrf": {csrf}, "option": {"basic.commandprefix"}, "value": {"$"}}).Code, http.StatusNotFound, t)
+	Check(info.Config.Basic.CommandPrefix, "?", t)
+	info.Config.Modules.CommandDisabled = nil
+	info.Config.Modules.CommandRoles = map[CommandID]map[DiscordRole]bool{"setconfig": {"10": true}}
+	Check(strings.Contains(c.do("GET", "/dashboard", nil).Body.String(), `href="/dashboard/10"`), false, t)
+	info.Config.Modules.CommandRoles = nil
+	Check(c.do("GET", "/dashboard/10", nil).Code, http.StatusOK, t)
+
+	w = c.do("POST", "/dashboard/logout", url.Values{"csrf": {csrf}})
+	Check(w.Code, http.StatusSeeOther, t)
+	Check(c.do("GET", "/dashboard/10", nil).Code, http.StatusSeeOther, t)
+}
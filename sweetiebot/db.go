@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"4d63.com/tz"
@@ -18,15 +19,19 @@ var ErrDuplicateEntry = errors.New("Error 1062: Duplicate entry for unique key")
 // ErrLockWaitTimeout - Error 1205: Lock wait timeout exceeded
 var ErrLockWaitTimeout = errors.New("Error 1205: Lock wait timeout exceeded")
 
+// ErrConfigConflict - The stored config was modified since it was last loaded
+var ErrConfigConflict = errors.New("Config was modified by another process")
+
 // BotDB contains the database connection and all database Prepared statements exposed as functions
 type BotDB struct {
 	db                        *sql.DB
 	Status                    AtomicBool
 	lastattempt               time.Time
-	log                       logger
+	log                       *Logger
 	driver                    string
 	conn                      string
 	statuslock                AtomicFlag
+	lite                      *sqliteStatements
 	sqlAddMessage             *sql.Stmt
 	sqlAddUser                *sql.Stmt
 	sqlAddMember              *sql.Stmt
@@ -93,10 +98,42 @@ type BotDB struct {
 	sqlGetTags                *sql.Stmt
 	sqlImportTag              *sql.Stmt
 	sqlRemoveGuild            *sql.Stmt
-}
-
-func dbLoad(log logger, driver string, conn string) (*BotDB, error) {
-	cdb, err := sql.Open(driver, conn)
+	sqlGetConfig              *sql.Stmt
+	sqlAddConfig              *sql.Stmt
+	sqlUpdateConfig           *sql.Stmt
+	sqlRemoveConfig           *sql.Stmt
+	sqlSetConfigExpiry        *sql.Stmt
+	sqlInitConfigExpiry       *sql.Stmt
+	sqlGetExpiredConfigs      *sql.Stmt
+	sqlAddConfigHistory       *sql.Stmt
+	sqlGetConfigHistory       *sql.Stmt
+	sqlGetConfigHistoryPath   *sql.Stmt
+	sqlGetConfigChange        *sql.Stmt
+	sqlGetConfigChangesSince  *sql.Stmt
+	sqlRemoveConfigHistory    *sql.Stmt
+	sqlSetCustomCommand       *sql.Stmt
+	sqlRemoveCustomCommand    *sql.Stmt
+	sqlGetCustomCommands      *sql.Stmt
+	sqlRemoveCustomCommands   *sql.Stmt
+	sqlGetTagItems            *sql.Stmt
+	sqlAddAPIToken            *sql.Stmt
+	sqlRemoveAPIToken         *sql.Stmt
+	sqlGetAPITokens           *sql.Stmt
+	sqlGetAPIToken            *sql.Stmt
+	sqlRemoveAPITokens        *sql.Stmt
+	sqlSetModuleState         *sql.Stmt
+	sqlGetModuleState         *sql.Stmt
+	sqlRemoveModuleStates     *sql.Stmt
+	sqlSetModuleData          *sql.Stmt
+	sqlGetModuleData          *sql.Stmt
+	sqlRemoveModuleData       *sql.Stmt
+	sqlListModuleData         *sql.Stmt
+	sqlRemoveExpiredData      *sql.Stmt
+	sqlRemoveGuildModuleData  *sql.Stmt
+}
+
+func dbLoad(log *Logger, driver string, conn string) (*BotDB, error) {
+	cdb, err := openMetricsDB(driver, conn)
 	r := BotDB{
 		db:          cdb,
 		lastattempt: time.Now().UTC(),
@@ -109,7 +146,11 @@ func dbLoad(log logger, driver string, conn string) (*BotDB, error) {
 		return &r, err
 	}
 
-	r.db.SetMaxOpenConns(70)
+	if driver == DriverSQLite {
+		r.db.SetMaxOpenConns(1) // SQLite only allows a single writer, and an in-memory database only exists on one connection
+	} else {
+		r.db.SetMaxOpenConns(70)
+	}
 	err = r.db.Ping()
 	r.Status.Set(err == nil)
 	return &r, err
@@ -135,14 +176,14 @@ func (db *BotDB) standardErr(err error) error {
 			return ErrLockWaitTimeout
 		}
 	}
-	return err
+	return db.sqliteErr(err)
 }
 
 // Prepare a sql statement and logs an error if it fails
 func (db *BotDB) Prepare(s string) (*sql.Stmt, error) {
 	statement, err := db.db.Prepare(s)
 	if err != nil {
-		fmt.Println("Preparing: ", s, "\nSQL Error: ", err.Error())
+		db.log.Error("Failed to prepare SQL statement: "+err.Error(), LogFields{"sql": s})
 	}
 	return statement, err
 }
@@ -163,7 +204,7 @@ func (db *BotDB) CheckStatus() bool {
 		}
 
 		if db.lastattempt.Add(DBReconnectTimeout).Before(time.Now().UTC()) {
-			db.log.Log("Database failure detected! Attempting to reboot database connection...")
+			db.log.Warn("Database failure detected! Attempting to reboot database connection...")
 			db.lastattempt = time.Now().UTC()
 			err := db.db.Ping()
 			if err != nil {
@@ -173,7 +214,7 @@ func (db *BotDB) CheckStatus() bool {
 			err = db.LoadStatements()                       // If we re-establish connection, we must reload statements in case they were lost or never loaded in the first place
 			db.log.LogError("LoadStatements failed: ", err) // if loading the statements fails we're screwed anyway so we just log the error and keep going
 			db.Status.Set(true)                             // Only after loading the statements do we set status to true
-			db.log.Log("Reconnection succeeded, exiting out of No Database mode.")
+			db.log.Info("Reconnection succeeded, exiting out of No Database mode.")
 		} else { // If not, just fail
 			return false
 		}
@@ -184,6 +225,9 @@ func (db *BotDB) CheckStatus() bool {
 
 // LoadStatements loads all Prepared statements
 func (db *BotDB) LoadStatements() error {
+	if db.driver == DriverSQLite {
+		return db.loadSQLiteStatements()
+	}
 	var err error
 	db.sqlAddMessage, err = db.Prepare("CALL AddChat(?,?,?,?,?,?)")
 	db.sqlAddUser, err = db.Prepare("CALL AddUser(?,?,?,?)")
@@ -250,6 +294,38 @@ func (db *BotDB) LoadStatements() error {
 	db.sqlGetTags, err = db.Prepare("SELECT T.Name, COUNT(M.Item) FROM tags T LEFT OUTER JOIN itemtags M ON T.ID = M.Tag WHERE T.Guild = ? GROUP BY T.Name")
 	db.sqlImportTag, err = db.Prepare("INSERT IGNORE INTO itemtags (Item, Tag) SELECT Item, ? FROM itemtags WHERE `Tag` = ?")
 	db.sqlRemoveGuild, err = db.Prepare("CALL RemoveGuild(?)")
+	db.sqlGetConfig, err = db.Prepare("SELECT Config, Version FROM guild_config WHERE Guild = ?")
+	db.sqlAddConfig, err = db.Prepare("INSERT INTO guild_config (Guild, Version, Expires, Updated, Config) VALUES (?, 1, ?, UTC_TIMESTAMP(), ?)")
+	db.sqlUpdateConfig, err = db.Prepare("UPDATE guild_config SET Config = ?, Version = Version + 1, Updated = UTC_TIMESTAMP() WHERE Guild = ? AND Version = ?")
+	db.sqlRemoveConfig, err = db.Prepare("DELETE FROM guild_config WHERE Guild = ?")
+	db.sqlSetConfigExpiry, err = db.Prepare("UPDATE guild_config SET Expires = ? WHERE Guild = ?")
+	db.sqlInitConfigExpiry, err = db.Prepare("UPDATE guild_config SET Expires = ? WHERE Expires = 0")
+	db.sqlGetExpiredConfigs, err = db.Prepare("SELECT Guild FROM guild_config WHERE Expires > 0 AND Expires < ?")
+	db.sqlAddConfigHistory, err = db.Prepare("INSERT INTO config_history (Guild, User, Timestamp, Path, OldValue, NewValue) VALUES (?, ?, UTC_TIMESTAMP(), ?, ?, ?)")
+	db.sqlGetConfigHistory, err = db.Prepare("SELECT ID, User, Timestamp, Path, OldValue, NewValue FROM config_history WHERE Guild = ? ORDER BY ID DESC LIMIT ? OFFSET ?")
+	db.sqlGetConfigHistoryPath, err = db.Prepare("SELECT ID, User, Timestamp, Path, OldValue, NewValue FROM config_history WHERE Guild = ? AND Path = ? ORDER BY ID DESC LIMIT ? OFFSET ?")
+	db.sqlGetConfigChange, err = db.Prepare("SELECT ID, User, Timestamp, Path, OldValue, NewValue FROM config_history WHERE Guild = ? AND ID = ?")
+	db.sqlGetConfigChangesSince, err = db.Prepare("SELECT ID, User, Timestamp, Path, OldValue, NewValue FROM config_history WHERE Guild = ? AND ID >= ? ORDER BY ID DESC")
+	db.sqlRemoveConfigHistory, err = db.Prepare("DELETE FROM config_history WHERE Guild = ?")
+	db.sqlSetCustomCommand, err = db.Prepare("INSERT INTO custom_commands (Guild, Name, Response, Author, Updated) VALUES (?, ?, ?, ?, UTC_TIMESTAMP()) ON DUPLICATE KEY UPDATE Response = VALUES(Response), Author = VALUES(Author), Updated = UTC_TIMESTAMP()")
+	db.sqlRemoveCustomCommand, err = db.Prepare("DELETE FROM custom_commands WHERE Guild = ? AND Name = ?")
+	db.sqlGetCustomCommands, err = db.Prepare("SELECT Name, Response, Author, Updated FROM custom_commands WHERE Guild = ? ORDER BY Name")
+	db.sqlRemoveCustomCommands, err = db.Prepare("DELETE FROM custom_commands WHERE Guild = ?")
+	db.sqlGetTagItems, err = db.Prepare("SELECT I.ID, I.Content FROM itemtags M INNER JOIN items I ON M.Item = I.ID WHERE M.Tag = ? ORDER BY I.ID LIMIT ? OFFSET ?")
+	db.sqlAddAPIToken, err = db.Prepare("INSERT INTO api_tokens (Guild, Name, Hash, Author, Created) VALUES (?, ?, ?, ?, UTC_TIMESTAMP())")
+	db.sqlRemoveAPIToken, err = db.Prepare("DELETE FROM api_tokens WHERE Guild = ? AND Name = ?")
+	db.sqlGetAPITokens, err = db.Prepare("SELECT Name, Author, Created FROM api_tokens WHERE Guild = ? ORDER BY Name")
+	db.sqlGetAPIToken, err = db.Prepare("SELECT Guild, Name FROM api_tokens WHERE Hash = ?")
+	db.sqlRemoveAPITokens, err = db.Prepare("DELETE FROM api_tokens WHERE Guild = ?")
+	db.sqlSetModuleState, err = db.Prepare("INSERT INTO module_state (Guild, Module, State, Updated) VALUES (?, ?, ?, UTC_TIMESTAMP()) ON DUPLICATE KEY UPDATE State = VALUES(State), Updated = UTC_TIMESTAMP()")
+	db.sqlGetModuleState, err = db.Prepare("SELECT State FROM module_state WHERE Guild = ? AND Module = ?")
+	db.sqlRemoveModuleStates, err = db.Prepare("DELETE FROM module_state WHERE Guild = ?")
+	db.sqlSetModuleData, err = db.Prepare("INSERT INTO module_data (Guild, Module, Name, Value, Expires) VALUES (?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE Value = VALUES(Value), Expires = VALUES(Expires)")
+	db.sqlGetModuleData, err = db.Prepare("SELECT Value FROM module_data WHERE Guild = ? AND Module = ? AND Name = ? AND (Expires = 0 OR Expires > ?)")
+	db.sqlRemoveModuleData, err = db.Prepare("DELETE FROM module_data WHERE Guild = ? AND Module = ? AND Name = ?")
+	db.sqlListModuleData, err = db.Prepare("SELECT Name, Value, Expires FROM module_data WHERE Guild = ? AND Module = ? AND Name >= ? AND Name < ? AND (Expires = 0 OR Expires > ?) ORDER BY Name LIMIT ?")
+	db.sqlRemoveExpiredData, err = db.Prepare("DELETE FROM module_data WHERE Expires > 0 AND Expires <= ?")
+	db.sqlRemoveGuildModuleData, err = db.Prepare("DELETE FROM module_data WHERE Guild = ?")
 	return err
 }
 
@@ -280,6 +356,9 @@ func (db *BotDB) CheckError(name string, err error) error {
 			db.log.LogError(name+" error: ", err)
 		}
 		if db.db.Ping() != nil {
+			if db.Status.Get() {
+				metricDBOutages.Inc()
+			}
 			db.Status.Set(false)
 		}
 	}
@@ -288,7 +367,12 @@ func (db *BotDB) CheckError(name string, err error) error {
 
 // AddMessage logs a message to the chatlog
 func (db *BotDB) AddMessage(id uint64, author *discordgo.User, message string, channel uint64, guild uint64) {
-	_, err := db.sqlAddMessage.Exec(id, SBatoi(author.ID), author.Username, message, channel, guild)
+	var err error
+	if db.lite != nil {
+		err = db.lite.addChat(db, id, SBatoi(author.ID), author.Username, message, channel, guild)
+	} else {
+		_, err = db.sqlAddMessage.Exec(id, SBatoi(author.ID), author.Username, message, channel, guild)
+	}
 	db.CheckError("AddMessage", err)
 }
 
@@ -301,7 +385,12 @@ type PingContext struct {
 
 // AddUser adds or updates user information
 func (db *BotDB) AddUser(id uint64, username string, discriminator int, isonline bool) {
-	_, err := db.sqlAddUser.Exec(id, username, discriminator, isonline)
+	var err error
+	if db.lite != nil {
+		err = db.lite.addUser(db, id, username, discriminator, isonline)
+	} else {
+		_, err = db.sqlAddUser.Exec(id, username, discriminator, isonline)
+	}
 	db.CheckError("AddUser", err)
 }
 
@@ -477,7 +566,7 @@ func (db *BotDB) Audit(ty uint8, user *discordgo.User, message string, guild uin
 	}
 
 	if err != nil && db.Status.Get() {
-		fmt.Println("Logger failed to log to database! ", err.Error())
+		db.log.Error("Failed to write to the audit log: " + err.Error())
 	}
 }
 
@@ -617,7 +706,12 @@ func (db *BotDB) CountNewUsers(seconds int64, guild uint64) int {
 
 // RemoveSchedule removes the event with the given ID and creates a new one after the repeat interval
 func (db *BotDB) RemoveSchedule(id uint64) error {
-	_, err := db.sqlRemoveSchedule.Exec(id)
+	var err error
+	if db.lite != nil {
+		err = db.lite.removeSchedule(db, id)
+	} else {
+		_, err = db.sqlRemoveSchedule.Exec(id)
+	}
 	return db.CheckError("RemoveSchedule", err)
 }
 
@@ -1015,6 +1109,30 @@ func (db *BotDB) GetTags(guild uint64) []struct {
 	return r
 }
 
+// TagItem is an item with the given tag
+type TagItem struct {
+	ID      uint64
+	Content string
+}
+
+// GetTagItems returns up to maxnum items with the given tag, sorted by ID and skipping the first offset items
+func (db *BotDB) GetTagItems(tag uint64, maxnum int, offset int) []TagItem {
+	q, err := db.sqlGetTagItems.Query(tag, maxnum, offset)
+	err = db.standardErr(err)
+	if db.CheckError("GetTagItems", err) != nil {
+		return []TagItem{}
+	}
+	defer q.Close()
+	r := make([]TagItem, 0, maxnum)
+	for q.Next() {
+		p := TagItem{}
+		if err := q.Scan(&p.ID, &p.Content); err == nil {
+			r = append(r, p)
+		}
+	}
+	return r
+}
+
 // ImportTag imports a tag from one server to another
 func (db *BotDB) ImportTag(srcTag uint64, destTag uint64) error {
 	_, err := db.sqlImportTag.Exec(destTag, srcTag)
@@ -1025,7 +1143,345 @@ func (db *BotDB) ImportTag(srcTag uint64, destTag uint64) error {
 
 // RemoveGuild removes the given guild from the database, if it exists
 func (db *BotDB) RemoveGuild(guild uint64) error {
-	_, err := db.sqlRemoveGuild.Exec(guild)
+	var err error
+	if db.lite != nil {
+		err = db.lite.removeGuild(db, guild)
+	} else {
+		_, err = db.sqlRemoveGuild.Exec(guild)
+	}
 	err = db.standardErr(err)
 	return db.CheckError("RemoveGuild", err)
 }
+
+// GetConfig returns the stored config for a guild along with its version, or sql.ErrNoRows if the guild has no config
+func (db *BotDB) GetConfig(guild uint64) ([]byte, uint64, error) {
+	var config []byte
+	var version uint64
+	err := db.sqlGetConfig.QueryRow(guild).Scan(&config, &version)
+	if err == sql.ErrNoRows {
+		return nil, 0, err
+	}
+	return config, version, db.CheckError("GetConfig", err)
+}
+
+// SaveConfig stores a guild config if the stored version still matches the given version, and returns the new version.
+// A version of 0 means the config has never been saved. If another process already changed the config, returns ErrConfigConflict.
+// On any error the returned version is 0 and nothing was saved.
+func (db *BotDB) SaveConfig(guild uint64, config []byte, expires int64, version uint64) (uint64, error) {
+	if version == 0 {
+		_, err := db.sqlAddConfig.Exec(guild, expires, config)
+		err = db.standardErr(err)
+		if err == ErrDuplicateEntry {
+			return 0, ErrConfigConflict
+		}
+		if err = db.CheckError("SaveConfig", err); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+	r, err := db.sqlUpdateConfig.Exec(config, guild, version)
+	if err = db.CheckError("SaveConfig", db.standardErr(err)); err != nil {
+		return 0, err
+	}
+	if n, err := r.RowsAffected(); err == nil && n == 0 {
+		return 0, ErrConfigConflict
+	}
+	return version + 1, nil
+}
+
+// RemoveConfig deletes the stored config for a guild along with its change history, custom commands, API tokens and module state and data
+func (db *BotDB) RemoveConfig(guild uint64) error {
+	_, err := db.sqlRemoveConfigHistory.Exec(guild)
+	if err == nil {
+		_, err = db.sqlRemoveCustomCommands.Exec(guild)
+	}
+	if err == nil {
+		_, err = db.sqlRemoveAPITokens.Exec(guild)
+	}
+	if err == nil {
+		_, err = db.sqlRemoveModuleStates.Exec(guild)
+	}
+	if err == nil {
+		_, err = db.sqlRemoveGuildModuleData.Exec(guild)
+	}
+	if err == nil {
+		_, err = db.sqlRemoveConfig.Exec(guild)
+	}
+	return db.CheckError("RemoveConfig", db.standardErr(err))
+}
+
+// SetConfigExpiry sets the unix timestamp after which the guild config expires, if the bot is no longer in the guild
+func (db *BotDB) SetConfigExpiry(guild uint64, expires int64) error {
+	_, err := db.sqlSetConfigExpiry.Exec(expires, guild)
+	return db.CheckError("SetConfigExpiry", db.standardErr(err))
+}
+
+// InitConfigExpiry sets the expiry time of all configs that don't have one yet
+func (db *BotDB) InitConfigExpiry(expires int64) error {
+	_, err := db.sqlInitConfigExpiry.Exec(expires)
+	return db.CheckError("InitConfigExpiry", db.standardErr(err))
+}
+
+// GetExpiredConfigs returns all guilds whose config expired before the given unix timestamp
+func (db *BotDB) GetExpiredConfigs(now int64) []uint64 {
+	q, err := db.sqlGetExpiredConfigs.Query(now)
+	if db.CheckError("GetExpiredConfigs", err) != nil {
+		return []uint64{}
+	}
+	defer q.Close()
+	r := make([]uint64, 0, 4)
+	for q.Next() {
+		var p uint64
+		if err := q.Scan(&p); err == nil {
+			r = append(r, p)
+		}
+	}
+	return r
+}
+
+// ConfigChange is a single recorded change to a guild config option
+type ConfigChange struct {
+	ID        uint64
+	User      uint64
+	Timestamp time.Time
+	Path      string
+	Old       string
+	New       string
+}
+
+func (db *BotDB) parseConfigChanges(q *sql.Rows) []ConfigChange {
+	defer q.Close()
+	r := make([]ConfigChange, 0, 4)
+	for q.Next() {
+		p := ConfigChange{}
+		if err := q.Scan(&p.ID, &p.User, &p.Timestamp, &p.Path, &p.Old, &p.New); err == nil {
+			r = append(r, p)
+		}
+	}
+	return r
+}
+
+// AddConfigHistory records a change to a config option
+func (db *BotDB) AddConfigHistory(guild uint64, user uint64, path string, old string, new string) error {
+	_, err := db.sqlAddConfigHistory.Exec(guild, user, path, old, new)
+	return db.CheckError("AddConfigHistory", db.standardErr(err))
+}
+
+// GetConfigHistory returns the most recent config changes for a guild, optionally restricted to a single option
+func (db *BotDB) GetConfigHistory(guild uint64, path string, maxresults uint64, offset uint64) []ConfigChange {
+	var q *sql.Rows
+	var err error
+	if len(path) > 0 {
+		q, err = db.sqlGetConfigHistoryPath.Query(guild, path, maxresults, offset)
+	} else {
+		q, err = db.sqlGetConfigHistory.Query(guild, maxresults, offset)
+	}
+	if db.CheckError("GetConfigHistory", err) != nil {
+		return []ConfigChange{}
+	}
+	return db.parseConfigChanges(q)
+}
+
+// GetConfigChange returns a single config change, or nil if it doesn't exist
+func (db *BotDB) GetConfigChange(guild uint64, id uint64) *ConfigChange {
+	p := &ConfigChange{}
+	err := db.sqlGetConfigChange.QueryRow(guild, id).Scan(&p.ID, &p.User, &p.Timestamp, &p.Path, &p.Old, &p.New)
+	if err == sql.ErrNoRows || db.CheckError("GetConfigChange", err) != nil {
+		return nil
+	}
+	return p
+}
+
+// GetConfigChangesSince returns all config changes starting at the given ID, newest first
+func (db *BotDB) GetConfigChangesSince(guild uint64, id uint64) []ConfigChange {
+	q, err := db.sqlGetConfigChangesSince.Query(guild, id)
+	if db.CheckError("GetConfigChangesSince", err) != nil {
+		return []ConfigChange{}
+	}
+	return db.parseConfigChanges(q)
+}
+
+// CustomCommand is a guild-specific command that responds with a templated message
+type CustomCommand struct {
+	Name     string
+	Response string
+	Author   uint64
+	Updated  time.Time
+}
+
+// SetCustomCommand creates a custom command, or replaces the response of an existing one
+func (db *BotDB) SetCustomCommand(guild uint64, name string, response string, author uint64) error {
+	_, err := db.sqlSetCustomCommand.Exec(guild, name, response, author)
+	return db.CheckError("SetCustomCommand", db.standardErr(err))
+}
+
+// RemoveCustomCommand deletes a custom command, returning sql.ErrNoRows if it didn't exist
+func (db *BotDB) RemoveCustomCommand(guild uint64, name string) error {
+	r, err := db.sqlRemoveCustomCommand.Exec(guild, name)
+	if err == nil {
+		if n, e := r.RowsAffected(); e == nil && n == 0 {
+			return sql.ErrNoRows
+		}
+	}
+	return db.CheckError("RemoveCustomCommand", db.standardErr(err))
+}
+
+// GetCustomCommands returns all custom commands for a guild, sorted by name
+func (db *BotDB) GetCustomCommands(guild uint64) []CustomCommand {
+	q, err := db.sqlGetCustomCommands.Query(guild)
+	if db.CheckError("GetCustomCommands", err) != nil {
+		return []CustomCommand{}
+	}
+	defer q.Close()
+	r := make([]CustomCommand, 0, 4)
+	for q.Next() {
+		p := CustomCommand{}
+		if err := q.Scan(&p.Name, &p.Response, &p.Author, &p.Updated); err == nil {
+			r = append(r, p)
+		}
+	}
+	return r
+}
+
+// APIToken describes an API token without revealing the token itself, which is only stored as a hash
+type APIToken struct {
+	Name    string
+	Author  uint64
+	Created time.Time
+}
+
+// AddAPIToken stores the hash of a new API token for the guild
+func (db *BotDB) AddAPIToken(guild uint64, name string, hash string, author uint64) error {
+	_, err := db.sqlAddAPIToken.Exec(guild, name, hash, author)
+	return db.CheckError("AddAPIToken", db.standardErr(err))
+}
+
+// RemoveAPIToken revokes an API token, returning sql.ErrNoRows if it didn't exist
+func (db *BotDB) RemoveAPIToken(guild uint64, name string) error {
+	r, err := db.sqlRemoveAPIToken.Exec(guild, name)
+	if err == nil {
+		if n, e := r.RowsAffected(); e == nil && n == 0 {
+			return sql.ErrNoRows
+		}
+	}
+	return db.CheckError("RemoveAPIToken", db.standardErr(err))
+}
+
+// GetAPITokens returns all API tokens for a guild, sorted by name
+func (db *BotDB) GetAPITokens(guild uint64) []APIToken {
+	q, err := db.sqlGetAPITokens.Query(guild)
+	if db.CheckError("GetAPITokens", err) != nil {
+		return []APIToken{}
+	}
+	defer q.Close()
+	r := make([]APIToken, 0, 2)
+	for q.Next() {
+		p := APIToken{}
+		if err := q.Scan(&p.Name, &p.Author, &p.Created); err == nil {
+			r = append(r, p)
+		}
+	}
+	return r
+}
+
+// GetAPIToken returns the guild and name of the API token with the given hash, or sql.ErrNoRows if there isn't one
+func (db *BotDB) GetAPIToken(hash string) (uint64, string, error) {
+	var guild uint64
+	var name string
+	err := db.sqlGetAPIToken.QueryRow(hash).Scan(&guild, &name)
+	if err == sql.ErrNoRows {
+		return 0, "", err
+	}
+	return guild, name, db.CheckError("GetAPIToken", err)
+}
+
+// SetModuleState stores a snapshot of a module's in-memory state for the guild, replacing any previous one
+func (db *BotDB) SetModuleState(guild uint64, module string, state []byte) error {
+	_, err := db.sqlSetModuleState.Exec(guild, module, state)
+	return db.CheckError("SetModuleState", db.standardErr(err))
+}
+
+// GetModuleState returns the last snapshot of a module's state for the guild, or sql.ErrNoRows if there isn't one
+func (db *BotDB) GetModuleState(guild uint64, module string) ([]byte, error) {
+	var state []byte
+	err := db.sqlGetModuleState.QueryRow(guild, module).Scan(&state)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	return state, db.CheckError("GetModuleState", err)
+}
+
+// MaxModuleDataKey is the longest key, in bytes, that can be stored in module data
+const MaxModuleDataKey = 255
+
+// ModuleData is a key and value stored by a module
+type ModuleData struct {
+	Key     string
+	Value   []byte
+	Expires int64 // Unix timestamp after which the value is deleted, or 0 if it never expires
+}
+
+// SetModuleData stores a value under a key in the module's data for the guild, replacing any previous value
+func (db *BotDB) SetModuleData(guild uint64, module string, key string, value []byte, expires int64) error {
+	_, err := db.sqlSetModuleData.Exec(guild, module, key, value, expires)
+	return db.CheckError("SetModuleData", db.standardErr(err))
+}
+
+// GetModuleData returns the value stored under a key in the module's data for the guild, or sql.ErrNoRows if there
+// isn't one or it expired before the given unix timestamp
+func (db *BotDB) GetModuleData(guild uint64, module string, key string, now int64) ([]byte, error) {
+	var value []byte
+	err := db.sqlGetModuleData.QueryRow(guild, module, key, now).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	return value, db.CheckError("GetModuleData", err)
+}
+
+// RemoveModuleData deletes a key from the module's data for the guild, returning sql.ErrNoRows if it didn't exist
+func (db *BotDB) RemoveModuleData(guild uint64, module string, key string) error {
+	r, err := db.sqlRemoveModuleData.Exec(guild, module, key)
+	if err == nil {
+		if n, e := r.RowsAffected(); e == nil && n == 0 {
+			return sql.ErrNoRows
+		}
+	}
+	return db.CheckError("RemoveModuleData", db.standardErr(err))
+}
+
+// ListModuleData returns up to maxresults keys that start with prefix in the module's data for the guild, sorted by
+// key, leaving out any that expired before the given unix timestamp
+func (db *BotDB) ListModuleData(guild uint64, module string, prefix string, now int64, maxresults int) ([]ModuleData, error) {
+	q, err := db.sqlListModuleData.Query(guild, module, prefix, prefixEnd(prefix), now, maxresults)
+	if db.CheckError("ListModuleData", err) != nil {
+		return nil, err
+	}
+	defer q.Close()
+	r := make([]ModuleData, 0, 4)
+	for q.Next() {
+		p := ModuleData{}
+		if err := q.Scan(&p.Key, &p.Value, &p.Expires); err == nil {
+			r = append(r, p)
+		}
+	}
+	return r, nil
+}
+
+// RemoveExpiredModuleData deletes all module data that expired before the given unix timestamp
+func (db *BotDB) RemoveExpiredModuleData(now int64) error {
+	_, err := db.sqlRemoveExpiredData.Exec(now)
+	return db.CheckError("RemoveExpiredModuleData", db.standardErr(err))
+}
+
+// prefixEnd returns the smallest string that sorts after every string starting with prefix, so keys can be matched
+// by prefix with a range query instead of LIKE, which is case insensitive and treats % and _ as wildcards
+func prefixEnd(prefix string) string {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1])
+		}
+	}
+	return strings.Repeat("\xff", MaxModuleDataKey+1) // Sorts after every key that can be stored
+}
@@ -0,0 +1,217 @@
+package sweetiebot
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+)
+
+var errNamedParameters = errors.New("this database driver doesn't support named parameters")
+
+// openMetricsDB opens a database whose driver connections time every query for the db_query_duration_seconds metric.
+// Optional driver interfaces are forwarded, so the driver behaves exactly as it would if it wasn't wrapped.
+func openMetricsDB(driverName string, conn string) (*sql.DB, error) {
+	db, err := sql.Open(driverName, conn)
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	db.Close() // sql.Open doesn't connect to anything, so this is only used to look up the driver
+	var connector driver.Connector
+	if dc, ok := d.(driver.DriverContext); ok {
+		if connector, err = dc.OpenConnector(conn); err != nil {
+			return nil, err
+		}
+	} else {
+		connector = &dsnConnector{conn, d}
+	}
+	return sql.OpenDB(&metricsConnector{connector}), nil
+}
+
+// statementType returns the first keyword of a query, which is used to label its latency
+func statementType(query string) string {
+	query = strings.TrimSpace(query)
+	if i := strings.IndexAny(query, " \t\r\n("); i >= 0 {
+		query = query[:i]
+	}
+	switch s := strings.ToLower(query); s {
+	case "select", "insert", "update", "delete", "replace", "call", "with":
+		return s
+	}
+	return "other"
+}
+
+func observeQuery(statement string, start time.Time) {
+	metricDBQueryDuration.WithLabelValues(statement).Observe(time.Since(start).Seconds())
+}
+
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c *dsnConnector) Connect(ctx context.Context) (driver.Conn, error) { return c.driver.Open(c.dsn) }
+func (c *dsnConnector) Driver() driver.Driver                            { return c.driver }
+
+type metricsConnector struct {
+	connector driver.Connector
+}
+
+func (c *metricsConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsConn{conn}, nil
+}
+func (c *metricsConnector) Driver() driver.Driver { return c.connector.Driver() }
+
+type metricsConn struct {
+	conn driver.Conn
+}
+
+func (c *metricsConn) Prepare(query string) (driver.Stmt, error) {
+	s, err := c.conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsStmt{s, c, statementType(query)}, nil
+}
+
+func (c *metricsConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	pc, ok := c.conn.(driver.ConnPrepareContext)
+	if !ok {
+		return c.Prepare(query)
+	}
+	s, err := pc.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsStmt{s, c, statementType(query)}, nil
+}
+
+func (c *metricsConn) Close() error { return c.conn.Close() }
+
+func (c *metricsConn) Begin() (driver.Tx, error) {
+	return c.conn.Begin()
+}
+
+func (c *metricsConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bt, ok := c.conn.(driver.ConnBeginTx); ok {
+		return bt.BeginTx(ctx, opts)
+	}
+	return c.Begin()
+}
+
+func (c *metricsConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip // database/sql falls back to preparing the statement
+	}
+	defer observeQuery(statementType(query), time.Now())
+	return e.ExecContext(ctx, query, args)
+}
+
+func (c *metricsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	defer observeQuery(statementType(query), time.Now())
+	return q.QueryContext(ctx, query, args)
+}
+
+func (c *metricsConn) Ping(ctx context.Context) error {
+	if p, ok := c.conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *metricsConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *metricsConn) IsValid() bool {
+	if v, ok := c.conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *metricsConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip // Use the default conversion
+}
+
+type metricsStmt struct {
+	stmt      driver.Stmt
+	conn      *metricsConn
+	statement string
+}
+
+func (s *metricsStmt) Close() error  { return s.stmt.Close() }
+func (s *metricsStmt) NumInput() int { return s.stmt.NumInput() }
+
+func (s *metricsStmt) Exec(args []driver.Value) (driver.Result, error) {
+	defer observeQuery(s.statement, time.Now())
+	return s.stmt.Exec(args)
+}
+
+func (s *metricsStmt) Query(args []driver.Value) (driver.Rows, error) {
+	defer observeQuery(s.statement, time.Now())
+	return s.stmt.Query(args)
+}
+
+func (s *metricsStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := s.stmt.(driver.StmtExecContext)
+	if !ok {
+		values, err := namedValues(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.Exec(values)
+	}
+	defer observeQuery(s.statement, time.Now())
+	return e.ExecContext(ctx, args)
+}
+
+func (s *metricsStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := s.stmt.(driver.StmtQueryContext)
+	if !ok {
+		values, err := namedValues(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.Query(values)
+	}
+	defer observeQuery(s.statement, time.Now())
+	return q.QueryContext(ctx, args)
+}
+
+// CheckNamedValue mirrors database/sql, which prefers the statement's value checker over the connection's
+func (s *metricsStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := s.stmt.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return s.conn.CheckNamedValue(nv)
+}
+
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, v := range args {
+		if len(v.Name) > 0 {
+			return nil, errNamedParameters
+		}
+		values[i] = v.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,311 @@
+package sweetiebot
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"modernc.org/sqlite" // Pure go sqlite driver, registers itself as "sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Database drivers that can be selected by the dbauth connection string
+const (
+	DriverMySQL  = "mysql"
+	DriverSQLite = "sqlite"
+)
+
+// SQLiteSchemaFile is the schema that is loaded into a new SQLite database
+const SQLiteSchemaFile = "sweetiebot_sqlite.sql"
+
+// ParseDBAuth picks a driver based on the scheme of the dbauth string. "sqlite://path/to/file.db" or "sqlite::memory:"
+// selects the embedded SQLite driver, anything else is assumed to be a MySQL DSN.
+func ParseDBAuth(dbauth string) (driver string, conn string) {
+	dbauth = strings.TrimSpace(dbauth)
+	if strings.HasPrefix(dbauth, "sqlite://") {
+		return DriverSQLite, sqliteDSN(dbauth[len("sqlite://"):])
+	}
+	if strings.HasPrefix(dbauth, "sqlite:") {
+		return DriverSQLite, sqliteDSN(dbauth[len("sqlite:"):])
+	}
+	return DriverMySQL, dbauth
+}
+
+// sqliteDSN enables foreign keys and forces a time format that SQLite's date functions understand
+func sqliteDSN(path string) string {
+	query := url.Values{}
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		query, _ = url.ParseQuery(path[i+1:])
+		path = path[:i]
+	}
+	if _, ok := query["_time_format"]; !ok {
+		query.Set("_time_format", "sqlite")
+	}
+	query.Add("_pragma", "foreign_keys(1)")
+	return path + "?" + query.Encode()
+}
+
+// sqliteStatements holds the statements used to emulate the stored procedures that only exist in the MySQL schema
+type sqliteStatements struct {
+	addChatUser       *sql.Stmt
+	addChatAlias      *sql.Stmt
+	addChatMessage    *sql.Stmt
+	getUsername       *sql.Stmt
+	upsertUser        *sql.Stmt
+	extendAlias       *sql.Stmt
+	touchAlias        *sql.Stmt
+	removeFuture      *sql.Stmt
+	removeOnce        *sql.Stmt
+	repeatSchedule    *sql.Stmt
+	removeGuildTables []*sql.Stmt
+}
+
+// OpenSQLite connects to the SQLite database described by dbauth, initializes its schema from scriptdir and loads
+// all statements. This is mostly useful for tests that need a real in-memory database, like "sqlite::memory:".
+func OpenSQLite(dbauth string, scriptdir string) (*BotDB, error) {
+	driver, conn := ParseDBAuth(dbauth)
+	if driver != DriverSQLite {
+		return nil, errors.New(dbauth + " is not a SQLite database")
+	}
+	db, err := dbLoad(nil, driver, conn)
+	if err == nil {
+		err = db.InitSQLite(scriptdir)
+	}
+	if err == nil {
+		err = db.LoadStatements()
+	}
+	return db, err
+}
+
+// InitSQLite loads the SQLite schema and timezone data from scriptdir if the database is empty
+func (db *BotDB) InitSQLite(scriptdir string) error {
+	var n int
+	if err := db.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'").Scan(&n); err != nil || n > 0 {
+		return err
+	}
+	if err := ExecuteSQLFile(db.db, filepath.Join(scriptdir, SQLiteSchemaFile)); err != nil {
+		return err
+	}
+	return ExecuteSQLFile(db.db, filepath.Join(scriptdir, "sweetiebot_tz.sql"))
+}
+
+func (db *BotDB) sqliteErr(err error) error {
+	if liteErr, ok := err.(*sqlite.Error); ok {
+		switch liteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY, sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+			return ErrDuplicateEntry
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+			return ErrLockWaitTimeout
+		}
+	}
+	return err
+}
+
+func (db *BotDB) loadSQLiteStatements() error {
+	var err error
+	lite := &sqliteStatements{}
+	lite.addChatUser, err = db.Prepare("INSERT INTO users (ID, Username, LastSeen, LastNameChange) VALUES (?, ?, datetime('now'), datetime('now')) ON CONFLICT(ID) DO UPDATE SET LastSeen = datetime('now')")
+	lite.addChatAlias, err = db.Prepare("INSERT OR IGNORE INTO aliases (`User`, Alias, Duration, `Timestamp`) VALUES (?, ?, 0, datetime('now'))")
+	lite.addChatMessage, err = db.Prepare("INSERT INTO chatlog (ID, Author, Message, Timestamp, Channel, Guild) VALUES (?, ?, ?, datetime('now'), ?, ?) ON CONFLICT(ID) DO UPDATE SET Message = excluded.Message, Timestamp = datetime('now')")
+	lite.getUsername, err = db.Prepare("SELECT Username FROM users WHERE ID = ?")
+	lite.upsertUser, err = db.Prepare("INSERT INTO users (ID, Username, Discriminator, LastSeen, LastNameChange) VALUES (?1, ?2, ?3, datetime('now'), datetime('now')) ON CONFLICT(ID) DO UPDATE SET Username = CASE WHEN excluded.Username = '' THEN Username ELSE excluded.Username END, Discriminator = CASE WHEN excluded.Discriminator = 0 THEN Discriminator ELSE excluded.Discriminator END, LastSeen = CASE WHEN ?4 > 0 THEN datetime('now') ELSE LastSeen END, LastNameChange = CASE WHEN excluded.Username = '' OR excluded.Username = Username THEN LastNameChange ELSE datetime('now') END")
+	lite.extendAlias, err = db.Prepare("INSERT INTO aliases (`User`, Alias, Duration, `Timestamp`) VALUES (?, ?, 0, datetime('now')) ON CONFLICT(`User`, Alias) DO UPDATE SET Duration = Duration + (strftime('%s', 'now') - strftime('%s', `Timestamp`)), `Timestamp` = datetime('now')")
+	lite.touchAlias, err = db.Prepare("INSERT INTO aliases (`User`, Alias, Duration, `Timestamp`) VALUES (?, ?, 0, datetime('now')) ON CONFLICT(`User`, Alias) DO UPDATE SET `Timestamp` = datetime('now')")
+	lite.removeFuture, err = db.Prepare("DELETE FROM `schedule` WHERE ID = ? AND datetime(Date) > datetime('now')")
+	lite.removeOnce, err = db.Prepare("DELETE FROM `schedule` WHERE ID = ? AND `Repeat` IS NULL AND `RepeatInterval` IS NULL")
+	lite.repeatSchedule, err = db.Prepare("UPDATE `schedule` SET Date = CASE `RepeatInterval` WHEN 1 THEN datetime(Date, '+' || `Repeat` || ' seconds') WHEN 2 THEN datetime(Date, '+' || `Repeat` || ' minutes') WHEN 3 THEN datetime(Date, '+' || `Repeat` || ' hours') WHEN 4 THEN datetime(Date, '+' || `Repeat` || ' days') WHEN 5 THEN datetime(Date, '+' || (`Repeat` * 7) || ' days') WHEN 6 THEN datetime(Date, '+' || `Repeat` || ' months') WHEN 7 THEN datetime(Date, '+' || (`Repeat` * 3) || ' months') WHEN 8 THEN datetime(Date, '+' || `Repeat` || ' years') ELSE Date END WHERE ID = ?")
+	for _, table := range []string{"members", "schedule", "chatlog", "debuglog", "tags"} {
+		var stmt *sql.Stmt
+		stmt, err = db.Prepare("DELETE FROM `" + table + "` WHERE Guild = ?")
+		lite.removeGuildTables = append(lite.removeGuildTables, stmt)
+	}
+	db.lite = lite
+
+	db.sqlAddMember, err = db.Prepare("INSERT INTO members (ID, Guild, FirstSeen, Nickname) VALUES (?, ?, ?, ?) ON CONFLICT(ID, Guild) DO UPDATE SET Nickname = excluded.Nickname, FirstSeen = CASE WHEN FirstSeen < '0002' THEN excluded.FirstSeen WHEN excluded.FirstSeen < '0002' OR datetime(FirstSeen) <= datetime(excluded.FirstSeen) THEN FirstSeen ELSE excluded.FirstSeen END")
+	db.sqlSawUser, err = db.Prepare("UPDATE users SET LastSeen = datetime('now') WHERE ID = ?")
+	db.sqlSetUserAlias, err = db.Prepare("INSERT OR IGNORE INTO aliases (`User`, Alias, Duration, `Timestamp`) VALUES (?, ?, 0, datetime('now'))")
+	db.sqlRemoveMember, err = db.Prepare("DELETE FROM `members` WHERE `Guild` = ? AND ID = ?")
+	db.sqlGetUser, err = db.Prepare("SELECT ID, Username, Discriminator, LastSeen, Location, DefaultServer FROM `users` WHERE ID = ?")
+	db.sqlGetMember, err = db.Prepare("SELECT U.ID, U.Username, U.Discriminator, U.LastSeen, M.Nickname, M.FirstSeen, M.FirstMessage FROM `members` M INNER JOIN users U ON U.ID = M.ID WHERE M.ID = ? AND M.Guild = ?")
+	db.sqlFindGuildUsers, err = db.Prepare("SELECT DISTINCT M.ID FROM `members` M LEFT OUTER JOIN aliases A ON A.User = M.ID WHERE M.Guild = ? AND (M.Nickname LIKE ? OR A.Alias LIKE ?) LIMIT ? OFFSET ?")
+	db.sqlFindUser, err = db.Prepare("SELECT DISTINCT U.ID FROM `users` U WHERE U.Discriminator = ? and U.Username LIKE ? LIMIT ? OFFSET ?")
+	db.sqlGetNewestUsers, err = db.Prepare("SELECT U.ID, U.Username, M.FirstSeen FROM `members` M INNER JOIN users U ON M.ID = U.ID WHERE M.Guild = ? ORDER BY datetime(M.FirstSeen) DESC LIMIT ?")
+	db.sqlGetRecentUsers, err = db.Prepare("SELECT U.ID, U.Username FROM `members` M INNER JOIN users U ON M.ID = U.ID WHERE M.Guild = ? AND datetime(M.FirstSeen) > datetime(?) ORDER BY datetime(M.FirstSeen) DESC")
+	db.sqlGetAliases, err = db.Prepare("SELECT Alias FROM `aliases` WHERE `User` = ? ORDER BY Duration DESC LIMIT 10")
+	db.sqlAddTranscript, err = db.Prepare("INSERT INTO transcripts (Season, Episode, Line, Speaker, Text) VALUES (?,?,?,?,?)")
+	db.sqlGetTranscript, err = db.Prepare("SELECT Season, Episode, Line, Speaker, Text FROM `transcripts` WHERE `Season` = ? AND `Episode` = ? AND `Line` >= ? AND `Line` <= ?")
+	db.sqlRemoveTranscript, err = db.Prepare("DELETE FROM `transcripts` WHERE `Season` = ? AND `Episode` = ? AND `Line` = ?")
+	db.sqlGetRandomQuoteInt, err = db.Prepare("SELECT ABS(RANDOM()) % MAX((SELECT COUNT(*) FROM `transcripts` WHERE `Text` != ''), 1)")
+	db.sqlGetRandomQuote, err = db.Prepare("SELECT Season, Episode, Line, Speaker, Text FROM `transcripts` WHERE `Text` != '' LIMIT 1 OFFSET ?")
+	db.sqlGetSpeechQuoteInt, err = db.Prepare("SELECT ABS(RANDOM()) % MAX((SELECT COUNT(*) FROM `transcripts` WHERE `Speaker` != 'ACTION' AND Text != ''), 1)")
+	db.sqlGetSpeechQuote, err = db.Prepare("SELECT Season, Episode, Line, Speaker, Text FROM `transcripts` WHERE `Speaker` != 'ACTION' AND Text != '' LIMIT 1 OFFSET ?")
+	db.sqlGetCharacterQuoteInt, err = db.Prepare("SELECT ABS(RANDOM()) % MAX((SELECT COUNT(*) FROM `transcripts` WHERE `Speaker` = ? AND Text != ''), 1)")
+	db.sqlGetCharacterQuote, err = db.Prepare("SELECT Season, Episode, Line, Speaker, Text FROM `transcripts` WHERE `Speaker` = ? AND Text != '' LIMIT 1 OFFSET ?")
+	db.sqlGetTableCounts, err = db.Prepare("SELECT 'Chatlog: ' || (SELECT COUNT(*) FROM `chatlog`) || ' rows' || char(10) || 'Aliases: ' || (SELECT COUNT(*) FROM `aliases`) || ' rows' || char(10) || 'Debuglog: ' || (SELECT COUNT(*) FROM `debuglog`) || ' rows' || char(10) || 'Users: ' || (SELECT COUNT(*) FROM `users`) || ' rows' || char(10) || 'Schedule: ' || (SELECT COUNT(*) FROM `schedule`) || ' rows ' || char(10) || 'Members: ' || (SELECT COUNT(*) FROM `members`) || ' rows ' || char(10) || 'Items: ' || (SELECT COUNT(*) FROM `items`) || ' rows ' || char(10) || 'Tags: ' || (SELECT COUNT(*) FROM `tags`) || ' rows ' || char(10) || 'itemtags: ' || (SELECT COUNT(*) FROM `itemtags`) || ' rows'")
+	db.sqlCountNewUsers, err = db.Prepare("SELECT COUNT(*) FROM `members` WHERE datetime(`FirstSeen`) > datetime('now', '-' || ? || ' seconds') AND `Guild` = ?")
+	db.sqlAudit, err = db.Prepare("INSERT INTO debuglog (Type, User, Message, Timestamp, Guild) VALUES (?, ?, ?, datetime('now'), ?)")
+	db.sqlGetAuditRows, err = db.Prepare("SELECT U.Username, D.Message, D.Timestamp, U.ID FROM `debuglog` D INNER JOIN users U ON D.User = U.ID WHERE D.Type = ? AND D.Guild = ? ORDER BY D.Timestamp DESC LIMIT ? OFFSET ?")
+	db.sqlGetAuditRowsUser, err = db.Prepare("SELECT U.Username, D.Message, D.Timestamp, U.ID FROM `debuglog` D INNER JOIN users U ON D.User = U.ID WHERE D.Type = ? AND D.Guild = ? AND D.User = ? ORDER BY D.Timestamp DESC LIMIT ? OFFSET ?")
+	db.sqlGetAuditRowsString, err = db.Prepare("SELECT U.Username, D.Message, D.Timestamp, U.ID FROM `debuglog` D INNER JOIN users U ON D.User = U.ID WHERE D.Type = ? AND D.Guild = ? AND D.Message LIKE ? ORDER BY D.Timestamp DESC LIMIT ? OFFSET ?")
+	db.sqlGetAuditRowsUserString, err = db.Prepare("SELECT U.Username, D.Message, D.Timestamp, U.ID FROM `debuglog` D INNER JOIN users U ON D.User = U.ID WHERE D.Type = ? AND D.Guild = ? AND D.User = ? AND D.Message LIKE ? ORDER BY D.Timestamp DESC LIMIT ? OFFSET ?")
+	db.sqlAddSchedule, err = db.Prepare("INSERT INTO schedule (Guild, Date, Type, Data) VALUES (?, ?, ?, ?)")
+	db.sqlAddScheduleRepeat, err = db.Prepare("INSERT INTO schedule (Guild, Date, `RepeatInterval`, `Repeat`, Type, Data) VALUES (?, ?, ?, ?, ?, ?)")
+	db.sqlGetSchedule, err = db.Prepare("SELECT ID, Date, Type, Data FROM `schedule` WHERE `Guild` = ? AND datetime(Date) <= datetime('now') ORDER BY datetime(Date) ASC")
+	db.sqlDeleteSchedule, err = db.Prepare("DELETE FROM `schedule` WHERE ID = ?")
+	db.sqlCountEvents, err = db.Prepare("SELECT COUNT(*) FROM `schedule` WHERE `Guild` = ?")
+	db.sqlGetEvent, err = db.Prepare("SELECT ID, Date, Type, Data FROM `schedule` WHERE `Guild` = ? AND ID = ?")
+	db.sqlGetEvents, err = db.Prepare("SELECT ID, Date, Type, Data FROM `schedule` WHERE `Guild` = ? AND `Type` != 0 AND `Type` != 4 AND `Type` != 6 ORDER BY datetime(Date) ASC LIMIT ?")
+	db.sqlGetEventsByType, err = db.Prepare("SELECT ID, Date, Type, Data FROM `schedule` WHERE `Guild` = ? AND Type = ? ORDER BY datetime(Date) ASC LIMIT ?")
+	db.sqlGetNextEvent, err = db.Prepare("SELECT ID, Date, Type, Data FROM `schedule` WHERE `Guild` = ? AND `Type` = ? ORDER BY datetime(Date) ASC LIMIT 1")
+	db.sqlGetReminders, err = db.Prepare("SELECT ID, Date, Type, Data FROM `schedule` WHERE `Guild` = ? AND `Type` = 6 AND `Data` LIKE ? ORDER BY datetime(Date) ASC LIMIT ?")
+	db.sqlGetScheduleDate, err = db.Prepare("SELECT Date FROM `schedule` WHERE `Guild` = ? AND `Type` = ? AND `Data` = ?")
+	db.sqlGetTimeZone, err = db.Prepare("SELECT Location FROM `users` WHERE ID = ?")
+	db.sqlFindTimeZone, err = db.Prepare("SELECT Location FROM `timezones` WHERE `Location` LIKE ?")
+	db.sqlFindTimeZoneOffset, err = db.Prepare("SELECT Location FROM `timezones` WHERE `Location` LIKE ? AND (`Offset` = ? OR `DST` = ?)")
+	db.sqlSetTimeZone, err = db.Prepare("UPDATE users SET Location = ? WHERE ID = ?")
+	db.sqlRemoveAlias, err = db.Prepare("DELETE FROM aliases WHERE `User` = ? AND `Alias` = ?")
+	db.sqlGetUserGuilds, err = db.Prepare("SELECT Guild FROM members WHERE ID = ?")
+	db.sqlFindEvent, err = db.Prepare("SELECT ID FROM `schedule` WHERE `Type` = ? AND `Data` = ? AND `Guild` = ?")
+	db.sqlSetDefaultServer, err = db.Prepare("UPDATE users SET DefaultServer = ? WHERE ID = ?")
+	db.sqlSentMessage, err = db.Prepare("UPDATE `members` SET `FirstMessage` = datetime('now') WHERE ID = ? AND `Guild` = ? AND `FirstMessage` IS NULL")
+	db.sqlGetNewcomers, err = db.Prepare("SELECT ID FROM `members` WHERE `Guild` = ? AND datetime(`FirstMessage`) > datetime('now', '-' || ? || ' seconds')")
+	db.sqlAddItem, err = db.Prepare("INSERT INTO items (Content) VALUES (?) ON CONFLICT(Content) DO UPDATE SET Content = excluded.Content RETURNING ID")
+	db.sqlGetItem, err = db.Prepare("SELECT ID FROM items WHERE `Content` = ?")
+	db.sqlRemoveItem, err = db.Prepare("DELETE FROM itemtags WHERE Item = ? AND Tag IN (SELECT ID FROM tags WHERE Guild = ?)")
+	db.sqlAddTag, err = db.Prepare("INSERT INTO itemtags (Item, Tag) VALUES (?, ?)")
+	db.sqlRemoveTag, err = db.Prepare("DELETE FROM itemtags WHERE `Item` = ? AND `Tag` = ?")
+	db.sqlCreateTag, err = db.Prepare("INSERT INTO tags (Name, Guild) VALUES (?, ?)")
+	db.sqlDeleteTag, err = db.Prepare("DELETE FROM tags WHERE `Name` = ? AND `Guild` = ?")
+	db.sqlGetTag, err = db.Prepare("SELECT ID FROM tags WHERE `Name` = ? AND `Guild` = ?")
+	db.sqlCountTag, err = db.Prepare("SELECT COUNT(*) FROM itemtags WHERE `Tag` = ?")
+	db.sqlCountItems, err = db.Prepare("SELECT COUNT(DISTINCT M.Item) FROM itemtags M INNER JOIN tags T ON M.Tag = T.ID WHERE T.Guild = ?")
+	db.sqlGetItemTags, err = db.Prepare("SELECT T.Name FROM itemtags M INNER JOIN tags T ON M.Tag = T.ID WHERE M.Item = ? AND T.Guild = ?")
+	db.sqlGetTags, err = db.Prepare("SELECT T.Name, COUNT(M.Item) FROM tags T LEFT OUTER JOIN itemtags M ON T.ID = M.Tag WHERE T.Guild = ? GROUP BY T.Name")
+	db.sqlImportTag, err = db.Prepare("INSERT OR IGNORE INTO itemtags (Item, Tag) SELECT Item, ? FROM itemtags WHERE `Tag` = ?")
+	db.sqlGetConfig, err = db.Prepare("SELECT Config, Version FROM guild_config WHERE Guild = ?")
+	db.sqlAddConfig, err = db.Prepare("INSERT INTO guild_config (Guild, Version, Expires, Updated, Config) VALUES (?, 1, ?, datetime('now'), ?)")
+	db.sqlUpdateConfig, err = db.Prepare("UPDATE guild_config SET Config = ?, Version = Version + 1, Updated = datetime('now') WHERE Guild = ? AND Version = ?")
+	db.sqlRemoveConfig, err = db.Prepare("DELETE FROM guild_config WHERE Guild = ?")
+	db.sqlSetConfigExpiry, err = db.Prepare("UPDATE guild_config SET Expires = ? WHERE Guild = ?")
+	db.sqlInitConfigExpiry, err = db.Prepare("UPDATE guild_config SET Expires = ? WHERE Expires = 0")
+	db.sqlGetExpiredConfigs, err = db.Prepare("SELECT Guild FROM guild_config WHERE Expires > 0 AND Expires < ?")
+	db.sqlAddConfigHistory, err = db.Prepare("INSERT INTO config_history (Guild, User, Timestamp, Path, OldValue, NewValue) VALUES (?, ?, datetime('now'), ?, ?, ?)")
+	db.sqlGetConfigHistory, err = db.Prepare("SELECT ID, User, Timestamp, Path, OldValue, NewValue FROM config_history WHERE Guild = ? ORDER BY ID DESC LIMIT ? OFFSET ?")
+	db.sqlGetConfigHistoryPath, err = db.Prepare("SELECT ID, User, Timestamp, Path, OldValue, NewValue FROM config_history WHERE Guild = ? AND Path = ? ORDER BY ID DESC LIMIT ? OFFSET ?")
+	db.sqlGetConfigChange, err = db.Prepare("SELECT ID, User, Timestamp, Path, OldValue, NewValue FROM config_history WHERE Guild = ? AND ID = ?")
+	db.sqlGetConfigChangesSince, err = db.Prepare("SELECT ID, User, Timestamp, Path, OldValue, NewValue FROM config_history WHERE Guild = ? AND ID >= ? ORDER BY ID DESC")
+	db.sqlRemoveConfigHistory, err = db.Prepare("DELETE FROM config_history WHERE Guild = ?")
+	db.sqlSetCustomCommand, err = db.Prepare("INSERT INTO custom_commands (Guild, Name, Response, Author, Updated) VALUES (?, ?, ?, ?, datetime('now')) ON CONFLICT(Guild, Name) DO UPDATE SET Response = excluded.Response, Author = excluded.Author, Updated = datetime('now')")
+	db.sqlRemoveCustomCommand, err = db.Prepare("DELETE FROM custom_commands WHERE Guild = ? AND Name = ?")
+	db.sqlGetCustomCommands, err = db.Prepare("SELECT Name, Response, Author, Updated FROM custom_commands WHERE Guild = ? ORDER BY Name")
+	db.sqlRemoveCustomCommands, err = db.Prepare("DELETE FROM custom_commands WHERE Guild = ?")
+	db.sqlGetTagItems, err = db.Prepare("SELECT I.ID, I.Content FROM itemtags M INNER JOIN items I ON M.Item = I.ID WHERE M.Tag = ? ORDER BY I.ID LIMIT ? OFFSET ?")
+	db.sqlAddAPIToken, err = db.Prepare("INSERT INTO api_tokens (Guild, Name, Hash, Author, Created) VALUES (?, ?, ?, ?, datetime('now'))")
+	db.sqlRemoveAPIToken, err = db.Prepare("DELETE FROM api_tokens WHERE Guild = ? AND Name = ?")
+	db.sqlGetAPITokens, err = db.Prepare("SELECT Name, Author, Created FROM api_tokens WHERE Guild = ? ORDER BY Name")
+	db.sqlGetAPIToken, err = db.Prepare("SELECT Guild, Name FROM api_tokens WHERE Hash = ?")
+	db.sqlRemoveAPITokens, err = db.Prepare("DELETE FROM api_tokens WHERE Guild = ?")
+	db.sqlSetModuleState, err = db.Prepare("INSERT INTO module_state (Guild, Module, State, Updated) VALUES (?, ?, ?, datetime('now')) ON CONFLICT(Guild, Module) DO UPDATE SET State = excluded.State, Updated = datetime('now')")
+	db.sqlGetModuleState, err = db.Prepare("SELECT State FROM module_state WHERE Guild = ? AND Module = ?")
+	db.sqlRemoveModuleStates, err = db.Prepare("DELETE FROM module_state WHERE Guild = ?")
+	db.sqlSetModuleData, err = db.Prepare("INSERT INTO module_data (Guild, Module, Name, Value, Expires) VALUES (?, ?, ?, ?, ?) ON CONFLICT(Guild, Module, Name) DO UPDATE SET Value = excluded.Value, Expires = excluded.Expires")
+	db.sqlGetModuleData, err = db.Prepare("SELECT Value FROM module_data WHERE Guild = ? AND Module = ? AND Name = ? AND (Expires = 0 OR Expires > ?)")
+	db.sqlRemoveModuleData, err = db.Prepare("DELETE FROM module_data WHERE Guild = ? AND Module = ? AND Name = ?")
+	db.sqlListModuleData, err = db.Prepare("SELECT Name, Value, Expires FROM module_data WHERE Guild = ? AND Module = ? AND Name >= ? AND Name < ? AND (Expires = 0 OR Expires > ?) ORDER BY Name LIMIT ?")
+	db.sqlRemoveExpiredData, err = db.Prepare("DELETE FROM module_data WHERE Expires > 0 AND Expires <= ?")
+	db.sqlRemoveGuildModuleData, err = db.Prepare("DELETE FROM module_data WHERE Guild = ?")
+	return err
+}
+
+// runTx executes each statement with its arguments inside a single transaction. SQLite has no stored procedures, so this stands in for them.
+func (db *BotDB) runTx(stmts []*sql.Stmt, args [][]interface{}) error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return err
+	}
+	for i, stmt := range stmts {
+		if _, err = tx.Stmt(stmt).Exec(args[i]...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (lite *sqliteStatements) addChat(db *BotDB, id uint64, author uint64, username string, message string, channel uint64, guild uint64) error {
+	return db.runTx([]*sql.Stmt{lite.addChatUser, lite.addChatAlias, lite.addChatMessage}, [][]interface{}{
+		{author, username},
+		{author, username},
+		{id, author, message, channel, guild},
+	})
+}
+
+func (lite *sqliteStatements) addUser(db *BotDB, id uint64, username string, discriminator int, isonline bool) error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return err
+	}
+	var oldname string
+	err = tx.Stmt(lite.getUsername).QueryRow(id).Scan(&oldname)
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	online := 0
+	if isonline {
+		online = 1
+	}
+	if err == nil {
+		_, err = tx.Stmt(lite.upsertUser).Exec(id, username, discriminator, online)
+	}
+	if err == nil && len(username) > 0 {
+		if len(oldname) > 0 {
+			_, err = tx.Stmt(lite.extendAlias).Exec(id, oldname)
+		}
+		if err == nil && username != oldname {
+			_, err = tx.Stmt(lite.touchAlias).Exec(id, username)
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (lite *sqliteStatements) removeSchedule(db *BotDB, id uint64) error {
+	return db.runTx([]*sql.Stmt{lite.removeFuture, lite.removeOnce, lite.repeatSchedule}, [][]interface{}{{id}, {id}, {id}})
+}
+
+func (lite *sqliteStatements) removeGuild(db *BotDB, guild uint64) error {
+	args := make([][]interface{}, len(lite.removeGuildTables))
+	for i := range args {
+		args[i] = []interface{}{guild}
+	}
+	return db.runTx(lite.removeGuildTables, args)
+}
+
+// insertIgnore returns the dialect-specific form of INSERT IGNORE for statements that are built at runtime
+func (db *BotDB) insertIgnore() string {
+	if db.driver == DriverSQLite {
+		return "INSERT OR IGNORE"
+	}
+	return "INSERT IGNORE"
+}
+
+// utcNow returns the dialect-specific SQL expression for the current UTC time
+func (db *BotDB) utcNow() string {
+	if db.driver == DriverSQLite {
+		return "datetime('now')"
+	}
+	return "UTC_TIMESTAMP()"
+}
+
+// Random returns the dialect-specific SQL function for a random number, for use in ORDER BY clauses
+func (db *BotDB) Random() string {
+	if db.driver == DriverSQLite {
+		return "RANDOM()"
+	}
+	return "RAND()"
+}
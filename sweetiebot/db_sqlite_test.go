@@ -0,0 +1,275 @@
+package sweetiebot
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func mockSQLiteDB(t *testing.T) *BotDB {
+	db, err := OpenSQLite("sqlite::memory:", "../docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestParseDBAuth(t *testing.T) {
+	driver, conn := ParseDBAuth(" user:pass@tcp(localhost:3306)/sweetiebot?parseTime=true ")
+	Check(driver, DriverMySQL, t)
+	Check(conn, "user:pass@tcp(localhost:3306)/sweetiebot?parseTime=true", t)
+	driver, conn = ParseDBAuth("sqlite://sweetiebot.db")
+	Check(driver, DriverSQLite, t)
+	Check(conn, "sweetiebot.db?_pragma=foreign_keys%281%29&_time_format=sqlite", t)
+	driver, conn = ParseDBAuth("sqlite:data/sb.db?_time_format=")
+	Check(driver, DriverSQLite, t)
+	Check(conn, "data/sb.db?_pragma=foreign_keys%281%29&_time_format=", t)
+}
+
+func TestSQLiteUsers(t *testing.T) {
+	db := mockSQLiteDB(t)
+	defer db.Close()
+
+	db.AddUser(1, "Sweetie", 1234, true)
+	db.AddUser(1, "Belle", 0, false)
+	u, _, _, _ := db.GetUser(1)
+	if u == nil {
+		t.Fatal("GetUser returned nil")
+	}
+	Check(u.Username, "Belle", t)
+	Check(u.Discriminator, "1234", t)
+	Check(len(db.GetAliases(1)), 2, t)
+
+	db.AddMember(1, 5, time.Now().UTC().Add(-time.Hour), "nick")
+	db.AddMember(1, 5, time.Now().UTC(), "nick2")
+	m, _, _ := db.GetMember(1, 5)
+	if m == nil {
+		t.Fatal("GetMember returned nil")
+	}
+	Check(m.Nick, "nick2", t)
+	Check(m.JoinedAt.Before(time.Now().UTC().Add(-30*time.Minute)), true, t)
+	Check(db.CountNewUsers(7200, 5), 1, t)
+
+	db.AddMessage(10, &discordgo.User{ID: "1", Username: "Belle"}, "hi", 2, 5)
+	loc, _ := time.LoadLocation("America/Chicago")
+	Check(db.SetTimeZone(1, loc), nil, t)
+	Check(db.GetTimeZone(1).String(), "America/Chicago", t)
+	Check(db.RemoveGuild(5), nil, t)
+	m, _, _ = db.GetMember(1, 5)
+	Check(m != nil && m.Nick == "", true, t)
+}
+
+func TestSQLiteTags(t *testing.T) {
+	db := mockSQLiteDB(t)
+	defer db.Close()
+
+	Check(db.CreateTag("pony", 5), nil, t)
+	tag, err := db.GetTag("pony", 5)
+	Check(err, nil, t)
+	item, err := db.AddItem("Rarity")
+	Check(err, nil, t)
+	again, err := db.AddItem("Rarity")
+	Check(err, nil, t)
+	Check(again, item, t)
+	Check(db.AddTag(item, tag), nil, t)
+	Check(db.AddTag(item, tag), ErrDuplicateEntry, t)
+	n, _ := db.CountItems(5)
+	Check(n, uint64(1), t)
+	Check(db.RemoveItem(item, 5), nil, t)
+	n, _ = db.CountItems(5)
+	Check(n, uint64(0), t)
+	_, err = db.GetItem("Rarity")
+	Check(err != nil, true, t)
+}
+
+func TestSQLiteSchedule(t *testing.T) {
+	db := mockSQLiteDB(t)
+	defer db.Close()
+
+	past := time.Now().UTC().Add(-time.Minute)
+	Check(db.AddScheduleRepeat(5, past, 4, 1, 1, "data"), nil, t)
+	events := db.GetSchedule(5)
+	Check(len(events), 1, t)
+	Check(db.RemoveSchedule(events[0].ID), nil, t)
+	Check(len(db.GetSchedule(5)), 0, t)
+	e := db.GetEvent(5, events[0].ID)
+	if e == nil {
+		t.Fatal("repeating event was deleted")
+	}
+	Check(e.Date.Sub(past) > 23*time.Hour, true, t)
+
+	Check(db.AddSchedule(5, past, 6, "once"), nil, t)
+	events = db.GetSchedule(5)
+	Check(len(events), 1, t)
+	Check(db.RemoveSchedule(events[0].ID), nil, t)
+	Check(db.GetEvent(5, events[0].ID) == nil, true, t)
+}
+
+func TestSQLiteConfig(t *testing.T) {
+	db := mockSQLiteDB(t)
+	defer db.Close()
+
+	_, _, err := db.GetConfig(5)
+	Check(err, sql.ErrNoRows, t)
+	v, err := db.SaveConfig(5, []byte("{}"), 100, 0)
+	Check(err, nil, t)
+	Check(v, uint64(1), t)
+	v, err = db.SaveConfig(5, []byte("{}"), 100, 0)
+	Check(err, ErrConfigConflict, t)
+	Check(v, uint64(0), t)
+	v, err = db.SaveConfig(5, []byte(`{"version":1}`), 100, 1)
+	Check(err, nil, t)
+	Check(v, uint64(2), t)
+	v, err = db.SaveConfig(5, []byte(`{"version":2}`), 100, 1)
+	Check(err, ErrConfigConflict, t)
+	Check(v, uint64(0), t)
+	data, version, err := db.GetConfig(5)
+	Check(err, nil, t)
+	Check(string(data), `{"version":1}`, t)
+	Check(version, uint64(2), t)
+
+	db.SaveConfig(6, []byte("{}"), 0, 0)
+	Check(db.InitConfigExpiry(200), nil, t)
+	Check(len(db.GetExpiredConfigs(150)), 1, t)
+	Check(db.SetConfigExpiry(5, 300), nil, t)
+	Check(len(db.GetExpiredConfigs(250)), 1, t)
+	Check(db.RemoveConfig(6), nil, t)
+	Check(len(db.GetExpiredConfigs(250)), 0, t)
+}
+
+func TestSQLiteConfigHistory(t *testing.T) {
+	db := mockSQLiteDB(t)
+	defer db.Close()
+
+	Check(db.AddConfigHistory(5, 1, "basic.aliases", "null", `{"a":"b"}`), nil, t)
+	Check(db.AddConfigHistory(5, 1, "spam.maxpressure", "60", "70"), nil, t)
+	Check(db.AddConfigHistory(6, 1, "spam.maxpressure", "60", "80"), nil, t)
+	r := db.GetConfigHistory(5, "", 10, 0)
+	Check(len(r), 2, t)
+	Check(r[0].Path, "spam.maxpressure", t)
+	Check(len(db.GetConfigHistory(5, "basic.aliases", 10, 0)), 1, t)
+	c := db.GetConfigChange(5, r[1].ID)
+	if c == nil {
+		t.Fatal("GetConfigChange returned nil")
+	}
+	Check(c.New, `{"a":"b"}`, t)
+	Check(db.GetConfigChange(6, r[1].ID) == nil, true, t)
+	Check(len(db.GetConfigChangesSince(5, r[1].ID)), 2, t)
+	Check(db.RemoveConfig(5), nil, t)
+	Check(len(db.GetConfigHistory(5, "", 10, 0)), 0, t)
+}
+
+func TestSQLiteCustomCommands(t *testing.T) {
+	db := mockSQLiteDB(t)
+	defer db.Close()
+
+	Check(db.SetCustomCommand(5, "hug", "hugs {user}", 1), nil, t)
+	Check(db.SetCustomCommand(5, "boop", "boops {user}", 1), nil, t)
+	Check(db.SetCustomCommand(6, "hug", "hugs", 2), nil, t)
+	Check(db.SetCustomCommand(5, "hug", "hugs {user} tightly", 3), nil, t)
+	r := db.GetCustomCommands(5)
+	Check(len(r), 2, t)
+	Check(r[0].Name, "boop", t)
+	Check(r[1].Response, "hugs {user} tightly", t)
+	Check(r[1].Author, uint64(3), t)
+	Check(db.RemoveCustomCommand(5, "boop"), nil, t)
+	Check(db.RemoveCustomCommand(5, "boop"), sql.ErrNoRows, t)
+	Check(len(db.GetCustomCommands(5)), 1, t)
+	Check(db.RemoveConfig(5), nil, t)
+	Check(len(db.GetCustomCommands(5)), 0, t)
+	Check(len(db.GetCustomCommands(6)), 1, t)
+}
+
+type stateTestModule struct{ state string }
+
+func (m *stateTestModule) Name() string                       { return "State" }
+func (m *stateTestModule) Commands() []Command                { return nil }
+func (m *stateTestModule) Description(info *GuildInfo) string { return "" }
+func (m *stateTestModule) SaveState(info *GuildInfo) ([]byte, error) {
+	return []byte(m.state), nil
+}
+func (m *stateTestModule) LoadState(info *GuildInfo, data []byte) error {
+	m.state = string(data)
+	return nil
+}
+
+func TestSQLiteModuleState(t *testing.T) {
+	db := mockSQLiteDB(t)
+	defer db.Close()
+
+	_, err := db.GetModuleState(5, "spam")
+	Check(err, sql.ErrNoRows, t)
+	Check(db.SetModuleState(5, "spam", []byte("first")), nil, t)
+	Check(db.SetModuleState(5, "spam", []byte("second")), nil, t)
+	state, err := db.GetModuleState(5, "spam")
+	Check(string(state), "second", t)
+
+	sb := &SweetieBot{DB: db, DG: &DiscordGoSession{&discordgo.Session{State: discordgo.NewState()}}}
+	info := NewGuildInfo(sb, &discordgo.Guild{ID: "5"})
+	info.RegisterModule(&stateTestModule{"saved"})
+	info.SaveModuleState()
+	m := &stateTestModule{}
+	info = NewGuildInfo(sb, &discordgo.Guild{ID: "5"})
+	info.RegisterModule(m)
+	info.LoadModuleState()
+	Check(m.state, "saved", t)
+
+	Check(db.RemoveConfig(5), nil, t)
+	_, err = db.GetModuleState(5, "state")
+	Check(err, sql.ErrNoRows, t)
+}
+
+func TestSQLiteModuleData(t *testing.T) {
+	db := mockSQLiteDB(t)
+	defer db.Close()
+	sb := &SweetieBot{DB: db, DG: &DiscordGoSession{&discordgo.Session{State: discordgo.NewState()}}}
+	info := NewGuildInfo(sb, &discordgo.Guild{ID: "5"})
+	store := info.Store(&stateTestModule{})
+	other := info.Store(&registryTestModule{name: "Other"})
+
+	_, err := store.Get("missing")
+	Check(err, sql.ErrNoRows, t)
+	Check(store.Set("", []byte("empty"), 0), errStoreKey, t)
+	Check(store.Set("user:1", []byte("first"), 0), nil, t)
+	Check(store.Set("user:1", []byte("second"), 0), nil, t)
+	Check(store.Set("user:2", []byte("third"), time.Hour), nil, t)
+	Check(store.Set("User:3", nil, 0), nil, t)
+	Check(store.Set("user_4", []byte("wildcard"), 0), nil, t)
+	Check(other.Set("user:1", []byte("other"), 0), nil, t)
+	value, err := store.Get("user:1")
+	Check(err, nil, t)
+	Check(string(value), "second", t)
+	value, _ = other.Get("user:1") // Each module has its own keys
+	Check(string(value), "other", t)
+
+	list, err := store.List("user:", 10)
+	Check(err, nil, t)
+	Check(len(list), 2, t) // Prefixes are case sensitive and _ isn't a wildcard
+	Check(list[0].Key, "user:1", t)
+	Check(list[0].Expires, int64(0), t)
+	Check(string(list[1].Value), "third", t)
+	Check(list[1].Expires > time.Now().Unix(), true, t)
+	list, _ = store.List("", 2)
+	Check(len(list), 2, t)
+	Check(list[0].Key, "User:3", t)
+	Check(prefixEnd("a\xff\xff"), "b", t)
+	Check(len(prefixEnd("\xff")), MaxModuleDataKey+1, t)
+
+	now := time.Now().UTC().Unix()
+	Check(db.SetModuleData(5, "state", "user:2", []byte("expired"), now-1), nil, t)
+	_, err = store.Get("user:2")
+	Check(err, sql.ErrNoRows, t)
+	list, _ = store.List("user:", 10)
+	Check(len(list), 1, t)
+	Check(db.RemoveExpiredModuleData(now), nil, t)
+	Check(store.Delete("user:2"), sql.ErrNoRows, t) // Already removed because it expired
+	Check(store.Delete("user:1"), nil, t)
+	_, err = store.Get("user:1")
+	Check(err, sql.ErrNoRows, t)
+
+	Check(db.RemoveConfig(5), nil, t)
+	_, err = other.Get("user:1")
+	Check(err, sql.ErrNoRows, t)
+}
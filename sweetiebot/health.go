@@ -0,0 +1,149 @@
+package sweetiebot
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// The deadlock detector sends a heartbeat every heartbeatInterval, so missing several in a row means the bot is stuck
+const maxHeartbeatAge = 3 * heartbeatInterval
+
+// Discord expects a gateway heartbeat about every 41 seconds, so an older acknowledgement means the connection is dead
+const maxGatewayAckAge = 2 * time.Minute
+
+// A readiness check fails once a work queue is this full, because events are about to start blocking the gateway
+const maxBacklog = 0.9
+
+// HealthQueue reports how full one of the bot's internal work queues is
+type HealthQueue struct {
+	Length   int `json:"length"`
+	Capacity int `json:"capacity"`
+}
+
+// Full returns true if the queue is close enough to its capacity that producers will soon block
+func (q HealthQueue) Full() bool {
+	return q.Capacity > 0 && float64(q.Length) >= float64(q.Capacity)*maxBacklog
+}
+
+// HealthStatus is the response body of the /healthz and /readyz endpoints
+type HealthStatus struct {
+	OK                 bool                   `json:"ok"`
+	Problems           []string               `json:"problems,omitempty"`
+	GatewayConnected   bool                   `json:"gatewayconnected"`
+	DisconnectedShards []int                  `json:"disconnectedshards,omitempty"`
+	GatewayAckAge      *float64               `json:"gatewayackage"` // Seconds since discord last acknowledged a gateway heartbeat on the slowest shard, or null if it never has
+	HeartbeatAge       *float64               `json:"heartbeatage"`  // Seconds since the deadlock detector's last heartbeat got through, or null if there hasn't been one yet
+	DatabaseConnected  bool                   `json:"databaseconnected"`
+	Queues             map[string]HealthQueue `json:"queues"`
+	Guilds             int                    `json:"guilds"`
+	Uptime             int64                  `json:"uptime"`
+}
+
+func (sb *SweetieBot) beat() {
+	atomic.AddUint32(&sb.heartbeat, 1)
+	atomic.StoreUint32(&sb.lastHeartbeat, uint32(time.Now().Unix()))
+}
+
+func ageOf(t time.Time, now time.Time) *float64 {
+	if t.IsZero() {
+		return nil
+	}
+	age := now.Sub(t).Seconds()
+	return &age
+}
+
+// Health gathers the current state of the bot
+func (sb *SweetieBot) Health() *HealthStatus {
+	now := time.Now()
+	status := &HealthStatus{
+		DatabaseConnected: sb.DB != nil && sb.DB.Status.Get(),
+		Queues: map[string]HealthQueue{
+			"member": {len(sb.memberChan), cap(sb.memberChan)},
+			"defer":  {len(sb.deferChan), cap(sb.deferChan)},
+		},
+		Uptime: now.Unix() - sb.StartTime,
+	}
+	shards := sb.Shards
+	if len(shards) == 0 && sb.DG != nil {
+		shards = []*DiscordGoSession{sb.DG}
+	}
+	status.GatewayConnected = len(shards) > 0
+	for _, s := range shards { // Report the worst shard, since every shard has to be connected for the bot to see all of its guilds
+		s.RLock()
+		if !s.DataReady {
+			status.GatewayConnected = false
+			status.DisconnectedShards = append(status.DisconnectedShards, s.ShardID)
+		}
+		if age := ageOf(s.LastHeartbeatAck, now); age != nil && (status.GatewayAckAge == nil || *age > *status.GatewayAckAge) {
+			status.GatewayAckAge = age
+		}
+		s.RUnlock()
+	}
+	if last := atomic.LoadUint32(&sb.lastHeartbeat); last != 0 {
+		status.HeartbeatAge = ageOf(time.Unix(int64(last), 0), now)
+	}
+	sb.GuildsLock.RLock()
+	status.Guilds = len(sb.Guilds)
+	sb.GuildsLock.RUnlock()
+	return status
+}
+
+// Alive checks for problems that can only be fixed by restarting the bot
+func (s *HealthStatus) Alive() bool {
+	if s.HeartbeatAge != nil && *s.HeartbeatAge > maxHeartbeatAge.Seconds() {
+		s.Problems = append(s.Problems, "The deadlock detector's heartbeat hasn't gotten through in "+TimeDiff(time.Duration(*s.HeartbeatAge)*time.Second))
+	}
+	s.OK = len(s.Problems) == 0
+	return s.OK
+}
+
+// Ready checks if the bot is alive and able to process events normally
+func (s *HealthStatus) Ready() bool {
+	s.Alive()
+	if len(s.DisconnectedShards) > 0 {
+		for _, id := range s.DisconnectedShards {
+			s.Problems = append(s.Problems, "Shard "+strconv.Itoa(id)+" is not connected to the discord gateway")
+		}
+	} else if !s.GatewayConnected {
+		s.Problems = append(s.Problems, "Not connected to the discord gateway")
+	}
+	if s.GatewayAckAge != nil && *s.GatewayAckAge > maxGatewayAckAge.Seconds() {
+		s.Problems = append(s.Problems, "Discord hasn't acknowledged a gateway heartbeat in "+TimeDiff(time.Duration(*s.GatewayAckAge)*time.Second))
+	}
+	if !s.DatabaseConnected {
+		s.Problems = append(s.Problems, "Running in No Database mode")
+	}
+	for _, k := range []string{"member", "defer"} {
+		if s.Queues[k].Full() {
+			s.Problems = append(s.Problems, "The "+k+" queue is almost full")
+		}
+	}
+	s.OK = len(s.Problems) == 0
+	return s.OK
+}
+
+func writeHealth(w http.ResponseWriter, status *HealthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !status.OK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(status)
+}
+
+// healthHandler responds with 503 if the bot is hung and should be restarted
+func (sb *SweetieBot) healthHandler(w http.ResponseWriter, r *http.Request) {
+	status := sb.Health()
+	status.Alive()
+	writeHealth(w, status)
+}
+
+// readyHandler responds with 503 if the bot is running but can't currently do its job
+func (sb *SweetieBot) readyHandler(w http.ResponseWriter, r *http.Request) {
+	status := sb.Health()
+	status.Ready()
+	writeHealth(w, status)
+}
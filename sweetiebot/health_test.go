@@ -0,0 +1,82 @@
+package sweetiebot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestHealth(t *testing.T) {
+	sb := &SweetieBot{
+		DB:         &BotDB{},
+		DG:         &DiscordGoSession{Session: &discordgo.Session{State: discordgo.NewState()}},
+		Guilds:     make(map[DiscordGuild]*GuildInfo),
+		StartTime:  time.Now().Unix(),
+		memberChan: make(chan *GuildInfo, 10),
+		deferChan:  make(chan deferPair, 10),
+	}
+	serve := func(handler http.HandlerFunc) int {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", "/", nil))
+		var status HealthStatus
+		if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+			t.Error(err)
+		}
+		Check(status.OK, w.Code == http.StatusOK, t)
+		return w.Code
+	}
+	check := func(healthz int, readyz int) {
+		_, file, line, _ := runtime.Caller(1)
+		if code := serve(sb.healthHandler); code != healthz {
+			t.Errorf("[%s:%v] expected /healthz to return %v but got %v", filepath.Base(file), line, healthz, code)
+		}
+		if code := serve(sb.readyHandler); code != readyz {
+			t.Errorf("[%s:%v] expected /readyz to return %v but got %v", filepath.Base(file), line, readyz, code)
+		}
+	}
+
+	// Before the gateway connects, the bot is alive but not ready
+	check(200, 503)
+
+	sb.DG.DataReady = true
+	sb.DB.Status.Set(true)
+	sb.beat()
+	check(200, 200)
+	status := sb.Health()
+	Check(*status.HeartbeatAge < 2, true, t)
+	Check(status.Queues["member"].Capacity, 10, t)
+
+	for i := 0; i < 9; i++ {
+		sb.deferChan <- deferPair{}
+	}
+	check(200, 503)
+	for i := 0; i < 9; i++ {
+		<-sb.deferChan
+	}
+
+	sb.DB.Status.Set(false)
+	check(200, 503)
+	sb.DB.Status.Set(true)
+
+	// A gateway connection that stopped acknowledging heartbeats isn't ready
+	sb.DG.LastHeartbeatAck = time.Now().Add(-maxGatewayAckAge - time.Second)
+	check(200, 503)
+	sb.DG.LastHeartbeatAck = time.Now()
+	check(200, 200)
+
+	// The checks are served by the monitoring server
+	w := httptest.NewRecorder()
+	sb.monitorMux().ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	Check(w.Code, http.StatusOK, t)
+
+	// A stalled heartbeat fails both checks
+	atomic.StoreUint32(&sb.lastHeartbeat, uint32(time.Now().Add(-maxHeartbeatAge-time.Second).Unix()))
+	check(503, 503)
+}
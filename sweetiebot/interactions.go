@@ -0,0 +1,366 @@
+package sweetiebot
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Discord limits how many application commands a guild can have, and how many options each command can have
+const (
+	maxApplicationCommands = 100
+	maxApplicationOptions  = 25
+)
+
+// DefaultSubcommand is the name of the subcommand that runs the default command of a command group, because discord
+// doesn't allow a command to have both subcommands and options.
+const DefaultSubcommand = "run"
+
+var applicationNameRegex = regexp.MustCompile(`^[-_\p{L}\p{N}]{1,32}$`)
+var optionNameReplace = regexp.MustCompile(`[^-_\p{L}\p{N}]+`)
+
+var errNoApplication = errors.New("the application ID hasn't been received from discord yet")
+
+// sensitivePermissions is the default permission needed to see sensitive commands. Server admins can change this in
+// discord's integration settings, and the bot still checks modules.commandroles either way.
+var sensitivePermissions int64 = discordgo.PermissionManageMessages
+
+func applicationDescription(s string) string {
+	s = strings.TrimSpace(strings.SplitN(s, "\n", 2)[0])
+	if len(s) == 0 {
+		return "No description."
+	}
+	if len(s) > 100 {
+		s = s[:97] + "..."
+	}
+	return s
+}
+
+func applicationOptionType(p ParamType) discordgo.ApplicationCommandOptionType {
+	switch p {
+	case ParamUser:
+		return discordgo.ApplicationCommandOptionUser
+	case ParamChannel:
+		return discordgo.ApplicationCommandOptionChannel
+	case ParamRole:
+		return discordgo.ApplicationCommandOptionRole
+	case ParamInt:
+		return discordgo.ApplicationCommandOptionInteger
+	}
+	return discordgo.ApplicationCommandOptionString
+}
+
+// applicationOptions converts the parameters of a command into application command options. Discord requires every
+// required option to come before any optional ones, so any required parameter after an optional one becomes optional.
+func applicationOptions(usage *CommandUsage) (options []*discordgo.ApplicationCommandOption) {
+	names := make(map[string]bool)
+	optional := false
+	for i, p := range usage.Params {
+		if i >= maxApplicationOptions {
+			break
+		}
+		name := strings.Trim(optionNameReplace.ReplaceAllString(strings.ToLower(p.Name), "_"), "_")
+		if len(name) > 28 {
+			name = name[:28]
+		}
+		if len(name) == 0 || names[name] {
+			name = fmt.Sprintf("%s%d", name, i+1)
+		}
+		names[name] = true
+		optional = optional || p.Optional
+		desc := p.Desc
+		if len(desc) == 0 {
+			desc = p.Name
+		}
+		options = append(options, &discordgo.ApplicationCommandOption{
+			Type:        applicationOptionType(p.Type),
+			Name:        name,
+			Description: applicationDescription(desc),
+			Required:    !optional,
+		})
+	}
+	return
+}
+
+// ApplicationCommand builds an application command from the Info and Usage of a command. Command groups become a
+// command with one subcommand for each command in the group. Returns nil if the command can't be represented.
+func (info *GuildInfo) ApplicationCommand(c Command) *discordgo.ApplicationCommand {
+	name := strings.ToLower(c.Info().Name)
+	if !applicationNameRegex.MatchString(name) || c.Info().Restricted {
+		return nil
+	}
+	cmd := &discordgo.ApplicationCommand{
+		Name:        name,
+		Description: applicationDescription(c.Info().Usage),
+	}
+	if c.Info().Sensitive {
+		cmd.DefaultMemberPermissions = &sensitivePermissions
+	}
+	group, ok := c.(*CommandGroup)
+	if !ok {
+		cmd.Options = applicationOptions(c.Usage(info))
+		return cmd
+	}
+	if group.Default != nil && group.Find(DefaultSubcommand) == nil {
+		cmd.Options = append(cmd.Options, &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        DefaultSubcommand,
+			Description: applicationDescription(group.Default.Info().Usage),
+			Options:     applicationOptions(group.Default.Usage(info)),
+		})
+	}
+	for _, v := range group.subcommands {
+		sub := strings.ToLower(v.name[len(group.Name)+1:])
+		if !applicationNameRegex.MatchString(sub) || len(cmd.Options) >= maxApplicationOptions {
+			continue
+		}
+		cmd.Options = append(cmd.Options, &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        sub,
+			Description: applicationDescription(v.Command.Info().Usage),
+			Options:     applicationOptions(v.Usage(info)),
+		})
+	}
+	return cmd
+}
+
+// ApplicationCommands builds an application command for every command in this guild, sorted by name. Discord only
+// allows 100 commands per guild, so any commands past that are left out.
+func (info *GuildInfo) ApplicationCommands() []*discordgo.ApplicationCommand {
+	info.commandLock.RLock()
+	cmds := make([]*discordgo.ApplicationCommand, 0, len(info.commands))
+	for _, c := range info.commands {
+		if _, sub := c.(*subCommand); !sub { // Subcommands are included in their group
+			if cmd := info.ApplicationCommand(c); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+		}
+	}
+	info.commandLock.RUnlock()
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+	if len(cmds) > maxApplicationCommands {
+		cmds = cmds[:maxApplicationCommands]
+	}
+	return cmds
+}
+
+// RegisterApplicationCommands replaces all of the application commands on this guild with its current commands
+func (sb *SweetieBot) RegisterApplicationCommands(info *GuildInfo) error {
+	if sb.AppID == 0 {
+		return errNoApplication
+	}
+	_, err := sb.DG.ApplicationCommandBulkOverwrite(SBitoa(sb.AppID), info.ID, info.ApplicationCommands())
+	return err
+}
+
+// UpdateApplicationCommands re-registers the application commands of this guild in the background, if the bot can
+// register application commands. This should be called whenever a command is added or removed after the guild loads.
+func (info *GuildInfo) UpdateApplicationCommands() {
+	if info.Bot.AppID != 0 && !info.Bot.IsUserMode {
+		go func() {
+			if err := info.Bot.RegisterApplicationCommands(info); err != nil {
+				info.Bot.Logger.Error("Error registering application commands for "+info.Name+": "+err.Error(), LogFields{"guild": info.ID})
+			}
+		}()
+	}
+}
+
+func interactionValue(opt *discordgo.ApplicationCommandInteractionDataOption, last bool) string {
+	switch opt.Type {
+	case discordgo.ApplicationCommandOptionUser:
+		return DiscordUser(fmt.Sprint(opt.Value)).Display()
+	case discordgo.ApplicationCommandOptionChannel:
+		return DiscordChannel(fmt.Sprint(opt.Value)).Display()
+	case discordgo.ApplicationCommandOptionRole:
+		return DiscordRole(fmt.Sprint(opt.Value)).Display()
+	case discordgo.ApplicationCommandOptionInteger:
+		if f, ok := opt.Value.(float64); ok {
+			return strconv.FormatInt(int64(f), 10)
+		}
+	}
+	s := fmt.Sprint(opt.Value)
+	if !last && (len(s) == 0 || strings.ContainsAny(s, " \t\n")) { // Quote the value so it is parsed as a single argument
+		s = "\"" + strings.Replace(s, "\"", "\\\"", -1) + "\""
+	}
+	return s
+}
+
+// InteractionCommand finds the command an application command interaction refers to, and builds the text command
+// message that is equivalent to it, so it can be processed exactly like a normal command.
+func (info *GuildInfo) InteractionCommand(i *discordgo.Interaction) (Command, *discordgo.Message, error) {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return nil, nil, errors.New("not an application command")
+	}
+	data := i.ApplicationCommandData()
+	c, ok := info.GetCommand(CommandID(strings.ToLower(data.Name)))
+	if !ok {
+		return nil, nil, fmt.Errorf("%s is not a command", data.Name)
+	}
+
+	content := info.Config.Basic.CommandPrefix + strings.ToLower(data.Name)
+	options := data.Options
+	if group, isgroup := c.(*CommandGroup); isgroup && len(options) > 0 && options[0].Type == discordgo.ApplicationCommandOptionSubCommand {
+		if sub := group.Find(options[0].Name); sub != nil {
+			c = sub
+			content += " " + options[0].Name
+		} else if options[0].Name != DefaultSubcommand || group.Default == nil {
+			return nil, nil, fmt.Errorf("%s is not a subcommand of %s", options[0].Name, data.Name)
+		}
+		options = options[0].Options
+	}
+
+	usage := c.Usage(info)
+	if group, isgroup := c.(*CommandGroup); isgroup && group.Default != nil {
+		usage = group.Default.Usage(info)
+	}
+	values := make(map[string]*discordgo.ApplicationCommandInteractionDataOption)
+	for _, opt := range options {
+		values[opt.Name] = opt
+	}
+	params := applicationOptions(usage)
+	skipped := 0
+	for k, p := range params { // Options are sent in whatever order the user filled them in, so we put them back in parameter order
+		opt, ok := values[p.Name]
+		if !ok {
+			skipped++
+			continue
+		}
+		// An omitted option is replaced with an empty argument, which ParseCommandArgs skips, so later options stay in place
+		content += strings.Repeat(" \"\"", skipped) + " " + interactionValue(opt, k+1 == len(params))
+		skipped = 0
+	}
+
+	m := &discordgo.Message{
+		ID:        i.ID,
+		ChannelID: i.ChannelID,
+		GuildID:   i.GuildID,
+		Content:   content,
+		Member:    i.Member,
+		Author:    i.User,
+		Type:      discordgo.MessageTypeDefault,
+	}
+	if i.Member != nil {
+		m.Author = i.Member.User
+	}
+	if m.Author == nil {
+		return nil, nil, errors.New("interaction has no user")
+	}
+	return c, m, nil
+}
+
+func interactionResponses(content string, embed *discordgo.MessageEmbed, ephemeral bool) (responses []*discordgo.InteractionResponseData) {
+	var flags discordgo.MessageFlags
+	if ephemeral {
+		flags = discordgo.MessageFlagsEphemeral
+	}
+	if embed != nil {
+		return []*discordgo.InteractionResponseData{{Embeds: []*discordgo.MessageEmbed{embed}, Flags: flags}}
+	}
+	for _, chunk := range SplitMessage(content) {
+		responses = append(responses, &discordgo.InteractionResponseData{Content: chunk, Flags: flags})
+	}
+	return
+}
+
+// InteractionEphemeral returns true if the reply to an interaction should only be visible to the user who sent it,
+// which is the case for sensitive commands and for interactions that can't be processed at all
+func (info *GuildInfo) InteractionEphemeral(i *discordgo.Interaction) bool {
+	if info == nil {
+		return true
+	}
+	c, _, err := info.InteractionCommand(i)
+	return err != nil || c.Info().Sensitive
+}
+
+// ProcessInteraction runs an application command interaction through the same middleware and Process path as a text
+// command. It returns the interaction response, followed by any followup messages needed for long replies. Replies
+// that would have been sent as a private message are sent as ephemeral messages instead.
+func (sb *SweetieBot) ProcessInteraction(i *discordgo.Interaction, info *GuildInfo) []*discordgo.InteractionResponseData {
+	if info == nil {
+		return interactionResponses(DefaultString(STRING_NO_SERVER), nil, true)
+	}
+	c, m, err := info.InteractionCommand(i)
+	if err != nil {
+		return interactionResponses("```\n"+err.Error()+"```", nil, true)
+	}
+	channelID := DiscordChannel(m.ChannelID)
+	_, isfree := info.Config.Basic.FreeChannels[channelID]
+	args, indices := ParseCommandArguments(m.Content, len(info.Config.Basic.CommandPrefix))
+	skip := 1
+	if _, sub := c.(*subCommand); sub {
+		skip = 2
+	}
+	ctx := &CommandContext{
+		Command:   c,
+		Name:      CommandID(strings.ToLower(c.Info().Name)),
+		Args:      args[skip:],
+		Indices:   indices[skip:],
+		Msg:       m,
+		Info:      info,
+		Channel:   channelID,
+		Timestamp: GetTimestamp(m).Unix(),
+		Debug:     info.IsDebug(channelID),
+		Free:      isfree,
+	}
+	if err := info.RunCommand(ctx); err == ErrCommandAborted {
+		return interactionResponses(info.GetString(STRING_INTERACTION_ABORTED), nil, true)
+	} else if err != nil {
+		return interactionResponses(err.Error(), nil, true)
+	}
+	if len(ctx.Result) == 0 && ctx.Embed == nil { // Every interaction needs a response, even if the command already replied on its own
+		return interactionResponses(info.GetString(STRING_INTERACTION_DONE), nil, true)
+	}
+	return interactionResponses(ctx.Result, ctx.Embed, ctx.UsePM)
+}
+
+// InteractionCreate discord hook
+func (sb *SweetieBot) InteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+	info := sb.getGuildFromID(i.GuildID)
+	if info != nil && boolXOR(sb.Debug, info.IsDebug(DiscordChannel(i.ChannelID))) {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			sb.Logger.Error(fmt.Sprint("Error while processing interaction: ", r), LogFields{"guild": i.GuildID})
+		}
+	}()
+
+	// Commands can take longer than the 3 seconds discord allows for a response, so we defer it and edit it afterwards.
+	// Whether the response is ephemeral can't be changed after deferring it, so this has to be decided up front.
+	ephemeral := info.InteractionEphemeral(i.Interaction)
+	var flags discordgo.MessageFlags
+	if ephemeral {
+		flags = discordgo.MessageFlagsEphemeral
+	}
+	if err := sb.DG.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: flags},
+	}); err != nil {
+		sb.Logger.Error("Error responding to interaction: "+err.Error(), LogFields{"guild": i.GuildID})
+		return
+	}
+	responses := sb.ProcessInteraction(i.Interaction, info)
+	first := responses[0]
+	if !ephemeral && first.Flags&discordgo.MessageFlagsEphemeral != 0 {
+		// A reply that would have been a private message can't go in a public response, so it is sent as ephemeral followups
+		first = &discordgo.InteractionResponseData{Content: info.GetString(STRING_INTERACTION_PRIVATE)}
+	} else {
+		responses = responses[1:]
+	}
+	if _, err := sb.DG.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &first.Content, Embeds: &first.Embeds}); err != nil {
+		sb.Logger.Error("Error editing interaction response: "+err.Error(), LogFields{"guild": i.GuildID})
+	}
+	for _, r := range responses {
+		if _, err := sb.DG.FollowupMessageCreate(i.Interaction, false, &discordgo.WebhookParams{Content: r.Content, Embeds: r.Embeds, Flags: r.Flags | flags}); err != nil {
+			sb.Logger.Error("Error sending interaction followup: "+err.Error(), LogFields{"guild": i.GuildID})
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package sweetiebot
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type echoTestCommand struct{}
+
+func (c *echoTestCommand) Info() *CommandInfo {
+	return &CommandInfo{Name: "Echo", Usage: "Repeats the arguments."}
+}
+func (c *echoTestCommand) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	return ProcessTypedCommand(c, args, msg, indices, info)
+}
+func (c *echoTestCommand) ProcessArgs(args *CommandArgs, msg *discordgo.Message, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	return fmt.Sprintf("%d %s %s", args.Int("count"), args.User("target"), args.String("reason")), args.Has("reason"), nil
+}
+func (c *echoTestCommand) Usage(info *GuildInfo) *CommandUsage {
+	return &CommandUsage{
+		Desc: "Repeats the arguments.",
+		Params: []CommandUsageParam{
+			{Name: "count", Desc: "A number.", Type: ParamInt},
+			{Name: "target user", Type: ParamUser, Optional: true},
+			{Name: "reason", Desc: "Some text.", Type: ParamRest, Optional: true},
+		},
+	}
+}
+
+type interactionTestModule struct{}
+
+func (m *interactionTestModule) Name() string                       { return "Test" }
+func (m *interactionTestModule) Commands() []Command                { return nil }
+func (m *interactionTestModule) Description(info *GuildInfo) string { return "" }
+
+func newInteractionTestGuild() *GuildInfo {
+	sb := &SweetieBot{DB: &BotDB{}, DG: &DiscordGoSession{&discordgo.Session{State: discordgo.NewState()}}}
+	info := NewGuildInfo(sb, &discordgo.Guild{ID: "1"})
+	info.Config.SetupDone = true
+	info.Config.Basic.FreeChannels = map[DiscordChannel]bool{"2": true}
+	info.AddCommand(&echoTestCommand{}, &interactionTestModule{})
+	info.AddCommand(NewCommandGroup("Group", "A group.", &groupTestCommand{"Default"}).Add("Sub", &groupTestCommand{"Sub"}), &interactionTestModule{})
+	return info
+}
+
+func parseTestInteraction(t *testing.T, data string) *discordgo.Interaction {
+	var i discordgo.Interaction
+	payload := `{"id":"1100000000000000000","application_id":"5","type":2,"guild_id":"1","channel_id":"2","token":"token","member":{"user":{"id":"3","username":"Test"}},"data":` + data + `}`
+	if err := json.Unmarshal([]byte(payload), &i); err != nil {
+		t.Fatal(err)
+	}
+	return &i
+}
+
+func TestApplicationCommand(t *testing.T) {
+	info := newInteractionTestGuild()
+	cmd := info.ApplicationCommand(&echoTestCommand{})
+	Check(cmd.Name, "echo", t)
+	Check(cmd.Description, "Repeats the arguments.", t)
+	Check(len(cmd.Options), 3, t)
+	Check(cmd.Options[0].Name, "count", t)
+	Check(cmd.Options[0].Type, discordgo.ApplicationCommandOptionInteger, t)
+	Check(cmd.Options[0].Required, true, t)
+	Check(cmd.Options[1].Name, "target_user", t)
+	Check(cmd.Options[1].Type, discordgo.ApplicationCommandOptionUser, t)
+	Check(cmd.Options[1].Description, "target user", t)
+	Check(cmd.Options[1].Required, false, t)
+	Check(cmd.Options[2].Type, discordgo.ApplicationCommandOptionString, t)
+	Check(cmd.DefaultMemberPermissions == nil, true, t)
+	Check(*info.ApplicationCommand(&setConfigCommand{}).DefaultMemberPermissions, int64(discordgo.PermissionManageMessages), t) // Sensitive commands are hidden from regular users by default
+
+	cmds := info.ApplicationCommands()
+	Check(len(cmds), 2, t)
+	Check(cmds[0].Name, "echo", t)
+	Check(cmds[1].Name, "group", t)
+	Check(len(cmds[1].Options), 2, t)
+	Check(cmds[1].Options[0].Name, DefaultSubcommand, t)
+	Check(cmds[1].Options[0].Type, discordgo.ApplicationCommandOptionSubCommand, t)
+	Check(cmds[1].Options[1].Name, "sub", t)
+}
+
+func TestProcessInteraction(t *testing.T) {
+	info := newInteractionTestGuild()
+	i := parseTestInteraction(t, `{"id":"9","name":"echo","type":1,"options":[{"name":"reason","type":3,"value":"being  rude"},{"name":"target_user","type":6,"value":"4"},{"name":"count","type":4,"value":3}]}`)
+	c, m, err := info.InteractionCommand(i)
+	Check(err, nil, t)
+	Check(c.Info().Name, "Echo", t)
+	Check(m.Content, "!echo 3 <@4> being  rude", t)
+	Check(m.Author.ID, "3", t)
+
+	r := info.Bot.ProcessInteraction(parseTestInteraction(t, `{"id":"9","name":"echo","type":1,"options":[{"name":"reason","type":3,"value":"being  rude"},{"name":"count","type":4,"value":3}]}`), info)
+	Check(len(r), 1, t)
+	Check(r[0].Content, "3  being  rude", t)
+	Check(r[0].Flags, discordgo.MessageFlagsEphemeral, t)
+
+	_, m, err = info.InteractionCommand(parseTestInteraction(t, `{"id":"9","name":"echo","type":1,"options":[{"name":"reason","type":3,"value":"<@4>"},{"name":"count","type":4,"value":3}]}`))
+	Check(err, nil, t)
+	Check(m.Content, "!echo 3 \"\" <@4>", t) // The omitted user option keeps its place, so the reason isn't parsed as a user
+	r = info.Bot.ProcessInteraction(parseTestInteraction(t, `{"id":"9","name":"echo","type":1,"options":[{"name":"reason","type":3,"value":"<@4>"},{"name":"count","type":4,"value":3}]}`), info)
+	Check(r[0].Content, "3  <@4>", t)
+
+	r = info.Bot.ProcessInteraction(parseTestInteraction(t, `{"id":"9","name":"echo","type":1,"options":[{"name":"count","type":4,"value":7}]}`), info)
+	Check(r[0].Content, "7  ", t)
+	Check(r[0].Flags, discordgo.MessageFlags(0), t)
+
+	r = info.Bot.ProcessInteraction(parseTestInteraction(t, `{"id":"9","name":"group","type":1,"options":[{"name":"sub","type":1,"options":[]}]}`), info)
+	Check(r[0].Content, "Sub", t)
+	r = info.Bot.ProcessInteraction(parseTestInteraction(t, `{"id":"9","name":"group","type":1,"options":[{"name":"run","type":1,"options":[]}]}`), info)
+	Check(r[0].Content, "Default", t)
+
+	r = info.Bot.ProcessInteraction(parseTestInteraction(t, `{"id":"9","name":"missing","type":1}`), info)
+	Check(r[0].Flags, discordgo.MessageFlagsEphemeral, t)
+
+	var nilinfo *GuildInfo
+	Check(nilinfo.InteractionEphemeral(i), true, t)
+	Check(info.InteractionEphemeral(i), false, t)
+	Check(info.InteractionEphemeral(parseTestInteraction(t, `{"id":"9","name":"missing","type":1}`)), true, t)
+}
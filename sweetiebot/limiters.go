@@ -1,6 +1,7 @@
 package sweetiebot
 
 import (
+	"sync"
 	"sync/atomic"
 )
 
@@ -94,3 +95,101 @@ func (b *AtomicBool) Set(value bool) {
 	}
 	atomic.StoreUint32(&b.flag, v)
 }
+
+// UserLimit is a per-user cooldown and quota for a single command, or for all commands if the command is *
+type UserLimit struct {
+	Command  CommandID
+	Cooldown int64 // Minimum number of seconds between two uses, or 0 for no cooldown
+	Quota    int64 // Maximum number of uses within Window seconds, or 0 for no quota
+	Window   int64
+}
+
+type userUses struct {
+	times   []int64 // Times of recent uses, oldest first
+	expires int64   // After this time, none of the uses matter anymore
+}
+
+// UserLimiter tracks when each user ran each command so it can enforce per-user cooldowns and quotas
+type UserLimiter struct {
+	lock      sync.Mutex
+	users     map[DiscordUser]map[CommandID]*userUses
+	lastsweep int64
+}
+
+// NewUserLimiter creates an empty UserLimiter
+func NewUserLimiter() *UserLimiter {
+	return &UserLimiter{users: make(map[DiscordUser]map[CommandID]*userUses)}
+}
+
+// retry returns the time the user will be allowed to use the command again under this limit, or 0 if they can use it now.
+// The boolean is true if the cooldown is what's stopping them, instead of the quota.
+func (l *UserLimit) retry(uses *userUses, curtime int64) (int64, bool) {
+	if uses == nil || len(uses.times) == 0 {
+		return 0, false
+	}
+	if l.Cooldown > 0 && curtime-uses.times[len(uses.times)-1] < l.Cooldown {
+		return uses.times[len(uses.times)-1] + l.Cooldown, true
+	}
+	if l.Quota > 0 && int64(len(uses.times)) >= l.Quota {
+		if oldest := uses.times[int64(len(uses.times))-l.Quota]; curtime-oldest < l.Window {
+			return oldest + l.Window, false
+		}
+	}
+	return 0, false
+}
+
+// Use checks every limit that applies to a command. If none of them are violated, the use is recorded and this returns nil.
+// Otherwise, it returns the limit that stopped them, the time they can try again, and whether it was the cooldown or the quota.
+func (u *UserLimiter) Use(user DiscordUser, limits []UserLimit, curtime int64) (*UserLimit, int64, bool) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if curtime-u.lastsweep > 3600 {
+		u.sweep(curtime)
+	}
+	commands := u.users[user]
+	for i := range limits {
+		if retry, cooldown := limits[i].retry(commands[limits[i].Command], curtime); retry > 0 {
+			return &limits[i], retry, cooldown
+		}
+	}
+	if len(limits) > 0 && commands == nil {
+		commands = make(map[CommandID]*userUses)
+		u.users[user] = commands
+	}
+	for _, l := range limits {
+		uses, ok := commands[l.Command]
+		if !ok {
+			uses = &userUses{}
+			commands[l.Command] = uses
+		}
+		uses.times = append(uses.times, curtime)
+		if l.Quota > 0 && int64(len(uses.times)) > l.Quota { // We only need to remember enough uses to check the quota
+			uses.times = uses.times[int64(len(uses.times))-l.Quota:]
+		} else if l.Quota == 0 {
+			uses.times = uses.times[len(uses.times)-1:]
+		}
+		expires := curtime + l.Cooldown
+		if l.Quota > 0 && curtime+l.Window > expires {
+			expires = curtime + l.Window
+		}
+		if expires > uses.expires {
+			uses.expires = expires
+		}
+	}
+	return nil, 0, false
+}
+
+// sweep removes any uses that have expired so users who stopped running commands don't stay in memory forever
+func (u *UserLimiter) sweep(curtime int64) {
+	u.lastsweep = curtime
+	for user, commands := range u.users {
+		for k, v := range commands {
+			if v.expires < curtime {
+				delete(commands, k)
+			}
+		}
+		if len(commands) == 0 {
+			delete(u.users, user)
+		}
+	}
+}
@@ -1,24 +1,255 @@
 package sweetiebot
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
-type logger interface {
-	Log(args ...interface{})
-	LogError(msg string, err error)
+// LogLevel is the severity of a log entry
+type LogLevel int
+
+// Log levels, from least to most severe
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	numLevels
+)
+
+var levelNames = [numLevels]string{"debug", "info", "warn", "error"}
+
+func (l LogLevel) String() string {
+	if l >= 0 && l < numLevels {
+		return levelNames[l]
+	}
+	return "unknown"
+}
+
+// ParseLogLevel converts the name of a log level into a LogLevel
+func ParseLogLevel(s string) (LogLevel, error) {
+	for i, v := range levelNames {
+		if strings.EqualFold(s, v) {
+			return LogLevel(i), nil
+		}
+	}
+	return LevelDebug, fmt.Errorf("%s is not a log level", s)
 }
 
-type emptyLog struct{}
+// Log destinations that a level can be routed to
+const (
+	LogStdout  = "stdout"
+	LogFile    = "file"
+	LogChannel = "channel" // The log.channel of the guild the entry belongs to
+)
+
+// LogConfig stores the logging options from selfhost.json
+type LogConfig struct {
+	JSON   bool                `json:"json"`   // Write entries to stdout and the log file as JSON objects
+	File   string              `json:"file"`   // Path of the log file, which is appended to
+	Routes map[string][]string `json:"routes"` // Maps each level to the destinations it is written to
+}
 
-func (log *emptyLog) Log(args ...interface{}) {
-	s := fmt.Sprint(args...)
-	fmt.Printf("[%s] %s\n", time.Now().Format(time.Stamp), s)
+// DefaultLogRoutes are used for any level that doesn't have a route in the log config. Debug messages never reach
+// log.channel unless they are explicitly routed there.
+var DefaultLogRoutes = map[string][]string{
+	"debug": {LogStdout},
+	"info":  {LogStdout, LogChannel},
+	"warn":  {LogStdout, LogChannel},
+	"error": {LogStdout, LogFile, LogChannel},
 }
 
-func (log *emptyLog) LogError(msg string, err error) {
+// LogFields are the structured fields attached to a log entry
+type LogFields map[string]interface{}
+
+const (
+	routeStdout = 1 << iota
+	routeFile
+	routeChannel
+)
+
+type logOutput struct {
+	lock   sync.Mutex
+	json   bool
+	routes [numLevels]uint8
+	stdout io.Writer
+	file   io.WriteCloser
+}
+
+// Logger writes leveled, structured log entries to the destinations configured for each level. Loggers created with
+// With share their destinations with the logger they came from. A nil Logger writes to stdout.
+type Logger struct {
+	out     *logOutput
+	fields  LogFields
+	channel func(string) // Posts a message to log.channel, if this logger belongs to a guild
+}
+
+var stdLogger = &Logger{out: &logOutput{routes: [numLevels]uint8{routeStdout, routeStdout, routeStdout, routeStdout}, stdout: os.Stdout}}
+
+// NewLogger creates a logger from the logging options in selfhost.json. A nil config uses the default routes.
+func NewLogger(config *LogConfig) (*Logger, error) {
+	if config == nil {
+		config = &LogConfig{}
+	}
+	out := &logOutput{json: config.JSON, stdout: os.Stdout}
+	for i, name := range levelNames {
+		routes, ok := config.Routes[name]
+		if !ok {
+			routes = DefaultLogRoutes[name]
+		}
+		for _, r := range routes {
+			switch strings.ToLower(r) {
+			case LogStdout:
+				out.routes[i] |= routeStdout
+			case LogFile:
+				out.routes[i] |= routeFile
+			case LogChannel:
+				out.routes[i] |= routeChannel
+			default:
+				return nil, fmt.Errorf("%s is not a log destination for %s messages", r, name)
+			}
+		}
+	}
+	for k := range config.Routes {
+		if _, err := ParseLogLevel(k); err != nil {
+			return nil, err
+		}
+	}
+	if len(config.File) > 0 {
+		f, err := os.OpenFile(config.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0664)
+		if err != nil {
+			return nil, err
+		}
+		out.file = f
+	}
+	return &Logger{out: out}, nil
+}
+
+func (l *Logger) get() *Logger {
+	if l == nil {
+		return stdLogger
+	}
+	return l
+}
+
+// With returns a logger that adds the given fields to every entry
+func (l *Logger) With(fields LogFields) *Logger {
+	l = l.get()
+	merged := make(LogFields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &Logger{out: l.out, fields: merged, channel: l.channel}
+}
+
+// WithChannel returns a logger that posts entries routed to log.channel using the given function
+func (l *Logger) WithChannel(channel func(string)) *Logger {
+	l = l.get()
+	return &Logger{out: l.out, fields: l.fields, channel: channel}
+}
+
+// Close closes the log file, if there is one
+func (l *Logger) Close() error {
+	l = l.get()
+	l.out.lock.Lock()
+	defer l.out.lock.Unlock()
+	if l.out.file != nil {
+		err := l.out.file.Close()
+		l.out.file = nil
+		return err
+	}
+	return nil
+}
+
+// Debug logs a message that is only useful when debugging
+func (l *Logger) Debug(msg string, fields ...LogFields) { l.Write(LevelDebug, msg, fields...) }
+
+// Info logs a normal message
+func (l *Logger) Info(msg string, fields ...LogFields) { l.Write(LevelInfo, msg, fields...) }
+
+// Warn logs something that might be a problem
+func (l *Logger) Warn(msg string, fields ...LogFields) { l.Write(LevelWarn, msg, fields...) }
+
+// Error logs an error message
+func (l *Logger) Error(msg string, fields ...LogFields) { l.Write(LevelError, msg, fields...) }
+
+// LogError logs msg followed by the error at the error level, but only if the error isn't nil
+func (l *Logger) LogError(msg string, err error) {
 	if err != nil {
-		log.Log(msg, err.Error())
+		l.Error(msg + err.Error())
+	}
+}
+
+func (l *Logger) format(level LogLevel, msg string, fields LogFields, t time.Time) []byte {
+	if l.out.json {
+		entry := make(map[string]interface{}, len(fields)+3)
+		for k, v := range fields {
+			if err, ok := v.(error); ok {
+				v = err.Error()
+			}
+			entry[k] = v
+		}
+		entry["time"] = t.UTC().Format(time.RFC3339)
+		entry["level"] = level.String()
+		entry["msg"] = msg
+		b, err := json.Marshal(entry)
+		if err != nil {
+			b, _ = json.Marshal(map[string]string{"time": entry["time"].(string), "level": level.String(), "msg": msg})
+		}
+		return append(b, '\n')
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s %s", t.Format(time.Stamp), strings.ToUpper(level.String()), msg)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, fields[k])
+	}
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+// Write logs a message at the given level with any additional fields
+func (l *Logger) Write(level LogLevel, msg string, fields ...LogFields) {
+	l = l.get()
+	if level < 0 || level >= numLevels {
+		level = LevelError
+	}
+	routes := l.out.routes[level]
+	all := l.fields
+	if len(fields) > 0 {
+		all = l.With(fields[0]).fields
+		for _, f := range fields[1:] {
+			for k, v := range f {
+				all[k] = v
+			}
+		}
+	}
+	if routes&(routeStdout|routeFile) != 0 {
+		line := l.format(level, msg, all, time.Now())
+		l.out.lock.Lock()
+		if routes&routeStdout != 0 && l.out.stdout != nil {
+			l.out.stdout.Write(line)
+		}
+		if routes&routeFile != 0 && l.out.file != nil {
+			l.out.file.Write(line)
+		}
+		l.out.lock.Unlock()
+	}
+	if routes&routeChannel != 0 && l.channel != nil {
+		l.channel(msg)
 	}
 }
@@ -0,0 +1,53 @@
+package sweetiebot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	l, err := NewLogger(&LogConfig{Routes: map[string][]string{"info": {"stdout"}}})
+	Check(err, nil, t)
+	var out bytes.Buffer
+	l.out.stdout = &out
+	posted := []string{}
+	guild := l.With(LogFields{"guild": "1"}).WithChannel(func(s string) { posted = append(posted, s) })
+
+	guild.Info("hello", LogFields{"module": "test"})
+	Check(strings.HasSuffix(out.String(), " INFO hello guild=1 module=test\n"), true, t)
+	Check(len(posted), 0, t) // info is only routed to stdout
+	guild.Debug("noise")
+	Check(strings.Contains(out.String(), "noise"), true, t)
+	Check(len(posted), 0, t) // debug never reaches the channel by default
+	guild.LogError("failed: ", nil)
+	guild.LogError("failed: ", errTestLog)
+	Check(len(posted), 1, t)
+	Check(posted[0], "failed: test error", t)
+	l.Info("no guild")
+	Check(strings.HasSuffix(out.String(), " INFO no guild\n"), true, t)
+
+	l, err = NewLogger(&LogConfig{JSON: true})
+	Check(err, nil, t)
+	out.Reset()
+	l.out.stdout = &out
+	l.With(LogFields{"guild": "2"}).Warn("careful", LogFields{"user": DiscordUser("3")})
+	entry := map[string]interface{}{}
+	Check(json.Unmarshal(out.Bytes(), &entry), nil, t)
+	Check(entry["level"], "warn", t)
+	Check(entry["msg"], "careful", t)
+	Check(entry["guild"], "2", t)
+	Check(entry["user"], "3", t)
+
+	_, err = NewLogger(&LogConfig{Routes: map[string][]string{"info": {"nowhere"}}})
+	Check(err != nil, true, t)
+	_, err = NewLogger(&LogConfig{Routes: map[string][]string{"verbose": {"stdout"}}})
+	Check(err != nil, true, t)
+
+	var nilLogger *Logger
+	nilLogger.Debug("a nil logger writes to stdout instead of crashing")
+}
+
+var errTestLog = errors.New("test error")
@@ -0,0 +1,134 @@
+package sweetiebot
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "sweetiebot"
+
+var (
+	metricCommands = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "commands_total",
+		Help:      "Number of commands run, including ones that were rejected by middleware.",
+	}, []string{"guild", "command"})
+	metricCommandErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "command_errors_total",
+		Help:      "Number of commands that failed with an error, such as a permission or rate limit error.",
+	}, []string{"guild", "command"})
+	metricCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "command_duration_seconds",
+		Help:      "Time taken to run a command through the middleware chain.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+	}, []string{"command"})
+	metricHookDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "module_hook_duration_seconds",
+		Help:      "Time taken by a module to process a discord event.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 8),
+	}, []string{"module", "hook"})
+	metricDBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Time taken to execute a database query, by the type of statement.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 4, 8),
+	}, []string{"statement"})
+	metricDBOutages = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "db_outages_total",
+		Help:      "Number of times the bot lost its database connection and entered No Database mode.",
+	})
+	metricMessagesSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "messages_sent_total",
+		Help:      "Number of messages sent to discord.",
+	}, []string{"guild", "type"})
+	metricSilences = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "silences_total",
+		Help:      "Number of users silenced by the spam module.",
+	}, []string{"guild"})
+	metricRaids = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "raids_total",
+		Help:      "Number of raids detected by the spam module.",
+	}, []string{"guild"})
+)
+
+// RecordSilence counts a user being silenced by the spam module in the given guild
+func RecordSilence(guild string) {
+	metricSilences.WithLabelValues(guild).Inc()
+}
+
+// RecordRaid counts a raid being detected in the given guild
+func RecordRaid(guild string) {
+	metricRaids.WithLabelValues(guild).Inc()
+}
+
+func observeCommand(ctx *CommandContext, start time.Time, err error) {
+	if ctx.Channel == "heartbeat" { // Heartbeats would drown out the real commands
+		return
+	}
+	command := string(ctx.Name)
+	metricCommands.WithLabelValues(ctx.Info.ID, command).Inc()
+	if err != nil && err != ErrCommandAborted {
+		metricCommandErrors.WithLabelValues(ctx.Info.ID, command).Inc()
+	}
+	metricCommandDuration.WithLabelValues(command).Observe(time.Since(start).Seconds())
+}
+
+func observeHook(m Module, hook string, start time.Time) {
+	metricHookDuration.WithLabelValues(strings.ToLower(m.Name()), hook).Observe(time.Since(start).Seconds())
+}
+
+// NewMetricsHandler creates a registry containing all the bot metrics and returns an http handler that serves it
+func (sb *SweetieBot) NewMetricsHandler() http.Handler {
+	r := prometheus.NewRegistry()
+	r.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		metricCommands,
+		metricCommandErrors,
+		metricCommandDuration,
+		metricHookDuration,
+		metricDBQueryDuration,
+		metricDBOutages,
+		metricMessagesSent,
+		metricSilences,
+		metricRaids,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "db_up",
+			Help:      "1 if the database is connected, or 0 if the bot is in No Database mode.",
+		}, func() float64 {
+			if sb.DB != nil && sb.DB.Status.Get() {
+				return 1
+			}
+			return 0
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "messages_received_total",
+			Help:      "Number of messages the bot has processed.",
+		}, func() float64 { return float64(atomic.LoadUint32(&sb.MessageCount)) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "guilds",
+			Help:      "Number of guilds the bot is in.",
+		}, func() float64 {
+			sb.GuildsLock.RLock()
+			defer sb.GuildsLock.RUnlock()
+			return float64(len(sb.Guilds))
+		}),
+	)
+	return promhttp.HandlerFor(r, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,50 @@
+package sweetiebot
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestStatementType(t *testing.T) {
+	Check(statementType("SELECT * FROM users"), "select", t)
+	Check(statementType("  insert INTO tags (Name) VALUES (?)"), "insert", t)
+	Check(statementType("CALL AddChat(?,?,?,?,?,?)"), "call", t)
+	Check(statementType("PRAGMA foreign_keys"), "other", t)
+	Check(statementType(""), "other", t)
+}
+
+func TestMetrics(t *testing.T) {
+	metricSilences.DeleteLabelValues("metrics") // The counters are global, so reset them in case the test runs more than once
+	metricRaids.DeleteLabelValues("metrics")
+	db := mockSQLiteDB(t)
+	defer db.Close()
+	db.AddUser(12345, "Test", 1, true)
+	if u, _, _, _ := db.GetUser(12345); u == nil {
+		t.Fatal("failed to get user")
+	}
+	if testutil.CollectAndCount(metricDBQueryDuration) == 0 {
+		t.Error("expected database queries to be timed")
+	}
+
+	sb := &SweetieBot{DB: db, Guilds: make(map[DiscordGuild]*GuildInfo), DG: &DiscordGoSession{Session: &discordgo.Session{State: discordgo.NewState()}}}
+	info := NewGuildInfo(sb, &discordgo.Guild{ID: "metrics"})
+	sb.Guilds["metrics"] = info
+	RecordSilence(info.ID)
+	RecordRaid(info.ID)
+	Check(testutil.ToFloat64(metricSilences.WithLabelValues("metrics")), 1.0, t)
+	Check(testutil.ToFloat64(metricRaids.WithLabelValues("metrics")), 1.0, t)
+
+	w := httptest.NewRecorder()
+	sb.NewMetricsHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	Check(w.Code, 200, t)
+	body := w.Body.String()
+	for _, s := range []string{"sweetiebot_db_up 1", "sweetiebot_guilds 1", `sweetiebot_silences_total{guild="metrics"} 1`, `sweetiebot_raids_total{guild="metrics"} 1`, "sweetiebot_db_query_duration_seconds_bucket"} {
+		if !strings.Contains(body, s) {
+			t.Errorf("metrics output is missing %s", s)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package sweetiebot
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// CommandContext holds the state of a single command as it passes through the middleware chain
+type CommandContext struct {
+	Command   Command
+	Name      CommandID
+	Module    ModuleID // Filled in by RunCommand if it is empty
+	Args      []string
+	Indices   []int
+	Msg       *discordgo.Message
+	Info      *GuildInfo
+	Channel   DiscordChannel
+	Timestamp int64
+	Private   bool // True if the command was sent in a private message
+	Debug     bool // True if the command was sent in a debug channel
+	Free      bool // True if the command was sent in a channel that is exempt from rate limits
+	Bypass    bool // True if the user is allowed to bypass rate limits, which is set by the permissions middleware
+	Result    string
+	UsePM     bool
+	Embed     *discordgo.MessageEmbed
+}
+
+// CommandHandler runs the rest of the middleware chain. Returning an error aborts the command and sends the error to the channel, unless it is ErrCommandAborted.
+type CommandHandler func(*CommandContext) error
+
+// CommandMiddleware is called before a command is processed, and must call next to continue processing it. The result of the command is available in the context after next returns.
+type CommandMiddleware func(ctx *CommandContext, next CommandHandler) error
+
+// ModuleCommandMiddleware is implemented by modules that want to wrap their own commands with a middleware
+type ModuleCommandMiddleware interface {
+	Module
+	CommandMiddleware(ctx *CommandContext, next CommandHandler) error
+}
+
+// ErrCommandAborted stops a command without sending an error message
+var ErrCommandAborted = errors.New("command aborted")
+
+// coreMiddleware runs before any guild or module middleware
+var coreMiddleware = []CommandMiddleware{auditMiddleware, setupMiddleware, permissionsMiddleware, rateLimitMiddleware, userLimitMiddleware}
+
+// AddMiddleware adds a middleware that runs on every command in this guild, after the core middleware
+func (info *GuildInfo) AddMiddleware(m CommandMiddleware) {
+	info.commandLock.Lock()
+	info.middleware = append(info.middleware, m)
+	info.commandLock.Unlock()
+}
+
+func chainMiddleware(middleware []CommandMiddleware, final CommandHandler) CommandHandler {
+	h := final
+	for i := len(middleware) - 1; i >= 0; i-- {
+		m, next := middleware[i], h
+		h = func(ctx *CommandContext) error { return m(ctx, next) }
+	}
+	return h
+}
+
+// RunCommand processes the command through the core, guild, and module middleware, leaving the result in the context
+func (info *GuildInfo) RunCommand(ctx *CommandContext) error {
+	info.commandLock.RLock()
+	if len(ctx.Module) == 0 {
+		ctx.Module = info.commandmap[ctx.Name]
+	}
+	chain := make([]CommandMiddleware, 0, len(coreMiddleware)+len(info.middleware)+len(info.moduleMiddleware[ctx.Module]))
+	chain = append(chain, coreMiddleware...)
+	chain = append(chain, info.middleware...)
+	chain = append(chain, info.moduleMiddleware[ctx.Module]...)
+	info.commandLock.RUnlock()
+	start := time.Now()
+	err := chainMiddleware(chain, processCommand)(ctx)
+	observeCommand(ctx, start, err)
+	return err
+}
+
+func processCommand(ctx *CommandContext) error {
+	if typed, ok := ctx.Command.(TypedCommand); ok {
+		ctx.Result, ctx.UsePM, ctx.Embed = ProcessTypedCommand(typed, ctx.Args, ctx.Msg, ctx.Indices, ctx.Info)
+	} else {
+		ctx.Result, ctx.UsePM, ctx.Embed = ctx.Command.Process(ctx.Args, ctx.Msg, ctx.Indices, ctx.Info)
+	}
+	return nil
+}
+
+func auditMiddleware(ctx *CommandContext, next CommandHandler) error {
+	sb := ctx.Info.Bot
+	if sb.DB.Status.Get() && !sb.SelfID.Equals(ctx.Msg.Author.ID) {
+		sb.DB.Audit(AuditTypeCommand, ctx.Msg.Author, ctx.Msg.Content, SBatoi(ctx.Info.ID))
+	}
+	return next(ctx)
+}
+
+func setupMiddleware(ctx *CommandContext, next CommandHandler) error {
+	if ctx.Channel != "heartbeat" && !ctx.Info.Config.SetupDone && ctx.Name != CommandID("setup") {
+		return fmt.Errorf(ctx.Info.GetString(STRING_SETUP_MESSAGE), ctx.Info.Config.Basic.CommandPrefix)
+	}
+	return next(ctx)
+}
+
+func permissionsMiddleware(ctx *CommandContext, next CommandHandler) error {
+	info := ctx.Info
+	ignore := false
+	if !ctx.Private {
+		ignore = info.checkOnCommand(ctx.Msg)
+		cch := info.Config.Modules.CommandChannels[ctx.Name]
+		if len(cch) > 0 {
+			_, reverse := cch["!"]
+			_, ok := cch[ctx.Channel]
+			ignore = ignore || ok == reverse
+		}
+	}
+
+	bypass, err := info.UserCanUseCommand(DiscordUser(ctx.Msg.Author.ID), ctx.Command, ignore) // Bypass is true for administrators, mods, and the bot owner
+	if ctx.Channel == "heartbeat" {                                                            // The heartbeat can never be ignored or disabled
+		bypass = true
+		err = nil
+	} else if err == errDisabled || err == errIgnored || err == errSilenced || err == errMainGuild {
+		return ErrCommandAborted
+	}
+	if err != nil {
+		return err
+	}
+	ctx.Bypass = bypass
+	return next(ctx)
+}
+
+func rateLimitMiddleware(ctx *CommandContext, next CommandHandler) error {
+	info := ctx.Info
+	if !ctx.Debug && !ctx.Free && !ctx.Bypass && info.Config.Modules.CommandPerDuration > 0 { // debug channels aren't limited
+		if len(info.commandlimit.times) < info.Config.Modules.CommandPerDuration*2 { // Check if we need to re-allocate the array because the configuration changed
+			info.commandlimit.times = make([]int64, info.Config.Modules.CommandPerDuration*2, info.Config.Modules.CommandPerDuration*2)
+		}
+		if info.commandlimit.check(info.Config.Modules.CommandPerDuration, info.Config.Modules.CommandMaxDuration, ctx.Timestamp) { // if we've hit the saturation limit, post an error (which itself will only post if the error saturation limit hasn't been hit)
+			return fmt.Errorf(info.GetString(STRING_COMMANDS_LIMIT), info.Config.Modules.CommandPerDuration, TimeDiff(time.Duration(info.Config.Modules.CommandMaxDuration)*time.Second), info.Bot.getAddMsg(info))
+		}
+		info.commandlimit.append(ctx.Timestamp)
+	}
+
+	cmdlimit := info.Config.Modules.CommandLimits[ctx.Name]
+	if !ctx.Free && cmdlimit > 0 && !ctx.Bypass {
+		cmdhash := ctx.Channel.String() + string(ctx.Name)
+		info.commandLock.RLock()
+		lastcmd := info.commandLast[cmdhash]
+		info.commandLock.RUnlock()
+		if !RateLimit(&lastcmd, cmdlimit, ctx.Timestamp) {
+			return fmt.Errorf(info.GetString(STRING_COMMAND_LIMIT), TimeDiff(time.Duration(cmdlimit)*time.Second), info.Bot.getAddMsg(info))
+		}
+		info.commandLock.Lock()
+		info.commandLast[cmdhash] = ctx.Timestamp
+		info.commandLock.Unlock()
+	}
+	return next(ctx)
+}
+
+func userLimitMiddleware(ctx *CommandContext, next CommandHandler) error {
+	info := ctx.Info
+	if ctx.Debug || ctx.Free || ctx.Bypass || ctx.Channel == "heartbeat" {
+		return next(ctx)
+	}
+	if err := info.useUserLimits(DiscordUser(ctx.Msg.Author.ID), ctx.Name, ctx.Timestamp); err != nil {
+		return err
+	}
+	return next(ctx)
+}
+
+// useUserLimits counts a use of the command against the user's cooldowns and quotas, or returns an error if they've hit one
+func (info *GuildInfo) useUserLimits(user DiscordUser, name CommandID, timestamp int64) error {
+	limits := info.GetUserLimits(user, name)
+	if len(limits) == 0 || info.UserIsMod(user) {
+		return nil
+	}
+	if limit, retry, cooldown := info.userlimit.Use(user, limits, timestamp); limit != nil {
+		wait := TimeDiff(time.Duration(retry-timestamp) * time.Second)
+		if cooldown {
+			return fmt.Errorf(info.GetString(STRING_USER_COOLDOWN), TimeDiff(time.Duration(limit.Cooldown)*time.Second), wait, info.Bot.getAddMsg(info))
+		}
+		return fmt.Errorf(info.GetString(STRING_USER_QUOTA), Pluralize(limit.Quota, " time"), TimeDiff(time.Duration(limit.Window)*time.Second), wait, info.Bot.getAddMsg(info))
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package sweetiebot
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestChainMiddleware(t *testing.T) {
+	order := ""
+	record := func(name string) CommandMiddleware {
+		return func(ctx *CommandContext, next CommandHandler) error {
+			order += name + "("
+			err := next(ctx)
+			order += ctx.Result + ")"
+			return err
+		}
+	}
+	final := func(ctx *CommandContext) error {
+		ctx.Result = "done"
+		return nil
+	}
+
+	ctx := &CommandContext{}
+	Check(chainMiddleware([]CommandMiddleware{record("a"), record("b")}, final)(ctx), nil, t)
+	Check(order, "a(b(done)done)", t)
+
+	order = ""
+	ctx = &CommandContext{}
+	errStop := errors.New("stop")
+	stop := func(ctx *CommandContext, next CommandHandler) error { return errStop }
+	Check(chainMiddleware([]CommandMiddleware{record("a"), stop, record("b")}, final)(ctx), errStop, t)
+	Check(order, "a()", t)
+	Check(ctx.Result, "", t)
+}
+
+func TestUserLimiter(t *testing.T) {
+	u := NewUserLimiter()
+	limits := []UserLimit{{Command: "pick", Cooldown: 10, Window: 100}, {Command: "*", Quota: 3, Window: 100}}
+
+	limit, _, _ := u.Use("1", limits, 1000)
+	Check(limit == nil, true, t)
+	limit, retry, cooldown := u.Use("1", limits, 1005)
+	Check(limit.Command, CommandID("pick"), t)
+	Check(retry, int64(1010), t)
+	Check(cooldown, true, t)
+
+	limit, _, _ = u.Use("2", limits, 1005) // Other users aren't affected
+	Check(limit == nil, true, t)
+	limit, _, _ = u.Use("1", limits, 1010)
+	Check(limit == nil, true, t)
+	limit, _, _ = u.Use("1", limits[1:], 1011)
+	Check(limit == nil, true, t)
+
+	limit, retry, cooldown = u.Use("1", limits[1:], 1012)
+	Check(limit.Command, CommandID("*"), t)
+	Check(retry, int64(1100), t)
+	Check(cooldown, false, t)
+	limit, _, _ = u.Use("1", limits[1:], 1100)
+	Check(limit == nil, true, t)
+
+	u.sweep(1300)
+	Check(len(u.users), 0, t)
+}
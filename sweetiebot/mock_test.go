@@ -24,6 +24,9 @@ func NewMock(t *testing.T) *Mock {
 }
 
 func (m *Mock) Input(args ...interface{}) {
+	if m == nil {
+		return
+	}
 	m.history = append(m.history, args)
 	if !m.Disable && (len(m.history) > len(m.expected) || !m.history[len(m.history)-1].Compare(m.expected[len(m.history)-1])) {
 		_, fn, line, _ := runtime.Caller(2)
@@ -67,6 +67,7 @@ func (b *Selfhost) SelfUpdate(ownerid DiscordUser) {
         // Rewrite MD5 hashes for all .sql files in case we updated them
         WriteMD5(filepath.Join(parent, "sweetiebot.sql"))
         WriteMD5(filepath.Join(parent, "sweetiebot_tz.sql"))
+        WriteMD5(filepath.Join(parent, "sweetiebot_sqlite.sql"))
         WriteMD5(filepath.Join(parent, "legacy_migrate.sql"))
         WriteMD5(filepath.Join(parent, "web.css"))
         WriteMD5(filepath.Join(parent, "web.html"))
@@ -0,0 +1,140 @@
+package sweetiebot
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ModuleFactory creates a new instance of a module for a guild. deps contains the guild's instances of every module
+// the factory was registered as depending on.
+type ModuleFactory func(guild *GuildInfo, deps map[ModuleID]Module) Module
+
+type moduleRegistration struct {
+	id           ModuleID
+	factory      ModuleFactory
+	dependencies []ModuleID
+	optional     map[ModuleID]bool
+}
+
+type moduleRegistry struct {
+	lock    sync.Mutex
+	modules map[ModuleID]*moduleRegistration
+}
+
+var registry = &moduleRegistry{modules: make(map[ModuleID]*moduleRegistration)}
+
+func init() {
+	RegisterModuleFactory("Information", func(guild *GuildInfo, deps map[ModuleID]Module) Module { return &InfoModule{} })
+	RegisterModuleFactory("Configuration", func(guild *GuildInfo, deps map[ModuleID]Module) Module { return &ConfigModule{} })
+	RegisterModuleFactory("Debug", func(guild *GuildInfo, deps map[ModuleID]Module) Module { return &DebugModule{} })
+}
+
+// RegisterModuleFactory makes a module available to the bot. It should be called from the init function of the
+// module's package, with the same name the module returns from Name(). The module is created after all of its
+// dependencies, which are passed to the factory. A dependency ending in ? is optional: if it doesn't exist or was
+// excluded, the module is still loaded and the dependency is left out of deps. Panics if a module with that name was
+// already registered.
+func RegisterModuleFactory(name string, factory ModuleFactory, dependencies ...string) {
+	registry.register(name, factory, dependencies...)
+}
+
+func (r *moduleRegistry) register(name string, factory ModuleFactory, dependencies ...string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	id := ModuleID(strings.ToLower(name))
+	if _, ok := r.modules[id]; ok {
+		panic("RegisterModuleFactory called twice for module " + name)
+	}
+	m := &moduleRegistration{id: id, factory: factory, optional: make(map[ModuleID]bool)}
+	for _, v := range dependencies {
+		d := ModuleID(strings.ToLower(strings.TrimSuffix(v, "?")))
+		m.dependencies = append(m.dependencies, d)
+		m.optional[d] = strings.HasSuffix(v, "?")
+	}
+	r.modules[id] = m
+}
+
+// NewModuleLoader returns a loader that creates every registered module for a guild, except the excluded ones.
+// Modules that depend on an excluded module are also excluded, unless the dependency is optional. Returns an error if
+// a module depends on one that was never registered, or if modules depend on each other in a cycle.
+func NewModuleLoader(exclude []string, logger *Logger) (func(*GuildInfo) []Module, error) {
+	return registry.loader(exclude, logger)
+}
+
+func (r *moduleRegistry) loader(exclude []string, logger *Logger) (func(*GuildInfo) []Module, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	excluded := make(map[ModuleID]bool)
+	for _, v := range exclude {
+		id := ModuleID(strings.ToLower(v))
+		if _, ok := r.modules[id]; !ok {
+			logger.Warn("Can't exclude "+v+" because no module with that name exists", LogFields{"module": v})
+		}
+		excluded[id] = true
+	}
+
+	// Sort the modules so dependencies always come first. Names are sorted to keep the order the same every time.
+	ids := make([]string, 0, len(r.modules))
+	for k := range r.modules {
+		ids = append(ids, string(k))
+	}
+	sort.Strings(ids)
+	order := make([]*moduleRegistration, 0, len(ids))
+	visiting := make(map[ModuleID]bool)
+	visited := make(map[ModuleID]bool)
+	var visit func(id ModuleID) error
+	visit = func(id ModuleID) error {
+		if visited[id] {
+			return nil
+		}
+		if visiting[id] {
+			return errors.New("module " + string(id) + " depends on itself")
+		}
+		visiting[id] = true
+		m := r.modules[id]
+		for _, d := range m.dependencies {
+			if _, ok := r.modules[d]; !ok {
+				if m.optional[d] {
+					continue
+				}
+				return errors.New("module " + string(id) + " depends on " + string(d) + ", which doesn't exist")
+			}
+			if err := visit(d); err != nil {
+				return err
+			}
+			if excluded[d] && !excluded[id] && !m.optional[d] {
+				logger.Warn("Excluding "+string(id)+" because it depends on "+string(d), LogFields{"module": id})
+				excluded[id] = true
+			}
+		}
+		visiting[id] = false
+		visited[id] = true
+		if !excluded[id] {
+			order = append(order, m)
+		}
+		return nil
+	}
+	for _, id := range ids {
+		if err := visit(ModuleID(id)); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(guild *GuildInfo) []Module {
+		loaded := make(map[ModuleID]Module, len(order))
+		modules := make([]Module, 0, len(order))
+		for _, m := range order {
+			deps := make(map[ModuleID]Module, len(m.dependencies))
+			for _, d := range m.dependencies {
+				if dep, ok := loaded[d]; ok {
+					deps[d] = dep
+				}
+			}
+			loaded[m.id] = m.factory(guild, deps)
+			modules = append(modules, loaded[m.id])
+		}
+		return modules
+	}, nil
+}
@@ -0,0 +1,176 @@
+package sweetiebot
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type registryTestModule struct {
+	name string
+	deps map[ModuleID]Module
+}
+
+func (m *registryTestModule) Name() string                       { return m.name }
+func (m *registryTestModule) Commands() []Command                { return nil }
+func (m *registryTestModule) Description(info *GuildInfo) string { return "" }
+
+func registryTestFactory(name string) ModuleFactory {
+	return func(guild *GuildInfo, deps map[ModuleID]Module) Module { return &registryTestModule{name, deps} }
+}
+
+func TestModuleRegistry(t *testing.T) {
+	r := &moduleRegistry{modules: make(map[ModuleID]*moduleRegistration)}
+	r.register("Filter", registryTestFactory("Filter"), "Spam")
+	r.register("Spam", registryTestFactory("Spam"))
+	r.register("Custom", registryTestFactory("Custom"), "Tag?", "Filter", "Missing?")
+	r.register("Tag", registryTestFactory("Tag"))
+	r.register("Bored", registryTestFactory("Bored"))
+
+	names := func(modules []Module) string {
+		s := ""
+		for _, m := range modules {
+			s += m.Name() + " "
+		}
+		return s
+	}
+	loader, err := r.loader(nil, nil)
+	Check(err, nil, t)
+	modules := loader(nil)
+	Check(names(modules), "Bored Tag Spam Filter Custom ", t)
+	Check(modules[4].(*registryTestModule).deps["tag"], modules[1], t)
+	Check(modules[4].(*registryTestModule).deps["filter"], modules[3], t)
+	Check(loader(nil)[0] != modules[0], true, t) // Every guild gets its own instances
+
+	loader, err = r.loader([]string{"spam", "missing"}, nil) // Excluding a module also excludes everything that depends on it
+	Check(err, nil, t)
+	Check(names(loader(nil)), "Bored Tag ", t)
+
+	loader, err = r.loader([]string{"tag"}, nil) // Excluding an optional dependency only leaves it out
+	Check(err, nil, t)
+	modules = loader(nil)
+	Check(names(modules), "Bored Spam Filter Custom ", t)
+	_, ok := modules[3].(*registryTestModule).deps["tag"]
+	Check(ok, false, t)
+
+	defer func() { Check(recover() != nil, true, t) }()
+	r.register("Loop", registryTestFactory("Loop"), "Cycle")
+	r.register("Cycle", registryTestFactory("Cycle"), "Loop")
+	_, err = r.loader(nil, nil)
+	Check(err != nil, true, t)
+	r.register("Broken", registryTestFactory("Broken"), "Nothing")
+	delete(r.modules, "loop")
+	delete(r.modules, "cycle")
+	_, err = r.loader(nil, nil)
+	Check(err != nil, true, t)
+	r.register("bored", registryTestFactory("Bored"))
+	t.Error("registering a module twice should panic")
+}
+
+type reloadTestModule struct {
+	id     int
+	ticks  int
+	events int
+	wait   chan bool
+}
+
+type reloadTestCommand struct {
+	m *reloadTestModule
+}
+
+func (c *reloadTestCommand) Info() *CommandInfo { return &CommandInfo{Name: "Generation"} }
+func (c *reloadTestCommand) Process(args []string, msg *discordgo.Message, indices []int, info *GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	return strconv.Itoa(c.m.id), false, nil
+}
+func (c *reloadTestCommand) Usage(info *GuildInfo) *CommandUsage { return &CommandUsage{} }
+
+func (m *reloadTestModule) Name() string                       { return "Reload" }
+func (m *reloadTestModule) Commands() []Command                { return []Command{&reloadTestCommand{m}} }
+func (m *reloadTestModule) Description(info *GuildInfo) string { return "" }
+func (m *reloadTestModule) OnTick(info *GuildInfo, t time.Time) {
+	if m.wait != nil { // Signal that the tick started, then block until the test lets it finish
+		m.wait <- true
+		<-m.wait
+	}
+	m.ticks++
+}
+func (m *reloadTestModule) Subscribe(info *GuildInfo, subscribe func(handler interface{}) func()) {
+	subscribe(func(info *GuildInfo, e *RaidEvent) { m.events++ })
+}
+func (m *reloadTestModule) SaveState(info *GuildInfo) ([]byte, error) {
+	return []byte(strconv.Itoa(m.ticks)), nil
+}
+func (m *reloadTestModule) LoadState(info *GuildInfo, data []byte) (err error) {
+	m.ticks, err = strconv.Atoi(string(data))
+	return
+}
+
+func TestReloadModules(t *testing.T) {
+	db := mockSQLiteDB(t)
+	defer db.Close()
+	count := 0
+	sb := &SweetieBot{
+		DB: db,
+		DG: &DiscordGoSession{&discordgo.Session{State: discordgo.NewState()}},
+		loader: func(*GuildInfo) []Module {
+			count++
+			return []Module{&reloadTestModule{id: count}}
+		},
+	}
+	info := NewGuildInfo(sb, &discordgo.Guild{ID: "1"})
+	info.Modules = sb.loader(info)
+	info.RegisterModule(info.Modules[0])
+	info.AddCommand(info.Modules[0].Commands()[0], info.Modules[0])
+	old := info.Modules[0].(*reloadTestModule)
+	other := 0
+	info.Bus.Subscribe(func(info *GuildInfo, e *RaidEvent) { other++ }) // Not added by a module, so it survives the reload
+	info.tick(time.Now())
+
+	// A tick that's still running when the modules are reloaded has to finish before the state is moved
+	old.wait = make(chan bool)
+	go info.tick(time.Now())
+	<-old.wait
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		old.wait <- true
+	}()
+
+	stop := make(chan bool)
+	done := make(chan bool)
+	go func() { // Events keep being dispatched while the modules are reloaded
+		for {
+			select {
+			case <-stop:
+				close(done)
+				return
+			default:
+				info.getHooks().done()
+				info.Bus.Publish(&SilenceEvent{})
+				info.GetCommand("generation")
+			}
+		}
+	}()
+	modules := info.ReloadModules()
+	close(stop)
+	<-done
+
+	Check(len(modules), 1, t)
+	m := modules[0].(*reloadTestModule)
+	Check(m.id, 2, t)
+	Check(info.GetModules()[0], modules[0], t)
+	Check(m.ticks, 2, t) // The old module's state was moved to the new one
+	c, ok := info.GetCommand("generation")
+	Check(ok, true, t)
+	result, _, _ := c.Process(nil, nil, nil, info)
+	Check(result, "2", t)
+	Check(len(info.getHooks().OnTick), 1, t)
+	info.tick(time.Now())
+	Check(m.ticks, 3, t)
+	Check(old.ticks, 2, t)
+	info.Bus.Publish(&RaidEvent{})
+	Check(m.events, 1, t)
+	Check(old.events, 0, t)
+	Check(other, 1, t)
+}
@@ -179,7 +179,11 @@ func FindUpgradeFiles(scriptdir string, version int) (files []int) {
 
 // UpgradeDatabase does the database migration portion of the upgrade
 func (b *SelfhostBase) UpgradeDatabase(scriptdir string, dbauth string) error {
-	db, err := sql.Open("mysql", strings.TrimSpace(dbauth))
+	driver, conn := ParseDBAuth(dbauth)
+	if driver == DriverSQLite {
+		return nil // The SQLite schema is created from sweetiebot_sqlite.sql on startup and has no migration scripts
+	}
+	db, err := sql.Open(driver, conn)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,136 @@
+package sweetiebot
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// identifyDelay is how long to wait between opening shards, because discord only accepts one identify every 5 seconds
+var identifyDelay = 5 * time.Second
+
+var errShardCount = errors.New("shardcount can't be negative")
+
+// shardIDs returns the shards this process should connect to
+func (sb *SweetieBot) shardIDs() ([]int, error) {
+	if sb.ShardCount < 0 {
+		return nil, errShardCount
+	}
+	count := sb.ShardCount
+	if count == 0 {
+		count = 1
+	}
+	if len(sb.ShardIDs) == 0 {
+		ids := make([]int, count)
+		for i := range ids {
+			ids[i] = i
+		}
+		return ids, nil
+	}
+	seen := make(map[int]bool)
+	for _, id := range sb.ShardIDs {
+		if id < 0 || id >= count {
+			return nil, errors.New("shard ID " + strconv.Itoa(id) + " must be between 0 and " + strconv.Itoa(count-1))
+		}
+		if seen[id] {
+			return nil, errors.New("shard ID " + strconv.Itoa(id) + " is listed more than once")
+		}
+		seen[id] = true
+	}
+	return sb.ShardIDs, nil
+}
+
+// newShards creates a gateway session for each shard this process runs, using sb.DG as the first one. Every shard
+// shares sb.DG's state and rate limiter, so channels, members and guilds can be looked up through sb.DG no matter
+// which shard they arrived on. Because each shard's READY replaces State.Guilds, use sb.Guilds to list guilds instead.
+func (sb *SweetieBot) newShards() error {
+	ids, err := sb.shardIDs()
+	if err != nil {
+		return err
+	}
+	count := sb.ShardCount
+	if count == 0 {
+		count = 1
+	}
+	sb.Shards = make([]*DiscordGoSession, len(ids))
+	for i, id := range ids {
+		s := sb.DG
+		if i > 0 {
+			dg, _ := discordgo.New(sb.DG.Token)
+			dg.Identify = sb.DG.Identify
+			dg.State = sb.DG.State
+			dg.Ratelimiter = sb.DG.Ratelimiter
+			dg.Client = sb.DG.Client
+			dg.Dialer = sb.DG.Dialer
+			dg.LogLevel = sb.DG.LogLevel
+			s = &DiscordGoSession{dg}
+		}
+		s.ShardID = id
+		s.ShardCount = count
+		sb.addHandlers(s)
+		sb.Shards[i] = s
+	}
+	return nil
+}
+
+func (sb *SweetieBot) addHandlers(s *DiscordGoSession) {
+	s.AddHandler(sb.OnReady)
+	s.AddHandler(sb.MessageCreate)
+	s.AddHandler(sb.MessageUpdate)
+	s.AddHandler(sb.MessageDelete)
+	s.AddHandler(sb.UserUpdate)
+	s.AddHandler(sb.GuildUpdate)
+	s.AddHandler(sb.GuildMemberAdd)
+	s.AddHandler(sb.GuildMemberRemove)
+	s.AddHandler(sb.GuildMemberUpdate)
+	s.AddHandler(sb.GuildMembersChunk)
+	s.AddHandler(sb.GuildBanAdd)
+	s.AddHandler(sb.GuildBanRemove)
+	s.AddHandler(sb.GuildRoleDelete)
+	s.AddHandler(sb.GuildCreate)
+	s.AddHandler(sb.InteractionCreate)
+}
+
+// openShards connects every shard to the gateway, one at a time
+func (sb *SweetieBot) openShards() error {
+	for i, s := range sb.Shards {
+		if i > 0 {
+			time.Sleep(identifyDelay)
+		}
+		if err := s.Open(); err != nil {
+			return errors.New("shard " + strconv.Itoa(s.ShardID) + ": " + err.Error())
+		}
+		sb.Logger.Info("Shard connected", LogFields{"shard": s.ShardID})
+	}
+	return nil
+}
+
+func (sb *SweetieBot) closeShards() {
+	for _, s := range sb.Shards {
+		if err := s.Close(); err != nil {
+			sb.Logger.Error("Error closing shard: "+err.Error(), LogFields{"shard": s.ShardID})
+		}
+	}
+}
+
+// ShardOf returns the session of the shard that receives events for the guild, or nil if this process doesn't run that shard
+func (sb *SweetieBot) ShardOf(guild DiscordGuild) *DiscordGoSession {
+	for _, s := range sb.Shards {
+		if s.ShardCount <= 1 || int((SBatoi(string(guild))>>22)%uint64(s.ShardCount)) == s.ShardID {
+			return s
+		}
+	}
+	return nil
+}
+
+// UpdateGameStatus sets the bot's status on every shard, because discord tracks it separately for each gateway session
+func (sb *SweetieBot) UpdateGameStatus(idle int, name string) (err error) {
+	for _, s := range sb.Shards {
+		if e := s.UpdateGameStatus(idle, name); e != nil {
+			err = e
+		}
+	}
+	return
+}
@@ -0,0 +1,237 @@
+package sweetiebot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gorilla/websocket"
+)
+
+// fakeGateway speaks just enough of discord's gateway protocol to connect shards and send them events. Every other
+// REST request gets a 404, so nothing in the test reaches the real discord.
+type fakeGateway struct {
+	server     *httptest.Server
+	guilds     []*discordgo.Guild
+	lock       sync.Mutex
+	identified map[int]int    // Maps each shard ID that identified to the shard count it sent
+	statuses   map[int]string // Last status each shard sent
+	conns      map[int]*websocket.Conn
+}
+
+type gatewayPayload struct {
+	Op   int         `json:"op"`
+	Seq  int         `json:"s,omitempty"`
+	Type string      `json:"t,omitempty"`
+	Data interface{} `json:"d"`
+}
+
+func newFakeGateway(guilds []*discordgo.Guild) *fakeGateway {
+	f := &fakeGateway{guilds: guilds, identified: make(map[int]int), statuses: make(map[int]string), conns: make(map[int]*websocket.Conn)}
+	f.server = httptest.NewServer(f)
+	return f
+}
+
+// RoundTrip sends every request to the fake gateway instead of discord
+func (f *fakeGateway) RoundTrip(r *http.Request) (*http.Response, error) {
+	u, _ := url.Parse(f.server.URL)
+	r.URL.Scheme = u.Scheme
+	r.URL.Host = u.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func shardOf(guild string, count int) int {
+	return int((SBatoi(guild) >> 22) % uint64(count))
+}
+
+func (f *fakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/gateway") {
+		json.NewEncoder(w).Encode(map[string]string{"url": "ws" + strings.TrimPrefix(f.server.URL, "http") + "/ws"})
+		return
+	}
+	if strings.TrimSuffix(r.URL.Path, "/") != "/ws" {
+		http.Error(w, `{"message": "Unknown", "code": 0}`, http.StatusNotFound)
+		return
+	}
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.WriteJSON(gatewayPayload{Op: 10, Data: map[string]int{"heartbeat_interval": 45000}})
+	var identify struct {
+		Op   int `json:"op"`
+		Data struct {
+			Shard [2]int `json:"shard"`
+		} `json:"d"`
+	}
+	if conn.ReadJSON(&identify) != nil || identify.Op != 2 {
+		return
+	}
+	shard, count := identify.Data.Shard[0], identify.Data.Shard[1]
+	if count == 0 {
+		count = 1
+	}
+	f.lock.Lock()
+	f.identified[shard] = count
+	f.conns[shard] = conn
+	unavailable := []map[string]interface{}{}
+	guilds := []*discordgo.Guild{}
+	for _, g := range f.guilds {
+		if shardOf(g.ID, count) == shard {
+			unavailable = append(unavailable, map[string]interface{}{"id": g.ID, "unavailable": true})
+			guilds = append(guilds, g)
+		}
+	}
+	conn.WriteJSON(gatewayPayload{Op: 0, Seq: 1, Type: "READY", Data: map[string]interface{}{
+		"v":          10,
+		"session_id": "session" + strconv.Itoa(shard),
+		"user":       map[string]interface{}{"id": "100", "username": "Sweetie Bot", "bot": true},
+		"guilds":     unavailable,
+		"shard":      []int{shard, count},
+	}})
+	for i, g := range guilds {
+		conn.WriteJSON(gatewayPayload{Op: 0, Seq: i + 2, Type: "GUILD_CREATE", Data: g})
+	}
+	f.lock.Unlock()
+
+	for {
+		var p struct {
+			Op   int             `json:"op"`
+			Data json.RawMessage `json:"d"`
+		}
+		if conn.ReadJSON(&p) != nil {
+			return
+		}
+		switch p.Op {
+		case 1:
+			f.lock.Lock()
+			conn.WriteJSON(gatewayPayload{Op: 11})
+			f.lock.Unlock()
+		case 3:
+			var status struct {
+				Activities []struct {
+					Name string `json:"name"`
+				} `json:"activities"`
+			}
+			json.Unmarshal(p.Data, &status)
+			f.lock.Lock()
+			if len(status.Activities) > 0 {
+				f.statuses[shard] = status.Activities[0].Name
+			}
+			f.lock.Unlock()
+		}
+	}
+}
+
+// send dispatches an event on the given shard
+func (f *fakeGateway) send(shard int, event string, data interface{}) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.conns[shard].WriteJSON(gatewayPayload{Op: 0, Seq: 100, Type: event, Data: data})
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	for i := 0; i < 200; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for " + what)
+}
+
+func TestShardIDs(t *testing.T) {
+	sb := &SweetieBot{}
+	ids, err := sb.shardIDs()
+	Check(err, nil, t)
+	Check(len(ids), 1, t)
+	sb.ShardCount = 3
+	ids, _ = sb.shardIDs()
+	Check(len(ids), 3, t)
+	sb.ShardIDs = []int{2}
+	ids, _ = sb.shardIDs()
+	Check(ids[0], 2, t)
+	sb.ShardIDs = []int{3}
+	_, err = sb.shardIDs()
+	Check(err != nil, true, t)
+	sb.ShardIDs = []int{1, 1}
+	_, err = sb.shardIDs()
+	Check(err != nil, true, t)
+	sb.ShardIDs = nil
+	sb.ShardCount = -1
+	Check(func() error { _, err := sb.shardIDs(); return err }(), errShardCount, t)
+}
+
+func TestShards(t *testing.T) {
+	defer func(delay time.Duration) { identifyDelay = delay }(identifyDelay)
+	identifyDelay = 0
+
+	guilds := []*discordgo.Guild{ // Guild IDs are assigned to shards by the bits above the timestamp's lowest 22 bits
+		{ID: strconv.Itoa(2 << 22), Name: "Even Server", OwnerID: "1", MemberCount: 300, Channels: []*discordgo.Channel{{ID: "11", Name: "general", Type: discordgo.ChannelTypeGuildText}}},
+		{ID: strconv.Itoa(3 << 22), Name: "Odd Server", OwnerID: "1", MemberCount: 300, Channels: []*discordgo.Channel{{ID: "12", Name: "general", Type: discordgo.ChannelTypeGuildText}}},
+	}
+	gateway := newFakeGateway(guilds)
+	defer gateway.server.Close()
+
+	db := mockSQLiteDB(t)
+	defer db.Close()
+	dg, _ := discordgo.New("Bot token")
+	dg.Client = &http.Client{Transport: gateway}
+	sb := &SweetieBot{
+		DB:         db,
+		DG:         &DiscordGoSession{dg},
+		Guilds:     make(map[DiscordGuild]*GuildInfo),
+		Owner:      "1",
+		ShardCount: 2,
+		loader:     func(*GuildInfo) []Module { return []Module{} },
+		memberChan: make(chan *GuildInfo, 10),
+		deferChan:  make(chan deferPair, 10),
+	}
+	sb.readyOnce.Do(func() {}) // Skip the application lookup and self update, which aren't part of the gateway
+	Check(sb.newShards(), nil, t)
+	Check(len(sb.Shards), 2, t)
+	Check(sb.Shards[0], sb.DG, t)
+	Check(sb.Shards[1].State, sb.DG.State, t)
+
+	Check(sb.openShards(), nil, t)
+	defer sb.closeShards()
+	Check(gateway.identified[0], 2, t)
+	Check(gateway.identified[1], 2, t)
+
+	// Each shard only receives its own guilds, but they all end up in the same bot
+	waitFor(t, "both guilds to be attached", func() bool {
+		sb.GuildsLock.RLock()
+		defer sb.GuildsLock.RUnlock()
+		return len(sb.Guilds) == 2
+	})
+	Check(sb.ShardOf(DiscordGuild(guilds[0].ID)).ShardID, 0, t)
+	Check(sb.ShardOf(DiscordGuild(guilds[1].ID)).ShardID, 1, t)
+	Check(sb.Health().GatewayConnected, true, t)
+
+	// Events from any shard update the shared state, so sb.DG can find channels on every shard
+	gateway.send(1, "CHANNEL_CREATE", &discordgo.Channel{ID: "13", GuildID: guilds[1].ID, Name: "new", Type: discordgo.ChannelTypeGuildText})
+	waitFor(t, "the new channel", func() bool {
+		_, err := sb.DG.State.Channel("13")
+		return err == nil
+	})
+	Check(sb.getChannelGuild("13"), sb.Guilds[DiscordGuild(guilds[1].ID)], t)
+
+	Check(sb.UpdateGameStatus(0, "with shards"), nil, t)
+	waitFor(t, "the status on both shards", func() bool {
+		gateway.lock.Lock()
+		defer gateway.lock.Unlock()
+		return gateway.statuses[0] == "with shards" && gateway.statuses[1] == "with shards"
+	})
+
+	result, _, _ := (&listGuildsCommand{}).Process([]string{}, &discordgo.Message{Author: &discordgo.User{ID: "1"}}, []int{}, sb.Guilds[DiscordGuild(guilds[0].ID)])
+	Check(strings.Contains(result, "Even Server (300) - <@1> [shard 0]"), true, t)
+	Check(strings.Contains(result, "Odd Server (300) - <@1> [shard 1]"), true, t)
+}
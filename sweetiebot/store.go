@@ -0,0 +1,69 @@
+package sweetiebot
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrDatabaseUnavailable is returned by a ModuleStore when the database can't be reached
+var ErrDatabaseUnavailable = errors.New("the database is unavailable")
+
+var errStoreKey = errors.New("keys must be between 1 and 255 bytes long")
+
+// ModuleStore persists keys and values for one module on one guild, so modules can store data without adding their
+// own tables or statements to the database, or putting it in the config. Values are removed when the guild's config is.
+type ModuleStore struct {
+	info   *GuildInfo
+	module string
+}
+
+// Store returns the key-value store for the module on this guild. Keys are only shared with other instances of a
+// module with the same name.
+func (info *GuildInfo) Store(m Module) *ModuleStore {
+	return &ModuleStore{info, strings.ToLower(m.Name())}
+}
+
+// Get returns the value stored under key, or sql.ErrNoRows if there isn't one or it expired
+func (s *ModuleStore) Get(key string) ([]byte, error) {
+	if !s.info.Bot.DB.CheckStatus() {
+		return nil, ErrDatabaseUnavailable
+	}
+	return s.info.Bot.DB.GetModuleData(SBatoi(s.info.ID), s.module, key, time.Now().UTC().Unix())
+}
+
+// Set stores value under key, replacing any previous value. If ttl is greater than zero, the value expires after that
+// long, otherwise it is kept until it is deleted.
+func (s *ModuleStore) Set(key string, value []byte, ttl time.Duration) error {
+	if len(key) == 0 || len(key) > MaxModuleDataKey {
+		return errStoreKey
+	}
+	if !s.info.Bot.DB.CheckStatus() {
+		return ErrDatabaseUnavailable
+	}
+	var expires int64
+	if ttl > 0 {
+		expires = time.Now().UTC().Add(ttl).Unix()
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return s.info.Bot.DB.SetModuleData(SBatoi(s.info.ID), s.module, key, value, expires)
+}
+
+// Delete removes key, returning sql.ErrNoRows if it didn't exist
+func (s *ModuleStore) Delete(key string) error {
+	if !s.info.Bot.DB.CheckStatus() {
+		return ErrDatabaseUnavailable
+	}
+	return s.info.Bot.DB.RemoveModuleData(SBatoi(s.info.ID), s.module, key)
+}
+
+// List returns up to maxresults keys that start with prefix, along with their values, sorted by key. An empty prefix
+// lists every key.
+func (s *ModuleStore) List(prefix string, maxresults int) ([]ModuleData, error) {
+	if !s.info.Bot.DB.CheckStatus() {
+		return nil, ErrDatabaseUnavailable
+	}
+	return s.info.Bot.DB.ListModuleData(SBatoi(s.info.ID), s.module, prefix, time.Now().UTC().Unix(), maxresults)
+}
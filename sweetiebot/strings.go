@@ -1,5 +1,13 @@
 package sweetiebot
 
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
 const (
 	STRING_INVALID_COMMAND                   = iota
 	STRING_PM_FAILURE                        = iota
@@ -89,6 +97,15 @@ const (
 	STRING_USERS_UNSILENCE                   = iota
 	STRING_USERS_UNSILENCE_DESCRIPTION       = iota
 	STRING_USERS_UNSILENCE_USER              = iota
+	STRING_USER_COOLDOWN                     = iota
+	STRING_USER_QUOTA                        = iota
+	STRING_INTERACTION_ABORTED               = iota
+	STRING_INTERACTION_DONE                  = iota
+	STRING_INTERACTION_PRIVATE               = iota
+	STRING_CHAIN_TOO_LONG                    = iota
+	STRING_CHAIN_EMPTY                       = iota
+	STRING_PIPE_PRIVATE                      = iota
+	STRING_PIPE_EMPTY                        = iota
 )
 
 // System-wide string map that can be substituted at runtime
@@ -180,4 +197,208 @@ var StringMap = map[int]string{
 	STRING_USERS_UNSILENCE:                   "```\nUnsilenced %v.```",
 	STRING_USERS_UNSILENCE_DESCRIPTION:       "Unsilences the given user.",
 	STRING_USERS_UNSILENCE_USER:              "A ping of the user, or simply their name.",
+	STRING_USER_COOLDOWN:                     "You have to wait %s between uses of that command. You can try again in %s!%s",
+	STRING_USER_QUOTA:                        "You can only run that command %s every %s. You can try again in %s!%s",
+	STRING_INTERACTION_ABORTED:               "```\nYou can't use that command here.```",
+	STRING_INTERACTION_DONE:                  "```\nDone.```",
+	STRING_INTERACTION_PRIVATE:               "```\nMy reply is only visible to you.```",
+	STRING_CHAIN_TOO_LONG:                    "You can't chain more than %v commands together.",
+	STRING_CHAIN_EMPTY:                       "One of the commands in that chain is empty.",
+	STRING_PIPE_PRIVATE:                      "The %s command sends its result privately, so it can't be piped into the next command.",
+	STRING_PIPE_EMPTY:                        "The %s command didn't return any text that can be piped into the next command.",
+}
+
+// StringNames gives every string a stable name, used by language packs and !strings so they don't break when new strings are added
+var StringNames = map[int]string{
+	STRING_INVALID_COMMAND:                   "invalid_command",
+	STRING_PM_FAILURE:                        "pm_failure",
+	STRING_CHECK_PM:                          "check_pm",
+	STRING_DATABASE_ERROR:                    "database_error",
+	STRING_NO_SERVER:                         "no_server",
+	STRING_COMMANDS_LIMIT:                    "commands_limit",
+	STRING_COMMAND_LIMIT:                     "command_limit",
+	STRING_SETUP_MESSAGE:                     "setup_message",
+	STRING_SPAM_DESCRIPTION:                  "spam_description",
+	STRING_SPAM_ERROR_UNSILENCING:            "spam_error_unsilencing",
+	STRING_SPAM_UNSILENCING:                  "spam_unsilencing",
+	STRING_SPAM_EMBEDDED_URLS:                "spam_embedded_urls",
+	STRING_SPAM_TRUNCATED:                    "spam_truncated",
+	STRING_SPAM_KILLING_SPAMMER_DETAIL:       "spam_killing_spammer_detail",
+	STRING_SPAM_AUTOBANNED_REASON:            "spam_autobanned_reason",
+	STRING_SPAM_BAN_ALERT:                    "spam_ban_alert",
+	STRING_SPAM_ERROR_RETRIEVE_MESSAGES:      "spam_error_retrieve_messages",
+	STRING_SPAM_WILL_BE_UNSILENCED:           "spam_will_be_unsilenced",
+	STRING_SPAM_SILENCE_ALERT:                "spam_silence_alert",
+	STRING_SPAM_KILLING_SPAMMER:              "spam_killing_spammer",
+	STRING_SPAM_REASON_MESSAGES:              "spam_reason_messages",
+	STRING_SPAM_REASON_FILES:                 "spam_reason_files",
+	STRING_SPAM_REASON_IMAGES:                "spam_reason_images",
+	STRING_SPAM_REASON_PINGS:                 "spam_reason_pings",
+	STRING_SPAM_REASON_LENGTH:                "spam_reason_length",
+	STRING_SPAM_REASON_NEWLINES:              "spam_reason_newlines",
+	STRING_SPAM_REASON_COPY:                  "spam_reason_copy",
+	STRING_SPAM_GUILD_NOT_FOUND:              "spam_guild_not_found",
+	STRING_SPAM_VERIFICATION_LEVEL_ERROR:     "spam_verification_level_error",
+	STRING_SPAM_LOCKDOWN_DISENGAGE_FAILURE:   "spam_lockdown_disengage_failure",
+	STRING_SPAM_LOCKDOWN_DISENGAGE:           "spam_lockdown_disengage",
+	STRING_SPAM_USER_JOINED:                  "spam_user_joined",
+	STRING_SPAM_JOINED_APPEND:                "spam_joined_append",
+	STRING_SPAM_RAIDSILENCE_ALL_POSTFIX:      "spam_raidsilence_all_postfix",
+	STRING_SPAM_RAIDSILENCE_ENGAGED:          "spam_raidsilence_engaged",
+	STRING_SPAM_RAID_DETECTED:                "spam_raid_detected",
+	STRING_SPAM_LOCKDOWN_ENGAGE_FAILURE:      "spam_lockdown_engage_failure",
+	STRING_SPAM_LOCKDOWN_ENGAGE:              "spam_lockdown_engage",
+	STRING_SPAM_RAIDSILENCE_USAGE:            "spam_raidsilence_usage",
+	STRING_SPAM_RAIDSILENCE_ARGS_ERROR:       "spam_raidsilence_args_error",
+	STRING_SPAM_RAIDSILENCE_ARGS:             "spam_raidsilence_args",
+	STRING_SPAM_RAIDSILENCE_DATABASE_ERROR:   "spam_raidsilence_database_error",
+	STRING_SPAM_RAIDSILENCE_SET_RAID:         "spam_raidsilence_set_raid",
+	STRING_SPAM_RAIDSILENCE_DETECTION:        "spam_raidsilence_detection",
+	STRING_SPAM_RAIDSILENCE_SET:              "spam_raidsilence_set",
+	STRING_SPAM_RAIDSILENCE_DESCRIPTION:      "spam_raidsilence_description",
+	STRING_SPAM_RAIDSILENCE_DESCRIPTION_NAME: "spam_raidsilence_description_name",
+	STRING_SPAM_WIPE_USAGE:                   "spam_wipe_usage",
+	STRING_SPAM_WIPE_ARG_ERROR:               "spam_wipe_arg_error",
+	STRING_SPAM_WIPE_PM_ERROR:                "spam_wipe_pm_error",
+	STRING_SPAM_WIPE_CHANNEL_ERROR:           "spam_wipe_channel_error",
+	STRING_SPAM_WIPE_NO_MESSAGES:             "spam_wipe_no_messages",
+	STRING_SPAM_WIPE_RETRIEVAL_ERROR:         "spam_wipe_retrieval_error",
+	STRING_SPAM_WIPE_DELETED:                 "spam_wipe_deleted",
+	STRING_SPAM_WIPE_DESCRIPTION:             "spam_wipe_description",
+	STRING_SPAM_WIPE_CHANNEL:                 "spam_wipe_channel",
+	STRING_SPAM_WIPE_MESSAGES:                "spam_wipe_messages",
+	STRING_SPAM_PRESSURE_USAGE:               "spam_pressure_usage",
+	STRING_SPAM_PRESSURE_ARG_ERROR:           "spam_pressure_arg_error",
+	STRING_SPAM_PRESSURE_DESCRIPTION:         "spam_pressure_description",
+	STRING_SPAM_PRESSURE_USER:                "spam_pressure_user",
+	STRING_SPAM_RAID_USAGE:                   "spam_raid_usage",
+	STRING_SPAM_RAID_NONE:                    "spam_raid_none",
+	STRING_SPAM_RAID_USERS:                   "spam_raid_users",
+	STRING_SPAM_RAID_DESCRIPTION:             "spam_raid_description",
+	STRING_SPAM_BANRAID_USAGE:                "spam_banraid_usage",
+	STRING_SPAM_BANRAID_REASON:               "spam_banraid_reason",
+	STRING_SPAM_BANRAID_RESULT:               "spam_banraid_result",
+	STRING_SPAM_BANRAID_DESCRIPTION:          "spam_banraid_description",
+	STRING_USERS_BAN_MOD_ERROR:               "users_ban_mod_error",
+	STRING_USERS_SILENCE_USAGE:               "users_silence_usage",
+	STRING_USERS_SILENCE_ARG_ERROR:           "users_silence_arg_error",
+	STRING_USERS_SILENCE_ERROR:               "users_silence_error",
+	STRING_USERS_SILENCE_MOD_ERROR:           "users_silence_mod_error",
+	STRING_USERS_SILENCE_ALREADY_SILENCED:    "users_silence_already_silenced",
+	STRING_USERS_SILENCE_WILL_BE_UNSILENCED:  "users_silence_will_be_unsilenced",
+	STRING_USERS_SILENCE_REASON:              "users_silence_reason",
+	STRING_USERS_SILENCE:                     "users_silence",
+	STRING_USERS_SILENCE_DESCRIPTION:         "users_silence_description",
+	STRING_USERS_SILENCE_USER:                "users_silence_user",
+	STRING_USERS_SILENCE_DURATION:            "users_silence_duration",
+	STRING_USERS_UNSILENCE_USAGE:             "users_unsilence_usage",
+	STRING_USERS_UNSILENCE_ARG_ERROR:         "users_unsilence_arg_error",
+	STRING_USERS_UNSILENCE_ERROR:             "users_unsilence_error",
+	STRING_USERS_UNSILENCE_MOD_ERROR:         "users_unsilence_mod_error",
+	STRING_USERS_UNSILENCE:                   "users_unsilence",
+	STRING_USERS_UNSILENCE_DESCRIPTION:       "users_unsilence_description",
+	STRING_USERS_UNSILENCE_USER:              "users_unsilence_user",
+	STRING_USER_COOLDOWN:                     "user_cooldown",
+	STRING_USER_QUOTA:                        "user_quota",
+	STRING_INTERACTION_ABORTED:               "interaction_aborted",
+	STRING_INTERACTION_DONE:                  "interaction_done",
+	STRING_INTERACTION_PRIVATE:               "interaction_private",
+	STRING_CHAIN_TOO_LONG:                    "chain_too_long",
+	STRING_CHAIN_EMPTY:                       "chain_empty",
+	STRING_PIPE_PRIVATE:                      "pipe_private",
+	STRING_PIPE_EMPTY:                        "pipe_empty",
+}
+
+var stringIDs = func() map[string]int {
+	ids := make(map[string]int, len(StringNames))
+	for k, v := range StringNames {
+		ids[v] = k
+	}
+	return ids
+}()
+
+// StringID returns the string with the given name, ignoring case
+func StringID(name string) (int, bool) {
+	id, ok := stringIDs[strings.ToLower(name)]
+	return id, ok
+}
+
+// Languages holds every language pack loaded from the web directory, indexed by language code. English is always available as StringMap.
+var Languages = map[string]map[int]string{}
+
+// LoadLanguagePacks loads every <language>.json file in dir as a language pack. Each file maps string names to their translation.
+// Files that can't be read are skipped, as are translations of unknown strings or with different formatting placeholders than the English string.
+func LoadLanguagePacks(dir string, log *Logger) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Error("Skipped language pack " + file + ": " + err.Error())
+			continue
+		}
+		strs := make(map[string]string)
+		if err = json.Unmarshal(data, &strs); err != nil {
+			log.Error("Skipped language pack " + file + ": " + err.Error())
+			continue
+		}
+		pack := make(map[int]string, len(strs))
+		for name, s := range strs {
+			if err := checkString(name, s); err != nil {
+				log.Warn("Skipped a string in language pack " + file + ": " + err.Error())
+			} else {
+				id, _ := StringID(name)
+				pack[id] = s
+			}
+		}
+		Languages[strings.ToLower(strings.TrimSuffix(filepath.Base(file), ".json"))] = pack
+	}
+	return nil
+}
+
+// checkString returns an error if name isn't a known string, or if s doesn't have the same number of formatting placeholders as the English string
+func checkString(name string, s string) error {
+	id, ok := StringID(name)
+	if !ok {
+		return fmt.Errorf("%s is not a valid message name", name)
+	}
+	if countFormatVerbs(s) != countFormatVerbs(StringMap[id]) {
+		return fmt.Errorf("%s must contain exactly %v formatting placeholders (like %%s or %%v), the same as the original message", StringNames[id], countFormatVerbs(StringMap[id]))
+	}
+	return nil
+}
+
+// DefaultString returns the English version of a string, for when there is no server to look up a language for
+func DefaultString(id int) string {
+	return StringMap[id]
+}
+
+// GetString looks up a string using this server's overrides and language, falling back to English if it hasn't been translated
+func (info *GuildInfo) GetString(id int) string {
+	if info == nil {
+		return StringMap[id]
+	}
+	if s, ok := info.Config.Basic.Strings[StringNames[id]]; ok {
+		return s
+	}
+	if s, ok := Languages[strings.ToLower(info.Config.Basic.Language)][id]; ok {
+		return s
+	}
+	return StringMap[id]
+}
+
+// countFormatVerbs returns the number of fmt verbs in s, ignoring escaped %% signs
+func countFormatVerbs(s string) (n int) {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' {
+			if i+1 < len(s) && s[i+1] == '%' {
+				i++
+			} else {
+				n++
+			}
+		}
+	}
+	return
 }
@@ -0,0 +1,59 @@
+package sweetiebot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetString(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "ES.json"), []byte(`{"pm_failure":"No pude enviarte un mensaje privado.","invalid_command":"%s no es un comando.","nonexistent":"x"}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"pm_failure":`), 0644)
+	Check(LoadLanguagePacks(dir, nil), nil, t)
+	defer delete(Languages, "es")
+	_, ok := Languages["bad"]
+	Check(ok, false, t)               // A broken file is skipped without stopping the others from loading
+	Check(len(Languages["es"]), 1, t) // Unknown names and mismatched placeholders are dropped
+
+	var nilinfo *GuildInfo
+	Check(nilinfo.GetString(STRING_PM_FAILURE), StringMap[STRING_PM_FAILURE], t)
+	info := &GuildInfo{Config: *DefaultConfig()}
+	Check(info.GetString(STRING_PM_FAILURE), StringMap[STRING_PM_FAILURE], t)
+	info.Config.Basic.Language = "es"
+	Check(info.GetString(STRING_PM_FAILURE), "No pude enviarte un mensaje privado.", t)
+	Check(info.GetString(STRING_CHECK_PM), StringMap[STRING_CHECK_PM], t)
+	info.Config.Basic.Strings = map[string]string{"pm_failure": "custom"}
+	Check(info.GetString(STRING_PM_FAILURE), "custom", t)
+	Check(info.Config.ValidateOption(nil, "basic.strings"), nil, t)
+	info.Config.Basic.Strings = map[string]string{"invalid_command": "%s"}
+	Check(info.Config.ValidateOption(nil, "basic.strings") != nil, true, t)
+	info.Config.Basic.Strings = map[string]string{"1": "custom"}
+	Check(info.Config.ValidateOption(nil, "basic.strings") != nil, true, t)
+	Check(info.Config.ValidateOption(nil, "basic.language"), nil, t)
+	info.Config.Basic.Language = "xx"
+	Check(info.Config.ValidateOption(nil, "basic.language") != nil, true, t)
+}
+
+func TestCountFormatVerbs(t *testing.T) {
+	Check(countFormatVerbs(""), 0, t)
+	Check(countFormatVerbs("100%% sure %s"), 1, t)
+	Check(countFormatVerbs(StringMap[STRING_INVALID_COMMAND]), 2, t)
+	Check(countFormatVerbs("trailing %"), 1, t)
+}
+
+func TestStringNames(t *testing.T) {
+	Check(len(stringIDs), len(StringNames), t) // Names must be unique
+	for id := range StringMap {
+		_, ok := StringNames[id]
+		Check(ok, true, t)
+	}
+	id, ok := StringID("PM_Failure")
+	Check(ok, true, t)
+	Check(id, STRING_PM_FAILURE, t)
+}
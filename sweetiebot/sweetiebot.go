@@ -1,11 +1,13 @@
 package sweetiebot
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"regexp"
 	"runtime/debug"
 	"runtime/pprof"
@@ -37,7 +39,7 @@ var guildfileregex = regexp.MustCompile("^([0-9]+)[.]json$")
 const DiscordEpoch uint64 = 1420070400000
 
 // BotVersion stores the current version of sweetiebot
-var BotVersion = Version{1, 0, 6, 0}
+var BotVersion = Version{1, 0, 7, 0}
 
 const (
 	MaxPublicLines    = 12
@@ -51,8 +53,10 @@ const (
 	CleanInterval     = 3600
 	ExpireTime        = 3600 * 72
 	MaxScheduleRows   = 5000
+	MaxCommandChain   = 5 // Maximum number of commands that can be chained together with | or ;
 	DelayTime         = time.Duration(200 * time.Millisecond)
 	heartbeatInterval = time.Duration(20 * time.Second)
+	StateSaveInterval = time.Duration(5 * time.Minute) // How often module state is saved, in case the bot doesn't shut down cleanly
 )
 
 type deferPair struct {
@@ -81,22 +85,34 @@ type SweetieBot struct {
 	GuildsLock      sync.RWMutex
 	LastMessages    map[DiscordChannel]int64
 	LastMessageLock sync.RWMutex
-	MaxConfigSize   int `json:"maxconfigsize"`
+	MaxConfigSize   int       `json:"maxconfigsize"`
+	Logging         LogConfig `json:"logging"`
+	Logger          *Logger   `json:"-"`
+	ExcludeModules  []string  `json:"excludemodules"` // Registered modules this process never loads
 	StartTime       int64
 	MessageCount    uint32 // 32-bit so we can do atomic ops on a 32-bit platform
 	heartbeat       uint32 // perpetually incrementing heartbeat counter to detect deadlock
+	lastHeartbeat   uint32 // unix time of the last heartbeat, used by the health checks
 	locknumber      uint32
 	loader          func(*GuildInfo) []Module
 	memberChan      chan *GuildInfo
 	deferChan       chan deferPair
 	Selfhoster      *Selfhost
-	IsUserMode      bool       `json:"runasuser"` // True if running as a user for some godawful reason
-	WebSecure       bool       `json:"websecure"`
-	WebDomain       string     `json:"webdomain"`
-	WebPort         string     `json:"webport"`
-	EmptyGuild      *GuildInfo // Holds an empty GuildInfo for running server independent commands
+	IsUserMode      bool          `json:"runasuser"`  // True if running as a user for some godawful reason
+	WebEnabled      bool          `json:"webenabled"` // Serves the website, dashboard and API. When shards run in separate processes, only one of them should enable this
+	WebSecure       bool          `json:"websecure"`
+	WebDomain       string        `json:"webdomain"`
+	WebPort         string        `json:"webport"`
+	MonitorAddr     string        `json:"monitoraddr"` // Address of a separate listener for /metrics, /healthz and /readyz, like 127.0.0.1:9100. Nothing is served if this is empty
+	OAuthSecret     string        `json:"oauthsecret"` // OAuth2 client secret of the application, which lets people log in to the web dashboard
+	OAuth           OAuthProvider `json:"-"`           // Overrides how people log in to the web dashboard
+	ShardCount      int           `json:"shardcount"`  // Total number of shards the bot is split into. 0 runs the whole bot on a single gateway session
+	ShardIDs        []int         `json:"shardids"`    // Shards this process runs, which defaults to all of them
+	EmptyGuild      *GuildInfo    // Holds an empty GuildInfo for running server independent commands
 	UpdateLock      AtomicFlag
 	Markov          *markovChain
+	Shards          []*DiscordGoSession // One gateway session per shard. DG is always the first one
+	readyOnce       sync.Once
 }
 
 type markovChain struct {
@@ -128,24 +144,26 @@ func (sb *SweetieBot) ChannelIsPrivate(channelID DiscordChannel) (*discordgo.Cha
 
 // OnReady discord hook
 func (sb *SweetieBot) OnReady(s *discordgo.Session, r *discordgo.Ready) {
-	fmt.Println("Ready message receieved, re-processing " + strconv.Itoa(len(r.Guilds)) + " existing guilds.")
+	sb.Logger.Info("Ready message receieved, re-processing "+strconv.Itoa(len(r.Guilds))+" existing guilds.", LogFields{"shard": s.ShardID})
 	sb.SelfID = DiscordUser(r.User.ID)
 	sb.SelfAvatar = r.User.Avatar
 	sb.SelfName = r.User.Username
-	sb.AppName = sb.SelfName
 	if r.Guilds != nil && sb.IsUserMode {
 		for _, G := range r.Guilds {
 			sb.AttachToGuild(G)
 		}
 	}
-	app, err := s.Application("@me")
-	if err == nil {
-		sb.Owner = DiscordUser(app.Owner.ID)
-		sb.AppID = SBatoi(app.ID)
-		sb.AppName = app.Name
-	}
+	sb.readyOnce.Do(func() { // Every shard sends its own ready message, but the application and the bot's files are the same for all of them
+		sb.AppName = sb.SelfName
+		app, err := s.Application("@me")
+		if err == nil {
+			sb.Owner = DiscordUser(app.Owner.ID)
+			sb.AppID = SBatoi(app.ID)
+			sb.AppName = app.Name
+		}
 
-	sb.Selfhoster.SelfUpdate(sb.Owner)
+		sb.Selfhoster.SelfUpdate(sb.Owner)
+	})
 }
 
 type moduleArray []Module
@@ -184,17 +202,19 @@ func (sb *SweetieBot) AttachToGuild(g *discordgo.Guild) {
 		}
 	}
 
-	fmt.Println("Initializing " + g.Name)
+	sb.Logger.Info("Initializing "+g.Name, LogFields{"guild": g.ID})
 	guild = NewGuildInfo(sb, g)
 	for _, m := range g.Members {
 		if sb.SelfID.Equals(m.User.ID) {
 			guild.BotNick = m.Nick
 		}
 	}
-	config, err := ioutil.ReadFile(g.ID + ".json")
+	config, err := guild.LoadConfig()
 	disableall := false
-	if err != nil {
-		fmt.Println("New Guild Detected: " + g.Name)
+	if err != nil && err != sql.ErrNoRows && !os.IsNotExist(err) {
+		sb.Logger.Error("Error reading config for "+g.Name+": "+err.Error(), LogFields{"guild": g.ID})
+	} else if err != nil {
+		sb.Logger.Info("New Guild Detected: "+g.Name, LogFields{"guild": g.ID})
 
 		perms, _ := guild.Bot.DG.UserPermissions(sb.SelfID, guild.ID)
 		warning := ""
@@ -244,7 +264,7 @@ func (sb *SweetieBot) AttachToGuild(g *discordgo.Guild) {
 
 		disableall = true
 	} else if err := guild.MigrateSettings(config); err != nil {
-		fmt.Println("Error reading config file for "+g.Name+": ", err.Error())
+		sb.Logger.Error("Error reading config file for "+g.Name+": "+err.Error(), LogFields{"guild": g.ID})
 	}
 
 	guild.Config.FillConfig()
@@ -271,16 +291,18 @@ func (sb *SweetieBot) AttachToGuild(g *discordgo.Guild) {
 	}
 
 	guild.Clean()
+	guild.UpdateApplicationCommands()
+	guild.LoadModuleState()
 	if sb.Debug {
 		for _, v := range guild.Modules {
-			_, ok := guild.commands[CommandID(strings.ToLower(v.Name()))]
-			if ok {
-				fmt.Println("WARNING: Ambiguous module/command name ", v.Name())
+			c, ok := guild.GetCommand(CommandID(strings.ToLower(v.Name())))
+			if _, isgroup := c.(*CommandGroup); ok && !isgroup {
+				sb.Logger.Warn("Ambiguous module/command name "+v.Name(), LogFields{"guild": g.ID})
 			}
 		}
 	}
 	if disableall {
-		for k := range guild.commands {
+		for k := range guild.GetCommands() {
 			guild.Config.Modules.CommandDisabled[k] = true
 		}
 		for _, v := range guild.Modules {
@@ -291,7 +313,7 @@ func (sb *SweetieBot) AttachToGuild(g *discordgo.Guild) {
 		guild.SaveConfig()
 	}
 	if sb.IsMainGuild(guild) {
-		sb.DB.log = guild
+		sb.DB.log = guild.Logger().With(LogFields{"module": "db"})
 	}
 
 	debug := "."
@@ -311,10 +333,47 @@ func (sb *SweetieBot) AttachToGuild(g *discordgo.Guild) {
 	}
 	guild.Log(sb.AppName+" version ", BotVersion.String(), " successfully loaded on ", g.Name, debug, changes)
 }
+
+// ImportConfigFiles moves any legacy <guild>.json config files in dir into the database. Imported files are renamed
+// to <guild>.json.imported so they are only imported once. Guilds that already have a stored config are skipped and their files are left
+// untouched.
+func (sb *SweetieBot) ImportConfigFiles(dir string) {
+	results, err := ioutil.ReadDir(dir)
+	if err != nil {
+		sb.Logger.Error("Error importing config files: " + err.Error())
+		return
+	}
+	for _, f := range results {
+		matches := guildfileregex.FindStringSubmatch(f.Name())
+		if f.IsDir() || len(matches) < 2 {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		data, err := ioutil.ReadFile(path)
+		config := BotConfig{}
+		if err == nil {
+			err = json.Unmarshal(data, &config)
+		}
+		if err == nil {
+			_, err = sb.DB.SaveConfig(SBatoi(matches[1]), data, config.Expires, 0)
+		}
+		if err == ErrConfigConflict {
+			sb.Logger.Warn("Skipped importing " + f.Name() + " because that server already has a stored config.")
+			continue
+		} else if err != nil {
+			sb.Logger.Error("Error importing " + f.Name() + ": " + err.Error())
+			continue
+		}
+		if err = os.Rename(path, path+".imported"); err != nil {
+			sb.Logger.Error("Error renaming " + f.Name() + ": " + err.Error())
+		}
+	}
+}
+
 func (sb *SweetieBot) getChannelGuild(id string) *GuildInfo {
 	c, err := sb.DG.State.Channel(id)
 	if err != nil {
-		fmt.Println("Failed to get channel " + id)
+		sb.Logger.Debug("Failed to get channel " + id)
 		return nil
 	}
 	return sb.getGuildFromID(c.GuildID)
@@ -357,13 +416,8 @@ func (sb *SweetieBot) ProcessCommand(m *discordgo.Message, info *GuildInfo, t in
 		}
 	}()
 
-	var prefix byte = '!'
-	if info != nil && len(info.Config.Basic.CommandPrefix) == 1 {
-		prefix = info.Config.Basic.CommandPrefix[0]
-	}
-
 	// Check if this is a command. If it is, process it as a command, otherwise process it with our modules.
-	if len(m.Content) > 1 && m.Content[0] == prefix && m.Content[1] != prefix { // We check for > 1 here because a single character can't possibly be a valid command
+	if prefix := sb.MatchPrefix(m.Content, info); prefix > 0 {
 		isfree := private
 		authorid := SBatoi(m.Author.ID)
 		channelID := DiscordChannel(m.ChannelID)
@@ -371,22 +425,21 @@ func (sb *SweetieBot) ProcessCommand(m *discordgo.Message, info *GuildInfo, t in
 			_, isfree = info.Config.Basic.FreeChannels[channelID]
 		}
 
-		// command := strings.ToLower(strings.SplitN(m.Content[1:], " ", 2)[0])
-		args, indices := ParseArguments(m.Content[1:])
+		args, _ := ParseCommandArguments(m.Content, prefix)
 		arg := CommandID(strings.ToLower(args[0]))
 		if info == nil {
 			info = sb.GetDefaultServer(authorid)
 		}
 		if info == nil {
 			gIDs := []uint64{}
-			if _, independent := sb.EmptyGuild.commands[arg]; !independent {
+			if _, independent := sb.EmptyGuild.GetCommand(arg); !independent {
 				if !sb.DB.Status.Get() {
-					sb.DG.ChannelMessageSend(m.ChannelID, StringMap[STRING_DATABASE_ERROR])
+					sb.DG.ChannelMessageSend(m.ChannelID, DefaultString(STRING_DATABASE_ERROR))
 					return
 				}
 				gIDs = sb.DB.GetUserGuilds(authorid)
 				if len(gIDs) != 1 {
-					sb.DG.ChannelMessageSend(m.ChannelID, StringMap[STRING_NO_SERVER])
+					sb.DG.ChannelMessageSend(m.ChannelID, DefaultString(STRING_NO_SERVER))
 					return
 				}
 			} else if sb.DB.Status.Get() {
@@ -404,120 +457,150 @@ func (sb *SweetieBot) ProcessCommand(m *discordgo.Message, info *GuildInfo, t in
 			}
 		}
 
-		c, ok := info.commands[arg] // First, we check if this matches an existing command so you can't alias yourself into a hole
-		if !ok {
-			if alias, aliasok := info.Config.Basic.Aliases[string(arg)]; aliasok {
-				if len(indices) > 1 {
-					m.Content = info.Config.Basic.CommandPrefix + alias + " " + m.Content[indices[1]:]
-				} else {
-					m.Content = info.Config.Basic.CommandPrefix + alias
-				}
-				args, indices = ParseArguments(m.Content[1:])
-				if m.ChannelID != "heartbeat" && len(args) < 1 {
-					info.SendError(channelID, "The "+string(arg)+" alias resolves to a blank command! Don't you know how dangerous that is?! That kind of abuse crashes bots! Go to your room and don't come back down until you've fixed that alias using '"+info.Config.Basic.CommandPrefix+"setconfig basic.aliases "+string(arg)+" [something else]', or leave out the fourth argument entirely if you want to delete it!", t)
+		stages := SplitCommandChain(m.Content[prefix:], info.isRawTextCommand)
+		if len(stages) > MaxCommandChain {
+			info.SendError(channelID, fmt.Sprintf(info.GetString(STRING_CHAIN_TOO_LONG), MaxCommandChain), t)
+			return
+		}
+		input := ""
+		for i, stage := range stages {
+			sm := m
+			if len(stages) > 1 { // Each stage in a chain is processed as if it was its own message
+				if len(stage.Content) == 0 {
+					info.SendError(channelID, info.GetString(STRING_CHAIN_EMPTY), t)
 					return
 				}
-				arg = CommandID(strings.ToLower(args[0]))
-				c, ok = info.commands[arg]
-			}
-		}
-		if ok {
-			if sb.DB.Status.Get() && !sb.SelfID.Equals(m.Author.ID) {
-				sb.DB.Audit(AuditTypeCommand, m.Author, m.Content, SBatoi(info.ID))
-			}
-			cmdname := CommandID(strings.ToLower(c.Info().Name))
-			if m.ChannelID != "heartbeat" && !info.Config.SetupDone && cmdname != CommandID("setup") {
-				info.SendError(channelID, fmt.Sprintf(StringMap[STRING_SETUP_MESSAGE], info.Config.Basic.CommandPrefix), t)
-				return
-			}
-
-			ignore := false
-			if !private {
-				ignore = info.checkOnCommand(m)
-				cch := info.Config.Modules.CommandChannels[cmdname]
-				if len(cch) > 0 {
-					_, reverse := cch["!"]
-					_, ok = cch[channelID]
-					ignore = ignore || ok == reverse
+				msg := *m
+				msg.Content = m.Content[:prefix] + stage.Content
+				if len(input) > 0 {
+					msg.Content += " " + input
 				}
+				sm = &msg
 			}
-
-			bypass, err := info.UserCanUseCommand(DiscordUser(m.Author.ID), c, ignore) // Bypass is true for administrators, mods, and the bot owner
-			if m.ChannelID == "heartbeat" {                                            // The heartbeat can never be ignored or disabled
-				bypass = true
-				err = nil
-			} else if err == errDisabled || err == errIgnored || err == errSilenced || err == errMainGuild {
+			ctx := sb.runCommand(sm, prefix, info, t, isdebug, private, isfree)
+			if ctx == nil {
 				return
 			}
-
-			if !isdebug && !isfree && !bypass && info.Config.Modules.CommandPerDuration > 0 { // debug channels aren't limited
-				if len(info.commandlimit.times) < info.Config.Modules.CommandPerDuration*2 { // Check if we need to re-allocate the array because the configuration changed
-					info.commandlimit.times = make([]int64, info.Config.Modules.CommandPerDuration*2, info.Config.Modules.CommandPerDuration*2)
+			input = ""
+			if i+1 < len(stages) && stages[i+1].Pipe { // The output of this command becomes the last argument of the next one
+				if ctx.UsePM {
+					info.SendError(channelID, fmt.Sprintf(info.GetString(STRING_PIPE_PRIVATE), ctx.Name), t)
+					return
 				}
-				if info.commandlimit.check(info.Config.Modules.CommandPerDuration, info.Config.Modules.CommandMaxDuration, t) { // if we've hit the saturation limit, post an error (which itself will only post if the error saturation limit hasn't been hit)
-					info.SendError(channelID, fmt.Sprintf(StringMap[STRING_COMMANDS_LIMIT], info.Config.Modules.CommandPerDuration, TimeDiff(time.Duration(info.Config.Modules.CommandMaxDuration)*time.Second), sb.getAddMsg(info)), t)
+				if input = StripCodeBlock(ctx.Result); ctx.Embed != nil || len(input) == 0 {
+					info.SendError(channelID, fmt.Sprintf(info.GetString(STRING_PIPE_EMPTY), ctx.Name), t)
 					return
 				}
-				info.commandlimit.append(t)
+			} else {
+				private = sb.sendCommandResult(ctx, private, t)
 			}
-			if err != nil {
-				info.SendError(channelID, err.Error(), t)
-				return
+		}
+	} else if info != nil { // If info is nil this was sent through a private message so just ignore it completely
+		hooks := info.getHooks()
+		defer hooks.done()
+		for _, h := range hooks.OnMessageCreate {
+			if info.ProcessModule(DiscordChannel(m.ChannelID), h) {
+				start := time.Now()
+				h.OnMessageCreate(info, m)
+				observeHook(h, "OnMessageCreate", start)
 			}
+		}
+	}
+}
 
-			cmdlimit := info.Config.Modules.CommandLimits[cmdname]
-			if !isfree && cmdlimit > 0 && !bypass {
-				cmdhash := channelID.String() + string(cmdname)
-				info.commandLock.RLock()
-				lastcmd := info.commandLast[cmdhash]
-				info.commandLock.RUnlock()
-				if !RateLimit(&lastcmd, cmdlimit, t) {
-					info.SendError(channelID, fmt.Sprintf(StringMap[STRING_COMMAND_LIMIT], TimeDiff(time.Duration(cmdlimit)*time.Second), sb.getAddMsg(info)), t)
-					return
-				}
-				info.commandLock.Lock()
-				info.commandLast[cmdhash] = t
-				info.commandLock.Unlock()
+// runCommand resolves aliases and subcommands in a single command message and runs it through the middleware. Returns
+// nil if the command didn't run, in which case any errors have already been sent.
+func (sb *SweetieBot) runCommand(m *discordgo.Message, prefix int, info *GuildInfo, t int64, isdebug bool, private bool, isfree bool) *CommandContext {
+	channelID := DiscordChannel(m.ChannelID)
+	args, indices := ParseCommandArguments(m.Content, prefix)
+	if len(args) < 1 {
+		return nil
+	}
+	arg := CommandID(strings.ToLower(args[0]))
+	c, ok := info.GetCommand(arg) // First, we check if this matches an existing command so you can't alias yourself into a hole
+	if !ok {
+		if alias, aliasok := info.Config.Basic.Aliases[string(arg)]; aliasok {
+			if len(indices) > 1 { // Keep whatever prefix was used so the indices still line up
+				m.Content = m.Content[:prefix] + alias + " " + m.Content[indices[1]:]
+			} else {
+				m.Content = m.Content[:prefix] + alias
 			}
-
-			result, usepm, resultembed := c.Process(args[1:], m, indices[1:], info)
-			if len(result) > 0 || resultembed != nil {
-				targetchannel := channelID
-				if usepm && !private {
-					channel, err := sb.DG.UserChannelCreate(m.Author.ID)
-					if err == nil {
-						targetchannel = DiscordChannel(channel.ID)
-						private = true
-						if rand.Float32() < 0.01 {
-							info.SendMessage(channelID, "Check your ~~privilege~~ Private Messages for my reply!")
-						} else {
-							info.SendMessage(channelID, StringMap[STRING_CHECK_PM])
-						}
-					} else {
-						info.SendError(channelID, StringMap[STRING_PM_FAILURE], t)
-					}
-				}
-
-				if resultembed != nil {
-					if err := info.SendEmbed(targetchannel, resultembed); err != nil {
-						fmt.Println(err)
-					}
-				} else if err := info.SendMessage(targetchannel, result); err != nil {
-					fmt.Println(err)
-				}
+			args, indices = ParseCommandArguments(m.Content, prefix)
+			if m.ChannelID != "heartbeat" && len(args) < 1 {
+				info.SendError(channelID, "The "+string(arg)+" alias resolves to a blank command! Don't you know how dangerous that is?! That kind of abuse crashes bots! Go to your room and don't come back down until you've fixed that alias using '"+info.Config.Basic.CommandPrefix+"setconfig basic.aliases "+string(arg)+" [something else]', or leave out the fourth argument entirely if you want to delete it!", t)
+				return nil
 			}
-		} else if !info.Config.Basic.IgnoreInvalidCommands {
+			arg = CommandID(strings.ToLower(args[0]))
+			c, ok = info.GetCommand(arg)
+		}
+	}
+	if !ok {
+		if !info.Config.Basic.IgnoreInvalidCommands {
 			if private || !info.checkOnCommand(m) {
-				info.SendError(channelID, fmt.Sprintf(StringMap[STRING_INVALID_COMMAND], info.Sanitize(args[0], CleanMentions|CleanPings|CleanEmotes|CleanCode), info.Config.Basic.CommandPrefix), t)
+				info.SendError(channelID, fmt.Sprintf(info.GetString(STRING_INVALID_COMMAND), info.Sanitize(args[0], CleanMentions|CleanPings|CleanEmotes|CleanCode), info.Config.Basic.CommandPrefix), t)
 			}
 		}
-	} else if info != nil { // If info is nil this was sent through a private message so just ignore it completely
-		for _, h := range info.hooks.OnMessageCreate {
-			if info.ProcessModule(DiscordChannel(m.ChannelID), h) {
-				h.OnMessageCreate(info, m)
+		return nil
+	}
+	if group, isgroup := c.(*CommandGroup); isgroup && len(args) > 1 {
+		if sub := group.Find(args[1]); sub != nil {
+			c = sub
+			args, indices = args[1:], indices[1:]
+		}
+	}
+	ctx := &CommandContext{
+		Command:   c,
+		Name:      CommandID(strings.ToLower(c.Info().Name)),
+		Args:      args[1:],
+		Indices:   indices[1:],
+		Msg:       m,
+		Info:      info,
+		Channel:   channelID,
+		Timestamp: t,
+		Private:   private,
+		Debug:     isdebug,
+		Free:      isfree,
+	}
+	if err := info.RunCommand(ctx); err != nil {
+		if err != ErrCommandAborted {
+			info.SendError(channelID, err.Error(), t)
+		}
+		return nil
+	}
+	return ctx
+}
+
+// sendCommandResult sends the result of a command to the channel it was used in, or to the user if the command
+// asked for a private message. Returns true if the result ended up in a private channel.
+func (sb *SweetieBot) sendCommandResult(ctx *CommandContext, private bool, t int64) bool {
+	info, channelID := ctx.Info, ctx.Channel
+	result, usepm, resultembed := ctx.Result, ctx.UsePM, ctx.Embed
+	if len(result) > 0 || resultembed != nil {
+		targetchannel := channelID
+		if usepm && !private {
+			channel, err := sb.DG.UserChannelCreate(ctx.Msg.Author.ID)
+			if err == nil {
+				targetchannel = DiscordChannel(channel.ID)
+				private = true
+				if rand.Float32() < 0.01 {
+					info.SendMessage(channelID, "Check your ~~privilege~~ Private Messages for my reply!")
+				} else {
+					info.SendMessage(channelID, info.GetString(STRING_CHECK_PM))
+				}
+			} else {
+				info.SendError(channelID, info.GetString(STRING_PM_FAILURE), t)
+			}
+		}
+
+		if resultembed != nil {
+			if err := info.SendEmbed(targetchannel, resultembed); err != nil {
+				sb.Logger.Error("Failed to send embed: "+err.Error(), LogFields{"guild": info.ID, "command": ctx.Name})
 			}
+		} else if err := info.SendMessage(targetchannel, result); err != nil {
+			sb.Logger.Error("Failed to send message: "+err.Error(), LogFields{"guild": info.ID, "command": ctx.Name})
 		}
 	}
+	return private
 }
 
 // MessageCreate discord hook
@@ -573,7 +656,7 @@ func (sb *SweetieBot) MessageCreate(s *discordgo.Session, m *discordgo.MessageCr
 		info, _ = sb.Guilds[sb.MainGuildID]
 		sb.GuildsLock.RUnlock()
 		if info == nil {
-			fmt.Println("Failed to get main guild during heartbeat test!")
+			sb.Logger.Error("Failed to get main guild during heartbeat test!")
 		}
 	}
 
@@ -606,9 +689,13 @@ func (sb *SweetieBot) MessageUpdate(s *discordgo.Session, m *discordgo.MessageUp
 	if sb.SelfID.Equals(m.Author.ID) {
 		return
 	}
-	for _, h := range info.hooks.OnMessageUpdate {
+	hooks := info.getHooks()
+	defer hooks.done()
+	for _, h := range hooks.OnMessageUpdate {
 		if info.ProcessModule(channelID, h) {
+			start := time.Now()
 			h.OnMessageUpdate(info, m.Message)
+			observeHook(h, "OnMessageUpdate", start)
 		}
 	}
 }
@@ -623,9 +710,13 @@ func (sb *SweetieBot) MessageDelete(s *discordgo.Session, m *discordgo.MessageDe
 	if boolXOR(sb.Debug, info.IsDebug(channelID)) {
 		return
 	}
-	for _, h := range info.hooks.OnMessageDelete {
+	hooks := info.getHooks()
+	defer hooks.done()
+	for _, h := range hooks.OnMessageDelete {
 		if info.ProcessModule(channelID, h) {
+			start := time.Now()
 			h.OnMessageDelete(info, m.Message)
+			observeHook(h, "OnMessageDelete", start)
 		}
 	}
 }
@@ -637,18 +728,22 @@ func (sb *SweetieBot) UserUpdate(s *discordgo.Session, u *discordgo.UserUpdate)
 
 // GuildUpdate discord hook
 func (sb *SweetieBot) GuildUpdate(s *discordgo.Session, m *discordgo.GuildUpdate) {
-	info := sb.getChannelGuild(m.ID)
+	info := sb.getGuildFromID(m.ID)
 	if info == nil {
 		return
 	}
-	fmt.Println("Guild update detected, updating", m.Name)
+	sb.Logger.Debug("Guild update detected, updating "+m.Name, LogFields{"guild": m.ID})
 	info.Name = m.Guild.Name
 	info.OwnerID = DiscordUser(m.Guild.OwnerID)
 	info.ProcessMembers(m.Guild.Members)
 
-	for _, h := range info.hooks.OnGuildUpdate {
+	hooks := info.getHooks()
+	defer hooks.done()
+	for _, h := range hooks.OnGuildUpdate {
 		if info.ProcessModule("", h) {
+			start := time.Now()
 			h.OnGuildUpdate(info, m.Guild)
+			observeHook(h, "OnGuildUpdate", start)
 		}
 	}
 }
@@ -661,9 +756,13 @@ func (sb *SweetieBot) GuildMemberAdd(s *discordgo.Session, m *discordgo.GuildMem
 	}
 	info.ProcessMember(m.Member)
 
-	for _, h := range info.hooks.OnGuildMemberAdd {
+	hooks := info.getHooks()
+	defer hooks.done()
+	for _, h := range hooks.OnGuildMemberAdd {
 		if info.ProcessModule("", h) {
+			start := time.Now()
 			h.OnGuildMemberAdd(info, m.Member, time.Now().UTC())
+			observeHook(h, "OnGuildMemberAdd", start)
 		}
 	}
 }
@@ -674,12 +773,16 @@ func (sb *SweetieBot) GuildMembersChunk(s *discordgo.Session, chunk *discordgo.G
 		return
 	}
 	info.ProcessMembers(chunk.Members)
+	hooks := info.getHooks()
+	defer hooks.done()
 	for _, m := range chunk.Members {
 		info.Bot.DG.State.MemberAdd(m)
 
-		for _, h := range info.hooks.OnGuildMemberAdd {
+		for _, h := range hooks.OnGuildMemberAdd {
 			if info.ProcessModule("", h) {
+				start := time.Now()
 				h.OnGuildMemberAdd(info, m, time.Now().UTC())
+				observeHook(h, "OnGuildMemberAdd", start)
 			}
 		}
 	}
@@ -696,14 +799,18 @@ func (sb *SweetieBot) GuildMemberRemove(s *discordgo.Session, m *discordgo.Guild
 		sb.DB.RemoveMember(userID.Convert(), SBatoi(info.ID))
 	}
 
-	for _, h := range info.hooks.OnGuildMemberRemove {
+	hooks := info.getHooks()
+	defer hooks.done()
+	for _, h := range hooks.OnGuildMemberRemove {
 		if info.ProcessModule("", h) {
+			start := time.Now()
 			h.OnGuildMemberRemove(info, m.Member, time.Now().UTC())
+			observeHook(h, "OnGuildMemberRemove", start)
 		}
 	}
 
 	if userID == sb.SelfID {
-		fmt.Println("Sweetie was removed from", info.Name)
+		sb.Logger.Info("Sweetie was removed from "+info.Name, LogFields{"guild": info.ID})
 		sb.GuildsLock.Lock()
 		delete(sb.Guilds, DiscordGuild(info.ID))
 		sb.GuildsLock.Unlock()
@@ -723,9 +830,13 @@ func (sb *SweetieBot) GuildMemberUpdate(s *discordgo.Session, m *discordgo.Guild
 
 	sb.deferChan <- deferPair{m, info}
 
-	for _, h := range info.hooks.OnGuildMemberUpdate {
+	hooks := info.getHooks()
+	defer hooks.done()
+	for _, h := range hooks.OnGuildMemberUpdate {
 		if info.ProcessModule("", h) {
+			start := time.Now()
 			h.OnGuildMemberUpdate(info, m.Member, time.Now().UTC())
+			observeHook(h, "OnGuildMemberUpdate", start)
 		}
 	}
 }
@@ -737,9 +848,13 @@ func (sb *SweetieBot) GuildBanAdd(s *discordgo.Session, m *discordgo.GuildBanAdd
 		return
 	}
 
-	for _, h := range info.hooks.OnGuildBanAdd {
+	hooks := info.getHooks()
+	defer hooks.done()
+	for _, h := range hooks.OnGuildBanAdd {
 		if info.ProcessModule("", h) {
+			start := time.Now()
 			h.OnGuildBanAdd(info, m)
+			observeHook(h, "OnGuildBanAdd", start)
 		}
 	}
 }
@@ -751,9 +866,13 @@ func (sb *SweetieBot) GuildBanRemove(s *discordgo.Session, m *discordgo.GuildBan
 		return
 	}
 
-	for _, h := range info.hooks.OnGuildBanRemove {
+	hooks := info.getHooks()
+	defer hooks.done()
+	for _, h := range hooks.OnGuildBanRemove {
 		if info.ProcessModule("", h) {
+			start := time.Now()
 			h.OnGuildBanRemove(info, m)
+			observeHook(h, "OnGuildBanRemove", start)
 		}
 	}
 }
@@ -765,9 +884,13 @@ func (sb *SweetieBot) GuildRoleDelete(s *discordgo.Session, m *discordgo.GuildRo
 		return
 	}
 
-	for _, h := range info.hooks.OnGuildRoleDelete {
+	hooks := info.getHooks()
+	defer hooks.done()
+	for _, h := range hooks.OnGuildRoleDelete {
 		if info.ProcessModule("", h) {
+			start := time.Now()
 			h.OnGuildRoleDelete(info, m)
+			observeHook(h, "OnGuildRoleDelete", start)
 		}
 	}
 }
@@ -780,7 +903,7 @@ func (sb *SweetieBot) GuildCreate(s *discordgo.Session, m *discordgo.GuildCreate
 // GuildDelete discord hook
 func (sb *SweetieBot) GuildDelete(s *discordgo.Session, m *discordgo.GuildDelete) {
 	if !m.Unavailable {
-		fmt.Println("Sweetie was deleted from", m.Guild.Name)
+		sb.Logger.Info("Sweetie was deleted from "+m.Guild.Name, LogFields{"guild": m.Guild.ID})
 		sb.GuildsLock.Lock()
 		delete(sb.Guilds, DiscordGuild(m.Guild.ID))
 		sb.GuildsLock.Unlock()
@@ -884,6 +1007,7 @@ func (sb *SweetieBot) deferProcessing() {
 }
 
 func (sb *SweetieBot) idleCheckLoop() {
+	lastsave := time.Now()
 	for atomic.LoadUint32(&sb.quit) != QuitNow {
 		sb.DB.CheckStatus()
 		sb.GuildsLock.RLock()
@@ -895,14 +1019,17 @@ func (sb *SweetieBot) idleCheckLoop() {
 		tm := time.Now()
 
 		for _, info := range infos {
-			for _, h := range info.hooks.OnTick {
-				if info.ProcessModule("", h) {
-					h.OnTick(info, tm)
-				}
+			info.tick(tm)
+		}
+
+		if tm.Sub(lastsave) >= StateSaveInterval {
+			lastsave = tm
+			for _, info := range infos {
+				info.SaveModuleState()
 			}
 		}
 
-		fmt.Println("Idle Check: ", tm)
+		sb.Logger.Debug(fmt.Sprint("Idle Check: ", tm))
 		time.Sleep(20 * time.Second)
 	}
 }
@@ -917,7 +1044,7 @@ func (sb *SweetieBot) deadlockTestFunc(s *discordgo.Session, m *discordgo.Messag
 	sb.GuildsLock.RLock()
 	sb.GuildsLock.RUnlock()
 	sb.locknumber++
-	sb.MessageCreate(&sb.DG.Session, m)
+	sb.MessageCreate(sb.DG.Session, m)
 }
 
 func (sb *SweetieBot) deadlockDetector() {
@@ -935,13 +1062,13 @@ func (sb *SweetieBot) deadlockDetector() {
 			break
 		}
 
-		fmt.Println(sb.MainGuildID, "MAIN GUILD CANNOT BE FOUND! Deadlock detector is nonfunctional until this is addressed.")
+		sb.Logger.Error("MAIN GUILD CANNOT BE FOUND! Deadlock detector is nonfunctional until this is addressed.", LogFields{"guild": sb.MainGuildID})
 		time.Sleep(heartbeatInterval)
 	}
 
 	for atomic.LoadUint32(&sb.quit) != QuitNow {
 		m := discordgo.MessageCreate{
-			&discordgo.Message{ChannelID: "heartbeat", Content: info.Config.Basic.CommandPrefix + "about",
+			Message: &discordgo.Message{ChannelID: "heartbeat", Content: info.Config.Basic.CommandPrefix + "about",
 				Author: &discordgo.User{
 					ID:       sb.SelfID.String(),
 					Verified: true,
@@ -951,18 +1078,18 @@ func (sb *SweetieBot) deadlockDetector() {
 			},
 		}
 		sb.locknumber = 0
-		go sb.deadlockTestFunc(&sb.DG.Session, &m) // Do this in another thread so the deadlock detector doesn't deadlock
+		go sb.deadlockTestFunc(sb.DG.Session, &m) // Do this in another thread so the deadlock detector doesn't deadlock
 		time.Sleep(heartbeatInterval)
 		if atomic.LoadUint32(&sb.heartbeat) == counter+1 {
 			counter++
 			missed = 0
 		} else {
 			missed++
-			fmt.Println("MISSED HEARTBEAT SIGNAL ", missed, " TIMES IN A ROW")
+			sb.Logger.Warn(fmt.Sprint("MISSED HEARTBEAT SIGNAL ", missed, " TIMES IN A ROW"))
 			counter = atomic.LoadUint32(&sb.heartbeat)
 		}
 		if missed >= 5 {
-			fmt.Println("FATAL ERROR: DEADLOCK DETECTED! (", sb.locknumber, ") TERMINATING PROGRAM...")
+			sb.Logger.Error(fmt.Sprint("FATAL ERROR: DEADLOCK DETECTED! (", sb.locknumber, ") TERMINATING PROGRAM..."))
 			name := fmt.Sprintf("stacktrace_%v.txt", time.Now().UTC().Unix())
 			if f, err := os.Create(name); err == nil {
 				pprof.Lookup("goroutine").WriteTo(f, 1)
@@ -979,7 +1106,8 @@ func (sb *SweetieBot) deadlockDetector() {
 	}
 }
 
-// New creates and initializes a new instance of Sweetiebot that's ready to connect. Returns nil on error.
+// New creates and initializes a new instance of Sweetiebot that's ready to connect. If loader is nil, every module
+// registered with RegisterModuleFactory is loaded, except the ones in excludemodules. Returns nil on error.
 func New(token string, loader func(*GuildInfo) []Module) *SweetieBot {
 	path, _ := GetCurrentDir()
 	selfhoster := &Selfhost{SelfhostBase{BotVersion.Integer()}, AtomicBool{0}}
@@ -1017,6 +1145,7 @@ func New(token string, loader func(*GuildInfo) []Module) *SweetieBot {
 		WebDomain:     "localhost",
 		WebPort:       ":80",
 		changelog: map[int]string{
+			AssembleVersion(1, 0, 7, 0):  "- Server configurations are now stored in the database instead of JSON files.\n- Added !confighistory and !configrollback, which list and undo configuration changes.\n- Configuration options are now validated when changed. Use !validateconfig to check your existing configuration.\n- Added !exportconfig and !importconfig, which copy a configuration between servers.\n- Added basic.language, which loads translated messages from language packs in the lang folder, and !strings, which customizes individual messages.\n- The filter and counter commands are now grouped under !filter and !counter, like !filter add or !counter inc. The old command names still work as aliases.\n- Added modules.usercooldowns and modules.userquotas, which limit how often each user can run a command depending on their roles.\n- basic.commandprefix can now be any length, basic.extraprefixes adds more prefixes, and pinging the bot always works as a prefix.\n- Added !addcommand and !removecommand, which create custom commands whose responses can include the author, a mentioned user, arguments, a random member, a random tag item and counter values.\n- Commands can now be chained with ; to run them one after another, or with | to add the output of a command to the end of the next one, like !pick cute | echo #general. Up to 5 commands can be chained, and each one is checked like it was run on its own.\n- Internal debug messages are no longer posted to log.channel. Selfhosted bots can configure logging in selfhost.json, including JSON output, a log file, and which levels are sent to log.channel.\n- Selfhosted bots can now serve Prometheus metrics on /metrics, including command, module hook, database, message and spam counters labeled by server. Metrics are served on a separate listener set by monitoraddr in selfhost.json, like 127.0.0.1:9100, so they can be kept private.\n- The monitoraddr listener also has /healthz and /readyz endpoints, which report the gateway connection, the deadlock detector's heartbeat, the database, the internal queues and the number of servers, so Docker or systemd can restart a stuck bot.\n- Added a web dashboard at /dashboard. Administrators and moderators log in with Discord and can change every configuration option of their servers, with the same checks as !setconfig. Selfhosted bots need to add their application's OAuth2 client secret to selfhost.json as oauthsecret. The website, dashboard and API are only served if webenabled is set in selfhost.json.\n- Added a read-only web API under /api/v1/ for schedules, tags, quotes, counters and rules. Administrators create and revoke access tokens with !apitoken create and !apitoken revoke.\n- Large selfhosted bots can now be split into shards by setting shardcount in selfhost.json, and optionally shardids to choose which shards each process runs. Status changes are sent on every shard, and !listguilds shows which shard each server is on.\n- The spam module now saves tracked pressure, silenced users, pending unsilences and lockdown state every 5 minutes and on shutdown, and restores them on startup. Silences that expired while the bot was offline are processed immediately.\n- Modules now communicate through an event bus on each server, which publishes added pressure, silenced users, raids, fired scheduled events and configuration changes. The filter module adds pressure through the bus instead of depending on the spam module.\n- Modules now register themselves when their package is imported, and are loaded after the modules they depend on. Selfhosted bots can stop loading modules entirely by listing them in excludemodules in selfhost.json.\n- Added !reloadmodules, which lets the bot owner rebuild a server's modules without restarting.\n- Modules can now store their own data with GuildInfo.Store, which supports key prefixes and expiration times.",
 			AssembleVersion(1, 0, 6, 0):  "- Deprecate domain name.",
 			AssembleVersion(1, 0, 5, 0):  "- Remove silence role, member role, jail channel, welcome channel, and the unsilence command.\n- The managed silence role was automatically deleted.\n- If you were still using a member role, you should give it's permissions to the everyone role and delete it.",
 			AssembleVersion(1, 0, 4, 0):  "- Remove all silver checks, assume everyone has silver, but decrease some limits to compensate.",
@@ -1198,6 +1327,19 @@ func New(token string, loader func(*GuildInfo) []Module) *SweetieBot {
 
 	json.Unmarshal(hostfile, sb)
 	sb.Token = strings.TrimSpace(sb.Token)
+	if logger, err := NewLogger(&sb.Logging); err == nil {
+		sb.Logger = logger
+	} else {
+		sb.Logger.Error("Error in logging options, using the default options instead: " + err.Error())
+		sb.Logger, _ = NewLogger(nil)
+	}
+	if sb.loader == nil {
+		var err error
+		if sb.loader, err = NewModuleLoader(sb.ExcludeModules, sb.Logger); err != nil {
+			sb.Logger.Error("Error loading modules: " + err.Error())
+			return nil
+		}
+	}
 	sb.EmptyGuild = NewGuildInfo(sb, &discordgo.Guild{})
 
 	sb.EmptyGuild.Config.FillConfig()
@@ -1217,27 +1359,41 @@ func New(token string, loader func(*GuildInfo) []Module) *SweetieBot {
 	// Load language override
 	if configHelpFile, err := ioutil.ReadFile("confighelp.json"); err == nil {
 		if err = json.Unmarshal(configHelpFile, &ConfigHelp); err != nil {
-			fmt.Println("Error loading config help replacement file: ", err)
+			sb.Logger.Error("Error loading config help replacement file: " + err.Error())
 		}
 	}
 
 	if stringsFile, err := ioutil.ReadFile("strings.json"); err == nil {
 		if err = json.Unmarshal(stringsFile, &StringMap); err != nil {
-			fmt.Println("Error loading strings replacement file: ", err)
+			sb.Logger.Error("Error loading strings replacement file: " + err.Error())
 		}
 	}
 
-	db, err := dbLoad(&emptyLog{}, "mysql", strings.TrimSpace(sb.DBAuth))
+	if err := LoadLanguagePacks(filepath.Join(sb.Selfhoster.GetWebDir(), "lang"), sb.Logger); err != nil {
+		sb.Logger.Error("Error loading language packs: " + err.Error())
+	}
+
+	driver, conn := ParseDBAuth(sb.DBAuth)
+	db, err := dbLoad(sb.Logger.With(LogFields{"module": "db"}), driver, conn)
 	sb.DB = db
+	if err == nil && driver == DriverSQLite {
+		if err = db.InitSQLite(sb.Selfhoster.GetWebDir()); err != nil {
+			sb.Logger.Error("Error initializing SQLite database: " + err.Error())
+			db.Status.Set(false)
+		}
+	}
 	if !db.Status.Get() {
-		fmt.Println("Database connection failure - running in No Database mode: ", err.Error())
+		sb.Logger.Error("Database connection failure - running in No Database mode: " + err.Error())
 	} else {
 		err = sb.DB.LoadStatements()
 		if err == nil {
-			fmt.Println("Finished loading database statements")
+			sb.Logger.Info("Finished loading database statements")
+			if dir, err := GetCurrentDir(); err == nil {
+				sb.ImportConfigFiles(dir)
+			}
 		} else {
-			fmt.Println("Loading database statements failed: ", err)
-			fmt.Println("DATABASE IS BADLY FORMATTED OR CORRUPT - TERMINATING SWEETIE BOT!")
+			sb.Logger.Error("Loading database statements failed: " + err.Error())
+			sb.Logger.Error("DATABASE IS BADLY FORMATTED OR CORRUPT - TERMINATING SWEETIE BOT!")
 			return nil
 		}
 	}
@@ -1245,33 +1401,22 @@ func New(token string, loader func(*GuildInfo) []Module) *SweetieBot {
 	var dg *discordgo.Session
 	if sb.IsUserMode {
 		dg, err = discordgo.New(sb.Token)
-		fmt.Println("Started SweetieBot on a user account.")
+		sb.Logger.Info("Started SweetieBot on a user account.")
 	} else {
 		dg, err = discordgo.New("Bot " + sb.Token)
 		dg.Identify.Intents = discordgo.MakeIntent(discordgo.IntentsAllWithoutPrivileged | discordgo.IntentsGuildMembers)
 	}
-	sb.DG = &DiscordGoSession{*dg}
+	sb.DG = &DiscordGoSession{dg}
 
 	if err != nil {
-		fmt.Println("Error creating discord session", err.Error())
+		sb.Logger.Error("Error creating discord session: " + err.Error())
 		return nil
 	}
 	sb.DG.LogLevel = discordgo.LogWarning
-
-	sb.DG.AddHandler(sb.OnReady)
-	sb.DG.AddHandler(sb.MessageCreate)
-	sb.DG.AddHandler(sb.MessageUpdate)
-	sb.DG.AddHandler(sb.MessageDelete)
-	sb.DG.AddHandler(sb.UserUpdate)
-	sb.DG.AddHandler(sb.GuildUpdate)
-	sb.DG.AddHandler(sb.GuildMemberAdd)
-	sb.DG.AddHandler(sb.GuildMemberRemove)
-	sb.DG.AddHandler(sb.GuildMemberUpdate)
-	sb.DG.AddHandler(sb.GuildMembersChunk)
-	sb.DG.AddHandler(sb.GuildBanAdd)
-	sb.DG.AddHandler(sb.GuildBanRemove)
-	sb.DG.AddHandler(sb.GuildRoleDelete)
-	sb.DG.AddHandler(sb.GuildCreate)
+	if err = sb.newShards(); err != nil {
+		sb.Logger.Error("Error in sharding options: " + err.Error())
+		return nil
+	}
 	return sb
 }
 
@@ -1290,10 +1435,24 @@ func (sb *SweetieBot) Connect() int {
 	go sb.deadlockDetector()
 	go sb.memberIngestionLoop()
 	go sb.buildMarkov()
+	if sb.WebEnabled {
+		go func() {
+			if err := sb.ServeWeb(); err != nil {
+				sb.Logger.Error("Web server stopped: " + err.Error())
+			}
+		}()
+	}
+	if len(sb.MonitorAddr) > 0 {
+		go func() {
+			if err := sb.ServeMonitor(); err != nil {
+				sb.Logger.Error("Monitoring server stopped: " + err.Error())
+			}
+		}()
+	}
 
-	err := sb.DG.Open()
+	err := sb.openShards()
 	if err == nil {
-		fmt.Println("Connection established")
+		sb.Logger.Info("Connection established")
 		for atomic.LoadUint32(&sb.quit) == QuitNone {
 			time.Sleep(800 * time.Millisecond)
 		}
@@ -1313,7 +1472,7 @@ func (sb *SweetieBot) Connect() int {
 			}
 		}
 	} else {
-		fmt.Println("Error opening websocket connection: ", err.Error())
+		sb.Logger.Error("Error opening websocket connection: " + err.Error())
 	}
 
 	/*if q, err := sb.DB.db.Query("SELECT DISTINCT Guild FROM members"); err == nil {
@@ -1329,11 +1488,17 @@ func (sb *SweetieBot) Connect() int {
 		}
 	}*/
 
-	fmt.Println("Sweetiebot quitting")
-	sb.DG.Close()
+	sb.Logger.Info("Sweetiebot quitting")
+	sb.closeShards()
+	sb.GuildsLock.RLock()
+	for _, g := range sb.Guilds { // Save module state after disconnecting so no more events can change it
+		g.SaveModuleState()
+	}
+	sb.GuildsLock.RUnlock()
 	sb.DB.Close()
 	sb.GuildsLock.Lock() // Prevents a race condition from sending a value to a closed channel
 	close(sb.memberChan)
 	sb.GuildsLock.Unlock()
+	sb.Logger.Close()
 	return BotVersion.Integer()
 }
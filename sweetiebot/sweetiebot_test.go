@@ -6,7 +6,6 @@ import (
 	"path/filepath"
 	"runtime"
 	"strconv"
-	"sync"
 	"testing"
 	"time"
 
@@ -56,6 +55,7 @@ const (
 	TestRoleMember     = iota << MaxServers
 	TestRoleAssign     = iota << MaxServers
 	TestRoleAssign2    = iota << MaxServers
+	TestRoleSilence    = iota << MaxServers
 	TestServer         = iota << MaxServers
 	TestChannel        = iota << MaxServers
 	TestChannel2       = iota << MaxServers
@@ -64,6 +64,8 @@ const (
 	TestChannelLog     = iota << MaxServers
 	TestChannelMod     = iota << MaxServers
 	TestChannelBored   = iota << MaxServers
+	TestChannelJail    = iota << MaxServers
+	TestChannelWelcome = iota << MaxServers
 	TestChannelPrivate = iota << MaxServers
 	TestChannelGroupDM = iota << MaxServers
 	NumServers         = 3
@@ -202,6 +204,13 @@ func mockDiscordChannel(channel int, index int) *discordgo.Channel {
 	case TestChannelBored:
 		name = "Bored Channel"
 		perms = append(perms, disallowSilence)
+	case TestChannelJail:
+		name = "Jail Channel"
+		perms = append(perms, disallowEveryone, allowMods, allowSilence)
+	case TestChannelWelcome:
+		name = "Welcome Channel"
+		perms = append(perms, disallowSilence)
+	}
 	return &discordgo.Channel{
 		ID:                   strconv.Itoa(channel | index),
 		GuildID:              strconv.Itoa(TestServer | index),
@@ -240,6 +249,7 @@ func mockDiscordGuild(index int) *discordgo.Guild {
 			mockDiscordMember(TestUserAssigned, index),
 			mockDiscordMember(TestUserNonAssign, index),
 			mockDiscordMember(TestUserBoring, index),
+			mockDiscordMember(TestUserSilence, index),
 			mockDiscordMember(TestUserBot, index),
 		},
 		Presences: []*discordgo.Presence{},
@@ -251,6 +261,8 @@ func mockDiscordGuild(index int) *discordgo.Guild {
 			mockDiscordChannel(TestChannelLog, index),
 			mockDiscordChannel(TestChannelMod, index),
 			mockDiscordChannel(TestChannelBored, index),
+			mockDiscordChannel(TestChannelJail, index),
+			mockDiscordChannel(TestChannelWelcome, index),
 		},
 		VoiceStates: []*discordgo.VoiceState{},
 	}
@@ -259,7 +271,7 @@ func mockDiscordGuild(index int) *discordgo.Guild {
 // Generate fake discordgo session
 func mockDiscordGo() *DiscordGoSession {
 	dg, _ := discordgo.New("Bot NotValidToken")
-	s := &DiscordGoSession{*dg}
+	s := &DiscordGoSession{dg}
 	for i := 0; i < NumServers; i++ {
 		s.State.GuildAdd(mockDiscordGuild(i))
 	}
@@ -276,11 +288,11 @@ func mockBotDB() (*BotDB, sqlmock.Sqlmock) {
 	botdb := &BotDB{
 		db:          db,
 		lastattempt: time.Now().UTC(),
-		log:         &emptyLog{},
+		log:         nil,
 		driver:      "mysql",
 		conn:        "",
 	}
-	for i := 0; i < 67; i++ {
+	for i := 0; i < 97; i++ {
 		mock.ExpectPrepare(".*")
 	}
 	botdb.Status.Set(botdb.LoadStatements() == nil)
@@ -347,27 +359,17 @@ func MockSweetieBot(t *testing.T) (*SweetieBot, sqlmock.Sqlmock, *Mock) {
 		StartTime:     time.Now().UTC().Unix(),
 		heartbeat:     4294967290,
 		memberChan:    make(chan *GuildInfo, 1500),
-		Selfhoster:    &Selfhost{SelfhostBase{BotVersion.Integer()}, AtomicBool{0}, sync.Map{}},
+		Selfhoster:    &Selfhost{SelfhostBase{BotVersion.Integer()}, AtomicBool{0}},
 	}
 	sb.EmptyGuild = NewGuildInfo(sb, &discordgo.Guild{})
 	sb.EmptyGuild.Config.FillConfig()
 	sb.EmptyGuild.Config.SetupDone = true
 
 	mock = NewMock(t)
+	t.Cleanup(func() { mock = nil }) // Tests that don't use the mock talk to the real session
 
 	for _, guild := range sb.DG.State.Guilds {
-		info := &GuildInfo{
-			ID:           guild.ID,
-			Name:         guild.Name,
-			OwnerID:      DiscordUser(guild.OwnerID),
-			commandLast:  make(map[string]int64),
-			commandlimit: &SaturationLimit{[]int64{}, 0, AtomicFlag{0}},
-			commands:     make(map[CommandID]Command),
-			commandmap:   make(map[CommandID]ModuleID),
-			lastlogerr:   0,
-			Bot:          sb,
-			Config:       *DefaultConfig(),
-		}
+		info := NewGuildInfo(sb, guild)
 		info.Config.FillConfig()
 		id := DiscordGuild(guild.ID)
 		sb.Guilds[id] = info
@@ -383,7 +385,6 @@ func MockSweetieBot(t *testing.T) (*SweetieBot, sqlmock.Sqlmock, *Mock) {
 		}
 		dbmock.ExpectExec("INSERT IGNORE INTO members.*").WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 0))
 		info.ProcessMembers(guild.Members)
-		sb.Selfhoster.CheckGuilds(map[DiscordGuild]*GuildInfo{id: info})
 		i := id.Convert() & ((1 << MaxServers) - 1)
 		info.Config.Modules.Channels["bored"] = map[DiscordChannel]bool{NewDiscordChannel(TestChannelBored | i): true}
 		info.Config.Log.Channel = NewDiscordChannel(TestChannelLog | i)
@@ -391,10 +392,7 @@ func MockSweetieBot(t *testing.T) (*SweetieBot, sqlmock.Sqlmock, *Mock) {
 		info.Config.Basic.BotChannel = NewDiscordChannel(TestChannelFree | i)
 		info.Config.Basic.FreeChannels[NewDiscordChannel(TestChannelFree|i)] = true
 		info.Config.Basic.ModChannel = NewDiscordChannel(TestChannelMod | i)
-		info.Config.Basic.SilenceRole = NewDiscordRole(TestRoleSilence | i)
-		info.Config.Basic.MemberRole = NewDiscordRole(TestRoleMember | i)
-		info.Config.Users.JailChannel = NewDiscordChannel(TestChannelJail | i)
-		info.Config.Users.WelcomeChannel = NewDiscordChannel(TestChannelWelcome | i)
+		info.Config.Users.NotifyChannel = NewDiscordChannel(TestChannelWelcome | i)
 		info.Config.SetupDone = true
 	}
 
@@ -414,7 +412,7 @@ func MockMessage(content string, channel int, t int64, user int, index int) *dis
 func TestProcessCommand(t *testing.T) {
 	sb, dbmock, _ := MockSweetieBot(t)
 
-	dbmock.ExpectQuery("SELECT .* FROM users.*").WithArgs(TestUserBoring).WillReturnRows(sqlmock.NewRows([]string{}))
+	dbmock.ExpectQuery("SELECT .* FROM `users`.*").WithArgs(TestUserBoring).WillReturnRows(sqlmock.NewRows([]string{}))
 	dbmock.ExpectQuery("SELECT Guild FROM members*").WithArgs(TestUserBoring).WillReturnRows(sqlmock.NewRows([]string{"Guild"}).AddRow(TestServer))
 	mock.Expect(sb.DG.RequestWithLockedBucket, "POST", MockAny{}, "application/json", MockAny{}, MockAny{}, 0)
 	sb.ProcessCommand(MockMessage("!about", TestChannelPrivate, 100000, TestUserBoring, 0), nil, 100000, false, false)
@@ -434,45 +432,45 @@ func TestProcessCommand(t *testing.T) {
 	}
 
 	Check(mock.Check(), true, t)
-	dbmock.ExpectQuery("SELECT .* FROM users.*").WillReturnRows(sqlmock.NewRows([]string{"ID", "Username", "Discriminator", "LastSeen", "Location", "DefaultServer"}).AddRow(0, "", 0, time.Now(), "", TestServer))
+	dbmock.ExpectQuery("SELECT .* FROM `users`.*").WillReturnRows(sqlmock.NewRows([]string{"ID", "Username", "Discriminator", "LastSeen", "Location", "DefaultServer"}).AddRow(0, "", 0, time.Now(), "", TestServer))
 	dbmock.ExpectExec("INSERT INTO debuglog .*").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.Expect(sb.DG.ChannelMessageSendEmbed, strconv.Itoa(TestChannelPrivate), MockAny{})
 	sb.ProcessCommand(MockMessage("!about", TestChannelPrivate, 1000, TestUserBoring, 0), nil, 1000, false, false)
 	Check(mock.Check(), true, t)
-	dbmock.ExpectQuery("SELECT .* FROM users.*").WillReturnRows(sqlmock.NewRows([]string{}))
+	dbmock.ExpectQuery("SELECT .* FROM `users`.*").WillReturnRows(sqlmock.NewRows([]string{}))
 	dbmock.ExpectQuery("SELECT Guild FROM members*").WithArgs(TestUserBoring).WillReturnRows(sqlmock.NewRows([]string{"Guild"}).AddRow(TestServer))
 	dbmock.ExpectExec("INSERT INTO debuglog .*").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.Expect(sb.DG.ChannelMessageSendEmbed, strconv.Itoa(TestChannelPrivate), MockAny{})
 	sb.ProcessCommand(MockMessage("!about", TestChannelPrivate, 20001, TestUserBoring, 0), nil, 20001, false, true)
 	Check(mock.Check(), true, t)
-	dbmock.ExpectQuery("SELECT .* FROM users.*").WillReturnRows(sqlmock.NewRows([]string{}))
+	dbmock.ExpectQuery("SELECT .* FROM `users`.*").WillReturnRows(sqlmock.NewRows([]string{}))
 	dbmock.ExpectQuery("SELECT Guild FROM members*").WithArgs(TestUserBoring).WillReturnRows(sqlmock.NewRows([]string{"Guild"}))
 	dbmock.ExpectExec("INSERT INTO debuglog .*").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.Expect(sb.DG.ChannelMessageSendEmbed, strconv.Itoa(TestChannelPrivate), MockAny{})
 	sb.ProcessCommand(MockMessage("!about", TestChannelPrivate, 30001, TestUserBoring, 0), nil, 30001, false, true)
 	Check(mock.Check(), true, t)
 
-	dbmock.ExpectQuery("SELECT .* FROM users.*").WillReturnRows(sqlmock.NewRows([]string{}))
+	dbmock.ExpectQuery("SELECT .* FROM `users`.*").WillReturnRows(sqlmock.NewRows([]string{}))
 	dbmock.ExpectQuery("SELECT Guild FROM members*").WithArgs(0).WillReturnRows(sqlmock.NewRows([]string{"Guild"}))
 	dbmock.ExpectExec("INSERT INTO debuglog .*").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.Expect(sb.DG.ChannelMessageSendEmbed, strconv.Itoa(TestChannelPrivate), MockAny{})
 	sb.ProcessCommand(MockMessage("!about", TestChannelPrivate, 40002, 0, 0), nil, 40002, false, false)
-	dbmock.ExpectQuery("SELECT .* FROM users.*").WillReturnRows(sqlmock.NewRows([]string{}))
+	dbmock.ExpectQuery("SELECT .* FROM `users`.*").WillReturnRows(sqlmock.NewRows([]string{}))
 	dbmock.ExpectQuery("SELECT Guild FROM members*").WithArgs(0).WillReturnRows(sqlmock.NewRows([]string{"Guild"}))
 	dbmock.ExpectExec("INSERT INTO debuglog .*").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.Expect(sb.DG.ChannelMessageSendEmbed, strconv.Itoa(TestChannelPrivate), MockAny{})
 	sb.ProcessCommand(MockMessage("!about", TestChannelPrivate, 50003, 0, 0), nil, 50003, false, true)
-	dbmock.ExpectQuery("SELECT .* FROM users.*").WillReturnRows(sqlmock.NewRows([]string{}))
+	dbmock.ExpectQuery("SELECT .* FROM `users`.*").WillReturnRows(sqlmock.NewRows([]string{}))
 	dbmock.ExpectQuery("SELECT Guild FROM members*").WithArgs(TestUserBoring).WillReturnRows(sqlmock.NewRows([]string{"Guild"}))
 	dbmock.ExpectExec("INSERT INTO debuglog .*").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.Expect(sb.DG.ChannelMessageSendEmbed, strconv.Itoa(TestChannelPrivate), MockAny{})
 	sb.ProcessCommand(MockMessage("!about", TestChannelPrivate, 60004, TestUserBoring, 0), nil, 60004, true, true)
-	dbmock.ExpectQuery("SELECT .* FROM users.*").WillReturnRows(sqlmock.NewRows([]string{}))
+	dbmock.ExpectQuery("SELECT .* FROM `users`.*").WillReturnRows(sqlmock.NewRows([]string{}))
 	dbmock.ExpectQuery("SELECT Guild FROM members*").WithArgs(TestUserBoring).WillReturnRows(sqlmock.NewRows([]string{"Guild"}))
 	dbmock.ExpectExec("INSERT INTO debuglog .*").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.Expect(sb.DG.ChannelMessageSendEmbed, strconv.Itoa(TestChannelPrivate), MockAny{})
 	sb.ProcessCommand(MockMessage("!about", TestChannelPrivate, 70005, TestUserBoring, 0), nil, 70005, true, true)
-	dbmock.ExpectQuery("SELECT .* FROM users.*").WillReturnRows(sqlmock.NewRows([]string{}))
+	dbmock.ExpectQuery("SELECT .* FROM `users`.*").WillReturnRows(sqlmock.NewRows([]string{}))
 	dbmock.ExpectQuery("SELECT Guild FROM members*").WithArgs(TestUserBoring).WillReturnRows(sqlmock.NewRows([]string{"Guild"}))
 	mock.Expect(sb.DG.ChannelMessageSend, strconv.Itoa(TestChannelPrivate), MockAny{})
 	sb.ProcessCommand(MockMessage("!asdf", TestChannelPrivate, 80005, TestUserBoring, 0), nil, 80005, true, true)
@@ -491,17 +489,17 @@ func TestProcessCommand(t *testing.T) {
 		dbmock.ExpectExec("INSERT INTO debuglog .*").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.Expect(sb.DG.ChannelMessageSendEmbed, strconv.Itoa(TestChannel|i), MockAny{})
 		sb.ProcessCommand(MockMessage("!about", TestChannel, 1000000, TestUserBoring, i), v, 1000000, false, false)
-		v.Config.Basic.CommandPrefix = ""
+		v.Config.Basic.CommandPrefix = "asdf"
+		sb.ProcessCommand(MockMessage("!about", TestChannel, 1000000, TestUserBoring, i), v, 1000000, false, false)
 		dbmock.ExpectExec("INSERT INTO debuglog .*").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.Expect(sb.DG.ChannelMessageSendEmbed, strconv.Itoa(TestChannel|i), MockAny{})
-		sb.ProcessCommand(MockMessage("!about", TestChannel, 1000000, TestUserBoring, i), v, 1000000, false, false)
-		v.Config.Basic.CommandPrefix = "asdf"
+		sb.ProcessCommand(MockMessage("asdfabout", TestChannel, 1000000, TestUserBoring, i), v, 1000000, false, false)
 		dbmock.ExpectExec("INSERT INTO debuglog .*").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.Expect(sb.DG.ChannelMessageSendEmbed, strconv.Itoa(TestChannel|i), MockAny{})
-		sb.ProcessCommand(MockMessage("!about", TestChannel, 1000000, TestUserBoring, i), v, 1000000, false, false)
+		sb.ProcessCommand(MockMessage("asdf about", TestChannel, 1000000, TestUserBoring, i), v, 1000000, false, false)
 		dbmock.ExpectExec("INSERT INTO debuglog .*").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.Expect(sb.DG.RequestWithLockedBucket, "POST", MockAny{}, "application/json", MockAny{}, MockAny{}, 0)
-		sb.ProcessCommand(MockMessage("!about", TestChannel, 1000000, TestUserBoring, i), v, 1000000, false, false)
+		sb.ProcessCommand(MockMessage("asdfabout", TestChannel, 1000000, TestUserBoring, i), v, 1000000, false, false)
 		Check(mock.Check(), true, t) // Check that the command saturation works
 		v.Config.Basic.CommandPrefix = "~"
 		dbmock.ExpectExec("INSERT INTO debuglog .*").WillReturnResult(sqlmock.NewResult(1, 1))
@@ -603,11 +601,10 @@ func TestProcessCommand(t *testing.T) {
 		dbmock.ExpectExec("INSERT INTO debuglog .*").WillReturnResult(sqlmock.NewResult(1, 1))
 		sb.ProcessCommand(MockMessage("!help asdf", TestChannel, 3900036, TestUserBoring, i), v, 3900036, false, false)
 
-		mock.Expect(sb.DG.GuildMember, v.ID, strconv.Itoa(TestUserNew))
-		mock.Expect(sb.DG.GuildMember, v.ID, strconv.Itoa(TestUserNew))
-		mock.Expect(sb.DG.GuildMember, v.ID, strconv.Itoa(TestUserNew))
+		mock.Expect(sb.DG.GuildMember, v.ID, strconv.Itoa(TestUserNew|i))
+		mock.Expect(sb.DG.ChannelMessageSendEmbed, strconv.Itoa(TestChannel|i), MockAny{})
 		dbmock.ExpectExec("INSERT INTO debuglog .*").WillReturnResult(sqlmock.NewResult(1, 1))
-		sb.ProcessCommand(MockMessage("!about", TestChannel, 6005000, TestUserNew, 0), v, 6005000, false, false)
+		sb.ProcessCommand(MockMessage("!about", TestChannel, 6005000, TestUserNew, i), v, 6005000, false, false)
 
 		mock.Expect(sb.DG.UserChannelCreate, strconv.Itoa(TestUserBoring|i))
 		mock.Expect(sb.DG.RequestWithLockedBucket, "POST", MockAny{}, "application/json", MockAny{}, MockAny{}, 0)
@@ -137,6 +137,53 @@ func ParseArguments(s string) ([]string, []int) {
 	return r, indices
 }
 
+// ParseCommandArguments parses the arguments of a command that starts after a prefix of the given length. Like
+// ParseArguments, the indices point into the entire content, so content[indices[i]:] is the rest of the message.
+func ParseCommandArguments(content string, prefix int) ([]string, []int) {
+	args, indices := ParseArguments(content[prefix:])
+	for i := range indices {
+		indices[i] += prefix - 1
+	}
+	return args, indices
+}
+
+// CommandStage is a single command in a chain of commands. If Pipe is true, the output of the previous command is
+// added to the end of this command.
+type CommandStage struct {
+	Content string
+	Pipe    bool
+}
+
+// SplitCommandChain splits a command into the stages separated by | or ; operators. An operator only counts if it is
+// an unquoted argument of its own, so "a|b" or "|" are left alone. If raw returns true for the name of a stage's
+// command, the rest of the message belongs to that stage, because the command stores its arguments as raw text.
+func SplitCommandChain(s string, raw func(name string) bool) []CommandStage {
+	args, indices := ParseArguments(s)
+	stages := []CommandStage{}
+	start, pipe, first := 0, false, true
+	for i, arg := range args {
+		pos := indices[i] - 1
+		if first && raw != nil && raw(arg) {
+			break
+		}
+		first = false
+		if (arg == "|" || arg == ";") && s[pos] != '"' {
+			stages = append(stages, CommandStage{strings.TrimSpace(s[start:pos]), pipe})
+			start, pipe, first = pos+1, arg == "|", true
+		}
+	}
+	return append(stages, CommandStage{strings.TrimSpace(s[start:]), pipe})
+}
+
+// StripCodeBlock removes the code block that wraps most command responses
+func StripCodeBlock(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.HasPrefix(s, "```") && strings.HasSuffix(s, "```") {
+		s = strings.TrimSpace(s[3 : len(s)-3])
+	}
+	return s
+}
+
 // boolXOR constructs an XOR operator for booleans
 func boolXOR(a bool, b bool) bool {
 	return (a && !b) || (!a && b)
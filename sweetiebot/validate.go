@@ -0,0 +1,208 @@
+package sweetiebot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Config options can declare constraints using a `validate` struct tag containing a comma separated list of rules:
+//   min=N      Numbers (or the numeric values of a map, including nested maps) must be at least N
+//   max=N      Numbers (or the numeric values of a map) must be at most N
+//   nonempty   Strings can't be empty
+//   enum=a|b   The value must be one of the listed values
+//   channel    Every channel in the option must exist on this server
+//   role       Every role in the option must exist on this server
+//   language   The language must be English or have a loaded language pack
+//   strings    Every key must be a message name, and its text must have the same formatting placeholders as the English message
+
+type configRule struct {
+	name  string
+	value string
+}
+
+func parseConfigRules(tag string) (rules []configRule) {
+	if len(tag) == 0 {
+		return
+	}
+	for _, rule := range strings.Split(tag, ",") {
+		pair := strings.SplitN(strings.TrimSpace(rule), "=", 2)
+		r := configRule{name: pair[0]}
+		if len(pair) > 1 {
+			r.value = pair[1]
+		}
+		rules = append(rules, r)
+	}
+	return
+}
+
+func validateNumber(f reflect.Value, rule configRule) error {
+	var x float64
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x = float64(f.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		x = float64(f.Uint())
+	case reflect.Float32, reflect.Float64:
+		x = f.Float()
+	default:
+		return nil
+	}
+	bound, err := strconv.ParseFloat(rule.value, 64)
+	if err != nil {
+		return nil
+	}
+	if rule.name == "min" && x < bound {
+		return fmt.Errorf("must be at least %v, but was set to %v", rule.value, f.Interface())
+	}
+	if rule.name == "max" && x > bound {
+		return fmt.Errorf("must be at most %v, but was set to %v", rule.value, f.Interface())
+	}
+	return nil
+}
+
+// collectConfigValues finds every value of type T in f, including map keys, map values and nested lists
+func collectConfigValues(f reflect.Value, t reflect.Type) (r []string) {
+	if f.Type() == t {
+		return []string{f.String()}
+	}
+	switch f.Kind() {
+	case reflect.Map:
+		for _, k := range f.MapKeys() {
+			r = append(r, collectConfigValues(k, t)...)
+			r = append(r, collectConfigValues(f.MapIndex(k), t)...)
+		}
+	case reflect.Slice:
+		for i := 0; i < f.Len(); i++ {
+			r = append(r, collectConfigValues(f.Index(i), t)...)
+		}
+	}
+	return
+}
+
+func validateConfigRule(info *GuildInfo, f reflect.Value, rule configRule) error {
+	switch rule.name {
+	case "min", "max":
+		if f.Kind() == reflect.Map {
+			keys := f.MapKeys()
+			sort.Sort(valueArray(keys))
+			for _, k := range keys {
+				if err := validateConfigRule(info, f.MapIndex(k), rule); err != nil { // Recurse so nested maps check their own values
+					return fmt.Errorf("%v %s", k.Interface(), err.Error())
+				}
+			}
+			return nil
+		}
+		return validateNumber(f, rule)
+	case "nonempty":
+		if f.Kind() == reflect.String && len(f.String()) == 0 {
+			return errors.New("can't be empty")
+		}
+	case "enum":
+		options := strings.Split(rule.value, "|")
+		value := fmt.Sprint(f.Interface())
+		for _, v := range options {
+			if strings.EqualFold(v, value) {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s, but was set to %s", strings.Join(options, ", "), value)
+	case "channel":
+		if info == nil { // Without a server there's nothing to check the channels against
+			break
+		}
+		for _, v := range collectConfigValues(f, reflect.TypeOf(ChannelEmpty)) {
+			if DiscordChannel(v) == ChannelEmpty || DiscordChannel(v) == ChannelExclusion {
+				continue
+			}
+			if ch, err := info.Bot.DG.State.Channel(v); err != nil || ch.GuildID != info.ID {
+				return fmt.Errorf("%s is not a channel on this server", DiscordChannel(v).Display())
+			}
+		}
+	case "role":
+		if info == nil {
+			break
+		}
+		for _, v := range collectConfigValues(f, reflect.TypeOf(RoleEmpty)) {
+			if DiscordRole(v) == RoleEmpty || DiscordRole(v) == RoleExclusion {
+				continue
+			}
+			if _, err := info.Bot.DG.State.Role(info.ID, v); err != nil {
+				return fmt.Errorf("%s is not a role on this server", DiscordRole(v).Display())
+			}
+		}
+	case "language":
+		if lang := strings.ToLower(f.String()); lang != "" && lang != "en" {
+			if _, ok := Languages[lang]; !ok {
+				return fmt.Errorf("%s is not an available language", f.String())
+			}
+		}
+	case "strings":
+		for _, k := range f.MapKeys() {
+			if id, ok := StringID(k.String()); ok && StringNames[id] != k.String() {
+				return fmt.Errorf("%s must be written as %s", k.String(), StringNames[id])
+			}
+			if err := checkString(k.String(), f.MapIndex(k).String()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateConfigField(info *GuildInfo, field reflect.StructField, f reflect.Value) error {
+	for _, rule := range parseConfigRules(field.Tag.Get("validate")) {
+		if err := validateConfigRule(info, f, rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateOption checks a single Category.Option against its validation constraints
+func (config *BotConfig) ValidateOption(info *GuildInfo, path string) error {
+	names := strings.SplitN(strings.ToLower(path), ".", 3)
+	t := reflect.ValueOf(config).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Kind() == reflect.Struct && strings.ToLower(t.Type().Field(i).Name) == names[0] {
+			for j := 0; j < t.Field(i).NumField() && len(names) > 1; j++ {
+				field := t.Field(i).Type().Field(j)
+				if strings.ToLower(field.Name) == names[1] {
+					if err := validateConfigField(info, field, t.Field(i).Field(j)); err != nil {
+						return fmt.Errorf("%s.%s %s", t.Type().Field(i).Name, field.Name, err.Error())
+					}
+					return nil
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks every config option against its validation constraints and returns a list of violations
+func (config *BotConfig) Validate(info *GuildInfo) (violations []string) {
+	t := reflect.ValueOf(config).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Kind() == reflect.Struct {
+			f := t.Field(i)
+			for j := 0; j < f.NumField(); j++ {
+				if err := validateConfigField(info, f.Type().Field(j), f.Field(j)); err != nil {
+					violations = append(violations, fmt.Sprintf("%s.%s %s", t.Type().Field(i).Name, f.Type().Field(j).Name, err.Error()))
+				}
+			}
+		}
+	}
+	return
+}
+
+// restoreConfigField resets a config option to a previously marshalled value
+func restoreConfigField(f reflect.Value, old []byte) {
+	v := reflect.New(f.Type())
+	if err := json.Unmarshal(old, v.Interface()); err == nil {
+		f.Set(v.Elem())
+	}
+}
@@ -142,7 +142,7 @@ func (sb *SweetieBot) generateCache(webdir string) *template.Template {
 			URL:         strings.ToLower(m.Name()),
 			Config:      config,
 		}
-		for _, c := range m.Commands() {
+		for _, c := range ExpandCommands(m.Commands()) {
 			command := webCommand{
 				URL:   strings.ToLower(c.Info().Name),
 				Usage: *c.Usage(sb.EmptyGuild),
@@ -158,7 +158,7 @@ func (sb *SweetieBot) generateCache(webdir string) *template.Template {
 	if home, err := os.Create(webdir + "/help/home/index.html"); err == nil {
 		defer home.Close()
 		if err = t.ExecuteTemplate(home, "home", data); err != nil {
-			fmt.Println(err)
+			sb.Logger.Error("Error generating help page: " + err.Error())
 		}
 	}
 	{
@@ -183,13 +183,33 @@ func (sb *SweetieBot) generateCache(webdir string) *template.Template {
 			data.Title = m.Name
 			data.Index = k
 			if err = t.ExecuteTemplate(cache, "module", data); err != nil {
-				fmt.Println(err)
+				sb.Logger.Error("Error generating help page: "+err.Error(), LogFields{"module": m.Name})
 			}
 		}
 	}
 	return t
 }
 
+// monitorMux serves the metrics and health checks, which are meant for monitoring systems rather than the public
+func (sb *SweetieBot) monitorMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", sb.NewMetricsHandler())
+	mux.HandleFunc("/healthz", sb.healthHandler)
+	mux.HandleFunc("/readyz", sb.readyHandler)
+	return mux
+}
+
+// ServeMonitor serves /metrics, /healthz and /readyz on MonitorAddr. Metrics reveal which guilds the bot is in and how
+// they use it, so they get their own listener that can be bound to a private address, instead of the public webserver.
+func (sb *SweetieBot) ServeMonitor() error {
+	s := &http.Server{
+		IdleTimeout: 5 * time.Minute,
+		Addr:        sb.MonitorAddr,
+		Handler:     sb.monitorMux(),
+	}
+	return s.ListenAndServe()
+}
+
 // ServeWeb starts a webserver on :80 and optionally on :443. If you're doing a reverse-proxy via nginx, SSL terminates at nginx, so use insecure mode.
 func (sb *SweetieBot) ServeWeb() error {
 	sb.generateCache(sb.Selfhoster.GetWebDir())
@@ -198,6 +218,13 @@ func (sb *SweetieBot) ServeWeb() error {
 	mux.HandleFunc("/", sb.Selfhoster.helpHandler)
 	mux.HandleFunc("/help", sb.Selfhoster.helpHandler)
 	mux.HandleFunc("/help/", sb.Selfhoster.helpHandler)
+	mux.Handle("/api/", newAPI(sb))
+	if d, err := newDashboard(sb, sb.Selfhoster.GetWebDir()); err != nil {
+		sb.Logger.Error("Error loading the dashboard template: " + err.Error())
+	} else {
+		mux.Handle("/dashboard", d)
+		mux.Handle("/dashboard/", d)
+	}
 	sb.Selfhoster.ConfigureMux(mux)
 	if sb.WebSecure {
 		go http.ListenAndServe(":80", http.HandlerFunc(fwdhttps))
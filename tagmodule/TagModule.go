@@ -2,6 +2,7 @@ package tagmodule
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"regexp"
 	"sort"
@@ -17,12 +18,19 @@ const (
 )
 
 var tagargregex = regexp.MustCompile("[^-+()| ][^-+()|]*")
+var errMismatched = errors.New("Mismatched parentheses!")
 
 // TagModule contains commands for manipulating tags
 type TagModule struct {
 	Cache map[string]*sql.Stmt
 }
 
+func init() {
+	bot.RegisterModuleFactory("Tag", func(guild *bot.GuildInfo, deps map[bot.ModuleID]bot.Module) bot.Module {
+		return New()
+	})
+}
+
 // New instance of TagModule
 func New() *TagModule {
 	return &TagModule{make(map[string]*sql.Stmt)}
@@ -76,6 +84,46 @@ func (w *TagModule) execStatement(query string, tags string, db *bot.BotDB, args
 	return q, db.CheckError("Query: "+query, err)
 }
 
+// PickItem returns a random item matching the tag search, or any item if the search is empty or *. Returns
+// sql.ErrNoRows if no items match.
+func (w *TagModule) PickItem(arg string, info *bot.GuildInfo) (string, error) {
+	gID := bot.SBatoi(info.ID)
+	var stmt *sql.Stmt
+	var params []interface{}
+	if len(arg) == 0 || arg == "*" {
+		var err error
+		stmt, err = w.prepStatement("SELECT I.Content FROM itemtags M INNER JOIN tags T ON M.Tag = T.ID INNER JOIN items I ON M.Item = I.ID WHERE T.Guild = ? GROUP BY I.Content ORDER BY "+info.Bot.DB.Random()+" LIMIT 1", "", info.Bot.DB)
+		if err != nil {
+			return "", err
+		}
+		params = []interface{}{gID}
+	} else {
+		if !ValidateWhereClause(arg) {
+			return "", errMismatched
+		}
+		clause, tags := BuildWhereClause(arg)
+		tagIDs, err := getTagIDs(tags, gID, info.Bot.DB)
+		if err != nil {
+			return "", err
+		}
+
+		stmt, err = w.prepStatement("SELECT I.Content FROM itemtags M INNER JOIN tags T ON M.Tag = T.ID INNER JOIN items I ON M.Item = I.ID WHERE "+clause+" AND T.Guild = ? GROUP BY I.Content ORDER BY "+info.Bot.DB.Random()+" LIMIT 1", arg, info.Bot.DB)
+		if err != nil {
+			return "", err
+		}
+
+		params = make([]interface{}, len(tagIDs), len(tagIDs))
+		params = append(params, gID)
+		for k, v := range tagIDs {
+			params[k] = v
+		}
+	}
+
+	var item string
+	err := stmt.QueryRow(params...).Scan(&item)
+	return item, err
+}
+
 func getTagIDs(tags []string, guild uint64, db *bot.BotDB) ([]uint64, error) {
 	tagIDs := make([]uint64, len(tags), len(tags))
 	for k, v := range tags {
@@ -427,45 +475,18 @@ func (c *pickCommand) Process(args []string, msg *discordgo.Message, indices []i
 	if !info.Bot.DB.CheckStatus() {
 		return "```\nA temporary database outage is preventing this command from being executed.```", false, nil
 	}
-	gID := bot.SBatoi(info.ID)
-	var stmt *sql.Stmt
-	var params []interface{}
-	arg := "any tag"
-	if len(args) < 1 || args[0] == "*" {
-		var err error
-		stmt, err = c.w.prepStatement("SELECT I.Content FROM itemtags M INNER JOIN tags T ON M.Tag = T.ID INNER JOIN items I ON M.Item = I.ID WHERE T.Guild = ? GROUP BY I.Content ORDER BY RAND() LIMIT 1", "", info.Bot.DB)
-		if err != nil {
-			return bot.ReturnError(err)
-		}
-		params = []interface{}{gID}
-	} else {
+	arg := ""
+	if len(args) > 0 {
 		//arg = msg.Content[indices[0]:]
 		arg = args[0]
-		if !ValidateWhereClause(arg) {
-			return "```\nMismatched parentheses!```", false, nil
-		}
-		clause, tags := BuildWhereClause(arg)
-		tagIDs, err := getTagIDs(tags, gID, info.Bot.DB)
-		if err != nil {
-			return bot.ReturnError(err)
-		}
-
-		stmt, err = c.w.prepStatement("SELECT I.Content FROM itemtags M INNER JOIN tags T ON M.Tag = T.ID INNER JOIN items I ON M.Item = I.ID WHERE "+clause+" AND T.Guild = ? GROUP BY I.Content ORDER BY RAND() LIMIT 1", arg, info.Bot.DB)
-		if err != nil {
-			return bot.ReturnError(err)
-		}
-
-		params = make([]interface{}, len(tagIDs), len(tagIDs))
-		params = append(params, gID)
-		for k, v := range tagIDs {
-			params[k] = v
-		}
 	}
-
-	var item string
-	q := stmt.QueryRow(params...)
-	err := q.Scan(&item)
-	if err == sql.ErrNoRows {
+	item, err := c.w.PickItem(arg, info)
+	if err == errMismatched {
+		return "```\nMismatched parentheses!```", false, nil
+	} else if err == sql.ErrNoRows {
+		if len(arg) == 0 || arg == "*" {
+			arg = "any tag"
+		}
 		return fmt.Sprintf("```No items were returned by %s!```", arg), false, nil
 	} else if err != nil {
 		return bot.ReturnError(err)
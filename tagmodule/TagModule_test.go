@@ -0,0 +1,66 @@
+package tagmodule
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	bot "github.com/erikmcclure/sweetiebot/sweetiebot"
+)
+
+func TestPickItem(t *testing.T) {
+	db, err := bot.OpenSQLite("sqlite::memory:", "../docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	sb := &bot.SweetieBot{DB: db, DG: &bot.DiscordGoSession{Session: &discordgo.Session{State: discordgo.NewState()}}}
+	info := bot.NewGuildInfo(sb, &discordgo.Guild{ID: "5"})
+	w := New()
+
+	if _, err := w.PickItem("", info); err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows from an empty guild but got %v", err)
+	}
+	for _, v := range []struct {
+		item string
+		tags []string
+	}{
+		{"Rarity", []string{"pony", "unicorn"}},
+		{"Applejack", []string{"pony"}},
+	} {
+		item, err := db.AddItem(v.item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range v.tags {
+			db.CreateTag(name, 5)
+			tag, err := db.GetTag(name, 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = db.AddTag(item, tag); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for _, v := range []struct {
+		search   string
+		expected []string
+	}{
+		{"", []string{"Rarity", "Applejack"}},
+		{"*", []string{"Rarity", "Applejack"}},
+		{"unicorn", []string{"Rarity"}},
+		{"pony+-unicorn", []string{"Applejack"}},
+	} {
+		item, err := w.PickItem(v.search, info)
+		if err != nil {
+			t.Errorf("%q: %s", v.search, err)
+		} else if item != v.expected[0] && (len(v.expected) < 2 || item != v.expected[1]) {
+			t.Errorf("%q: unexpected item %q", v.search, item)
+		}
+	}
+	if _, err := w.PickItem("missing", info); err == nil {
+		t.Error("expected an error for a tag that doesn't exist")
+	}
+}
@@ -16,6 +16,12 @@ import (
 type UsersModule struct {
 }
 
+func init() {
+	bot.RegisterModuleFactory("Users", func(guild *bot.GuildInfo, deps map[bot.ModuleID]bot.Module) bot.Module {
+		return New()
+	})
+}
+
 // New instance of UsersModule
 func New() *UsersModule {
 	return &UsersModule{}
@@ -102,12 +108,15 @@ func (c *newUsersCommand) Info() *bot.CommandInfo {
 }
 
 func (c *newUsersCommand) Process(args []string, msg *discordgo.Message, indices []int, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	return bot.ProcessTypedCommand(c, args, msg, indices, info)
+}
+func (c *newUsersCommand) ProcessArgs(args *bot.CommandArgs, msg *discordgo.Message, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
 	if !info.Bot.DB.CheckStatus() {
 		return "```\nA temporary database outage is preventing this command from being executed.```", false, nil
 	}
 	maxresults := 5
-	if len(args) > 0 {
-		maxresults, _ = strconv.Atoi(args[0])
+	if args.Has("maxresults") {
+		maxresults = int(args.Int("maxresults"))
 	}
 	if maxresults < 1 {
 		return "```\nHow I return no results???```", false, nil
@@ -127,7 +136,7 @@ func (c *newUsersCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
 	return &bot.CommandUsage{
 		Desc: "Lists up to maxresults users, starting with the newest user to join the server.",
 		Params: []bot.CommandUsageParam{
-			{Name: "maxresults", Desc: "Defaults to 5 results, returns a maximum of 40.", Optional: true},
+			{Name: "maxresults", Desc: "Defaults to 5 results, returns a maximum of 40.", Optional: true, Type: bot.ParamInt},
 		},
 	}
 }
@@ -146,16 +155,13 @@ func (c *akaCommand) Name() string {
 	return "aka"
 }
 func (c *akaCommand) Process(args []string, msg *discordgo.Message, indices []int, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	return bot.ProcessTypedCommand(c, args, msg, indices, info)
+}
+func (c *akaCommand) ProcessArgs(args *bot.CommandArgs, msg *discordgo.Message, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
 	if !info.Bot.DB.CheckStatus() {
 		return "```\nA temporary database outage is preventing this command from being executed.```", false, nil
 	}
-	if len(args) < 1 {
-		return "```\nYou must provide a user to search for.```", false, nil
-	}
-	user, err := bot.ParseUser(msg.Content[indices[0]:], info)
-	if err != nil {
-		return bot.ReturnError(err)
-	}
+	user := args.User("user")
 
 	r := info.Bot.DB.GetAliases(user.Convert())
 	u, err := info.Bot.DG.GetMember(user, info.ID)
@@ -175,63 +181,24 @@ func (c *akaCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
 	return &bot.CommandUsage{
 		Desc: "Lists all known aliases of the user in question, up to a maximum of 10, with the names used the longest first.",
 		Params: []bot.CommandUsageParam{
-			{Name: "user", Desc: "A ping of the user, or simply their name.", Optional: true},
+			{Name: "user", Desc: "A ping of the user, or simply their name.", Optional: false, Type: bot.ParamUser},
 		},
 	}
 }
 
-func processDurationAndReason(args []string, msg *discordgo.Message, indices []int, ty uint8, data string, gID uint64, db *bot.BotDB) (string, error) {
-	if ty == 6 {
-		return "", errors.New("Illegal event type.")
+// scheduleEvent adds an event of the given type that fires after the duration has passed, if a duration was given
+func scheduleEvent(args *bot.CommandArgs, msg *discordgo.Message, info *bot.GuildInfo, ty uint8, data string) error {
+	if !args.Has("duration") {
+		return nil
 	}
-	reason := ""
-	if len(args) > 0 {
-		if strings.ToLower(args[0]) == "for:" {
-			if len(args) < 3 {
-				return "", errors.New("Duration should be specified as 'for: 5 DAYS' or 'for: 72 HOURS'")
-			}
-			duration, err := strconv.Atoi(args[1])
-			if err != nil {
-				return "", errors.New("Duration number was not an integer.")
-			}
-
-			t := bot.GetTimestamp(msg)
-			switch bot.ParseRepeatInterval(args[2]) {
-			case 1:
-				t = t.Add(time.Duration(duration) * time.Second)
-			case 2:
-				t = t.Add(time.Duration(duration) * time.Minute)
-			case 3:
-				t = t.Add(time.Duration(duration) * time.Hour)
-			case 4:
-				t = t.AddDate(0, 0, duration)
-			case 5:
-				t = t.AddDate(0, 0, duration*7)
-			case 6:
-				t = t.AddDate(0, duration, 0)
-			case 8:
-				t = t.AddDate(duration, 0, 0)
-			case 7, 255:
-				return "", errors.New("unrecognized interval.")
-			}
-
-			if err := db.AddSchedule(gID, t, ty, data); err != nil {
-				return "", err
-			}
-
-			scheduleID := db.FindEvent(data, gID, ty)
-			if scheduleID == nil {
-				return "", errors.New("Could not find inserted event!")
-			}
-
-			if len(args) > 3 {
-				reason = msg.Content[indices[3]:]
-			}
-		} else {
-			reason = msg.Content[indices[0]:]
-		}
+	gID := bot.SBatoi(info.ID)
+	if err := info.Bot.DB.AddSchedule(gID, bot.GetTimestamp(msg).Add(args.Duration("duration")), ty, data); err != nil {
+		return err
 	}
-	return reason, nil
+	if info.Bot.DB.FindEvent(data, gID, ty) == nil {
+		return errors.New("Could not find inserted event!")
+	}
+	return nil
 }
 
 // Ban command that tracks who banned someone, why, and optionally make the ban temporary
@@ -254,26 +221,21 @@ func (c *banCommand) Process(args []string, msg *discordgo.Message, indices []in
 	if !info.Bot.DB.CheckStatus() {
 		return "```\nA temporary database outage is preventing this command from being executed.```", false, nil
 	}
-	if len(args) < 1 {
-		return "```\nYou must specify a username (in quotes, if it has spaces).```", false, nil
-	}
-	name, err := bot.ParseUser(args[0], info)
-	if err != nil {
-		return bot.ReturnError(err)
-	}
-
+	return bot.ProcessTypedCommand(c, args, msg, indices, info)
+}
+func (c *banCommand) ProcessArgs(args *bot.CommandArgs, msg *discordgo.Message, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	name := args.User("user")
 	if info.UserIsMod(name) || info.UserIsAdmin(name) {
-		return fmt.Sprintf(bot.StringMap[bot.STRING_USERS_BAN_MOD_ERROR], info.GetUserName(name)), false, nil
+		return fmt.Sprintf(info.GetString(bot.STRING_USERS_BAN_MOD_ERROR), info.GetUserName(name)), false, nil
 	}
 
-	reason, err := processDurationAndReason(args[1:], msg, indices[1:], 0, name.String(), bot.SBatoi(info.ID), info.Bot.DB)
-	if err != nil {
+	if err := scheduleEvent(args, msg, info, 0, name.String()); err != nil {
 		return bot.ReturnError(err)
 	}
-	reason = fmt.Sprintf("Banned by %s#%s for %s", msg.Author.Username, msg.Author.Discriminator, reason)
+	reason := fmt.Sprintf("Banned by %s#%s for %s", msg.Author.Username, msg.Author.Discriminator, args.String("reason"))
 	username := info.GetUserName(name)
 
-	err = info.Bot.DG.GuildBanCreateWithReason(info.ID, name.String(), reason, 1) // Note that this will probably generate a SawBan event
+	err := info.Bot.DG.GuildBanCreateWithReason(info.ID, name.String(), reason, 1) // Note that this will probably generate a SawBan event
 	if err != nil {
 		return bot.ReturnError(err)
 	}
@@ -283,9 +245,9 @@ func (c *banCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
 	return &bot.CommandUsage{
 		Desc: "Bans the given user. Examples: `'" + info.Config.Basic.CommandPrefix + "ban @CrystalFlash for: 5 MINUTES because he's a dunce` or `" + info.Config.Basic.CommandPrefix + "ban \"Name With Spaces\" caught stealing cookies`",
 		Params: []bot.CommandUsageParam{
-			{Name: "user", Desc: "A ping of the user, or simply their name. If the name has spaces, this argument must be put in quotes.", Optional: false},
-			{Name: "for: duration", Desc: "If the keyword `for:` is used after the username, looks for a duration of the form `for: 50 MINUTES` and creates an unban event that will be fired after that much time has passed from now.", Optional: true},
-			{Name: "reason", Desc: "The rest of the message is treated as a reason for the ban.", Optional: true},
+			{Name: "user", Desc: "A ping of the user, or simply their name. If the name has spaces, this argument must be put in quotes.", Optional: false, Type: bot.ParamUser},
+			{Name: "duration", Desc: "If the keyword `for:` is used after the username, looks for a duration of the form `for: 50 MINUTES` and creates an unban event that will be fired after that much time has passed from now.", Optional: true, Type: bot.ParamDuration},
+			{Name: "reason", Desc: "The rest of the message is treated as a reason for the ban.", Optional: true, Type: bot.ParamRest},
 		},
 	}
 }
@@ -350,15 +312,13 @@ func (c *timeCommand) Process(args []string, msg *discordgo.Message, indices []i
 	if !info.Bot.DB.CheckStatus() {
 		return "```\nA temporary database outage is preventing this command from being executed.```", false, nil
 	}
-	if len(args) < 1 {
+	return bot.ProcessTypedCommand(c, args, msg, indices, info)
+}
+func (c *timeCommand) ProcessArgs(args *bot.CommandArgs, msg *discordgo.Message, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	if !args.Has("user") {
 		return "```\nThis server's local time is: " + info.ApplyTimezone(bot.GetTimestamp(msg), bot.UserEmpty).Format("Jan 2, 3:04pm```"), false, nil
 	}
-
-	user, err := bot.ParseUser(msg.Content[indices[0]:], info)
-	if err != nil {
-		return bot.ReturnError(err)
-	}
-	tz := info.Bot.DB.GetTimeZone(user.Convert())
+	tz := info.Bot.DB.GetTimeZone(args.User("user").Convert())
 	if tz == nil {
 		return "```\nThat user has not specified what their timezone is.```", false, nil
 	}
@@ -368,7 +328,7 @@ func (c *timeCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
 	return &bot.CommandUsage{
 		Desc: "Gets the local time for the specified user, or simply gets the local time for this server.",
 		Params: []bot.CommandUsageParam{
-			{Name: "user", Desc: "A ping of the user, or simply their name.", Optional: true},
+			{Name: "user", Desc: "A ping of the user, or simply their name.", Optional: true, Type: bot.ParamUser},
 		},
 	}
 }
@@ -447,17 +407,14 @@ func (c *userInfoCommand) Name() string {
 	return "UserInfo"
 }
 func (c *userInfoCommand) Process(args []string, msg *discordgo.Message, indices []int, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	return bot.ProcessTypedCommand(c, args, msg, indices, info)
+}
+func (c *userInfoCommand) ProcessArgs(args *bot.CommandArgs, msg *discordgo.Message, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
 	if !info.Bot.DB.CheckStatus() {
 		return "```\nA temporary database outage is preventing this command from being executed.```", false, nil
 	}
-	if len(args) < 1 {
-		return "```\nYou must provide a user to search for.```", false, nil
-	}
 
-	user, err := bot.ParseUser(msg.Content[indices[0]:], info)
-	if err != nil {
-		return bot.ReturnError(err)
-	}
+	user := args.User("user")
 	id := user.Convert()
 	aliases := info.Bot.DB.GetAliases(id)
 	dbuser, lastseen, tz, _ := info.Bot.DB.GetUser(id)
@@ -529,7 +486,7 @@ func (c *userInfoCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
 	return &bot.CommandUsage{
 		Desc: "Lists the ID, username, nickname, timezone, roles, avatar, join date, and other information about a given user.",
 		Params: []bot.CommandUsageParam{
-			{Name: "user", Desc: "A ping of the user, or simply their name.", Optional: false},
+			{Name: "user", Desc: "A ping of the user, or simply their name.", Optional: false, Type: bot.ParamUser},
 		},
 	}
 }
@@ -604,46 +561,37 @@ type silenceCommand struct {
 func (c *silenceCommand) Info() *bot.CommandInfo {
 	return &bot.CommandInfo{
 		Name:      "Silence",
-		Usage:     bot.StringMap[bot.STRING_USERS_SILENCE_USAGE],
+		Usage:     bot.DefaultString(bot.STRING_USERS_SILENCE_USAGE),
 		Sensitive: true,
 	}
 }
 
 func (c *silenceCommand) Process(args []string, msg *discordgo.Message, indices []int, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
 	if len(args) < 1 {
-		return bot.StringMap[bot.STRING_USERS_SILENCE_ARG_ERROR], false, nil
-	}
-	index := len(args)
-	for i := 1; i < len(args); i++ {
-		if strings.ToLower(args[i]) == "for:" {
-			index = i
-			break
-		}
-	}
-
-	user, err := bot.ParseUser(strings.Join(args[0:index], " "), info)
-	if err != nil {
-		return bot.ReturnError(err)
+		return info.GetString(bot.STRING_USERS_SILENCE_ARG_ERROR), false, nil
 	}
-
+	return bot.ProcessTypedCommand(c, args, msg, indices, info)
+}
+func (c *silenceCommand) ProcessArgs(args *bot.CommandArgs, msg *discordgo.Message, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	user := args.User("user")
 	if info.UserIsMod(user) || info.UserIsAdmin(user) {
-		return fmt.Sprintf(bot.StringMap[bot.STRING_USERS_SILENCE_MOD_ERROR], info.GetUserName(user)), false, nil
+		return fmt.Sprintf(info.GetString(bot.STRING_USERS_SILENCE_MOD_ERROR), info.GetUserName(user)), false, nil
 	}
 
 	timeout, err := info.TimeoutMember(user.String())
 	if err != nil {
-		return fmt.Sprintf(bot.StringMap[bot.STRING_USERS_SILENCE_ERROR], info.GetUserName(user), info.ResolveRoleAddError(err).Error()), false, nil
+		return fmt.Sprintf(info.GetString(bot.STRING_USERS_SILENCE_ERROR), info.GetUserName(user), info.ResolveRoleAddError(err).Error()), false, nil
 	} else if timeout != time.Duration(0) {
-		return fmt.Sprintf(bot.StringMap[bot.STRING_USERS_SILENCE_WILL_BE_UNSILENCED], info.GetUserName(user), bot.TimeDiff(timeout)), false, nil
+		return fmt.Sprintf(info.GetString(bot.STRING_USERS_SILENCE_WILL_BE_UNSILENCED), info.GetUserName(user), bot.TimeDiff(timeout)), false, nil
 	}
-	return fmt.Sprintf(bot.StringMap[bot.STRING_USERS_SILENCE], info.GetUserName(user), ""), false, nil
+	return fmt.Sprintf(info.GetString(bot.STRING_USERS_SILENCE), info.GetUserName(user), ""), false, nil
 }
 func (c *silenceCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
 	return &bot.CommandUsage{
-		Desc: bot.StringMap[bot.STRING_USERS_SILENCE_DESCRIPTION],
+		Desc: info.GetString(bot.STRING_USERS_SILENCE_DESCRIPTION),
 		Params: []bot.CommandUsageParam{
-			{Name: "user", Desc: bot.StringMap[bot.STRING_USERS_SILENCE_USER], Optional: false},
-			{Name: "for: duration", Desc: bot.StringMap[bot.STRING_USERS_SILENCE_DURATION], Optional: true},
+			{Name: "user", Desc: info.GetString(bot.STRING_USERS_SILENCE_USER), Optional: false, Type: bot.ParamUser},
+			{Name: "duration", Desc: info.GetString(bot.STRING_USERS_SILENCE_DURATION), Optional: true, Type: bot.ParamDuration},
 		},
 	}
 }
@@ -663,28 +611,12 @@ func (c *assignRoleCommand) Process(args []string, msg *discordgo.Message, indic
 	if len(args) < 2 {
 		return "```\nYou must provide a role to assign and a user to assign it to.```", false, nil
 	}
-	index := len(args)
-	for i := 1; i < len(args); i++ {
-		if strings.ToLower(args[i]) == "for:" {
-			index = i
-			break
-		}
-	}
-
-	g, err := info.GetGuild()
-	role, err := bot.ParseRole(args[0], g)
-	if err != nil {
-		return bot.ReturnError(err)
-	}
-
-	user, err := bot.ParseUser(strings.Join(args[1:index], " "), info)
-	if err != nil {
-		return bot.ReturnError(err)
-	}
-
-	gID := bot.SBatoi(info.ID)
-	reason, err := processDurationAndReason(args[index:], msg, indices[index:], 9, user.String()+"|"+role.String(), gID, info.Bot.DB)
-	if err != nil {
+	return bot.ProcessTypedCommand(c, args, msg, indices, info)
+}
+func (c *assignRoleCommand) ProcessArgs(args *bot.CommandArgs, msg *discordgo.Message, info *bot.GuildInfo) (string, bool, *discordgo.MessageEmbed) {
+	role := args.Role("role")
+	user := args.User("user")
+	if err := scheduleEvent(args, msg, info, 9, user.String()+"|"+role.String()); err != nil {
 		return bot.ReturnError(err)
 	}
 
@@ -694,15 +626,16 @@ func (c *assignRoleCommand) Process(args []string, msg *discordgo.Message, indic
 	} else if code == 1 {
 		var t *time.Time
 		if info.Bot.DB.Status.Get() {
-			t = info.Bot.DB.GetScheduleDate(gID, 9, user.String()+"|"+role.String())
+			t = info.Bot.DB.GetScheduleDate(bot.SBatoi(info.ID), 9, user.String()+"|"+role.String())
 		}
 		if t == nil {
 			return "```\n" + info.GetUserName(user) + " already has that role!```", false, nil
 		}
 		return fmt.Sprintf("```\n%s already has that role, which will be removed in %s```", info.GetUserName(user), bot.TimeDiff(t.Sub(bot.GetTimestamp(msg)))), false, nil
 	}
-	if len(reason) > 0 {
-		reason = " because " + reason
+	reason := ""
+	if args.Has("reason") {
+		reason = " because " + args.String("reason")
 	}
 	return fmt.Sprintf("```\nAssigned the %s role to %s%s.```", role.Show(info), info.GetUserName(user), reason), false, nil
 }
@@ -710,9 +643,10 @@ func (c *assignRoleCommand) Usage(info *bot.GuildInfo) *bot.CommandUsage {
 	return &bot.CommandUsage{
 		Desc: "Assigns the role to the given user, and optionally adds an event to remove it in the future.",
 		Params: []bot.CommandUsageParam{
-			{Name: "role", Desc: "The role to add, either as a ping or as the name, but must be in quotes if it has spaces.", Optional: false},
-			{Name: "user", Desc: "A ping of the user, or simply their name.", Optional: false},
-			{Name: "for: duration", Desc: "If the keyword `for:` is used after the username, looks for a duration of the form `for: 50 MINUTES` and creates an event that will remove the role after that much time has passed from now.", Optional: true},
+			{Name: "role", Desc: "The role to add, either as a ping or as the name, but must be in quotes if it has spaces.", Optional: false, Type: bot.ParamRole},
+			{Name: "user", Desc: "A ping of the user, or simply their name.", Optional: false, Type: bot.ParamUser},
+			{Name: "duration", Desc: "If the keyword `for:` is used after the username, looks for a duration of the form `for: 50 MINUTES` and creates an event that will remove the role after that much time has passed from now.", Optional: true, Type: bot.ParamDuration},
+			{Name: "reason", Desc: "The rest of the message is treated as a reason for assigning the role.", Optional: true, Type: bot.ParamRest},
 		},
 	}
 }
@@ -19,6 +19,12 @@ type WittyModule struct {
 	remarks      [][]string
 }
 
+func init() {
+	bot.RegisterModuleFactory("Witty", func(guild *bot.GuildInfo, deps map[bot.ModuleID]bot.Module) bot.Module {
+		return New(guild)
+	})
+}
+
 // New instance of WittyModule
 func New(guild *bot.GuildInfo) *WittyModule {
 	w := &WittyModule{lastcomment: 0, lastdelete: 0}